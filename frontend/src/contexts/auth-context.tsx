@@ -7,6 +7,12 @@ import {
   useState,
 } from "react";
 
+import {
+  consumeLoginFragment,
+  refreshAccessToken,
+  removeAuthToken,
+  setRefreshToken,
+} from "@/lib/api-client";
 import { API_BASE_URL } from "@/types/api";
 
 interface User {
@@ -20,7 +26,10 @@ interface AuthContextType {
   isAuthenticated: boolean;
   isLoading: boolean;
   isAuthEnabled: boolean;
-  login: (username: string, password: string) => Promise<void>;
+  // Resolves to a challenge when the account needs a two-factor code,
+  // which verifyTwoFactor then completes
+  login: (username: string, password: string) => Promise<string | null>;
+  verifyTwoFactor: (challenge: string, code: string) => Promise<void>;
   logout: () => void;
   checkAuth: () => Promise<boolean>;
 }
@@ -60,16 +69,28 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
 
   const verifyToken = useCallback(async (tokenToVerify: string) => {
     try {
-      const response = await fetch(`${API_BASE_URL}/api/v1/auth/me`, {
-        headers: {
-          Authorization: `Bearer ${tokenToVerify}`,
-        },
-      });
+      const fetchMe = (token: string) =>
+        fetch(`${API_BASE_URL}/api/v1/auth/me`, {
+          headers: {
+            Authorization: `Bearer ${token}`,
+          },
+        });
+
+      let verifiedToken = tokenToVerify;
+      let response = await fetchMe(verifiedToken);
+      if (response.status === 401) {
+        // The access token may just have expired
+        const refreshed = await refreshAccessToken();
+        if (refreshed) {
+          verifiedToken = refreshed;
+          response = await fetchMe(verifiedToken);
+        }
+      }
 
       if (response.ok) {
         const data = await response.json();
         setUser(data.user);
-        setToken(tokenToVerify);
+        setToken(verifiedToken);
         setIsAuthEnabled(true);
       } else if (response.status === 404) {
         // Auth endpoint doesn't exist - auth is disabled
@@ -79,7 +100,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
         setUser(null);
       } else {
         // Token is invalid, clear it
-        localStorage.removeItem(TOKEN_KEY);
+        removeAuthToken();
         setToken(null);
         setUser(null);
       }
@@ -94,6 +115,8 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
   }, []);
 
   useEffect(() => {
+    // A single sign-on login comes back with the session in the fragment
+    consumeLoginFragment();
     const storedToken = localStorage.getItem(TOKEN_KEY);
     if (storedToken) {
       setToken(storedToken);
@@ -135,6 +158,17 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     }
   };
 
+  const storeLogin = (data: {
+    token: string;
+    refresh_token?: string;
+    user: User;
+  }) => {
+    localStorage.setItem(TOKEN_KEY, data.token);
+    setRefreshToken(data.refresh_token);
+    setToken(data.token);
+    setUser(data.user);
+  };
+
   const login = async (username: string, password: string) => {
     try {
       const response = await fetch(`${API_BASE_URL}/api/v1/auth/login`, {
@@ -151,19 +185,45 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
       }
 
       const data = await response.json();
+      if (data.two_factor_required) {
+        return data.challenge as string;
+      }
 
-      // Store token and user
-      localStorage.setItem(TOKEN_KEY, data.token);
-      setToken(data.token);
-      setUser(data.user);
+      storeLogin(data);
+      return null;
     } catch (error) {
       console.error("Login error:", error);
       throw error;
     }
   };
 
+  const verifyTwoFactor = async (challenge: string, code: string) => {
+    const response = await fetch(`${API_BASE_URL}/api/v1/auth/login/2fa`, {
+      method: "POST",
+      headers: {
+        "Content-Type": "application/json",
+      },
+      body: JSON.stringify({ challenge, code }),
+    });
+
+    if (!response.ok) {
+      const errorText = await response.text();
+      throw new Error(errorText || "Verification failed");
+    }
+
+    storeLogin(await response.json());
+  };
+
   const logout = () => {
-    localStorage.removeItem(TOKEN_KEY);
+    const storedToken = localStorage.getItem(TOKEN_KEY);
+    if (storedToken) {
+      // End the session server-side so the tokens stop working
+      fetch(`${API_BASE_URL}/api/v1/auth/logout`, {
+        method: "POST",
+        headers: { Authorization: `Bearer ${storedToken}` },
+      }).catch((error) => console.error("Failed to log out:", error));
+    }
+    removeAuthToken();
     setToken(null);
     setUser(null);
   };
@@ -175,6 +235,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     isLoading,
     isAuthEnabled,
     login,
+    verifyTwoFactor,
     logout,
     checkAuth,
   };
@@ -1,4 +1,45 @@
+import { API_BASE_URL } from "@/types/api";
+
 const TOKEN_KEY = "vps-monitor_auth_token";
+const REFRESH_TOKEN_KEY = "vps-monitor_refresh_token";
+
+let refreshInFlight: Promise<string | null> | null = null;
+
+/**
+ * Swaps the stored refresh token for a new access token. Concurrent callers
+ * share one request, since each refresh token can only be used once.
+ */
+export function refreshAccessToken(): Promise<string | null> {
+  const refreshToken = localStorage.getItem(REFRESH_TOKEN_KEY);
+  if (!refreshToken) {
+    return Promise.resolve(null);
+  }
+  if (!refreshInFlight) {
+    refreshInFlight = (async () => {
+      try {
+        const response = await fetch(`${API_BASE_URL}/api/v1/auth/refresh`, {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ refresh_token: refreshToken }),
+        });
+        if (!response.ok) {
+          localStorage.removeItem(REFRESH_TOKEN_KEY);
+          return null;
+        }
+        const data = await response.json();
+        localStorage.setItem(TOKEN_KEY, data.token);
+        localStorage.setItem(REFRESH_TOKEN_KEY, data.refresh_token);
+        return data.token as string;
+      } catch (error) {
+        console.error("Failed to refresh session:", error);
+        return null;
+      } finally {
+        refreshInFlight = null;
+      }
+    })();
+  }
+  return refreshInFlight;
+}
 
 /**
  * Authenticated fetch wrapper that automatically adds Authorization header
@@ -24,15 +65,28 @@ export async function authenticatedFetch(
     headers.set("Authorization", `Bearer ${token}`);
   }
 
-  const response = await fetch(input, {
+  let response = await fetch(input, {
     ...init,
     headers,
   });
 
+  // Access tokens are short-lived: refresh once and retry
+  if (response.status === 401) {
+    const refreshed = await refreshAccessToken();
+    if (refreshed) {
+      headers.set("Authorization", `Bearer ${refreshed}`);
+      response = await fetch(input, {
+        ...init,
+        headers,
+      });
+    }
+  }
+
   // Handle 401 Unauthorized - token expired or invalid
   // Only redirect if auth is enabled (check by seeing if login endpoint exists)
   if (response.status === 401) {
     localStorage.removeItem(TOKEN_KEY);
+    localStorage.removeItem(REFRESH_TOKEN_KEY);
 
     // Check if auth is enabled before redirecting
     try {
@@ -73,8 +127,41 @@ export function setAuthToken(token: string): void {
 }
 
 /**
- * Helper to remove the auth token
+ * Stores the session a single sign-on login hands over in the URL fragment
+ * (#token=...&refresh_token=...) and removes it from the address bar.
+ * Returns whether the fragment held a session.
+ */
+export function consumeLoginFragment(): boolean {
+  const params = new URLSearchParams(window.location.hash.slice(1));
+  const token = params.get("token");
+  if (!token) {
+    return false;
+  }
+  localStorage.setItem(TOKEN_KEY, token);
+  setRefreshToken(params.get("refresh_token") ?? undefined);
+  window.history.replaceState(
+    window.history.state,
+    "",
+    window.location.pathname + window.location.search
+  );
+  return true;
+}
+
+/**
+ * Helper to set the refresh token
+ */
+export function setRefreshToken(token: string | undefined): void {
+  if (token) {
+    localStorage.setItem(REFRESH_TOKEN_KEY, token);
+  } else {
+    localStorage.removeItem(REFRESH_TOKEN_KEY);
+  }
+}
+
+/**
+ * Helper to remove the auth and refresh tokens
  */
 export function removeAuthToken(): void {
   localStorage.removeItem(TOKEN_KEY);
+  localStorage.removeItem(REFRESH_TOKEN_KEY);
 }
@@ -1,11 +1,13 @@
 import { redirect } from "@tanstack/react-router";
 
+import { consumeLoginFragment } from "@/lib/api-client";
 import { API_BASE_URL } from "@/types/api";
 
 /**
  * Auth guard function that checks if authentication is enabled and redirects to login if needed.
  *
  * This function:
+ * - Stores the session a single sign-on login left in the URL fragment
  * - Gets the token from localStorage
  * - If no token exists, checks if auth is enabled by calling the login endpoint
  * - Returns (allows access) when the endpoint responds with 404 (auth disabled)
@@ -13,6 +15,7 @@ import { API_BASE_URL } from "@/types/api";
  * - Preserves existing catch behavior that only rethrows redirect errors
  */
 export async function requireAuthIfEnabled(): Promise<void> {
+  consumeLoginFragment();
   const token = localStorage.getItem("vps-monitor_auth_token");
 
   // If no token, check if auth is required
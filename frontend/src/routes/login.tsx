@@ -1,11 +1,12 @@
 import { createFileRoute, useNavigate } from "@tanstack/react-router";
-import { useState } from "react";
+import { useEffect, useState } from "react";
 
 import { Button } from "@/components/ui/button";
 import { Card } from "@/components/ui/card";
 import { Input } from "@/components/ui/input";
 import { Label } from "@/components/ui/label";
 import { useAuth } from "@/contexts/auth-context";
+import { API_BASE_URL } from "@/types/api";
 
 export const Route = createFileRoute("/login")({
   component: LoginPage,
@@ -14,19 +15,45 @@ export const Route = createFileRoute("/login")({
 function LoginPage() {
   const [username, setUsername] = useState("");
   const [password, setPassword] = useState("");
+  const [challenge, setChallenge] = useState<string | null>(null);
+  const [code, setCode] = useState("");
   const [error, setError] = useState("");
   const [isLoading, setIsLoading] = useState(false);
+  const [isSSOEnabled, setIsSSOEnabled] = useState(false);
 
-  const { login } = useAuth();
+  const { login, verifyTwoFactor, isAuthenticated } = useAuth();
   const navigate = useNavigate();
 
+  // Single sign-on may send the browser back here with a session
+  useEffect(() => {
+    if (isAuthenticated) {
+      navigate({ to: "/" });
+    }
+  }, [isAuthenticated, navigate]);
+
+  useEffect(() => {
+    fetch(`${API_BASE_URL}/api/v1/auth/oidc`)
+      .then((response) => (response.ok ? response.json() : null))
+      .then((data) => setIsSSOEnabled(data?.enabled === true))
+      .catch((error) => console.error("Failed to check SSO status:", error));
+  }, []);
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setError("");
     setIsLoading(true);
 
     try {
-      await login(username, password);
+      if (challenge) {
+        await verifyTwoFactor(challenge, code);
+      } else {
+        const next = await login(username, password);
+        if (next) {
+          // The account has two-factor authentication: ask for a code
+          setChallenge(next);
+          return;
+        }
+      }
       // Redirect to home page on successful login
       navigate({ to: "/" });
     } catch (err) {
@@ -51,33 +78,56 @@ function LoginPage() {
         </div>
 
         <form onSubmit={handleSubmit} className="space-y-4">
-          <div className="space-y-2">
-            <Label htmlFor="username">Username</Label>
-            <Input
-              id="username"
-              type="text"
-              value={username}
-              onChange={(e) => setUsername(e.target.value)}
-              placeholder="Enter your username"
-              required
-              autoComplete="username"
-              disabled={isLoading}
-            />
-          </div>
-
-          <div className="space-y-2">
-            <Label htmlFor="password">Password</Label>
-            <Input
-              id="password"
-              type="password"
-              value={password}
-              onChange={(e) => setPassword(e.target.value)}
-              placeholder="Enter your password"
-              required
-              autoComplete="current-password"
-              disabled={isLoading}
-            />
-          </div>
+          {challenge ? (
+            <div className="space-y-2">
+              <Label htmlFor="code">Authentication code</Label>
+              <Input
+                id="code"
+                type="text"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                placeholder="6-digit code or recovery code"
+                required
+                autoFocus
+                autoComplete="one-time-code"
+                disabled={isLoading}
+              />
+              <p className="text-xs text-muted-foreground">
+                Enter the code from your authenticator app, or one of your
+                recovery codes.
+              </p>
+            </div>
+          ) : (
+            <>
+              <div className="space-y-2">
+                <Label htmlFor="username">Username</Label>
+                <Input
+                  id="username"
+                  type="text"
+                  value={username}
+                  onChange={(e) => setUsername(e.target.value)}
+                  placeholder="Enter your username"
+                  required
+                  autoComplete="username"
+                  disabled={isLoading}
+                />
+              </div>
+
+              <div className="space-y-2">
+                <Label htmlFor="password">Password</Label>
+                <Input
+                  id="password"
+                  type="password"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  placeholder="Enter your password"
+                  required
+                  autoComplete="current-password"
+                  disabled={isLoading}
+                />
+              </div>
+            </>
+          )}
 
           {error && (
             <div className="p-3 text-sm text-red-600 bg-red-50 dark:bg-red-900/20 dark:text-red-400 rounded-md border border-red-200 dark:border-red-800">
@@ -86,8 +136,46 @@ function LoginPage() {
           )}
 
           <Button type="submit" className="w-full" disabled={isLoading}>
-            {isLoading ? "Signing in..." : "Sign in"}
+            {isLoading ? "Signing in..." : challenge ? "Verify" : "Sign in"}
           </Button>
+
+          {!challenge && isSSOEnabled && (
+            <>
+              <div className="flex items-center gap-3 text-xs text-muted-foreground">
+                <div className="h-px flex-1 bg-border" />
+                or
+                <div className="h-px flex-1 bg-border" />
+              </div>
+              <Button
+                type="button"
+                variant="outline"
+                className="w-full"
+                disabled={isLoading}
+                onClick={() => {
+                  // The server redirects to the identity provider and back
+                  window.location.href = `${API_BASE_URL}/api/v1/auth/oidc/login`;
+                }}
+              >
+                Sign in with SSO
+              </Button>
+            </>
+          )}
+
+          {challenge && (
+            <Button
+              type="button"
+              variant="ghost"
+              className="w-full"
+              disabled={isLoading}
+              onClick={() => {
+                setChallenge(null);
+                setCode("");
+                setError("");
+              }}
+            >
+              Back
+            </Button>
+          )}
         </form>
 
         <div className="text-center text-xs text-muted-foreground">
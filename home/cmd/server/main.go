@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	_ "time/tzdata" // maintenance window time zones on images without tzdata
 
 	"github.com/hhftechnology/vps-monitor/internal/alerts"
 	"github.com/hhftechnology/vps-monitor/internal/api"
+	"github.com/hhftechnology/vps-monitor/internal/audit"
 	"github.com/hhftechnology/vps-monitor/internal/auth"
 	"github.com/hhftechnology/vps-monitor/internal/bot"
 	"github.com/hhftechnology/vps-monitor/internal/config"
@@ -15,7 +19,10 @@ import (
 	"github.com/hhftechnology/vps-monitor/internal/coolify"
 	"github.com/hhftechnology/vps-monitor/internal/docker"
 	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/notify"
+	"github.com/hhftechnology/vps-monitor/internal/push"
 	"github.com/hhftechnology/vps-monitor/internal/scanner"
+	"github.com/hhftechnology/vps-monitor/internal/server"
 	"github.com/hhftechnology/vps-monitor/internal/services"
 	"github.com/hhftechnology/vps-monitor/internal/system"
 )
@@ -52,6 +59,17 @@ func main() {
 		log.Println("Authentication is ENABLED")
 	}
 
+	// OIDC single sign-on issues the same JWTs, so it needs auth enabled
+	var oidc *auth.OIDC
+	if cfg.OIDC.Enabled() {
+		oidc = auth.NewOIDC(cfg.OIDC)
+		if authService == nil || authService.IsDisabled() {
+			log.Println("OIDC login is configured but unavailable while authentication is disabled")
+		} else {
+			log.Printf("OIDC login is ENABLED (issuer %s)", cfg.OIDC.IssuerURL)
+		}
+	}
+
 	if cfg.ReadOnly {
 		log.Println("READ-ONLY MODE is ENABLED - all mutating operations are disabled")
 		log.Println("   To disable read-only mode, set: READONLY_MODE=false or unset the variable")
@@ -79,38 +97,90 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to open scan database: %v", err)
 	}
-	defer scanDB.Close()
 	log.Printf("Scan database opened at %s", dbPath)
 
+	// User accounts log in next to the built-in admin; API tokens are
+	// accepted next to JWTs, which belong to revocable sessions
+	users := auth.NewUsers(scanDB)
+	tokens := auth.NewTokens(scanDB)
+	sessions := auth.NewSessions(scanDB, cfg.Sessions)
+	twoFactor := auth.NewTwoFactor(scanDB)
+	authService.SetUsers(users)
+	authService.SetTokens(tokens)
+	authService.SetSessions(sessions)
+	authService.SetTwoFactor(twoFactor)
+
+	// Changes made through the API are recorded in the audit log
+	auditLog := audit.NewLog(scanDB, cfg.Audit.Retention)
+	log.Printf("Audit log is persisted (retention: %s)", cfg.Audit.Retention)
+
 	// Alert monitor / stats collection
 	// alertMonitor starts nil and is injected after creation when alerts are enabled.
 	var alertMonitor *alerts.Monitor
 	registry := services.NewRegistry(multiHostClient, coolifyClient, authService, cfg, alertMonitor)
 
+	// Mobile push: UnifiedPush needs no server setup, FCM a service account key
+	pushService := push.NewService(scanDB)
+	pushService.SetSender(models.PushUnifiedPush, push.NewUnifiedPushSender(cfg.Push.UnifiedPushAllowedHosts))
+	// Devices only receive the alerts their owner may see
+	pushService.SetUserLookup(func(username string) (models.User, bool, error) {
+		return registry.Auth().LookupUser(username)
+	})
+	if cfg.Push.FCMCredentialsFile != "" {
+		fcm, err := push.NewFCMSender(cfg.Push.FCMCredentialsFile)
+		if err != nil {
+			log.Fatalf("Failed to set up FCM push: %v", err)
+		}
+		pushService.SetSender(models.PushFCM, fcm)
+		log.Println("FCM push notifications are ENABLED")
+	}
+
 	var statsCollector *containerstats.Collector
+	notifier := notify.NewDispatcher(cfg)
+	notifier.SetDeliveryStore(scanDB)
 	if cfg.Alerts.Enabled {
 		alertMonitor = alerts.NewMonitor(multiHostClient, &cfg.Alerts, scanDB, containerStatsRetention)
+		alertMonitor.SetNotifier(notifier)
+		alertMonitor.SetPusher(pushService)
 		registry.SwapAlerts(alertMonitor)
 		alertMonitor.Start()
-		defer alertMonitor.Stop()
 		log.Println("Alert monitoring is ENABLED")
-		log.Printf("   CPU threshold: %.1f%%, Memory threshold: %.1f%%, Check interval: %s",
-			cfg.Alerts.CPUThreshold, cfg.Alerts.MemoryThreshold, cfg.Alerts.CheckInterval)
+		log.Printf("   Check interval: %s (rules are managed at /api/v1/alerts/rules)", cfg.Alerts.CheckInterval)
+		log.Printf("   Alert history is persisted (retention: %s)", cfg.Alerts.HistoryRetention)
 		if cfg.Alerts.WebhookURL != "" {
 			log.Println("   Webhook notifications are ENABLED")
 		}
+		if n := len(cfg.Notifications.Channels); n > 0 {
+			log.Printf("   %d notification channel(s) configured", n)
+		}
 	} else {
 		statsCollector = containerstats.NewCollector(registry, scanDB, cfg.Stats.SampleInterval, containerStatsRetention)
 		statsCollector.Start()
-		defer statsCollector.Stop()
 		log.Println("Alert monitoring is DISABLED")
 		log.Println("   Background container stats collection remains ENABLED")
 		log.Println("   To enable alerts, set: ALERTS_ENABLED=true")
 	}
 
+	// Repeated failed logins lock out the client IP and the username; each
+	// lockout is raised as an alert when alerts are enabled
+	loginGuard := auth.NewLoginGuard(cfg.LoginProtection)
+	loginGuard.SetLockoutHandler(func(lockout models.LoginLockout) {
+		if monitor := registry.Alerts(); monitor != nil {
+			monitor.ReportLoginLockout(lockout)
+		}
+	})
+	if cfg.LoginProtection.MaxFailures > 0 {
+		log.Printf("Login lockout after %d failures (%s, up to %s)", cfg.LoginProtection.MaxFailures, cfg.LoginProtection.Lockout, cfg.LoginProtection.MaxLockout)
+	}
+	log.Printf("Rate limits: login %s, exec %s, scans %s", cfg.RateLimits.Login, cfg.RateLimits.Exec, cfg.RateLimits.Scans)
+	if cfg.Metrics.Token != "" {
+		log.Println("Prometheus metrics at /metrics (METRICS_TOKEN required)")
+	} else {
+		log.Println("Prometheus metrics at /metrics (API authentication)")
+	}
+
 	telegramBot := bot.NewService(registry, cfg.Bot)
 	telegramBot.Start()
-	defer telegramBot.Stop()
 
 	// Build initial scanner config from env, then load/merge with DB settings
 	envScannerCfg := configToScannerConfig(cfg.Scanner)
@@ -131,7 +201,6 @@ func main() {
 	} else {
 		log.Println("Auto-scan is DISABLED")
 	}
-	defer autoScanner.Stop()
 
 	// Hot-reload callback
 	manager.OnChange(func(newCfg *config.Config) {
@@ -159,7 +228,12 @@ func main() {
 		// Recreate auth service from file config (env-based auth is immutable)
 		fc := manager.FileConfigSnapshot()
 		if manager.Sources().Auth == config.SourceFile && fc.Auth != nil {
-			registry.SwapAuth(auth.NewServiceFromFileConfig(fc.Auth))
+			newAuth := auth.NewServiceFromFileConfig(fc.Auth)
+			newAuth.SetUsers(users)
+			newAuth.SetTokens(tokens)
+			newAuth.SetSessions(sessions)
+			newAuth.SetTwoFactor(twoFactor)
+			registry.SwapAuth(newAuth)
 		}
 
 		// Update scanner configuration from DB (with env overrides)
@@ -179,6 +253,7 @@ func main() {
 		}
 
 		telegramBot.UpdateConfig(newCfg.Bot)
+		notifier.Update(newCfg)
 
 		log.Println("Configuration reloaded successfully")
 	})
@@ -189,12 +264,81 @@ func main() {
 		ScanDB:         scanDB,
 		ScannerService: scannerService,
 		AutoScanner:    autoScanner,
+		PushService:    pushService,
+		Users:          users,
+		Tokens:         tokens,
+		OIDC:           oidc,
+		Sessions:       sessions,
+		TwoFactor:      twoFactor,
+		Audit:          auditLog,
+		LoginGuard:     loginGuard,
 	}
 	apiRouter := api.NewRouter(registry, manager, routerOpts)
 
-	log.Println("Server starting on :6789")
-	if err := http.ListenAndServe(":6789", apiRouter); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	// Listen addresses, TLS and the Unix socket come from env or the config
+	// file and only apply at startup, so they are not passed on by
+	// manager.OnChange; renewed certificates in the same files are picked up
+	// live
+	srv, err := server.New(cfg.Server, apiRouter)
+	if err != nil {
+		log.Fatalf("Invalid server configuration: %v", err)
+	}
+
+	// SIGINT and SIGTERM drain the server and stop the background workers
+	// in order; a second signal kills the process
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	failed := false
+	select {
+	case err := <-serveErr:
+		log.Printf("Server failed: %v", err)
+		failed = true
+	case <-ctx.Done():
+		log.Printf("Shutting down (timeout: %s)", cfg.ShutdownTimeout)
+	}
+	stopSignals()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// New requests and scans are refused first, then the workers that
+	// write to the database stop before it is closed
+	if !failed {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to drain HTTP connections: %v", err)
+		}
+		if err := <-serveErr; err != nil {
+			log.Printf("Server failed: %v", err)
+		}
+		log.Println("HTTP server stopped")
+	}
+	autoScanner.Stop()
+	if err := scannerService.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to stop scanner: %v", err)
+	} else {
+		log.Println("Scanner stopped")
+	}
+	telegramBot.Stop()
+	log.Println("Bots stopped")
+	if alertMonitor != nil {
+		alertMonitor.Stop(shutdownCtx)
+	}
+	if statsCollector != nil {
+		statsCollector.Stop()
+		log.Println("Container stats collector stopped")
+	}
+	if err := scanDB.Close(); err != nil {
+		log.Printf("Failed to close scan database: %v", err)
+	}
+	log.Println("Shutdown complete")
+
+	if failed {
+		cancel()
+		os.Exit(1)
 	}
 }
 
@@ -0,0 +1,175 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/vps-monitor/internal/docker"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// hostDownRuleID is the RuleID of host_down alerts
+const hostDownRuleID = "host-down"
+
+// checkDockerHosts pings every configured Docker host and raises host_down
+// after HostDownAfter consecutive failures. listErrors are the container
+// listing failures of the same cycle and count as failed checks too.
+func (m *Monitor) checkDockerHosts(ctx context.Context, listErrors []docker.HostError) {
+	dockerClient := m.getDockerClient()
+	if dockerClient == nil {
+		return
+	}
+
+	listFailed := make(map[string]error, len(listErrors))
+	for _, hostErr := range listErrors {
+		listFailed[hostErr.HostName] = hostErr.Err
+	}
+
+	type pingResult struct {
+		latency time.Duration
+		err     error
+	}
+	hosts := dockerClient.GetHosts()
+	results := make([]pingResult, len(hosts))
+
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+			latency, err := dockerClient.PingHost(pingCtx, host.Name)
+			results[i] = pingResult{latency: latency, err: err}
+		}()
+	}
+	wg.Wait()
+
+	now := time.Now()
+	configured := make(map[string]struct{}, len(hosts))
+	for i, host := range hosts {
+		configured[host.Name] = struct{}{}
+		err := results[i].err
+		if err == nil {
+			err = listFailed[host.Name]
+		}
+		m.recordHostCheck(host.Name, results[i].latency, err, now)
+	}
+	m.forgetDockerHosts(configured)
+}
+
+// recordHostCheck updates the status of a Docker host, opening a host_down
+// alert once it keeps failing and raising host_recovered when it answers again
+func (m *Monitor) recordHostCheck(hostName string, latency time.Duration, err error, now time.Time) {
+	key := activeAlertKey(hostName, "", hostDownRuleID)
+
+	m.hostsMu.Lock()
+	status, ok := m.hostStatus[hostName]
+	if !ok {
+		status = &models.DockerHostStatus{Name: hostName}
+		m.hostStatus[hostName] = status
+	}
+	status.LastCheck = now.Unix()
+
+	if err != nil {
+		status.Reachable = false
+		status.LastError = err.Error()
+		status.LastErrorAt = now.Unix()
+		status.ConsecutiveFailures++
+		failures := status.ConsecutiveFailures
+		m.hostsMu.Unlock()
+
+		if failures >= m.config.HostDownAfter {
+			m.openAlert(key, models.Alert{
+				Type:     models.AlertHostDown,
+				Host:     hostName,
+				RuleID:   hostDownRuleID,
+				Severity: models.AlertSeverityCritical,
+				Message:  fmt.Sprintf("Docker host %s is unreachable after %d failed checks: %v", hostName, failures, err),
+			})
+		}
+		return
+	}
+
+	status.Reachable = true
+	status.LastSuccess = now.Unix()
+	status.LatencyMs = float64(latency.Microseconds()) / 1000
+	status.ConsecutiveFailures = 0
+	m.hostsMu.Unlock()
+
+	down, wasDown := m.closeAlert(key)
+	if !wasDown {
+		return
+	}
+	downtime := time.Duration(down.DurationSeconds) * time.Second
+	m.triggerAlert(models.Alert{
+		ID:              uuid.New().String(),
+		Type:            models.AlertHostRecovered,
+		Host:            hostName,
+		RuleID:          hostDownRuleID,
+		Severity:        down.Severity, // notify wherever host_down was sent
+		DurationSeconds: down.DurationSeconds,
+		Message:         fmt.Sprintf("Docker host %s is reachable again after %s", hostName, downtime),
+		Timestamp:       now.Unix(),
+		// A recovery is only worth notifying if the outage was
+		Silenced:   down.Silenced,
+		SilencedBy: down.SilencedBy,
+	})
+}
+
+// forgetDockerHosts drops the status of hosts that are no longer configured
+// and resolves their host_down alerts
+func (m *Monitor) forgetDockerHosts(configured map[string]struct{}) {
+	m.hostsMu.Lock()
+	for name := range m.hostStatus {
+		if _, ok := configured[name]; !ok {
+			delete(m.hostStatus, name)
+		}
+	}
+	m.hostsMu.Unlock()
+
+	var stale []string
+	m.alertsMu.Lock()
+	for key, alert := range m.activeAlerts {
+		if alert.Type != models.AlertHostDown {
+			continue
+		}
+		if _, ok := configured[alert.Host]; !ok {
+			stale = append(stale, key)
+		}
+	}
+	m.alertsMu.Unlock()
+
+	for _, key := range stale {
+		m.resolveAlert(key)
+	}
+}
+
+// HostStatuses returns the connectivity of every configured Docker host,
+// sorted by name. Hosts not checked yet are reported with zero values.
+func (m *Monitor) HostStatuses() []models.DockerHostStatus {
+	var hosts []string
+	if dockerClient := m.getDockerClient(); dockerClient != nil {
+		for _, host := range dockerClient.GetHosts() {
+			hosts = append(hosts, host.Name)
+		}
+	}
+
+	m.hostsMu.Lock()
+	defer m.hostsMu.Unlock()
+
+	result := make([]models.DockerHostStatus, 0, len(hosts))
+	for _, name := range hosts {
+		if status, ok := m.hostStatus[name]; ok {
+			result = append(result, *status)
+		} else {
+			result = append(result, models.DockerHostStatus{Name: name})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
@@ -0,0 +1,66 @@
+package alerts
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestHostDownAfterConsecutiveFailuresAndRecovery(t *testing.T) {
+	m := newTestMonitor(0)
+	m.config.HostDownAfter = 3
+	now := time.Now()
+	refused := errors.New("connection refused")
+
+	m.recordHostCheck("edge", 0, refused, now)
+	m.recordHostCheck("edge", 0, refused, now)
+	if got := len(m.history.GetAll()); got != 0 {
+		t.Fatalf("expected no alert before %d failures, got %d", m.config.HostDownAfter, got)
+	}
+
+	m.recordHostCheck("edge", 0, refused, now)
+	m.recordHostCheck("edge", 0, refused, now)
+	alerts := m.history.GetAll()
+	if len(alerts) != 1 || alerts[0].Type != models.AlertHostDown || alerts[0].Status != models.AlertStatusFiring {
+		t.Fatalf("expected a single firing host_down alert, got %+v", alerts)
+	}
+
+	status := m.hostStatus["edge"]
+	if status.Reachable || status.ConsecutiveFailures != 4 || status.LastError != "connection refused" {
+		t.Fatalf("unexpected status while down: %+v", status)
+	}
+
+	m.recordHostCheck("edge", 25*time.Millisecond, nil, now)
+	alerts = m.history.GetAll()
+	if len(alerts) != 2 || alerts[0].Type != models.AlertHostRecovered || alerts[1].Status != models.AlertStatusResolved {
+		t.Fatalf("expected host_recovered and a resolved host_down, got %+v", alerts)
+	}
+
+	status = m.hostStatus["edge"]
+	if !status.Reachable || status.ConsecutiveFailures != 0 || status.LatencyMs != 25 || status.LastSuccess == 0 {
+		t.Fatalf("unexpected status after recovery: %+v", status)
+	}
+
+	// Another success must not report a second recovery
+	m.recordHostCheck("edge", 25*time.Millisecond, nil, now)
+	if got := len(m.history.GetAll()); got != 2 {
+		t.Fatalf("expected no new alerts, got %d", got)
+	}
+}
+
+func TestRemovedHostsAreForgotten(t *testing.T) {
+	m := newTestMonitor(0)
+	m.config.HostDownAfter = 1
+
+	m.recordHostCheck("old", 0, errors.New("timeout"), time.Now())
+	m.forgetDockerHosts(map[string]struct{}{"new": {}})
+
+	if _, ok := m.hostStatus["old"]; ok {
+		t.Fatal("expected status of removed host to be dropped")
+	}
+	if alerts := m.history.GetAll(); len(alerts) != 1 || alerts[0].Status != models.AlertStatusResolved {
+		t.Fatalf("expected host_down of removed host to resolve, got %+v", alerts)
+	}
+}
@@ -0,0 +1,193 @@
+package alerts
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/notify"
+)
+
+// escalation tracks a notified alert that has not been acknowledged yet
+type escalation struct {
+	alert models.Alert
+	count int       // escalations sent so far
+	last  time.Time // when the last escalation was sent
+}
+
+// Acknowledge marks an alert as acknowledged by user and stops escalating it
+func (m *Monitor) Acknowledge(alertID, user string) bool {
+	if !m.history.Acknowledge(alertID, user) {
+		return false
+	}
+	m.escalationsMu.Lock()
+	delete(m.escalations, alertID)
+	m.escalationsMu.Unlock()
+	m.publish(models.AlertEvent{Type: models.AlertEventAcknowledged, AlertID: alertID, AcknowledgedBy: user})
+	return true
+}
+
+// AcknowledgeAll marks every alert as acknowledged by user, stops all
+// escalations and returns how many alerts changed
+func (m *Monitor) AcknowledgeAll(user string) int {
+	count := m.history.AcknowledgeAll(user)
+	m.escalationsMu.Lock()
+	clear(m.escalations)
+	m.escalationsMu.Unlock()
+	if count > 0 {
+		m.publish(models.AlertEvent{Type: models.AlertEventAcknowledged, AcknowledgedBy: user})
+	}
+	return count
+}
+
+// AcknowledgeVisible is AcknowledgeAll limited to the alerts visible
+// accepts, e.g. those a user is granted. Only their escalations stop.
+func (m *Monitor) AcknowledgeVisible(user string, visible func(models.Alert) bool) int {
+	unacknowledged := false
+	var ids []string
+	err := m.history.each(models.AlertQuery{Acknowledged: &unacknowledged}, func(alert models.Alert) {
+		if visible(alert) {
+			ids = append(ids, alert.ID)
+		}
+	})
+	if err != nil {
+		log.Printf("Alert monitor: failed to list unacknowledged alerts: %v", err)
+		return 0
+	}
+
+	count := 0
+	for _, id := range ids {
+		if !m.history.Acknowledge(id, user) {
+			continue
+		}
+		m.escalationsMu.Lock()
+		delete(m.escalations, id)
+		m.escalationsMu.Unlock()
+		count++
+	}
+	if count > 0 {
+		m.publish(models.AlertEvent{Type: models.AlertEventAcknowledged, AcknowledgedBy: user})
+	}
+	return count
+}
+
+// trackEscalation starts watching a notified alert for acknowledgement
+func (m *Monitor) trackEscalation(alert models.Alert) {
+	if alert.Acknowledged || alert.Silenced {
+		return
+	}
+	m.escalationsMu.Lock()
+	defer m.escalationsMu.Unlock()
+	if _, tracked := m.escalations[alert.ID]; !tracked {
+		m.escalations[alert.ID] = &escalation{alert: alert}
+	}
+}
+
+// stopEscalation stops watching an alert, e.g. because it resolved
+func (m *Monitor) stopEscalation(alertID string) {
+	m.escalationsMu.Lock()
+	delete(m.escalations, alertID)
+	m.escalationsMu.Unlock()
+}
+
+// checkEscalations notifies again about alerts that stayed unacknowledged
+// past their escalation policy. Alerts no policy matches are dropped, so
+// only alerts covered by a policy are kept in memory.
+func (m *Monitor) checkEscalations(now time.Time) {
+	dispatcher := m.notifier.Load()
+
+	type due struct {
+		alert    models.Alert
+		channels []string
+	}
+	var pending []due
+
+	m.escalationsMu.Lock()
+	for id, esc := range m.escalations {
+		if dispatcher == nil {
+			delete(m.escalations, id)
+			continue
+		}
+		n := notify.Notification{Event: notify.EventEscalated, Alert: esc.alert, Severity: effectiveSeverity(esc.alert)}
+		policy := dispatcher.EscalationPolicy(n)
+		if policy == nil || (esc.count > 0 && policy.RepeatMinutes == 0) {
+			delete(m.escalations, id)
+			continue
+		}
+
+		next := time.Unix(esc.alert.Timestamp, 0).Add(time.Duration(policy.AfterMinutes) * time.Minute)
+		if esc.count > 0 {
+			next = esc.last.Add(time.Duration(policy.RepeatMinutes) * time.Minute)
+		}
+		if now.Before(next) {
+			continue
+		}
+		esc.count++
+		esc.last = now
+		pending = append(pending, due{alert: esc.alert, channels: policy.Channels})
+	}
+	m.escalationsMu.Unlock()
+
+	for _, d := range pending {
+		log.Printf("Alert escalated: %s - %s (not acknowledged)", d.alert.Type, d.alert.Message)
+		m.dispatch(dispatcher, d.alert, notify.EventEscalated, d.channels)
+	}
+}
+
+// dispatch delivers a notification in the background, to channels when
+// given and through the routes and to registered devices otherwise
+func (m *Monitor) dispatch(dispatcher *notify.Dispatcher, alert models.Alert, event string, channels []string) {
+	n := notify.Notification{
+		Event:    event,
+		Alert:    alert,
+		Severity: effectiveSeverity(alert),
+		Labels:   m.ContainerLabels(alert.Host, alert.ContainerID),
+	}
+
+	if pusher := m.pusher.Load(); pusher != nil && len(channels) == 0 {
+		m.deliver(alert, func(ctx context.Context) {
+			ctx, cancel := context.WithTimeout(ctx, time.Minute)
+			defer cancel()
+			if err := pusher.Notify(ctx, n); err != nil {
+				log.Printf("Failed to push alert %s to devices: %v", alert.ID, err)
+			}
+		})
+	}
+	if dispatcher == nil {
+		return
+	}
+
+	m.deliver(alert, func(ctx context.Context) {
+		// Leaves room for webhook retries
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer cancel()
+
+		var err error
+		if len(channels) > 0 {
+			err = dispatcher.DispatchTo(ctx, n, channels)
+		} else {
+			err = dispatcher.Dispatch(ctx, n)
+		}
+		if err != nil {
+			log.Printf("Failed to send notifications for alert %s: %v", alert.ID, err)
+		}
+	})
+}
+
+// deliver runs send in the background, tracked so that Stop can wait for
+// it. Once the monitor stopped, notifications are dropped.
+func (m *Monitor) deliver(alert models.Alert, send func(ctx context.Context)) {
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+	if m.notifyStopped {
+		log.Printf("Alert monitor stopped, not notifying alert %s", alert.ID)
+		return
+	}
+
+	m.notifyWg.Add(1)
+	go func() {
+		defer m.notifyWg.Done()
+		send(m.notifyCtx)
+	}()
+}
@@ -0,0 +1,216 @@
+package alerts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/notify"
+	"github.com/hhftechnology/vps-monitor/internal/push"
+)
+
+// notificationLog records the path and event of every webhook request
+type notificationLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *notificationLog) count(entry string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, e := range l.events {
+		if e == entry {
+			n++
+		}
+	}
+	return n
+}
+
+// waitFor waits until entry was received want times; notifications are sent in the background
+func (l *notificationLog) waitFor(t *testing.T, entry string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for l.count(entry) < want && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := l.count(entry); got != want {
+		t.Fatalf("expected %d %s notifications, got %d", want, entry, got)
+	}
+}
+
+func newEscalationTestMonitor(t *testing.T) (*Monitor, *notificationLog) {
+	t.Helper()
+	sent := &notificationLog{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent.mu.Lock()
+		sent.events = append(sent.events, r.URL.Path+" "+r.Header.Get(notify.EventHeader))
+		sent.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+
+	m := newTestMonitor(0)
+	m.SetNotifier(notify.NewDispatcher(&config.Config{Notifications: config.NotificationsConfig{
+		Channels: []config.NotificationChannel{
+			{Name: "chat", Type: config.ChannelWebhook, Enabled: true, URL: srv.URL + "/chat"},
+			{Name: "pager", Type: config.ChannelWebhook, Enabled: true, URL: srv.URL + "/pager"},
+		},
+		Routes: []config.NotificationRoute{{Channels: []string{"chat"}}},
+		Escalations: []config.EscalationPolicy{
+			{Name: "page on-call", MinSeverity: "critical", AfterMinutes: 10, RepeatMinutes: 30, Channels: []string{"pager"}},
+		},
+	}}))
+	return m, sent
+}
+
+func TestUnacknowledgedAlertEscalatesUntilAcknowledged(t *testing.T) {
+	m, sent := newEscalationTestMonitor(t)
+	firedAt := time.Now().Add(-15 * time.Minute)
+
+	alert := models.Alert{
+		ID:        "a1",
+		Type:      models.AlertContainerOOM,
+		Host:      "host-a",
+		Severity:  models.AlertSeverityCritical,
+		Message:   "Container web was killed by the OOM killer",
+		Timestamp: firedAt.Unix(),
+	}
+	m.triggerAlert(alert)
+	sent.waitFor(t, "/chat fired", 1)
+
+	now := time.Now()
+	m.checkEscalations(now)
+	sent.waitFor(t, "/pager escalated", 1)
+
+	m.checkEscalations(now.Add(10 * time.Minute))
+	m.checkEscalations(now.Add(31 * time.Minute))
+	sent.waitFor(t, "/pager escalated", 2)
+
+	if !m.Acknowledge("a1", "ops") {
+		t.Fatal("Acknowledge() = false, want true")
+	}
+	m.checkEscalations(now.Add(2 * time.Hour))
+	time.Sleep(50 * time.Millisecond)
+	sent.waitFor(t, "/pager escalated", 2)
+
+	acked := m.history.GetAll()[0]
+	if !acked.Acknowledged || acked.AcknowledgedBy != "ops" || acked.AcknowledgedAt == 0 {
+		t.Fatalf("expected the acknowledgement to be recorded, got %+v", acked)
+	}
+}
+
+func TestEscalationSkipsUnmatchedAndResolvedAlerts(t *testing.T) {
+	m, sent := newEscalationTestMonitor(t)
+	now := time.Now()
+
+	// Warnings are below the policy's minimum severity
+	m.triggerAlert(models.Alert{
+		ID:        "w1",
+		Type:      models.AlertContainerStopped,
+		Host:      "host-a",
+		Severity:  models.AlertSeverityWarning,
+		Timestamp: now.Add(-time.Hour).Unix(),
+	})
+	sent.waitFor(t, "/chat fired", 1)
+
+	// A critical rule alert that resolves before it is due
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 95, now)
+	sent.waitFor(t, "/chat fired", 2)
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 10, now)
+
+	m.escalationsMu.Lock()
+	tracked := len(m.escalations)
+	m.escalationsMu.Unlock()
+	if tracked != 1 {
+		t.Fatalf("expected only the warning to be tracked until the next check, got %d", tracked)
+	}
+
+	m.checkEscalations(now.Add(time.Hour))
+	time.Sleep(50 * time.Millisecond)
+	if got := sent.count("/pager escalated"); got != 0 {
+		t.Fatalf("expected no escalations, got %d", got)
+	}
+	m.escalationsMu.Lock()
+	tracked = len(m.escalations)
+	m.escalationsMu.Unlock()
+	if tracked != 0 {
+		t.Fatalf("alerts without a matching policy should be dropped, %d left", tracked)
+	}
+}
+
+func TestFiredAlertsArePushedToDevices(t *testing.T) {
+	sent := &notificationLog{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent.mu.Lock()
+		sent.events = append(sent.events, r.URL.Path)
+		sent.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(srv.Close)
+
+	pusher := push.NewService(nil)
+	pusher.SetSender(models.PushUnifiedPush, push.NewUnifiedPushSender([]string{"127.0.0.1"}))
+	if _, _, err := pusher.Register(models.Device{Provider: models.PushUnifiedPush, Token: srv.URL + "/phone"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// No notification channels are needed for push
+	m := newTestMonitor(0)
+	m.SetPusher(pusher)
+	m.triggerAlert(models.Alert{
+		ID:        "a1",
+		Type:      models.AlertContainerOOM,
+		Host:      "host-a",
+		Severity:  models.AlertSeverityCritical,
+		Timestamp: time.Now().Unix(),
+	})
+	sent.waitFor(t, "/phone", 1)
+}
+
+func TestStopWaitsForNotifications(t *testing.T) {
+	release := make(chan struct{})
+	unblock := make(chan struct{})
+	var delivered sync.WaitGroup
+	delivered.Add(1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+			delivered.Done()
+			return
+		}
+		// Never answers; the monitor has to give up on the notification
+		<-unblock
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(unblock) })
+
+	newMonitor := func(path string) *Monitor {
+		m := newTestMonitor(0)
+		m.SetNotifier(notify.NewDispatcher(&config.Config{Notifications: config.NotificationsConfig{
+			Channels: []config.NotificationChannel{{Name: "chat", Type: config.ChannelWebhook, Enabled: true, URL: srv.URL + path}},
+			Routes:   []config.NotificationRoute{{Channels: []string{"chat"}}},
+		}}))
+		m.triggerAlert(models.Alert{ID: "a1", Type: models.AlertContainerOOM, Host: "host-a", Timestamp: time.Now().Unix()})
+		return m
+	}
+
+	m := newMonitor("/slow")
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+	m.Stop(context.Background())
+	// Stop returned, so the notification was delivered
+	delivered.Wait()
+
+	m = newMonitor("/hang")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	m.Stop(ctx)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected Stop to cancel a hanging notification, took %s", elapsed)
+	}
+}
@@ -0,0 +1,327 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/google/uuid"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const (
+	// A die shortly after a kill comes from docker stop/kill, not a crash
+	intentionalStopWindow = 30 * time.Second
+	maxLogTailBytes       = 4096
+)
+
+// containerEvents tracks recent lifecycle events for one container
+type containerEvents struct {
+	dies          []time.Time
+	lastKill      time.Time
+	loopAlertedAt time.Time
+}
+
+// syncEventListeners starts an event listener for every configured host and
+// stops listeners for hosts that were removed
+func (m *Monitor) syncEventListeners() {
+	if !m.config.EventsEnabled {
+		return
+	}
+	dockerClient := m.getDockerClient()
+	if dockerClient == nil {
+		return
+	}
+
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+
+	if !m.running {
+		return
+	}
+
+	wanted := make(map[string]struct{})
+	for _, host := range dockerClient.GetHosts() {
+		wanted[host.Name] = struct{}{}
+		if _, ok := m.eventListeners[host.Name]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		m.eventListeners[host.Name] = cancel
+		m.wg.Add(1)
+		go m.listenEvents(ctx, host.Name)
+	}
+
+	for host, cancel := range m.eventListeners {
+		if _, ok := wanted[host]; !ok {
+			cancel()
+			delete(m.eventListeners, host)
+		}
+	}
+}
+
+// stopEventListeners cancels all event listeners
+func (m *Monitor) stopEventListeners() {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+
+	m.running = false
+	for host, cancel := range m.eventListeners {
+		cancel()
+		delete(m.eventListeners, host)
+	}
+}
+
+// listenEvents follows the container event stream of one host, reconnecting
+// with backoff and replaying events missed while disconnected
+func (m *Monitor) listenEvents(ctx context.Context, hostName string) {
+	defer m.wg.Done()
+
+	backoff := time.Second
+	maxBackoff := 5 * time.Minute
+	var lastNano int64
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		err := m.streamEvents(ctx, hostName, &lastNano)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Alert monitor: event stream error on %s: %v", hostName, err)
+		} else {
+			backoff = time.Second
+		}
+
+		log.Printf("Alert monitor: event stream disconnected for %s, reconnecting in %v", hostName, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}
+}
+
+// streamEvents consumes events until the stream ends. lastNano is the
+// timestamp of the last handled event and is used to resume the stream.
+func (m *Monitor) streamEvents(ctx context.Context, hostName string, lastNano *int64) error {
+	dockerClient := m.getDockerClient()
+	if dockerClient == nil {
+		return errors.New("docker client unavailable")
+	}
+	apiClient, err := dockerClient.GetClient(hostName)
+	if err != nil {
+		return err
+	}
+
+	filter := filters.NewArgs()
+	filter.Add("type", string(events.ContainerEventType))
+	for _, action := range []events.Action{events.ActionDie, events.ActionKill, events.ActionOOM, events.ActionHealthStatus} {
+		filter.Add("event", string(action))
+	}
+	opts := events.ListOptions{Filters: filter}
+	if *lastNano > 0 {
+		opts.Since = strconv.FormatInt(*lastNano/int64(time.Second), 10)
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	eventCh, errCh := apiClient.Events(streamCtx, opts)
+	log.Printf("Alert monitor: listening for container events on %s", hostName)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-eventCh:
+			if !ok {
+				return nil
+			}
+			// Since has one-second resolution, so a resumed stream repeats events
+			if event.TimeNano <= *lastNano {
+				continue
+			}
+			*lastNano = event.TimeNano
+			m.handleContainerEvent(hostName, event)
+		case err, ok := <-errCh:
+			if !ok {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// handleContainerEvent turns a container event into an alert
+func (m *Monitor) handleContainerEvent(hostName string, event events.Message) {
+	attrs := event.Actor.Attributes
+	containerID := event.Actor.ID
+	containerName := attrs["name"]
+	if containerName == "" {
+		containerName = containerID[:min(12, len(containerID))]
+	}
+	// Event attributes include the container labels
+	if resolveThresholds(m.config, hostName, containerName, attrs).disabled {
+		return
+	}
+	// Containers started since the last state check have no labels recorded yet
+	m.setContainerLabels(fmt.Sprintf("%s:%s", hostName, containerID), attrs, false)
+
+	at := time.Now()
+	if event.TimeNano > 0 {
+		at = time.Unix(0, event.TimeNano)
+	}
+
+	base := models.Alert{
+		ContainerID:   containerID,
+		ContainerName: containerName,
+		Host:          hostName,
+		Timestamp:     at.Unix(),
+	}
+
+	switch {
+	case event.Action == events.ActionKill:
+		m.eventState(hostName, containerID, func(state *containerEvents) {
+			state.lastKill = at
+		})
+
+	case event.Action == events.ActionOOM:
+		alert := base
+		alert.ID = uuid.New().String()
+		alert.Type = models.AlertContainerOOM
+		alert.Severity = models.AlertSeverityCritical
+		alert.Message = fmt.Sprintf("Container %s was killed by the OOM killer", containerName)
+		m.triggerAlert(alert)
+
+	case event.Action == events.ActionDie:
+		exitCode, _ := strconv.Atoi(attrs["exitCode"])
+		m.handleDie(base, exitCode, at)
+
+	case strings.HasPrefix(string(event.Action), string(events.ActionHealthStatus)):
+		if event.Action != events.ActionHealthStatusUnhealthy {
+			return
+		}
+		alert := base
+		alert.ID = uuid.New().String()
+		alert.Type = models.AlertContainerUnhealthy
+		alert.Severity = models.AlertSeverityWarning
+		alert.Message = fmt.Sprintf("Container %s healthcheck is failing", containerName)
+		m.triggerAlert(alert)
+	}
+}
+
+// handleDie raises a crash alert for non-zero exits, escalating to a restart
+// loop alert when the container keeps crashing within RestartLoopWindow
+func (m *Monitor) handleDie(base models.Alert, exitCode int, at time.Time) {
+	var crashes int
+	var intentional, loop, suppressed bool
+
+	m.eventState(base.Host, base.ContainerID, func(state *containerEvents) {
+		intentional = !state.lastKill.IsZero() && at.Sub(state.lastKill) < intentionalStopWindow
+		state.lastKill = time.Time{}
+		if exitCode == 0 || intentional {
+			return
+		}
+
+		cutoff := at.Add(-m.config.RestartLoopWindow)
+		kept := state.dies[:0]
+		for _, t := range state.dies {
+			if t.After(cutoff) {
+				kept = append(kept, t)
+			}
+		}
+		state.dies = append(kept, at)
+		crashes = len(state.dies)
+
+		suppressed = !state.loopAlertedAt.IsZero() && at.Sub(state.loopAlertedAt) < m.config.RestartLoopWindow
+		if !suppressed && crashes >= m.config.RestartLoopCount {
+			loop = true
+			state.loopAlertedAt = at
+		}
+	})
+
+	// While a restart loop alert is recent, individual crashes stay quiet
+	if exitCode == 0 || intentional || suppressed {
+		return
+	}
+
+	alert := base
+	alert.ID = uuid.New().String()
+	alert.ExitCode = exitCode
+	alert.LogTail = m.logTail(base.Host, base.ContainerID)
+	if loop {
+		alert.Type = models.AlertContainerRestartLoop
+		alert.Severity = models.AlertSeverityCritical
+		alert.Message = fmt.Sprintf("Container %s crashed %d times in %s (last exit code %d)",
+			base.ContainerName, crashes, m.config.RestartLoopWindow, exitCode)
+	} else {
+		alert.Type = models.AlertContainerDied
+		alert.Severity = models.AlertSeverityWarning
+		alert.Message = fmt.Sprintf("Container %s exited with code %d", base.ContainerName, exitCode)
+	}
+	m.triggerAlert(alert)
+}
+
+// eventState runs fn with the tracked events of a container
+func (m *Monitor) eventState(host, containerID string, fn func(*containerEvents)) {
+	key := fmt.Sprintf("%s:%s", host, containerID)
+
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+
+	state, ok := m.containerEvents[key]
+	if !ok {
+		state = &containerEvents{}
+		m.containerEvents[key] = state
+	}
+	fn(state)
+}
+
+// forgetContainerEvents drops event tracking for a removed container
+func (m *Monitor) forgetContainerEvents(key string) {
+	m.eventsMu.Lock()
+	delete(m.containerEvents, key)
+	m.eventsMu.Unlock()
+}
+
+// logTail returns the last LogTailLines log lines of a container
+func (m *Monitor) logTail(host, containerID string) string {
+	if m.config.LogTailLines <= 0 {
+		return ""
+	}
+	dockerClient := m.getDockerClient()
+	if dockerClient == nil {
+		return ""
+	}
+
+	entries, err := dockerClient.GetContainerLogsParsed(host, containerID, models.LogOptions{
+		Tail:       strconv.Itoa(m.config.LogTailLines),
+		ShowStdout: true,
+		ShowStderr: true,
+	})
+	if err != nil {
+		log.Printf("Alert monitor: failed to read logs of %s on %s: %v", containerID, host, err)
+		return ""
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, entry.Raw)
+	}
+	tail := strings.Join(lines, "\n")
+	if len(tail) > maxLogTailBytes {
+		tail = tail[len(tail)-maxLogTailBytes:]
+	}
+	return tail
+}
@@ -0,0 +1,129 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func newEventTestMonitor() *Monitor {
+	return NewMonitor(nil, &config.AlertConfig{
+		Enabled:           true,
+		EventsEnabled:     true,
+		RestartLoopCount:  3,
+		RestartLoopWindow: 5 * time.Minute,
+	}, nil, 0)
+}
+
+func containerEvent(action events.Action, at time.Time, attrs map[string]string) events.Message {
+	attributes := map[string]string{"name": "web"}
+	for k, v := range attrs {
+		attributes[k] = v
+	}
+	return events.Message{
+		Type:     events.ContainerEventType,
+		Action:   action,
+		Actor:    events.Actor{ID: "c1", Attributes: attributes},
+		TimeNano: at.UnixNano(),
+	}
+}
+
+func alertTypes(alerts []models.Alert) []models.AlertType {
+	types := make([]models.AlertType, 0, len(alerts))
+	for _, alert := range alerts {
+		types = append(types, alert.Type)
+	}
+	return types
+}
+
+func TestDieEventRaisesCrashAlertWithExitCode(t *testing.T) {
+	m := newEventTestMonitor()
+	now := time.Now()
+
+	m.handleContainerEvent("host-a", containerEvent(events.ActionDie, now, map[string]string{"exitCode": "0"}))
+	if got := len(m.history.GetAll()); got != 0 {
+		t.Fatalf("clean exit should not alert, got %d alerts", got)
+	}
+
+	m.handleContainerEvent("host-a", containerEvent(events.ActionDie, now, map[string]string{"exitCode": "137"}))
+	alerts := m.history.GetAll()
+	if len(alerts) != 1 {
+		t.Fatalf("expected one crash alert, got %d", len(alerts))
+	}
+	if alerts[0].Type != models.AlertContainerDied || alerts[0].ExitCode != 137 || alerts[0].Host != "host-a" {
+		t.Fatalf("unexpected crash alert: %+v", alerts[0])
+	}
+}
+
+func TestDieAfterKillIsIgnored(t *testing.T) {
+	m := newEventTestMonitor()
+	now := time.Now()
+
+	m.handleContainerEvent("host-a", containerEvent(events.ActionKill, now, nil))
+	m.handleContainerEvent("host-a", containerEvent(events.ActionDie, now.Add(2*time.Second), map[string]string{"exitCode": "143"}))
+
+	if got := len(m.history.GetAll()); got != 0 {
+		t.Fatalf("docker stop should not alert, got %d alerts", got)
+	}
+}
+
+func TestRepeatedCrashesRaiseRestartLoop(t *testing.T) {
+	m := newEventTestMonitor()
+	now := time.Now()
+
+	for i := range 5 {
+		at := now.Add(time.Duration(i) * 10 * time.Second)
+		m.handleContainerEvent("host-a", containerEvent(events.ActionDie, at, map[string]string{"exitCode": "1"}))
+	}
+
+	// Newest first: the loop alert replaces further crash alerts
+	got := alertTypes(m.history.GetAll())
+	want := []models.AlertType{models.AlertContainerRestartLoop, models.AlertContainerDied, models.AlertContainerDied}
+	if len(got) != len(want) {
+		t.Fatalf("expected alerts %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected alerts %v, got %v", want, got)
+		}
+	}
+
+	// Crashes spread wider than the window do not count as a loop
+	m = newEventTestMonitor()
+	for i := range 3 {
+		at := now.Add(time.Duration(i) * 3 * time.Minute)
+		m.handleContainerEvent("host-a", containerEvent(events.ActionDie, at, map[string]string{"exitCode": "1"}))
+	}
+	for _, alertType := range alertTypes(m.history.GetAll()) {
+		if alertType != models.AlertContainerDied {
+			t.Fatalf("expected only crash alerts, got %v", alertTypes(m.history.GetAll()))
+		}
+	}
+}
+
+func TestOOMAndUnhealthyEvents(t *testing.T) {
+	m := newEventTestMonitor()
+	now := time.Now()
+
+	m.handleContainerEvent("host-a", containerEvent(events.ActionOOM, now, nil))
+	m.handleContainerEvent("host-a", containerEvent(events.ActionHealthStatusHealthy, now, nil))
+	m.handleContainerEvent("host-a", containerEvent(events.ActionHealthStatusUnhealthy, now, nil))
+
+	got := alertTypes(m.history.GetAll())
+	if len(got) != 2 || got[0] != models.AlertContainerUnhealthy || got[1] != models.AlertContainerOOM {
+		t.Fatalf("expected unhealthy and oom alerts, got %v", got)
+	}
+}
+
+func TestEventsRespectDisabledLabel(t *testing.T) {
+	m := newEventTestMonitor()
+
+	m.handleContainerEvent("host-a", containerEvent(events.ActionOOM, time.Now(), map[string]string{LabelAlertDisabled: "true"}))
+
+	if got := len(m.history.GetAll()); got != 0 {
+		t.Fatalf("disabled container should not alert, got %d alerts", got)
+	}
+}
@@ -1,19 +1,40 @@
 package alerts
 
 import (
+	"log"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hhftechnology/vps-monitor/internal/models"
 )
 
-// AlertHistory stores recent alerts in memory using a ring buffer
+// AlertStore persists alert history beyond the lifetime of the process.
+type AlertStore interface {
+	InsertAlert(alert models.Alert) error
+	QueryAlerts(params models.AlertQuery) (*models.AlertPage, error)
+	GetAlert(id string) (*models.Alert, error)
+	ResolveAlert(id string, resolvedAt int64) (bool, error)
+	AcknowledgeAlert(id, user string, at int64) (bool, error)
+	AcknowledgeAllAlerts(user string, at int64) (int, error)
+	CountUnacknowledgedAlerts() (int, error)
+	PruneAlertsOlderThan(cutoff time.Time) error
+}
+
+// scanPageSize is how many alerts are read at a time when going through
+// every alert matching a query
+const scanPageSize = 500
+
+// AlertHistory stores alerts either in a persistent store or, when no store
+// is configured, in memory using a ring buffer
 type AlertHistory struct {
 	alerts  []models.Alert
 	mu      sync.RWMutex
 	maxSize int
+	store   AlertStore
 }
 
-// NewAlertHistory creates a new alert history with the specified max size
+// NewAlertHistory creates a new in-memory alert history with the specified max size
 func NewAlertHistory(maxSize int) *AlertHistory {
 	return &AlertHistory{
 		alerts:  make([]models.Alert, 0, maxSize),
@@ -21,8 +42,23 @@ func NewAlertHistory(maxSize int) *AlertHistory {
 	}
 }
 
+// NewPersistentAlertHistory creates an alert history backed by store.
+// maxSize bounds the number of alerts returned by GetAll.
+func NewPersistentAlertHistory(store AlertStore, maxSize int) *AlertHistory {
+	h := NewAlertHistory(maxSize)
+	h.store = store
+	return h
+}
+
 // Add adds an alert to the history
 func (h *AlertHistory) Add(alert models.Alert) {
+	if h.store != nil {
+		if err := h.store.InsertAlert(alert); err != nil {
+			log.Printf("Alert history: failed to persist alert %s: %v", alert.ID, err)
+		}
+		return
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -35,8 +71,137 @@ func (h *AlertHistory) Add(alert models.Alert) {
 	}
 }
 
+// Query returns a filtered, paginated page of alerts, newest first
+func (h *AlertHistory) Query(params models.AlertQuery) (*models.AlertPage, error) {
+	if h.store != nil {
+		return h.store.QueryAlerts(params)
+	}
+
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > h.maxSize {
+		params.PageSize = h.maxSize
+	}
+
+	h.mu.RLock()
+	matched := make([]models.Alert, 0, len(h.alerts))
+	for _, alert := range h.alerts {
+		if alertMatchesQuery(alert, params) {
+			matched = append(matched, alert)
+		}
+	}
+	h.mu.RUnlock()
+
+	page := &models.AlertPage{
+		Alerts:   []models.Alert{},
+		Total:    len(matched),
+		Page:     params.Page,
+		PageSize: params.PageSize,
+	}
+	if params.PageSize > 0 {
+		page.TotalPages = (page.Total + params.PageSize - 1) / params.PageSize
+	}
+
+	start := (params.Page - 1) * params.PageSize
+	if start < len(matched) {
+		end := min(start+params.PageSize, len(matched))
+		page.Alerts = matched[start:end]
+	}
+	return page, nil
+}
+
+// QueryVisible is Query limited to the alerts visible accepts, e.g. those a
+// user is granted. Every matching alert is read to count the visible ones.
+func (h *AlertHistory) QueryVisible(params models.AlertQuery, visible func(models.Alert) bool) (*models.AlertPage, error) {
+	// Query settles the page and page size the same way for both backends
+	first, err := h.Query(params)
+	if err != nil {
+		return nil, err
+	}
+	page := &models.AlertPage{
+		Alerts:   []models.Alert{},
+		Page:     first.Page,
+		PageSize: first.PageSize,
+	}
+	start := (page.Page - 1) * page.PageSize
+
+	err = h.each(params, func(alert models.Alert) {
+		if !visible(alert) {
+			return
+		}
+		if page.Total >= start && len(page.Alerts) < page.PageSize {
+			page.Alerts = append(page.Alerts, alert)
+		}
+		page.Total++
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if page.PageSize > 0 {
+		page.TotalPages = (page.Total + page.PageSize - 1) / page.PageSize
+	}
+	return page, nil
+}
+
+// each calls fn for every alert matching params, newest first, reading
+// them a page at a time
+func (h *AlertHistory) each(params models.AlertQuery, fn func(models.Alert)) error {
+	params.PageSize = scanPageSize
+	for params.Page = 1; ; params.Page++ {
+		batch, err := h.Query(params)
+		if err != nil {
+			return err
+		}
+		for _, alert := range batch.Alerts {
+			fn(alert)
+		}
+		if params.Page >= batch.TotalPages {
+			return nil
+		}
+	}
+}
+
+// Get returns the alert with the given ID
+func (h *AlertHistory) Get(alertID string) (models.Alert, bool) {
+	if h.store != nil {
+		alert, err := h.store.GetAlert(alertID)
+		if err != nil {
+			log.Printf("Alert history: failed to load alert %s: %v", alertID, err)
+			return models.Alert{}, false
+		}
+		if alert == nil {
+			return models.Alert{}, false
+		}
+		return *alert, true
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, alert := range h.alerts {
+		if alert.ID == alertID {
+			return alert, true
+		}
+	}
+	return models.Alert{}, false
+}
+
 // GetRecent returns the most recent alerts up to the specified limit
 func (h *AlertHistory) GetRecent(limit int) []models.Alert {
+	if h.store != nil {
+		if limit <= 0 {
+			limit = h.maxSize
+		}
+		page, err := h.store.QueryAlerts(models.AlertQuery{PageSize: limit})
+		if err != nil {
+			log.Printf("Alert history: failed to load recent alerts: %v", err)
+			return []models.Alert{}
+		}
+		return page.Alerts
+	}
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
@@ -50,8 +215,13 @@ func (h *AlertHistory) GetRecent(limit int) []models.Alert {
 	return result
 }
 
-// GetAll returns all alerts in history
+// GetAll returns all alerts in history. For persistent histories only the
+// most recent maxSize alerts are returned; use Query to page through the rest.
 func (h *AlertHistory) GetAll() []models.Alert {
+	if h.store != nil {
+		return h.GetRecent(h.maxSize)
+	}
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
@@ -60,22 +230,109 @@ func (h *AlertHistory) GetAll() []models.Alert {
 	return result
 }
 
-// Acknowledge marks an alert as acknowledged
-func (h *AlertHistory) Acknowledge(alertID string) bool {
+// Resolve records that a firing alert was resolved at resolvedAt
+func (h *AlertHistory) Resolve(alertID string, resolvedAt int64) bool {
+	if h.store != nil {
+		ok, err := h.store.ResolveAlert(alertID, resolvedAt)
+		if err != nil {
+			log.Printf("Alert history: failed to resolve alert %s: %v", alertID, err)
+			return false
+		}
+		return ok
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	for i := range h.alerts {
 		if h.alerts[i].ID == alertID {
-			h.alerts[i].Acknowledged = true
+			h.alerts[i].Status = models.AlertStatusResolved
+			h.alerts[i].ResolvedAt = resolvedAt
+			h.alerts[i].DurationSeconds = max(resolvedAt-h.alerts[i].Timestamp, 0)
 			return true
 		}
 	}
 	return false
 }
 
+// Firing returns alerts that are still open
+func (h *AlertHistory) Firing() ([]models.Alert, error) {
+	var firing []models.Alert
+	err := h.each(models.AlertQuery{Status: models.AlertStatusFiring}, func(alert models.Alert) {
+		firing = append(firing, alert)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return firing, nil
+}
+
+// Acknowledge marks an alert as acknowledged by user. An alert keeps its
+// first acknowledgement.
+func (h *AlertHistory) Acknowledge(alertID, user string) bool {
+	now := time.Now().Unix()
+	if h.store != nil {
+		ok, err := h.store.AcknowledgeAlert(alertID, user, now)
+		if err != nil {
+			log.Printf("Alert history: failed to acknowledge alert %s: %v", alertID, err)
+			return false
+		}
+		return ok
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := range h.alerts {
+		if h.alerts[i].ID == alertID {
+			if !h.alerts[i].Acknowledged {
+				h.alerts[i].Acknowledged = true
+				h.alerts[i].AcknowledgedBy = user
+				h.alerts[i].AcknowledgedAt = now
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// AcknowledgeAll marks every unacknowledged alert as acknowledged by user
+// and returns how many alerts changed
+func (h *AlertHistory) AcknowledgeAll(user string) int {
+	now := time.Now().Unix()
+	if h.store != nil {
+		count, err := h.store.AcknowledgeAllAlerts(user, now)
+		if err != nil {
+			log.Printf("Alert history: failed to acknowledge alerts: %v", err)
+		}
+		return count
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := 0
+	for i := range h.alerts {
+		if !h.alerts[i].Acknowledged {
+			h.alerts[i].Acknowledged = true
+			h.alerts[i].AcknowledgedBy = user
+			h.alerts[i].AcknowledgedAt = now
+			count++
+		}
+	}
+	return count
+}
+
 // GetUnacknowledgedCount returns the count of unacknowledged alerts
 func (h *AlertHistory) GetUnacknowledgedCount() int {
+	if h.store != nil {
+		count, err := h.store.CountUnacknowledgedAlerts()
+		if err != nil {
+			log.Printf("Alert history: failed to count unacknowledged alerts: %v", err)
+		}
+		return count
+	}
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
@@ -88,9 +345,57 @@ func (h *AlertHistory) GetUnacknowledgedCount() int {
 	return count
 }
 
-// Clear removes all alerts from history
+// Prune removes alerts older than the cutoff, except those still firing
+func (h *AlertHistory) Prune(cutoff time.Time) error {
+	if h.store != nil {
+		return h.store.PruneAlertsOlderThan(cutoff)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	kept := h.alerts[:0]
+	for _, alert := range h.alerts {
+		if alert.Timestamp >= cutoff.Unix() || alert.Status == models.AlertStatusFiring {
+			kept = append(kept, alert)
+		}
+	}
+	h.alerts = kept
+	return nil
+}
+
+// Clear removes all in-memory alerts from history
 func (h *AlertHistory) Clear() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.alerts = make([]models.Alert, 0, h.maxSize)
 }
+
+func alertMatchesQuery(alert models.Alert, params models.AlertQuery) bool {
+	if params.Host != "" && alert.Host != params.Host {
+		return false
+	}
+	if params.Container != "" && alert.ContainerID != params.Container &&
+		!strings.Contains(alert.ContainerName, params.Container) {
+		return false
+	}
+	if params.Type != "" && alert.Type != params.Type {
+		return false
+	}
+	if params.Status != "" && alert.Status != params.Status {
+		return false
+	}
+	if params.StartDate > 0 && alert.Timestamp < params.StartDate {
+		return false
+	}
+	if params.EndDate > 0 && alert.Timestamp > params.EndDate {
+		return false
+	}
+	if params.Acknowledged != nil && alert.Acknowledged != *params.Acknowledged {
+		return false
+	}
+	if params.Silenced != nil && alert.Silenced != *params.Silenced {
+		return false
+	}
+	return true
+}
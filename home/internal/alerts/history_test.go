@@ -0,0 +1,73 @@
+package alerts
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestInMemoryHistoryQueryFiltersAndPrunes(t *testing.T) {
+	history := NewAlertHistory(10)
+	now := time.Now()
+
+	history.Add(models.Alert{ID: "old", Type: models.AlertCPUThreshold, Host: "host-a", Timestamp: now.Add(-2 * time.Hour).Unix()})
+	history.Add(models.Alert{ID: "cpu", Type: models.AlertCPUThreshold, Host: "host-a", ContainerName: "web", Timestamp: now.Unix()})
+	history.Add(models.Alert{ID: "mem", Type: models.AlertMemoryThreshold, Host: "host-b", ContainerName: "db", Timestamp: now.Unix()})
+
+	page, err := history.Query(models.AlertQuery{Host: "host-a"})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if page.Total != 2 || page.Alerts[0].ID != "cpu" {
+		t.Fatalf("unexpected host filter result: %+v", page.Alerts)
+	}
+
+	if err := history.Prune(now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if got := len(history.GetAll()); got != 2 {
+		t.Fatalf("expected 2 alerts after prune, got %d", got)
+	}
+
+	if count := history.AcknowledgeAll("admin"); count != 2 {
+		t.Fatalf("AcknowledgeAll() = %d, want 2", count)
+	}
+	if count := history.GetUnacknowledgedCount(); count != 0 {
+		t.Fatalf("GetUnacknowledgedCount() = %d, want 0", count)
+	}
+}
+
+func TestQueryVisiblePaginatesVisibleAlerts(t *testing.T) {
+	history := NewAlertHistory(10)
+	now := time.Now().Unix()
+	for i, host := range []string{"host-a", "host-b", "host-a", "host-b", "host-a"} {
+		history.Add(models.Alert{ID: fmt.Sprintf("%s-%d", host, i), Host: host, Timestamp: now + int64(i)})
+	}
+
+	onHostA := func(alert models.Alert) bool { return alert.Host == "host-a" }
+	page, err := history.QueryVisible(models.AlertQuery{Page: 2, PageSize: 2}, onHostA)
+	if err != nil {
+		t.Fatalf("QueryVisible() error = %v", err)
+	}
+	if page.Total != 3 || page.TotalPages != 2 || len(page.Alerts) != 1 || page.Alerts[0].ID != "host-a-0" {
+		t.Fatalf("unexpected page %+v", page)
+	}
+}
+
+func TestFiringReadsEveryPage(t *testing.T) {
+	history := NewAlertHistory(1200)
+	for i := range 1100 {
+		history.Add(models.Alert{ID: fmt.Sprintf("a%d", i), Status: models.AlertStatusFiring})
+	}
+	history.Add(models.Alert{ID: "resolved", Status: models.AlertStatusResolved})
+
+	firing, err := history.Firing()
+	if err != nil {
+		t.Fatalf("Firing() error = %v", err)
+	}
+	if len(firing) != 1100 {
+		t.Fatalf("expected every firing alert, got %d", len(firing))
+	}
+}
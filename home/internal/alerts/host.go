@@ -0,0 +1,145 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/system"
+)
+
+// hostCheck is one host metric compared against its configured threshold
+type hostCheck struct {
+	id        string // unique per host, stored as the alert's RuleID
+	alertType models.AlertType
+	severity  models.AlertSeverity
+	name      string
+	value     float64
+	threshold float64
+	percent   bool
+}
+
+// checkHost evaluates CPU, memory, load and disk usage of the machine
+// running the monitor
+func (m *Monitor) checkHost(ctx context.Context) {
+	if !m.config.Host.Enabled {
+		// Resolve anything left firing from before host alerts were disabled
+		m.evaluateHostChecks("", nil, time.Now())
+		return
+	}
+
+	metrics, err := system.GetHostMetrics(ctx)
+	if err != nil {
+		log.Printf("Alert monitor: failed to read host metrics: %v", err)
+		return
+	}
+	m.evaluateHostChecks(metrics.Hostname, hostChecks(m.config.Host, metrics), time.Now())
+}
+
+// hostChecks turns host metrics into the checks enabled in cfg
+func hostChecks(cfg config.HostAlertConfig, metrics *system.HostMetrics) []hostCheck {
+	var checks []hostCheck
+
+	if cfg.CPUThreshold > 0 {
+		checks = append(checks, hostCheck{
+			id: "host-cpu", alertType: models.AlertHostCPU, severity: models.AlertSeverityWarning,
+			name: "CPU usage", value: metrics.CPUPercent, threshold: cfg.CPUThreshold, percent: true,
+		})
+	}
+	if cfg.MemoryThreshold > 0 {
+		checks = append(checks, hostCheck{
+			id: "host-memory", alertType: models.AlertHostMemory, severity: models.AlertSeverityCritical,
+			name: "memory usage", value: metrics.MemoryPercent, threshold: cfg.MemoryThreshold, percent: true,
+		})
+	}
+	if cfg.LoadThreshold > 0 && metrics.CPUCount > 0 {
+		checks = append(checks, hostCheck{
+			id: "host-load", alertType: models.AlertHostLoad, severity: models.AlertSeverityWarning,
+			name: "load average per core", value: metrics.Load5 / float64(metrics.CPUCount), threshold: cfg.LoadThreshold,
+		})
+	}
+
+	for _, d := range metrics.Disks {
+		if cfg.IgnoresMount(d.Mountpoint) {
+			continue
+		}
+		if cfg.DiskThreshold > 0 {
+			checks = append(checks, hostCheck{
+				id: "host-disk:" + d.Mountpoint, alertType: models.AlertHostDisk, severity: models.AlertSeverityCritical,
+				name: "disk usage on " + d.Mountpoint, value: d.Percent, threshold: cfg.DiskThreshold, percent: true,
+			})
+		}
+		// Some filesystems (btrfs, zfs) have no fixed inode table and report 0
+		if cfg.InodeThreshold > 0 && d.InodesPercent > 0 {
+			checks = append(checks, hostCheck{
+				id: "host-inodes:" + d.Mountpoint, alertType: models.AlertHostInodes, severity: models.AlertSeverityCritical,
+				name: "inode usage on " + d.Mountpoint, value: d.InodesPercent, threshold: cfg.InodeThreshold, percent: true,
+			})
+		}
+	}
+
+	return checks
+}
+
+// evaluateHostChecks fires and resolves host alerts. Firing host alerts
+// without a matching check, such as for an unmounted disk, are resolved.
+func (m *Monitor) evaluateHostChecks(hostname string, checks []hostCheck, now time.Time) {
+	current := make(map[string]struct{}, len(checks))
+
+	for _, check := range checks {
+		key := activeAlertKey(hostname, "", check.id)
+		current[key] = struct{}{}
+
+		breached := check.value > check.threshold
+		alert := models.Alert{
+			Type:      check.alertType,
+			Host:      hostname,
+			RuleID:    check.id,
+			Severity:  check.severity,
+			Value:     check.value,
+			Threshold: check.threshold,
+			Message: fmt.Sprintf("Host %s %s (%s) is above threshold (%s)%s",
+				hostname, check.name, check.format(check.value), check.format(check.threshold), forSuffix(m.config.Host.For)),
+		}
+		m.evaluateThreshold(key, alert, m.sustained(key, breached, m.config.Host.For, now), !breached)
+	}
+
+	var stale []string
+	m.alertsMu.Lock()
+	for key, alert := range m.activeAlerts {
+		if _, ok := current[key]; !ok && isHostAlert(alert) {
+			stale = append(stale, key)
+		}
+	}
+	for key := range m.breachSince {
+		// Host check keys have an empty container ID
+		_, rest, _ := strings.Cut(key, ":")
+		if _, ok := current[key]; !ok && strings.HasPrefix(rest, ":") {
+			delete(m.breachSince, key)
+		}
+	}
+	m.alertsMu.Unlock()
+
+	for _, key := range stale {
+		m.resolveAlert(key)
+	}
+}
+
+func (c hostCheck) format(value float64) string {
+	if c.percent {
+		return fmt.Sprintf("%.1f%%", value)
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+func isHostAlert(alert models.Alert) bool {
+	switch alert.Type {
+	case models.AlertHostCPU, models.AlertHostMemory, models.AlertHostLoad, models.AlertHostDisk, models.AlertHostInodes:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/system"
+)
+
+func TestHostChecksSkipDisabledAndIgnoredMounts(t *testing.T) {
+	cfg := config.HostAlertConfig{
+		Enabled:        true,
+		DiskThreshold:  90,
+		InodeThreshold: 90,
+		LoadThreshold:  2,
+		IgnoreMounts:   []string{"/boot*"},
+	}
+	metrics := &system.HostMetrics{
+		CPUPercent: 99,
+		CPUCount:   4,
+		Load5:      6,
+		Disks: []system.DiskUsage{
+			{Mountpoint: "/", Percent: 95, InodesPercent: 10},
+			{Mountpoint: "/boot/efi", Percent: 99, InodesPercent: 99},
+			{Mountpoint: "/data", Percent: 40},
+		},
+	}
+
+	checks := hostChecks(cfg, metrics)
+	got := make(map[string]float64, len(checks))
+	for _, check := range checks {
+		got[check.id] = check.value
+	}
+
+	want := map[string]float64{
+		"host-load":       1.5,
+		"host-disk:/":     95,
+		"host-inodes:/":   10,
+		"host-disk:/data": 40,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected checks %v, got %v", want, got)
+	}
+	for id, value := range want {
+		if got[id] != value {
+			t.Fatalf("expected checks %v, got %v", want, got)
+		}
+	}
+}
+
+func TestHostAlertFiresAfterForAndResolvesWhenMountDisappears(t *testing.T) {
+	m := newTestMonitor(0)
+	m.config.Host = config.HostAlertConfig{Enabled: true, For: time.Minute}
+	now := time.Now()
+
+	disk := hostCheck{id: "host-disk:/", alertType: models.AlertHostDisk, severity: models.AlertSeverityCritical,
+		name: "disk usage on /", value: 95, threshold: 90, percent: true}
+
+	m.evaluateHostChecks("vps", []hostCheck{disk}, now)
+	if got := len(m.history.GetAll()); got != 0 {
+		t.Fatalf("expected no alert before the for window, got %d", got)
+	}
+
+	m.evaluateHostChecks("vps", []hostCheck{disk}, now.Add(time.Minute))
+	alerts := m.history.GetAll()
+	if len(alerts) != 1 || alerts[0].Type != models.AlertHostDisk || alerts[0].Host != "vps" || alerts[0].ContainerID != "" {
+		t.Fatalf("expected one host disk alert, got %+v", alerts)
+	}
+
+	// Container rule cleanup must leave host alerts alone
+	m.resolveInactive(map[string][]models.ContainerInfo{"vps": nil}, map[string]struct{}{}, nil)
+	if alerts := m.history.GetAll(); alerts[0].Status != models.AlertStatusFiring {
+		t.Fatalf("expected host alert to keep firing, got %+v", alerts[0])
+	}
+
+	m.evaluateHostChecks("vps", nil, now.Add(2*time.Minute))
+	if alerts := m.history.GetAll(); alerts[0].Status != models.AlertStatusResolved {
+		t.Fatalf("expected host alert to resolve, got %+v", alerts[0])
+	}
+}
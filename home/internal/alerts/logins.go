@@ -0,0 +1,28 @@
+package alerts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// ReportLoginLockout raises a login_lockout alert for a client IP or
+// username locked out after repeated failed logins
+func (m *Monitor) ReportLoginLockout(lockout models.LoginLockout) {
+	subject := "client " + lockout.IP
+	if lockout.Username != "" {
+		subject = fmt.Sprintf("user %q", lockout.Username)
+	}
+	wait := time.Duration(lockout.DurationSeconds) * time.Second
+
+	m.triggerAlert(models.Alert{
+		ID:        uuid.New().String(),
+		Type:      models.AlertLoginLockout,
+		Severity:  models.AlertSeverityWarning,
+		Value:     float64(lockout.Failures),
+		Message:   fmt.Sprintf("Logins for %s are locked out for %s after %d failed attempts", subject, wait, lockout.Failures),
+		Timestamp: time.Now().Unix(),
+	})
+}
@@ -0,0 +1,29 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestReportLoginLockout(t *testing.T) {
+	m := newTestMonitor(0)
+
+	m.ReportLoginLockout(models.LoginLockout{IP: "203.0.113.7", Failures: 5, DurationSeconds: 60, Until: time.Now().Add(time.Minute).Unix()})
+	m.ReportLoginLockout(models.LoginLockout{Username: "admin", Failures: 6, DurationSeconds: 120, Until: time.Now().Add(2 * time.Minute).Unix()})
+
+	alerts := m.history.GetAll()
+	if len(alerts) != 2 {
+		t.Fatalf("expected two alerts, got %+v", alerts)
+	}
+	if alerts[0].Type != models.AlertLoginLockout || alerts[0].Severity != models.AlertSeverityWarning || alerts[0].Value != 6 {
+		t.Fatalf("unexpected username lockout alert: %+v", alerts[0])
+	}
+	if want := `Logins for user "admin" are locked out for 2m0s after 6 failed attempts`; alerts[0].Message != want {
+		t.Fatalf("unexpected message %q", alerts[0].Message)
+	}
+	if want := "Logins for client 203.0.113.7 are locked out for 1m0s after 5 failed attempts"; alerts[1].Message != want {
+		t.Fatalf("unexpected message %q", alerts[1].Message)
+	}
+}
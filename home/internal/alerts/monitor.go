@@ -6,12 +6,15 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/hhftechnology/vps-monitor/internal/config"
 	"github.com/hhftechnology/vps-monitor/internal/docker"
 	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/notify"
+	"github.com/hhftechnology/vps-monitor/internal/push"
 	"github.com/hhftechnology/vps-monitor/internal/stats"
 )
 
@@ -22,7 +25,7 @@ type Monitor struct {
 	config   *config.AlertConfig
 	history  *AlertHistory
 	stats    *stats.HistoryManager
-	store    statsStore
+	store    monitorStore
 	stopCh   chan struct{}
 	wg       sync.WaitGroup
 
@@ -31,6 +34,51 @@ type Monitor struct {
 	statesMu        sync.RWMutex
 	statsRetention  time.Duration
 	lastPrune       time.Time
+
+	rules    *RuleSet
+	silences *Silences
+
+	// Labels of known containers, keyed by host:containerID, for matching silences
+	containerLabels map[string]map[string]string
+	labelsMu        sync.RWMutex
+
+	// Firing rule alerts, keyed by host:containerID:ruleID
+	activeAlerts map[string]models.Alert
+	lastResolved map[string]time.Time
+	breachSince  map[string]time.Time // when a rule condition started holding
+	alertsMu     sync.Mutex
+
+	// Previous sample per host:containerID, used to derive I/O rates
+	lastSamples map[string]models.ContainerStats
+
+	// Docker event listeners per host and recent events per host:containerID
+	eventListeners  map[string]context.CancelFunc
+	containerEvents map[string]*containerEvents
+	running         bool
+	eventsMu        sync.Mutex
+
+	// Connectivity of each configured Docker host
+	hostStatus map[string]*models.DockerHostStatus
+	hostsMu    sync.Mutex
+
+	notifier atomic.Pointer[notify.Dispatcher]
+	pusher   atomic.Pointer[push.Service]
+
+	// Notifications being delivered in the background. Stop waits for them
+	// and cancels notifyCtx when it cannot wait any longer.
+	notifyCtx     context.Context
+	notifyCancel  context.CancelFunc
+	notifyWg      sync.WaitGroup
+	notifyStopped bool
+	notifyMu      sync.Mutex
+
+	// Notified alerts awaiting acknowledgement, keyed by alert ID
+	escalations   map[string]*escalation
+	escalationsMu sync.Mutex
+
+	// Alert stream subscribers
+	subscribers   map[chan models.AlertEvent]struct{}
+	subscribersMu sync.Mutex
 }
 
 type statsStore interface {
@@ -38,17 +86,49 @@ type statsStore interface {
 	PruneContainerStatsOlderThan(cutoff time.Time) error
 }
 
+// monitorStore persists container samples, alert history, alert rules and silences.
+type monitorStore interface {
+	statsStore
+	AlertStore
+	RuleStore
+	SilenceStore
+}
+
 // NewMonitor creates a new alert monitor
-func NewMonitor(dockerClient *docker.MultiHostClient, alertConfig *config.AlertConfig, store statsStore, statsRetention time.Duration) *Monitor {
+func NewMonitor(dockerClient *docker.MultiHostClient, alertConfig *config.AlertConfig, store monitorStore, statsRetention time.Duration) *Monitor {
+	history := NewAlertHistory(100) // Keep last 100 alerts when nothing is persisted
+	var ruleStore RuleStore
+	var silenceStore SilenceStore
+	if store != nil {
+		history = NewPersistentAlertHistory(store, 100)
+		ruleStore = store
+		silenceStore = store
+	}
+
+	notifyCtx, notifyCancel := context.WithCancel(context.Background())
 	return &Monitor{
 		docker:          dockerClient,
 		config:          alertConfig,
-		history:         NewAlertHistory(100), // Keep last 100 alerts
+		history:         history,
 		stats:           stats.NewHistoryManager(),
 		store:           store,
 		stopCh:          make(chan struct{}),
 		containerStates: make(map[string]string),
 		statsRetention:  statsRetention,
+		rules:           NewRuleSet(ruleStore, DefaultRules(alertConfig)),
+		silences:        NewSilences(silenceStore),
+		containerLabels: make(map[string]map[string]string),
+		activeAlerts:    make(map[string]models.Alert),
+		lastResolved:    make(map[string]time.Time),
+		breachSince:     make(map[string]time.Time),
+		lastSamples:     make(map[string]models.ContainerStats),
+		eventListeners:  make(map[string]context.CancelFunc),
+		containerEvents: make(map[string]*containerEvents),
+		hostStatus:      make(map[string]*models.DockerHostStatus),
+		escalations:     make(map[string]*escalation),
+		subscribers:     make(map[chan models.AlertEvent]struct{}),
+		notifyCtx:       notifyCtx,
+		notifyCancel:    notifyCancel,
 	}
 }
 
@@ -56,6 +136,18 @@ func (m *Monitor) UpdateDockerClient(client *docker.MultiHostClient) {
 	m.dockerMu.Lock()
 	m.docker = client
 	m.dockerMu.Unlock()
+
+	m.syncEventListeners()
+}
+
+// SetNotifier sets the dispatcher that delivers alert notifications
+func (m *Monitor) SetNotifier(dispatcher *notify.Dispatcher) {
+	m.notifier.Store(dispatcher)
+}
+
+// SetPusher sets the service that pushes alerts to registered mobile devices
+func (m *Monitor) SetPusher(pusher *push.Service) {
+	m.pusher.Store(pusher)
 }
 
 func (m *Monitor) getDockerClient() *docker.MultiHostClient {
@@ -66,17 +158,47 @@ func (m *Monitor) getDockerClient() *docker.MultiHostClient {
 
 // Start begins the background monitoring
 func (m *Monitor) Start() {
-	log.Printf("Starting alert monitor (interval: %s, CPU threshold: %.1f%%, Memory threshold: %.1f%%)",
-		m.config.CheckInterval, m.config.CPUThreshold, m.config.MemoryThreshold)
+	rules, err := m.rules.List()
+	if err != nil {
+		log.Printf("Alert monitor: failed to load alert rules: %v", err)
+	}
+	log.Printf("Starting alert monitor (interval: %s, %d alert rules)", m.config.CheckInterval, len(rules))
+
+	m.restoreActiveAlerts()
+
+	m.eventsMu.Lock()
+	m.running = true
+	m.eventsMu.Unlock()
+	m.syncEventListeners()
 
 	m.wg.Add(1)
 	go m.monitorLoop()
 }
 
-// Stop gracefully stops the monitor
-func (m *Monitor) Stop() {
+// Stop gracefully stops the monitor. Notifications still being delivered
+// get until ctx ends, then they are cancelled.
+func (m *Monitor) Stop(ctx context.Context) {
+	m.stopEventListeners()
 	close(m.stopCh)
 	m.wg.Wait()
+
+	m.notifyMu.Lock()
+	m.notifyStopped = true
+	m.notifyMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.notifyWg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("Alert monitor: cancelling notifications still being delivered: %v", ctx.Err())
+		m.notifyCancel()
+		<-done
+	}
+	m.notifyCancel()
 	log.Println("Alert monitor stopped")
 }
 
@@ -85,6 +207,16 @@ func (m *Monitor) GetHistory() *AlertHistory {
 	return m.history
 }
 
+// GetRules returns the alert rules evaluated by the monitor
+func (m *Monitor) GetRules() *RuleSet {
+	return m.rules
+}
+
+// GetSilences returns the silences and maintenance windows applied to alerts
+func (m *Monitor) GetSilences() *Silences {
+	return m.silences
+}
+
 func (m *Monitor) GetStatsHistory() *stats.HistoryManager {
 	return m.stats
 }
@@ -109,26 +241,63 @@ func (m *Monitor) monitorLoop() {
 	}
 }
 
+// restoreActiveAlerts reloads alerts that were still firing when the
+// process last stopped, so they resolve instead of firing a second time
+func (m *Monitor) restoreActiveAlerts() {
+	firing, err := m.history.Firing()
+	if err != nil {
+		log.Printf("Alert monitor: failed to load firing alerts: %v", err)
+		return
+	}
+
+	m.alertsMu.Lock()
+	defer m.alertsMu.Unlock()
+	for _, alert := range firing {
+		if alert.RuleID == "" {
+			// Alerts raised before rules existed map onto the default rules
+			switch alert.Type {
+			case models.AlertCPUThreshold:
+				alert.RuleID = DefaultCPURuleID
+			case models.AlertMemoryThreshold:
+				alert.RuleID = DefaultMemoryRuleID
+			}
+		}
+		key := activeAlertKey(alert.Host, alert.ContainerID, alert.RuleID)
+		if _, exists := m.activeAlerts[key]; !exists {
+			m.activeAlerts[key] = alert
+			if m.config.AlertsFilter != "critical" || isCriticalAlert(alert) {
+				m.trackEscalation(alert)
+			}
+		}
+	}
+}
+
 // checkAll performs all monitoring checks
 func (m *Monitor) checkAll() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	m.checkContainerStates(ctx)
-	m.checkResourceThresholds(ctx)
+	containersMap, hostErrors := m.checkContainerStates(ctx)
+	m.checkDockerHosts(ctx, hostErrors)
+	m.checkRules(ctx, containersMap)
+	m.checkHost(ctx)
+	m.checkEscalations(time.Now())
+	m.pruneHistory()
 }
 
-// checkContainerStates checks for container state changes
-func (m *Monitor) checkContainerStates(ctx context.Context) {
+// checkContainerStates checks for container state changes and returns the
+// listed containers and the hosts whose containers could not be listed. The
+// map is nil when listing failed.
+func (m *Monitor) checkContainerStates(ctx context.Context) (map[string][]models.ContainerInfo, []docker.HostError) {
 	dockerClient := m.getDockerClient()
 	if dockerClient == nil {
-		return
+		return nil, nil
 	}
 
-	containersMap, _, err := dockerClient.ListContainersAllHosts(ctx)
+	containersMap, hostErrors, err := dockerClient.ListContainersAllHosts(ctx)
 	if err != nil {
 		log.Printf("Alert monitor: failed to list containers: %v", err)
-		return
+		return nil, nil
 	}
 
 	m.statesMu.Lock()
@@ -146,9 +315,11 @@ func (m *Monitor) checkContainerStates(ctx context.Context) {
 			if len(ctr.Names) > 0 {
 				containerName = strings.TrimPrefix(ctr.Names[0], "/")
 			}
+			m.setContainerLabels(key, ctr.Labels, true)
 
 			// Check if state changed
-			if prevState, exists := m.containerStates[key]; exists {
+			disabled := resolveThresholds(m.config, hostName, containerName, ctr.Labels).disabled
+			if prevState, exists := m.containerStates[key]; exists && !disabled {
 				if prevState != ctr.State {
 					// State changed
 					if ctr.State == "exited" || ctr.State == "dead" {
@@ -183,31 +354,54 @@ func (m *Monitor) checkContainerStates(ctx context.Context) {
 	for key := range m.containerStates {
 		if _, exists := currentContainers[key]; !exists {
 			delete(m.containerStates, key)
+			m.forgetContainerEvents(key)
 			parts := strings.SplitN(key, ":", 2)
 			if len(parts) == 2 {
 				m.stats.CleanupContainer(parts[0], parts[1])
 			}
 		}
 	}
+
+	m.labelsMu.Lock()
+	for key := range m.containerLabels {
+		if _, exists := currentContainers[key]; !exists {
+			delete(m.containerLabels, key)
+		}
+	}
+	m.labelsMu.Unlock()
+
+	return containersMap, hostErrors
 }
 
-// checkResourceThresholds checks CPU and memory thresholds
-func (m *Monitor) checkResourceThresholds(ctx context.Context) {
+// checkRules samples the running containers listed by checkContainerStates
+// and evaluates the alert rules
+func (m *Monitor) checkRules(ctx context.Context, containersMap map[string][]models.ContainerInfo) {
 	dockerClient := m.getDockerClient()
-	if dockerClient == nil {
+	if dockerClient == nil || containersMap == nil {
 		return
 	}
 
-	containersMap, _, err := dockerClient.ListContainersAllHosts(ctx)
+	allRules, err := m.rules.List()
 	if err != nil {
-		return
+		log.Printf("Alert monitor: failed to load alert rules: %v", err)
+	}
+	rules := make([]models.AlertRule, 0, len(allRules))
+	for _, rule := range allRules {
+		if rule.Enabled {
+			rules = append(rules, rule)
+		}
 	}
 
+	// Containers that are still running; rule alerts for anything else are resolved
+	running := make(map[string]struct{})
+
 	for hostName, containers := range containersMap {
 		for _, ctr := range containers {
 			if ctr.State != "running" {
 				continue
 			}
+			containerKey := fmt.Sprintf("%s:%s", hostName, ctr.ID)
+			running[containerKey] = struct{}{}
 
 			stats, err := dockerClient.GetContainerStatsOnce(ctx, hostName, ctr.ID)
 			if err != nil {
@@ -225,45 +419,342 @@ func (m *Monitor) checkResourceThresholds(ctx context.Context) {
 				containerName = strings.TrimPrefix(ctr.Names[0], "/")
 			}
 
-			// Check CPU threshold
-			if stats.CPUPercent > m.config.CPUThreshold {
-				m.triggerAlert(models.Alert{
-					ID:            uuid.New().String(),
-					Type:          models.AlertCPUThreshold,
-					ContainerID:   ctr.ID,
-					ContainerName: containerName,
-					Host:          hostName,
-					Message:       fmt.Sprintf("Container %s CPU usage (%.1f%%) exceeds threshold (%.1f%%)", containerName, stats.CPUPercent, m.config.CPUThreshold),
-					Value:         stats.CPUPercent,
-					Threshold:     m.config.CPUThreshold,
-					Timestamp:     time.Now().Unix(),
-				})
+			sample := &metricSample{current: *stats}
+			if prev, ok := m.lastSamples[containerKey]; ok {
+				sample.previous = &prev
 			}
+			m.lastSamples[containerKey] = *stats
+
+			limits := resolveThresholds(m.config, hostName, containerName, ctr.Labels)
 
-			// Check memory threshold
-			if stats.MemoryPercent > m.config.MemoryThreshold {
-				m.triggerAlert(models.Alert{
-					ID:            uuid.New().String(),
-					Type:          models.AlertMemoryThreshold,
+			for _, rule := range rules {
+				key := activeAlertKey(hostName, ctr.ID, rule.ID)
+				// Disabled or out-of-scope containers only resolve what is already firing
+				if limits.disabled || !scopeMatches(rule.Scope, hostName, ctr, containerName) {
+					m.evaluateThreshold(key, models.Alert{}, false, true)
+					continue
+				}
+
+				value, ok := m.metricValue(ctx, dockerClient, rule.Metric, hostName, ctr.ID, sample)
+				if !ok {
+					continue
+				}
+				m.evaluateRule(applyThresholdOverrides(rule, limits), models.Alert{
 					ContainerID:   ctr.ID,
 					ContainerName: containerName,
 					Host:          hostName,
-					Message:       fmt.Sprintf("Container %s memory usage (%.1f%%) exceeds threshold (%.1f%%)", containerName, stats.MemoryPercent, m.config.MemoryThreshold),
-					Value:         stats.MemoryPercent,
-					Threshold:     m.config.MemoryThreshold,
-					Timestamp:     time.Now().Unix(),
-				})
+				}, value, time.Now())
+			}
+		}
+	}
+
+	m.resolveInactive(containersMap, running, rules)
+}
+
+// metricSample holds what is known about a container during one check
+type metricSample struct {
+	current      models.ContainerStats
+	previous     *models.ContainerStats
+	restartCount *int
+}
+
+// metricValue returns the value of metric for a container. ok is false when
+// the value cannot be determined yet, e.g. rates before a second sample.
+func (m *Monitor) metricValue(ctx context.Context, dockerClient *docker.MultiHostClient, metric models.AlertRuleMetric, host, containerID string, sample *metricSample) (float64, bool) {
+	cur := sample.current
+	switch metric {
+	case models.MetricCPU:
+		return cur.CPUPercent, true
+	case models.MetricMemory:
+		return cur.MemoryPercent, true
+	case models.MetricPIDs:
+		return float64(cur.PIDs), true
+	case models.MetricNetworkRxRate:
+		return counterRate(sample.previous, cur, func(s models.ContainerStats) uint64 { return s.NetworkRx })
+	case models.MetricNetworkTxRate:
+		return counterRate(sample.previous, cur, func(s models.ContainerStats) uint64 { return s.NetworkTx })
+	case models.MetricBlockReadRate:
+		return counterRate(sample.previous, cur, func(s models.ContainerStats) uint64 { return s.BlockRead })
+	case models.MetricBlockWriteRate:
+		return counterRate(sample.previous, cur, func(s models.ContainerStats) uint64 { return s.BlockWrite })
+	case models.MetricRestartCount:
+		if sample.restartCount == nil {
+			info, err := dockerClient.GetContainer(ctx, host, containerID)
+			if err != nil || info.ContainerJSONBase == nil {
+				return 0, false
 			}
+			sample.restartCount = &info.RestartCount
 		}
+		return float64(*sample.restartCount), true
+	default:
+		return 0, false
+	}
+}
+
+// counterRate derives a per-second rate from two samples of a cumulative counter
+func counterRate(prev *models.ContainerStats, cur models.ContainerStats, counter func(models.ContainerStats) uint64) (float64, bool) {
+	if prev == nil {
+		return 0, false
+	}
+	elapsed := cur.Timestamp - prev.Timestamp
+	before, after := counter(*prev), counter(cur)
+	if elapsed <= 0 || after < before {
+		return 0, false
+	}
+	return float64(after-before) / float64(elapsed), true
+}
+
+// applyThresholdOverrides applies per-host, per-container and label
+// overrides to the default CPU and memory rules
+func applyThresholdOverrides(rule models.AlertRule, limits thresholds) models.AlertRule {
+	var threshold float64
+	switch rule.ID {
+	case DefaultCPURuleID:
+		threshold = limits.cpu
+	case DefaultMemoryRuleID:
+		threshold = limits.memory
+	}
+	if threshold <= 0 {
+		return rule
 	}
+	rule.Threshold = threshold
+	if rule.ClearThreshold > 0 && compare(rule.Comparator, rule.ClearThreshold, threshold) {
+		rule.ClearThreshold = 0
+	}
+	return rule
+}
+
+// evaluateRule fires the rule's alert once its condition has held for
+// ForSeconds and resolves it once the value passes the clear threshold
+func (m *Monitor) evaluateRule(rule models.AlertRule, alert models.Alert, value float64, now time.Time) {
+	key := activeAlertKey(alert.Host, alert.ContainerID, rule.ID)
+
+	breached := compare(rule.Comparator, value, rule.Threshold)
+	clearAt := rule.Threshold
+	if rule.ClearThreshold > 0 {
+		clearAt = rule.ClearThreshold
+	}
+	cleared := !compare(rule.Comparator, value, clearAt)
+
+	sustained := m.sustained(key, breached, time.Duration(rule.ForSeconds)*time.Second, now)
+
+	alert.Type = ruleAlertType(rule.Metric)
+	alert.RuleID = rule.ID
+	alert.Severity = rule.Severity
+	alert.Value = value
+	alert.Threshold = rule.Threshold
+	alert.Message = fmt.Sprintf("Container %s %s (%s) is %s threshold (%s)%s",
+		alert.ContainerName, rule.Name, formatMetric(rule.Metric, value), comparatorText[rule.Comparator],
+		formatMetric(rule.Metric, rule.Threshold), forSuffix(time.Duration(rule.ForSeconds)*time.Second))
+
+	m.evaluateThreshold(key, alert, sustained, cleared)
+}
+
+// sustained tracks when the condition under key started holding and reports
+// whether it has held for at least window
+func (m *Monitor) sustained(key string, breached bool, window time.Duration, now time.Time) bool {
+	m.alertsMu.Lock()
+	defer m.alertsMu.Unlock()
+
+	if !breached {
+		delete(m.breachSince, key)
+		return false
+	}
+	since, ok := m.breachSince[key]
+	if !ok {
+		since = now
+		m.breachSince[key] = now
+	}
+	return now.Sub(since) >= window
+}
+
+// evaluateThreshold opens the alert when breached is true and resolves any
+// matching firing alert once cleared is true. Between the two the current
+// state is kept, so a metric hovering around the limit does not flap.
+func (m *Monitor) evaluateThreshold(key string, alert models.Alert, breached, cleared bool) {
+	switch {
+	case breached:
+		m.openAlert(key, alert)
+	case cleared:
+		m.alertsMu.Lock()
+		delete(m.breachSince, key)
+		m.alertsMu.Unlock()
+		m.resolveAlert(key)
+	}
+}
 
-	if m.store != nil && m.statsRetention > 0 && (m.lastPrune.IsZero() || time.Since(m.lastPrune) >= time.Hour) {
+func forSuffix(window time.Duration) string {
+	if window <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" for %s", window)
+}
+
+// openAlert fires alert unless the same alert is already firing or was
+// resolved less than the configured cooldown ago
+func (m *Monitor) openAlert(key string, alert models.Alert) {
+	if !m.config.Enabled {
+		return
+	}
+
+	now := time.Now()
+
+	m.alertsMu.Lock()
+	if _, firing := m.activeAlerts[key]; firing {
+		m.alertsMu.Unlock()
+		return
+	}
+	if resolvedAt, ok := m.lastResolved[key]; ok && now.Sub(resolvedAt) < m.config.Cooldown {
+		m.alertsMu.Unlock()
+		return
+	}
+	delete(m.lastResolved, key)
+
+	alert.ID = uuid.New().String()
+	alert.Status = models.AlertStatusFiring
+	alert.Timestamp = now.Unix()
+	// Silences are decided when the alert opens, so its resolution follows suit
+	m.applySilences(&alert)
+	m.activeAlerts[key] = alert
+	m.alertsMu.Unlock()
+
+	m.triggerAlert(alert)
+}
+
+// resolveAlert closes the firing alert stored under key, if any
+func (m *Monitor) resolveAlert(key string) {
+	alert, ok := m.closeAlert(key)
+	if !ok {
+		return
+	}
+
+	subject := alert.ContainerName
+	if subject == "" {
+		subject = alert.Host
+	}
+	log.Printf("Alert resolved: %s - %s (after %s)", alert.Type, subject, time.Duration(alert.DurationSeconds)*time.Second)
+	if alert.Silenced {
+		return
+	}
+	m.notify(alert, notify.EventResolved)
+}
+
+// closeAlert marks the firing alert stored under key as resolved in the
+// history without notifying. Returns false when nothing was firing.
+func (m *Monitor) closeAlert(key string) (models.Alert, bool) {
+	now := time.Now()
+
+	m.alertsMu.Lock()
+	alert, firing := m.activeAlerts[key]
+	if !firing {
+		m.alertsMu.Unlock()
+		return alert, false
+	}
+	delete(m.activeAlerts, key)
+	m.lastResolved[key] = now
+	m.alertsMu.Unlock()
+	m.stopEscalation(alert.ID)
+
+	alert.Status = models.AlertStatusResolved
+	alert.ResolvedAt = now.Unix()
+	alert.DurationSeconds = max(alert.ResolvedAt-alert.Timestamp, 0)
+	m.history.Resolve(alert.ID, alert.ResolvedAt)
+	m.publishAlert(models.AlertEventResolved, alert)
+	return alert, true
+}
+
+// resolveInactive resolves firing alerts for containers that are no longer
+// running and for rules that were disabled or deleted. Hosts missing from
+// containersMap could not be listed and are left untouched.
+func (m *Monitor) resolveInactive(containersMap map[string][]models.ContainerInfo, running map[string]struct{}, rules []models.AlertRule) {
+	enabled := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		enabled[rule.ID] = struct{}{}
+	}
+
+	var stale []string
+
+	m.alertsMu.Lock()
+	for key, alert := range m.activeAlerts {
+		if alert.ContainerID == "" {
+			continue // host alerts are managed by their own checks
+		}
+		if _, ok := enabled[alert.RuleID]; !ok {
+			stale = append(stale, key)
+			continue
+		}
+		if _, listed := containersMap[alert.Host]; !listed {
+			continue
+		}
+		if _, ok := running[fmt.Sprintf("%s:%s", alert.Host, alert.ContainerID)]; !ok {
+			stale = append(stale, key)
+		}
+	}
+	for key := range m.breachSince {
+		host, rest, _ := strings.Cut(key, ":")
+		containerID, _, _ := strings.Cut(rest, ":")
+		if containerID == "" {
+			continue // host checks
+		}
+		if _, listed := containersMap[host]; !listed {
+			continue
+		}
+		if _, ok := running[host+":"+containerID]; !ok {
+			delete(m.breachSince, key)
+		}
+	}
+	for key, resolvedAt := range m.lastResolved {
+		if time.Since(resolvedAt) >= m.config.Cooldown {
+			delete(m.lastResolved, key)
+		}
+	}
+	m.alertsMu.Unlock()
+
+	for key := range m.lastSamples {
+		host, _, _ := strings.Cut(key, ":")
+		if _, listed := containersMap[host]; !listed {
+			continue
+		}
+		if _, ok := running[key]; !ok {
+			delete(m.lastSamples, key)
+		}
+	}
+
+	for _, key := range stale {
+		m.resolveAlert(key)
+	}
+}
+
+func activeAlertKey(host, containerID, ruleID string) string {
+	return fmt.Sprintf("%s:%s:%s", host, containerID, ruleID)
+}
+
+// pruneHistory removes persisted stats and alerts past their retention, at most once an hour.
+func (m *Monitor) pruneHistory() {
+	if !m.lastPrune.IsZero() && time.Since(m.lastPrune) < time.Hour {
+		return
+	}
+
+	ok := true
+	if m.store != nil && m.statsRetention > 0 {
 		if err := m.store.PruneContainerStatsOlderThan(time.Now().Add(-m.statsRetention)); err != nil {
 			log.Printf("Alert monitor: failed to prune persisted stats: %v", err)
-		} else {
-			m.lastPrune = time.Now()
+			ok = false
 		}
 	}
+	if m.config.HistoryRetention > 0 {
+		if err := m.history.Prune(time.Now().Add(-m.config.HistoryRetention)); err != nil {
+			log.Printf("Alert monitor: failed to prune alert history: %v", err)
+			ok = false
+		}
+		if err := m.silences.Prune(time.Now().Add(-m.config.HistoryRetention)); err != nil {
+			log.Printf("Alert monitor: failed to prune expired silences: %v", err)
+			ok = false
+		}
+	}
+	if ok {
+		m.lastPrune = time.Now()
+	}
 }
 
 // triggerAlert handles a new alert
@@ -272,28 +763,101 @@ func (m *Monitor) triggerAlert(alert models.Alert) {
 		return
 	}
 
-	log.Printf("Alert: %s - %s", alert.Type, alert.Message)
+	m.applySilences(&alert)
+	if alert.Silenced {
+		log.Printf("Alert (silenced by %s): %s - %s", alert.SilencedBy, alert.Type, alert.Message)
+	} else {
+		log.Printf("Alert: %s - %s", alert.Type, alert.Message)
+	}
 
-	// Add to history
+	// Add to history; silenced alerts are kept there but not notified
 	m.history.Add(alert)
+	m.publishAlert(models.AlertEventFired, alert)
+	if alert.Silenced {
+		return
+	}
 
-	// Send webhook
-	if m.config.WebhookURL != "" {
-		if m.config.AlertsFilter == "critical" && !isCriticalAlert(alert) {
-			return
-		}
+	m.notify(alert, notify.EventFired)
+}
 
-		go func() {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
+// applySilences marks alert as silenced when an active silence or
+// maintenance window matches it at the time it was raised
+func (m *Monitor) applySilences(alert *models.Alert) {
+	if alert.Silenced {
+		return
+	}
 
-			if err := SendWebhook(ctx, m.config.WebhookURL, alert); err != nil {
-				log.Printf("Failed to send webhook for alert %s: %v", alert.ID, err)
-			}
-		}()
+	var labels map[string]string
+	if alert.ContainerID != "" {
+		labels = m.ContainerLabels(alert.Host, alert.ContainerID)
+	}
+
+	at := time.Now()
+	if alert.Timestamp > 0 {
+		at = time.Unix(alert.Timestamp, 0)
+	}
+	silencedBy, err := m.silences.Match(*alert, labels, at)
+	if err != nil {
+		log.Printf("Alert monitor: failed to check silences: %v", err)
+		return
+	}
+	if silencedBy != "" {
+		alert.Silenced = true
+		alert.SilencedBy = silencedBy
 	}
 }
 
+// ContainerLabels returns the labels last seen on a container, or nil for
+// containers the monitor does not know (anymore)
+func (m *Monitor) ContainerLabels(host, containerID string) map[string]string {
+	m.labelsMu.RLock()
+	defer m.labelsMu.RUnlock()
+	return m.containerLabels[fmt.Sprintf("%s:%s", host, containerID)]
+}
+
+// setContainerLabels records the labels of the container under key
+// (host:containerID). Unless replace is set, known labels are kept.
+func (m *Monitor) setContainerLabels(key string, labels map[string]string, replace bool) {
+	m.labelsMu.Lock()
+	defer m.labelsMu.Unlock()
+	if _, known := m.containerLabels[key]; known && !replace {
+		return
+	}
+	m.containerLabels[key] = labels
+}
+
+// notify sends an alert transition to the notification channels
+func (m *Monitor) notify(alert models.Alert, event string) {
+	dispatcher := m.notifier.Load()
+	if dispatcher == nil && m.pusher.Load() == nil {
+		return
+	}
+	if m.config.AlertsFilter == "critical" && !isCriticalAlert(alert) {
+		return
+	}
+
+	if event == notify.EventFired && dispatcher != nil {
+		m.trackEscalation(alert)
+	}
+	m.dispatch(dispatcher, alert, event, nil)
+}
+
 func isCriticalAlert(alert models.Alert) bool {
-	return alert.Type == models.AlertCPUThreshold || alert.Type == models.AlertMemoryThreshold
+	return effectiveSeverity(alert) == models.AlertSeverityCritical
+}
+
+// effectiveSeverity returns the alert's severity, deriving one for alerts
+// recorded before severities existed
+func effectiveSeverity(alert models.Alert) models.AlertSeverity {
+	if alert.Severity != "" {
+		return alert.Severity
+	}
+	switch alert.Type {
+	case models.AlertCPUThreshold, models.AlertMemoryThreshold:
+		return models.AlertSeverityCritical
+	case models.AlertContainerStopped:
+		return models.AlertSeverityWarning
+	default:
+		return models.AlertSeverityInfo
+	}
 }
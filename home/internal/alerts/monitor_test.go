@@ -2,7 +2,9 @@ package alerts
 
 import (
 	"testing"
+	"time"
 
+	"github.com/hhftechnology/vps-monitor/internal/config"
 	"github.com/hhftechnology/vps-monitor/internal/models"
 )
 
@@ -17,3 +19,193 @@ func TestIsCriticalAlertMatchesThresholdAlertsOnly(t *testing.T) {
 		t.Fatal("expected container stopped alerts to be excluded from critical-only filtering")
 	}
 }
+
+func TestIsCriticalAlertPrefersSeverity(t *testing.T) {
+	if isCriticalAlert(models.Alert{Type: models.AlertCPUThreshold, Severity: models.AlertSeverityWarning}) {
+		t.Fatal("expected a warning CPU alert not to be critical")
+	}
+	if !isCriticalAlert(models.Alert{Type: models.AlertMetricThreshold, Severity: models.AlertSeverityCritical}) {
+		t.Fatal("expected a critical rule alert to be critical")
+	}
+}
+
+func newTestMonitor(cooldown time.Duration) *Monitor {
+	return NewMonitor(nil, &config.AlertConfig{
+		Enabled:         true,
+		Cooldown:        cooldown,
+		CPUThreshold:    80,
+		MemoryThreshold: 90,
+	}, nil, 0)
+}
+
+func cpuRule() models.AlertRule {
+	return models.AlertRule{
+		ID:         DefaultCPURuleID,
+		Name:       "High CPU usage",
+		Enabled:    true,
+		Metric:     models.MetricCPU,
+		Comparator: models.ComparatorGreater,
+		Threshold:  80,
+		Severity:   models.AlertSeverityCritical,
+	}
+}
+
+func webContainer(host string) models.Alert {
+	return models.Alert{ContainerID: "c1", ContainerName: "web", Host: host}
+}
+
+func TestRuleAlertFiresOnceAndResolves(t *testing.T) {
+	m := newTestMonitor(0)
+	now := time.Now()
+
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 95, now)
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 97, now)
+
+	alerts := m.history.GetAll()
+	if len(alerts) != 1 {
+		t.Fatalf("expected a single firing alert, got %d", len(alerts))
+	}
+	if alerts[0].Status != models.AlertStatusFiring || alerts[0].RuleID != DefaultCPURuleID {
+		t.Fatalf("unexpected firing alert: %+v", alerts[0])
+	}
+	if alerts[0].Type != models.AlertCPUThreshold || alerts[0].Severity != models.AlertSeverityCritical {
+		t.Fatalf("unexpected alert type or severity: %+v", alerts[0])
+	}
+
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 10, now)
+
+	alerts = m.history.GetAll()
+	if len(alerts) != 1 || alerts[0].Status != models.AlertStatusResolved {
+		t.Fatalf("expected the alert to be resolved, got %+v", alerts)
+	}
+	if alerts[0].ResolvedAt == 0 {
+		t.Fatal("expected resolved_at to be set")
+	}
+
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 95, now)
+	if got := len(m.history.GetAll()); got != 2 {
+		t.Fatalf("expected a new alert after resolve without cooldown, got %d", got)
+	}
+}
+
+func TestRuleAlertCooldownSuppressesRefire(t *testing.T) {
+	m := newTestMonitor(time.Hour)
+	now := time.Now()
+
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 95, now)
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 10, now)
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 95, now)
+
+	if got := len(m.history.GetAll()); got != 1 {
+		t.Fatalf("expected cooldown to suppress the refire, got %d alerts", got)
+	}
+}
+
+func TestRuleAlertClearThresholdKeepsAlertFiring(t *testing.T) {
+	m := newTestMonitor(0)
+	rule := cpuRule()
+	rule.ClearThreshold = 70
+	now := time.Now()
+
+	m.evaluateRule(rule, webContainer("host-a"), 95, now)
+	// Back under the threshold but still above the clear threshold
+	m.evaluateRule(rule, webContainer("host-a"), 75, now)
+
+	alerts := m.history.GetAll()
+	if len(alerts) != 1 || alerts[0].Status != models.AlertStatusFiring {
+		t.Fatalf("expected the alert to keep firing, got %+v", alerts)
+	}
+
+	m.evaluateRule(rule, webContainer("host-a"), 70, now)
+	if alerts := m.history.GetAll(); alerts[0].Status != models.AlertStatusResolved {
+		t.Fatalf("expected the alert to resolve at the clear threshold, got %+v", alerts[0])
+	}
+}
+
+func TestRuleForDurationDelaysFiring(t *testing.T) {
+	m := newTestMonitor(0)
+	rule := cpuRule()
+	rule.ForSeconds = 300
+	start := time.Now()
+
+	m.evaluateRule(rule, webContainer("host-a"), 95, start)
+	m.evaluateRule(rule, webContainer("host-a"), 95, start.Add(4*time.Minute))
+	if got := len(m.history.GetAll()); got != 0 {
+		t.Fatalf("expected no alert before the window elapsed, got %d", got)
+	}
+
+	// A dip restarts the window
+	m.evaluateRule(rule, webContainer("host-a"), 50, start.Add(4*time.Minute+30*time.Second))
+	m.evaluateRule(rule, webContainer("host-a"), 95, start.Add(5*time.Minute))
+	m.evaluateRule(rule, webContainer("host-a"), 95, start.Add(9*time.Minute))
+	if got := len(m.history.GetAll()); got != 0 {
+		t.Fatalf("expected the dip to restart the window, got %d alerts", got)
+	}
+
+	m.evaluateRule(rule, webContainer("host-a"), 95, start.Add(10*time.Minute))
+	if got := len(m.history.GetAll()); got != 1 {
+		t.Fatalf("expected the alert once the condition held for 5m, got %d", got)
+	}
+}
+
+func TestResolveInactiveSkipsUnlistedHosts(t *testing.T) {
+	m := newTestMonitor(0)
+	now := time.Now()
+
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 95, now)
+	m.evaluateRule(cpuRule(), webContainer("host-b"), 95, now)
+
+	// host-a was listed without c1 running; host-b failed to list
+	m.resolveInactive(map[string][]models.ContainerInfo{"host-a": {}}, map[string]struct{}{}, []models.AlertRule{cpuRule()})
+
+	page, err := m.history.Query(models.AlertQuery{Status: models.AlertStatusFiring})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if page.Total != 1 || page.Alerts[0].Host != "host-b" {
+		t.Fatalf("expected only the host-b alert to keep firing, got %+v", page.Alerts)
+	}
+
+	// Disabling the rule resolves the rest
+	m.resolveInactive(map[string][]models.ContainerInfo{}, map[string]struct{}{}, nil)
+	page, err = m.history.Query(models.AlertQuery{Status: models.AlertStatusFiring})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if page.Total != 0 {
+		t.Fatalf("expected alerts of disabled rules to resolve, got %+v", page.Alerts)
+	}
+}
+
+func TestCounterRate(t *testing.T) {
+	prev := models.ContainerStats{NetworkRx: 1000, Timestamp: 100}
+	cur := models.ContainerStats{NetworkRx: 4000, Timestamp: 110}
+	rx := func(s models.ContainerStats) uint64 { return s.NetworkRx }
+
+	if rate, ok := counterRate(&prev, cur, rx); !ok || rate != 300 {
+		t.Fatalf("counterRate() = %v, %v; want 300, true", rate, ok)
+	}
+	if _, ok := counterRate(nil, cur, rx); ok {
+		t.Fatal("expected no rate without a previous sample")
+	}
+	reset := models.ContainerStats{NetworkRx: 10, Timestamp: 120}
+	if _, ok := counterRate(&cur, reset, rx); ok {
+		t.Fatal("expected no rate after a counter reset")
+	}
+}
+
+func TestApplyThresholdOverridesOnlyTouchesDefaultRules(t *testing.T) {
+	rule := cpuRule()
+	rule.ClearThreshold = 70
+
+	got := applyThresholdOverrides(rule, thresholds{cpu: 60})
+	if got.Threshold != 60 || got.ClearThreshold != 0 {
+		t.Fatalf("expected the override with the clear threshold dropped, got %+v", got)
+	}
+
+	custom := cpuRule()
+	custom.ID = "custom"
+	if got := applyThresholdOverrides(custom, thresholds{cpu: 60}); got.Threshold != 80 {
+		t.Fatalf("expected custom rules to ignore overrides, got %v", got.Threshold)
+	}
+}
@@ -0,0 +1,355 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// IDs of the rules seeded from the ALERTS_CPU_* and ALERTS_MEMORY_* settings
+const (
+	DefaultCPURuleID    = "default-cpu"
+	DefaultMemoryRuleID = "default-memory"
+)
+
+// defaultRulesSeededKey marks that default rules were written once, so
+// deleting them is not undone on the next start
+const defaultRulesSeededKey = "alerts.default_rules_seeded"
+
+// ErrInvalidRule is returned when an alert rule fails validation
+var ErrInvalidRule = errors.New("invalid alert rule")
+
+// RuleStore persists alert rules
+type RuleStore interface {
+	ListAlertRules() ([]models.AlertRule, error)
+	GetAlertRule(id string) (*models.AlertRule, error)
+	SaveAlertRule(rule models.AlertRule) error
+	DeleteAlertRule(id string) (bool, error)
+	GetSetting(key string) (string, error)
+	SetSetting(key, value string) error
+}
+
+// RuleSet holds the alert rules evaluated by the monitor, either in a
+// persistent store or, when none is configured, in memory
+type RuleSet struct {
+	store RuleStore
+	mu    sync.RWMutex
+	rules []models.AlertRule
+}
+
+// NewRuleSet creates a rule set backed by store, seeding it with defaults
+// the first time it is used. A nil store keeps rules in memory.
+func NewRuleSet(store RuleStore, defaults []models.AlertRule) *RuleSet {
+	rs := &RuleSet{store: store}
+	if store == nil {
+		rs.rules = append([]models.AlertRule(nil), defaults...)
+		return rs
+	}
+
+	seeded, err := store.GetSetting(defaultRulesSeededKey)
+	if err != nil {
+		log.Printf("Alert rules: failed to read seed marker: %v", err)
+		return rs
+	}
+	if seeded == "true" {
+		return rs
+	}
+	for _, rule := range defaults {
+		if err := store.SaveAlertRule(rule); err != nil {
+			log.Printf("Alert rules: failed to seed rule %s: %v", rule.ID, err)
+			return rs
+		}
+	}
+	if err := store.SetSetting(defaultRulesSeededKey, "true"); err != nil {
+		log.Printf("Alert rules: failed to write seed marker: %v", err)
+	}
+	return rs
+}
+
+// DefaultRules converts the CPU and memory alert settings into rules
+func DefaultRules(cfg *config.AlertConfig) []models.AlertRule {
+	now := time.Now().Unix()
+	return []models.AlertRule{
+		{
+			ID:             DefaultCPURuleID,
+			Name:           "High CPU usage",
+			Enabled:        true,
+			Metric:         models.MetricCPU,
+			Comparator:     models.ComparatorGreater,
+			Threshold:      cfg.CPUThreshold,
+			ClearThreshold: cfg.CPUClear,
+			ForSeconds:     int64(cfg.CPUFor / time.Second),
+			Severity:       models.AlertSeverityCritical,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		},
+		{
+			ID:             DefaultMemoryRuleID,
+			Name:           "High memory usage",
+			Enabled:        true,
+			Metric:         models.MetricMemory,
+			Comparator:     models.ComparatorGreater,
+			Threshold:      cfg.MemoryThreshold,
+			ClearThreshold: cfg.MemoryClear,
+			ForSeconds:     int64(cfg.MemoryFor / time.Second),
+			Severity:       models.AlertSeverityCritical,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		},
+	}
+}
+
+// List returns all rules
+func (rs *RuleSet) List() ([]models.AlertRule, error) {
+	if rs.store != nil {
+		return rs.store.ListAlertRules()
+	}
+
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	result := make([]models.AlertRule, len(rs.rules))
+	copy(result, rs.rules)
+	return result, nil
+}
+
+// Get returns the rule with the given ID, or nil if it does not exist
+func (rs *RuleSet) Get(id string) (*models.AlertRule, error) {
+	if rs.store != nil {
+		return rs.store.GetAlertRule(id)
+	}
+
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	for _, rule := range rs.rules {
+		if rule.ID == id {
+			return &rule, nil
+		}
+	}
+	return nil, nil
+}
+
+// Create validates and stores a new rule, assigning its ID and timestamps
+func (rs *RuleSet) Create(rule models.AlertRule) (*models.AlertRule, error) {
+	if err := normalizeRule(&rule); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	rule.ID = uuid.New().String()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	if err := rs.save(rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Update validates and replaces the rule with the given ID.
+// Returns nil when the rule does not exist.
+func (rs *RuleSet) Update(id string, rule models.AlertRule) (*models.AlertRule, error) {
+	existing, err := rs.Get(id)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	if err := normalizeRule(&rule); err != nil {
+		return nil, err
+	}
+	rule.ID = id
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now().Unix()
+
+	if err := rs.save(rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Delete removes the rule with the given ID.
+// Returns false when the rule does not exist.
+func (rs *RuleSet) Delete(id string) (bool, error) {
+	if rs.store != nil {
+		return rs.store.DeleteAlertRule(id)
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for i, rule := range rs.rules {
+		if rule.ID == id {
+			rs.rules = append(rs.rules[:i], rs.rules[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (rs *RuleSet) save(rule models.AlertRule) error {
+	if rs.store != nil {
+		return rs.store.SaveAlertRule(rule)
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for i := range rs.rules {
+		if rs.rules[i].ID == rule.ID {
+			rs.rules[i] = rule
+			return nil
+		}
+	}
+	rs.rules = append(rs.rules, rule)
+	return nil
+}
+
+// normalizeRule fills defaults and validates a rule submitted through the API
+func normalizeRule(rule *models.AlertRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+
+	switch rule.Metric {
+	case models.MetricCPU, models.MetricMemory, models.MetricPIDs,
+		models.MetricNetworkRxRate, models.MetricNetworkTxRate,
+		models.MetricBlockReadRate, models.MetricBlockWriteRate,
+		models.MetricRestartCount:
+	default:
+		return fmt.Errorf("%w: unknown metric %q", ErrInvalidRule, rule.Metric)
+	}
+
+	switch rule.Comparator {
+	case "":
+		rule.Comparator = models.ComparatorGreater
+	case models.ComparatorGreater, models.ComparatorGreaterOrEqual,
+		models.ComparatorLess, models.ComparatorLessOrEqual:
+	default:
+		return fmt.Errorf("%w: unknown comparator %q", ErrInvalidRule, rule.Comparator)
+	}
+
+	switch rule.Severity {
+	case "":
+		rule.Severity = models.AlertSeverityWarning
+	case models.AlertSeverityInfo, models.AlertSeverityWarning, models.AlertSeverityCritical:
+	default:
+		return fmt.Errorf("%w: unknown severity %q", ErrInvalidRule, rule.Severity)
+	}
+
+	if math.IsNaN(rule.Threshold) || math.IsInf(rule.Threshold, 0) || rule.Threshold < 0 {
+		return fmt.Errorf("%w: threshold must be a non-negative number", ErrInvalidRule)
+	}
+	if (rule.Metric == models.MetricCPU || rule.Metric == models.MetricMemory) && rule.Threshold > 100 {
+		return fmt.Errorf("%w: %s threshold must be between 0 and 100", ErrInvalidRule, rule.Metric)
+	}
+	if math.IsNaN(rule.ClearThreshold) || math.IsInf(rule.ClearThreshold, 0) || rule.ClearThreshold < 0 {
+		return fmt.Errorf("%w: clear_threshold must be a non-negative number", ErrInvalidRule)
+	}
+	if rule.ClearThreshold > 0 && compare(rule.Comparator, rule.ClearThreshold, rule.Threshold) {
+		return fmt.Errorf("%w: clear_threshold must be on the non-alerting side of threshold", ErrInvalidRule)
+	}
+	if rule.ForSeconds < 0 {
+		return fmt.Errorf("%w: for_seconds must not be negative", ErrInvalidRule)
+	}
+
+	rule.Scope.Host = strings.TrimSpace(rule.Scope.Host)
+	rule.Scope.Container = strings.TrimSpace(rule.Scope.Container)
+	rule.Scope.Label = strings.TrimSpace(rule.Scope.Label)
+	rule.Scope.Image = strings.TrimSpace(rule.Scope.Image)
+	for _, pattern := range []string{rule.Scope.Container, rule.Scope.Image} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: invalid scope pattern %q", ErrInvalidRule, pattern)
+		}
+	}
+	return nil
+}
+
+// compare reports whether value satisfies comparator against threshold
+func compare(comparator models.AlertComparator, value, threshold float64) bool {
+	switch comparator {
+	case models.ComparatorGreaterOrEqual:
+		return value >= threshold
+	case models.ComparatorLess:
+		return value < threshold
+	case models.ComparatorLessOrEqual:
+		return value <= threshold
+	default:
+		return value > threshold
+	}
+}
+
+// scopeMatches reports whether a rule's scope covers the container
+func scopeMatches(scope models.AlertRuleScope, host string, ctr models.ContainerInfo, containerName string) bool {
+	if scope.Host != "" && scope.Host != host {
+		return false
+	}
+	if scope.Container != "" {
+		if ok, _ := path.Match(scope.Container, containerName); !ok {
+			return false
+		}
+	}
+	if scope.Image != "" {
+		if ok, _ := path.Match(scope.Image, ctr.Image); !ok {
+			return false
+		}
+	}
+	if scope.Label != "" {
+		key, want, hasValue := strings.Cut(scope.Label, "=")
+		got, ok := ctr.Labels[key]
+		if !ok || (hasValue && got != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleAlertType maps a rule to the alert type it raises. CPU and memory
+// keep their dedicated types so existing filters continue to work.
+func ruleAlertType(metric models.AlertRuleMetric) models.AlertType {
+	switch metric {
+	case models.MetricCPU:
+		return models.AlertCPUThreshold
+	case models.MetricMemory:
+		return models.AlertMemoryThreshold
+	default:
+		return models.AlertMetricThreshold
+	}
+}
+
+// formatMetric renders a metric value for alert messages
+func formatMetric(metric models.AlertRuleMetric, value float64) string {
+	switch metric {
+	case models.MetricCPU, models.MetricMemory:
+		return fmt.Sprintf("%.1f%%", value)
+	case models.MetricNetworkRxRate, models.MetricNetworkTxRate,
+		models.MetricBlockReadRate, models.MetricBlockWriteRate:
+		return formatBytes(value) + "/s"
+	default:
+		return fmt.Sprintf("%.0f", value)
+	}
+}
+
+func formatBytes(value float64) string {
+	const unit = 1024
+	if value < unit {
+		return fmt.Sprintf("%.0f B", value)
+	}
+	div, exp := float64(unit), 0
+	for n := value / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value/div, "KMGTP"[exp])
+}
+
+var comparatorText = map[models.AlertComparator]string{
+	models.ComparatorGreater:        "above",
+	models.ComparatorGreaterOrEqual: "at or above",
+	models.ComparatorLess:           "below",
+	models.ComparatorLessOrEqual:    "at or below",
+}
@@ -0,0 +1,101 @@
+package alerts
+
+import (
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+type fakeRuleStore struct {
+	rules    map[string]models.AlertRule
+	settings map[string]string
+}
+
+func newFakeRuleStore() *fakeRuleStore {
+	return &fakeRuleStore{rules: map[string]models.AlertRule{}, settings: map[string]string{}}
+}
+
+func (f *fakeRuleStore) ListAlertRules() ([]models.AlertRule, error) {
+	rules := make([]models.AlertRule, 0, len(f.rules))
+	for _, rule := range f.rules {
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (f *fakeRuleStore) GetAlertRule(id string) (*models.AlertRule, error) {
+	rule, ok := f.rules[id]
+	if !ok {
+		return nil, nil
+	}
+	return &rule, nil
+}
+
+func (f *fakeRuleStore) SaveAlertRule(rule models.AlertRule) error {
+	f.rules[rule.ID] = rule
+	return nil
+}
+
+func (f *fakeRuleStore) DeleteAlertRule(id string) (bool, error) {
+	_, ok := f.rules[id]
+	delete(f.rules, id)
+	return ok, nil
+}
+
+func (f *fakeRuleStore) GetSetting(key string) (string, error) { return f.settings[key], nil }
+
+func (f *fakeRuleStore) SetSetting(key, value string) error {
+	f.settings[key] = value
+	return nil
+}
+
+func TestNewRuleSetSeedsDefaultsOnce(t *testing.T) {
+	store := newFakeRuleStore()
+	cfg := &config.AlertConfig{CPUThreshold: 85, MemoryThreshold: 95, CPUClear: 70}
+
+	rs := NewRuleSet(store, DefaultRules(cfg))
+	cpu, err := rs.Get(DefaultCPURuleID)
+	if err != nil || cpu == nil {
+		t.Fatalf("expected the default CPU rule, got %v, %v", cpu, err)
+	}
+	if cpu.Threshold != 85 || cpu.ClearThreshold != 70 || cpu.Severity != models.AlertSeverityCritical {
+		t.Fatalf("unexpected default CPU rule: %+v", cpu)
+	}
+
+	if _, err := rs.Delete(DefaultCPURuleID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	NewRuleSet(store, DefaultRules(cfg))
+	if _, ok := store.rules[DefaultCPURuleID]; ok {
+		t.Fatal("expected a deleted default rule to stay deleted")
+	}
+}
+
+func TestScopeMatches(t *testing.T) {
+	ctr := models.ContainerInfo{
+		Image:  "postgres:16",
+		Labels: map[string]string{"com.docker.compose.project": "shop"},
+	}
+
+	cases := []struct {
+		scope models.AlertRuleScope
+		want  bool
+	}{
+		{models.AlertRuleScope{}, true},
+		{models.AlertRuleScope{Host: "db"}, true},
+		{models.AlertRuleScope{Host: "web"}, false},
+		{models.AlertRuleScope{Container: "shop-*"}, true},
+		{models.AlertRuleScope{Container: "api-*"}, false},
+		{models.AlertRuleScope{Image: "postgres:*"}, true},
+		{models.AlertRuleScope{Image: "redis:*"}, false},
+		{models.AlertRuleScope{Label: "com.docker.compose.project"}, true},
+		{models.AlertRuleScope{Label: "com.docker.compose.project=shop"}, true},
+		{models.AlertRuleScope{Label: "com.docker.compose.project=blog"}, false},
+	}
+	for _, tc := range cases {
+		if got := scopeMatches(tc.scope, "db", ctr, "shop-postgres"); got != tc.want {
+			t.Fatalf("scopeMatches(%+v) = %v, want %v", tc.scope, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// maintenancePrefix marks SilencedBy values that refer to a maintenance window
+const maintenancePrefix = "maintenance:"
+
+// maxMaintenanceMinutes bounds a maintenance window to one day, so only
+// today's and yesterday's occurrence can be active
+const maxMaintenanceMinutes = 24 * 60
+
+// ErrInvalidSilence is returned when a silence or maintenance window fails validation
+var ErrInvalidSilence = errors.New("invalid silence")
+
+// SilenceStore persists silences and maintenance windows
+type SilenceStore interface {
+	ListSilences() ([]models.Silence, error)
+	GetSilence(id string) (*models.Silence, error)
+	SaveSilence(silence models.Silence) error
+	PruneSilencesEndedBefore(cutoff time.Time) error
+	ListMaintenanceWindows() ([]models.MaintenanceWindow, error)
+	GetMaintenanceWindow(id string) (*models.MaintenanceWindow, error)
+	SaveMaintenanceWindow(window models.MaintenanceWindow) error
+	DeleteMaintenanceWindow(id string) (bool, error)
+}
+
+// Silences holds the silences and maintenance windows that mute alert
+// notifications, either in a persistent store or, when none is configured,
+// in memory
+type Silences struct {
+	store    SilenceStore
+	mu       sync.RWMutex
+	silences []models.Silence
+	windows  []models.MaintenanceWindow
+}
+
+// NewSilences creates a silence set backed by store. A nil store keeps
+// silences in memory.
+func NewSilences(store SilenceStore) *Silences {
+	return &Silences{store: store}
+}
+
+// List returns all silences, including expired ones not pruned yet
+func (s *Silences) List() ([]models.Silence, error) {
+	if s.store != nil {
+		return s.store.ListSilences()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]models.Silence, len(s.silences))
+	copy(result, s.silences)
+	return result, nil
+}
+
+// Get returns the silence with the given ID, or nil if it does not exist
+func (s *Silences) Get(id string) (*models.Silence, error) {
+	if s.store != nil {
+		return s.store.GetSilence(id)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, silence := range s.silences {
+		if silence.ID == id {
+			return &silence, nil
+		}
+	}
+	return nil, nil
+}
+
+// Create validates and stores a new silence. StartsAt defaults to now.
+func (s *Silences) Create(silence models.Silence) (*models.Silence, error) {
+	if err := normalizeMatcher(&silence.SilenceMatcher); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	if silence.StartsAt == 0 {
+		silence.StartsAt = now
+	}
+	if silence.EndsAt <= now {
+		return nil, fmt.Errorf("%w: ends_at must be in the future", ErrInvalidSilence)
+	}
+	if silence.EndsAt <= silence.StartsAt {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidSilence)
+	}
+	silence.Comment = strings.TrimSpace(silence.Comment)
+	if silence.Comment == "" {
+		return nil, fmt.Errorf("%w: comment is required", ErrInvalidSilence)
+	}
+	silence.ID = uuid.New().String()
+	silence.CreatedAt = now
+
+	if err := s.saveSilence(silence); err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+// Expire ends the silence with the given ID now. Silences that already
+// ended are returned unchanged. Returns nil when the silence does not exist.
+func (s *Silences) Expire(id string) (*models.Silence, error) {
+	silence, err := s.Get(id)
+	if err != nil || silence == nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	if silence.EndsAt <= now {
+		return silence, nil
+	}
+	silence.EndsAt = now
+	silence.StartsAt = min(silence.StartsAt, now)
+
+	if err := s.saveSilence(*silence); err != nil {
+		return nil, err
+	}
+	return silence, nil
+}
+
+// Prune removes silences that ended before cutoff
+func (s *Silences) Prune(cutoff time.Time) error {
+	if s.store != nil {
+		return s.store.PruneSilencesEndedBefore(cutoff)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.silences = slices.DeleteFunc(s.silences, func(silence models.Silence) bool {
+		return silence.EndsAt < cutoff.Unix()
+	})
+	return nil
+}
+
+func (s *Silences) saveSilence(silence models.Silence) error {
+	if s.store != nil {
+		return s.store.SaveSilence(silence)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.silences {
+		if s.silences[i].ID == silence.ID {
+			s.silences[i] = silence
+			return nil
+		}
+	}
+	s.silences = append(s.silences, silence)
+	return nil
+}
+
+// ListWindows returns all maintenance windows
+func (s *Silences) ListWindows() ([]models.MaintenanceWindow, error) {
+	if s.store != nil {
+		return s.store.ListMaintenanceWindows()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]models.MaintenanceWindow, len(s.windows))
+	copy(result, s.windows)
+	return result, nil
+}
+
+// GetWindow returns the maintenance window with the given ID, or nil if it
+// does not exist
+func (s *Silences) GetWindow(id string) (*models.MaintenanceWindow, error) {
+	if s.store != nil {
+		return s.store.GetMaintenanceWindow(id)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, window := range s.windows {
+		if window.ID == id {
+			return &window, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateWindow validates and stores a new maintenance window
+func (s *Silences) CreateWindow(window models.MaintenanceWindow) (*models.MaintenanceWindow, error) {
+	if err := normalizeWindow(&window); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	window.ID = uuid.New().String()
+	window.CreatedAt = now
+	window.UpdatedAt = now
+
+	if err := s.saveWindow(window); err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// UpdateWindow validates and replaces the maintenance window with the given
+// ID. Returns nil when the window does not exist.
+func (s *Silences) UpdateWindow(id string, window models.MaintenanceWindow) (*models.MaintenanceWindow, error) {
+	existing, err := s.GetWindow(id)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	if err := normalizeWindow(&window); err != nil {
+		return nil, err
+	}
+	window.ID = id
+	window.CreatedAt = existing.CreatedAt
+	window.UpdatedAt = time.Now().Unix()
+
+	if err := s.saveWindow(window); err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// DeleteWindow removes the maintenance window with the given ID.
+// Returns false when the window does not exist.
+func (s *Silences) DeleteWindow(id string) (bool, error) {
+	if s.store != nil {
+		return s.store.DeleteMaintenanceWindow(id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, window := range s.windows {
+		if window.ID == id {
+			s.windows = append(s.windows[:i], s.windows[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Silences) saveWindow(window models.MaintenanceWindow) error {
+	if s.store != nil {
+		return s.store.SaveMaintenanceWindow(window)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.windows {
+		if s.windows[i].ID == window.ID {
+			s.windows[i] = window
+			return nil
+		}
+	}
+	s.windows = append(s.windows, window)
+	return nil
+}
+
+// Match returns what mutes alert at the given time: a silence ID, or
+// "maintenance:<id>" for a maintenance window. Returns "" when nothing does.
+// labels are the labels of the alert's container, if known.
+func (s *Silences) Match(alert models.Alert, labels map[string]string, at time.Time) (string, error) {
+	silences, err := s.List()
+	if err != nil {
+		return "", fmt.Errorf("load silences: %w", err)
+	}
+	for _, silence := range silences {
+		if silenceActive(silence, at) && matcherMatches(silence.SilenceMatcher, alert, labels) {
+			return silence.ID, nil
+		}
+	}
+
+	windows, err := s.ListWindows()
+	if err != nil {
+		return "", fmt.Errorf("load maintenance windows: %w", err)
+	}
+	for _, window := range windows {
+		if windowActive(window, at) && matcherMatches(window.SilenceMatcher, alert, labels) {
+			return maintenancePrefix + window.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// silenceActive reports whether silence covers the given time
+func silenceActive(silence models.Silence, at time.Time) bool {
+	return silence.StartsAt <= at.Unix() && at.Unix() < silence.EndsAt
+}
+
+// windowActive reports whether an occurrence of the maintenance window
+// covers the given time. Windows may span midnight, so the occurrence that
+// started the day before is checked as well.
+func windowActive(window models.MaintenanceWindow, at time.Time) bool {
+	if !window.Enabled {
+		return false
+	}
+	loc, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		return false
+	}
+	start, err := time.Parse("15:04", window.StartTime)
+	if err != nil {
+		return false
+	}
+
+	local := at.In(loc)
+	for _, daysAgo := range []int{0, 1} {
+		day := local.AddDate(0, 0, -daysAgo)
+		begin := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		if len(window.Weekdays) > 0 && !slices.Contains(window.Weekdays, int(begin.Weekday())) {
+			continue
+		}
+		end := begin.Add(time.Duration(window.DurationMinutes) * time.Minute)
+		if !at.Before(begin) && at.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// matcherMatches reports whether a silence matcher covers the alert
+func matcherMatches(matcher models.SilenceMatcher, alert models.Alert, labels map[string]string) bool {
+	if matcher.Host != "" && matcher.Host != alert.Host {
+		return false
+	}
+	if matcher.AlertType != "" && matcher.AlertType != alert.Type {
+		return false
+	}
+	if matcher.Container != "" {
+		if alert.ContainerName == "" {
+			return false
+		}
+		if ok, _ := path.Match(matcher.Container, alert.ContainerName); !ok {
+			return false
+		}
+	}
+	if matcher.Label != "" {
+		key, want, hasValue := strings.Cut(matcher.Label, "=")
+		got, ok := labels[key]
+		if !ok || (hasValue && got != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeMatcher trims and validates a matcher submitted through the API
+func normalizeMatcher(matcher *models.SilenceMatcher) error {
+	matcher.Host = strings.TrimSpace(matcher.Host)
+	matcher.Container = strings.TrimSpace(matcher.Container)
+	matcher.Label = strings.TrimSpace(matcher.Label)
+	matcher.AlertType = models.AlertType(strings.TrimSpace(string(matcher.AlertType)))
+
+	if matcher.Host == "" && matcher.Container == "" && matcher.Label == "" && matcher.AlertType == "" {
+		return fmt.Errorf("%w: at least one of host, container, label or alert_type is required", ErrInvalidSilence)
+	}
+	if _, err := path.Match(matcher.Container, ""); err != nil {
+		return fmt.Errorf("%w: invalid container pattern %q", ErrInvalidSilence, matcher.Container)
+	}
+	if matcher.AlertType != "" && !slices.Contains(silenceableTypes, matcher.AlertType) {
+		return fmt.Errorf("%w: unknown alert_type %q", ErrInvalidSilence, matcher.AlertType)
+	}
+	return nil
+}
+
+// normalizeWindow fills defaults and validates a maintenance window
+// submitted through the API
+func normalizeWindow(window *models.MaintenanceWindow) error {
+	window.Name = strings.TrimSpace(window.Name)
+	if window.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSilence)
+	}
+	if err := normalizeMatcher(&window.SilenceMatcher); err != nil {
+		return err
+	}
+
+	if _, err := time.Parse("15:04", window.StartTime); err != nil {
+		return fmt.Errorf("%w: start_time must be HH:MM", ErrInvalidSilence)
+	}
+	if window.DurationMinutes <= 0 || window.DurationMinutes > maxMaintenanceMinutes {
+		return fmt.Errorf("%w: duration_minutes must be between 1 and %d", ErrInvalidSilence, maxMaintenanceMinutes)
+	}
+
+	window.Timezone = strings.TrimSpace(window.Timezone)
+	if window.Timezone == "" {
+		window.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(window.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidSilence, window.Timezone)
+	}
+
+	for _, day := range window.Weekdays {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("%w: weekdays must be between 0 (Sunday) and 6 (Saturday)", ErrInvalidSilence)
+		}
+	}
+	slices.Sort(window.Weekdays)
+	window.Weekdays = slices.Compact(window.Weekdays)
+	window.Comment = strings.TrimSpace(window.Comment)
+	return nil
+}
+
+// silenceableTypes are the alert types a silence may match on
+var silenceableTypes = []models.AlertType{
+	models.AlertContainerStopped,
+	models.AlertContainerStarted,
+	models.AlertCPUThreshold,
+	models.AlertMemoryThreshold,
+	models.AlertMetricThreshold,
+	models.AlertContainerDied,
+	models.AlertContainerOOM,
+	models.AlertContainerUnhealthy,
+	models.AlertContainerRestartLoop,
+	models.AlertHostCPU,
+	models.AlertHostMemory,
+	models.AlertHostLoad,
+	models.AlertHostDisk,
+	models.AlertHostInodes,
+	models.AlertHostDown,
+	models.AlertHostRecovered,
+	models.AlertLoginLockout,
+}
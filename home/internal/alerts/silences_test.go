@@ -0,0 +1,187 @@
+package alerts
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestSilencedAlertsAreRecordedWithMarker(t *testing.T) {
+	m := newEventTestMonitor()
+	silence, err := m.silences.Create(models.Silence{
+		SilenceMatcher: models.SilenceMatcher{Container: "web*", Label: "com.docker.compose.project=shop"},
+		Comment:        "deploying shop",
+		EndsAt:         time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	now := time.Now()
+	m.handleContainerEvent("host-a", containerEvent(events.ActionOOM, now, map[string]string{"com.docker.compose.project": "shop"}))
+	blog := containerEvent(events.ActionOOM, now, map[string]string{"com.docker.compose.project": "blog"})
+	blog.Actor.ID = "c2"
+	m.handleContainerEvent("host-a", blog)
+
+	alerts := m.history.GetAll()
+	if len(alerts) != 2 {
+		t.Fatalf("expected both alerts in history, got %d", len(alerts))
+	}
+	// History is newest first
+	if !alerts[1].Silenced || alerts[1].SilencedBy != silence.ID {
+		t.Fatalf("expected the shop alert to be silenced, got %+v", alerts[1])
+	}
+	if alerts[0].Silenced {
+		t.Fatalf("label mismatch should not be silenced, got %+v", alerts[0])
+	}
+}
+
+func TestSilencedRuleAlertResolvesSilently(t *testing.T) {
+	m := newTestMonitor(0)
+	silence, err := m.silences.Create(models.Silence{
+		SilenceMatcher: models.SilenceMatcher{Host: "host-a", AlertType: models.AlertCPUThreshold},
+		Comment:        "load test",
+		EndsAt:         time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	now := time.Now()
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 95, now)
+	if _, err := m.silences.Expire(silence.ID); err != nil {
+		t.Fatalf("Expire() error = %v", err)
+	}
+
+	key := activeAlertKey("host-a", "c1", DefaultCPURuleID)
+	m.alertsMu.Lock()
+	firing := m.activeAlerts[key]
+	m.alertsMu.Unlock()
+	if !firing.Silenced {
+		t.Fatalf("expected the firing alert to stay silenced after the silence expired, got %+v", firing)
+	}
+
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 10, now)
+	alerts := m.history.GetAll()
+	if len(alerts) != 1 || alerts[0].Status != models.AlertStatusResolved || !alerts[0].Silenced {
+		t.Fatalf("expected one resolved silenced alert, got %+v", alerts)
+	}
+}
+
+func TestSilenceValidationAndExpiry(t *testing.T) {
+	s := NewSilences(nil)
+	future := time.Now().Add(time.Hour).Unix()
+
+	for name, silence := range map[string]models.Silence{
+		"no matcher":   {Comment: "x", EndsAt: future},
+		"no comment":   {SilenceMatcher: models.SilenceMatcher{Host: "a"}, EndsAt: future},
+		"already over": {SilenceMatcher: models.SilenceMatcher{Host: "a"}, Comment: "x", EndsAt: time.Now().Unix() - 1},
+		"unknown type": {SilenceMatcher: models.SilenceMatcher{AlertType: "disk_full"}, Comment: "x", EndsAt: future},
+		"bad pattern":  {SilenceMatcher: models.SilenceMatcher{Container: "["}, Comment: "x", EndsAt: future},
+	} {
+		if _, err := s.Create(silence); !errors.Is(err, ErrInvalidSilence) {
+			t.Fatalf("%s: expected ErrInvalidSilence, got %v", name, err)
+		}
+	}
+
+	silence, err := s.Create(models.Silence{SilenceMatcher: models.SilenceMatcher{Host: " a "}, Comment: "x", EndsAt: future})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if silence.Host != "a" || silence.StartsAt == 0 {
+		t.Fatalf("expected trimmed host and default start, got %+v", silence)
+	}
+
+	alert := models.Alert{Host: "a", Type: models.AlertContainerStopped, Timestamp: time.Now().Unix()}
+	if id, _ := s.Match(alert, nil, time.Now()); id != silence.ID {
+		t.Fatalf("expected the silence to match, got %q", id)
+	}
+	expired, err := s.Expire(silence.ID)
+	if err != nil || expired == nil || expired.EndsAt > time.Now().Unix() {
+		t.Fatalf("Expire() = %+v, %v", expired, err)
+	}
+	if id, _ := s.Match(alert, nil, time.Now()); id != "" {
+		t.Fatalf("expired silence still matches: %q", id)
+	}
+	if missing, err := s.Expire("missing"); missing != nil || err != nil {
+		t.Fatalf("Expire(missing) = %+v, %v", missing, err)
+	}
+}
+
+func TestWindowActive(t *testing.T) {
+	// Saturday 23:00 to Sunday 01:00 in Berlin
+	window := models.MaintenanceWindow{
+		Enabled:         true,
+		Weekdays:        []int{int(time.Saturday)},
+		StartTime:       "23:00",
+		DurationMinutes: 120,
+		Timezone:        "Europe/Berlin",
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"before start", time.Date(2026, 3, 7, 22, 59, 0, 0, berlin), false},
+		{"saturday night", time.Date(2026, 3, 7, 23, 30, 0, 0, berlin), true},
+		{"after midnight", time.Date(2026, 3, 8, 0, 30, 0, 0, berlin), true},
+		{"end is exclusive", time.Date(2026, 3, 8, 1, 0, 0, 0, berlin), false},
+		{"other weekday", time.Date(2026, 3, 6, 23, 30, 0, 0, berlin), false},
+		{"same instant in UTC", time.Date(2026, 3, 7, 22, 30, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := windowActive(window, tt.at); got != tt.want {
+			t.Fatalf("%s: windowActive() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	window.Enabled = false
+	if windowActive(window, time.Date(2026, 3, 7, 23, 30, 0, 0, berlin)) {
+		t.Fatal("disabled window should never be active")
+	}
+}
+
+func TestMaintenanceWindowSilencesMatchingAlerts(t *testing.T) {
+	s := NewSilences(nil)
+	now := time.Now().UTC()
+	window, err := s.CreateWindow(models.MaintenanceWindow{
+		Name:            "nightly deploy",
+		Enabled:         true,
+		SilenceMatcher:  models.SilenceMatcher{AlertType: models.AlertContainerStopped},
+		StartTime:       now.Add(-time.Minute).Format("15:04"),
+		DurationMinutes: 30,
+	})
+	if err != nil {
+		t.Fatalf("CreateWindow() error = %v", err)
+	}
+	if window.Timezone != "UTC" {
+		t.Fatalf("expected the time zone to default to UTC, got %q", window.Timezone)
+	}
+
+	stopped := models.Alert{Type: models.AlertContainerStopped, Host: "a"}
+	if id, _ := s.Match(stopped, nil, now); id != maintenancePrefix+window.ID {
+		t.Fatalf("expected the maintenance window to match, got %q", id)
+	}
+	if id, _ := s.Match(models.Alert{Type: models.AlertContainerOOM, Host: "a"}, nil, now); id != "" {
+		t.Fatalf("other alert types should not match, got %q", id)
+	}
+
+	for name, w := range map[string]models.MaintenanceWindow{
+		"bad start":    {Name: "x", SilenceMatcher: models.SilenceMatcher{Host: "a"}, StartTime: "25:00", DurationMinutes: 10},
+		"too long":     {Name: "x", SilenceMatcher: models.SilenceMatcher{Host: "a"}, StartTime: "01:00", DurationMinutes: 1441},
+		"bad weekday":  {Name: "x", SilenceMatcher: models.SilenceMatcher{Host: "a"}, StartTime: "01:00", DurationMinutes: 10, Weekdays: []int{7}},
+		"bad timezone": {Name: "x", SilenceMatcher: models.SilenceMatcher{Host: "a"}, StartTime: "01:00", DurationMinutes: 10, Timezone: "Mars/Base"},
+	} {
+		if _, err := s.CreateWindow(w); !errors.Is(err, ErrInvalidSilence) {
+			t.Fatalf("%s: expected ErrInvalidSilence, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package alerts
+
+import (
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// streamBuffer is how many events a subscriber may fall behind before it
+// is disconnected
+const streamBuffer = 64
+
+// Subscribe returns a channel receiving every alert event from now on and a
+// function to stop receiving them. The channel is closed when the
+// subscriber is cancelled or falls too far behind; clients should reload the
+// alert history before subscribing again.
+func (m *Monitor) Subscribe() (<-chan models.AlertEvent, func()) {
+	ch := make(chan models.AlertEvent, streamBuffer)
+
+	m.subscribersMu.Lock()
+	m.subscribers[ch] = struct{}{}
+	m.subscribersMu.Unlock()
+
+	cancel := func() {
+		m.subscribersMu.Lock()
+		defer m.subscribersMu.Unlock()
+		if _, ok := m.subscribers[ch]; ok {
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// publish sends event to all subscribers without blocking the monitor
+func (m *Monitor) publish(event models.AlertEvent) {
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().Unix()
+	}
+
+	m.subscribersMu.Lock()
+	defer m.subscribersMu.Unlock()
+	for ch := range m.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Dropping the event silently would leave the client out of sync
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publishAlert sends a fired or resolved alert to all subscribers
+func (m *Monitor) publishAlert(eventType models.AlertEventType, alert models.Alert) {
+	m.publish(models.AlertEvent{Type: eventType, Alert: &alert})
+}
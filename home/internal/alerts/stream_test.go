@@ -0,0 +1,68 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestSubscribersReceiveAlertLifecycle(t *testing.T) {
+	m := newTestMonitor(0)
+	events, unsubscribe := m.Subscribe()
+	defer unsubscribe()
+
+	now := time.Now()
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 95, now)
+	m.evaluateRule(cpuRule(), webContainer("host-a"), 10, now)
+
+	fired := <-events
+	if fired.Type != models.AlertEventFired || fired.Alert == nil || fired.Alert.Status != models.AlertStatusFiring {
+		t.Fatalf("expected a fired event, got %+v", fired)
+	}
+	resolved := <-events
+	if resolved.Type != models.AlertEventResolved || resolved.Alert == nil || resolved.Alert.ID != fired.Alert.ID || resolved.Alert.ResolvedAt == 0 {
+		t.Fatalf("expected the alert to resolve, got %+v", resolved)
+	}
+
+	if !m.Acknowledge(fired.Alert.ID, "ops") {
+		t.Fatal("Acknowledge() = false, want true")
+	}
+	acked := <-events
+	if acked.Type != models.AlertEventAcknowledged || acked.AlertID != fired.Alert.ID || acked.AcknowledgedBy != "ops" || acked.Timestamp == 0 {
+		t.Fatalf("expected an acknowledged event, got %+v", acked)
+	}
+
+	unsubscribe()
+	if _, ok := <-events; ok {
+		t.Fatal("expected the channel to be closed after unsubscribing")
+	}
+	// Publishing without subscribers must not block
+	m.triggerAlert(models.Alert{ID: "a2", Type: models.AlertContainerStopped, Host: "host-a", Timestamp: now.Unix()})
+}
+
+func TestLaggingSubscriberIsDisconnected(t *testing.T) {
+	m := newTestMonitor(0)
+	slow, cancelSlow := m.Subscribe()
+	defer cancelSlow()
+
+	for i := 0; i <= streamBuffer; i++ {
+		m.publish(models.AlertEvent{Type: models.AlertEventAcknowledged, AcknowledgedBy: "ops"})
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != streamBuffer {
+		t.Fatalf("expected %d buffered events before the disconnect, got %d", streamBuffer, received)
+	}
+
+	// Other subscribers are unaffected
+	events, unsubscribe := m.Subscribe()
+	defer unsubscribe()
+	m.publish(models.AlertEvent{Type: models.AlertEventAcknowledged})
+	if event := <-events; event.Type != models.AlertEventAcknowledged {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
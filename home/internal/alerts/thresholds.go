@@ -0,0 +1,71 @@
+package alerts
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+)
+
+// Container labels that override alert thresholds for a single container
+const (
+	LabelAlertCPU      = "vps-monitor.alert.cpu"
+	LabelAlertMemory   = "vps-monitor.alert.memory"
+	LabelAlertDisabled = "vps-monitor.alert.disabled"
+)
+
+// thresholds holds the overridden alert settings for one container.
+// Zero thresholds keep the value of the default rule.
+type thresholds struct {
+	cpu      float64
+	memory   float64
+	disabled bool
+}
+
+// resolveThresholds applies configured overrides in order, later matches
+// winning, and then the container's labels
+func resolveThresholds(cfg *config.AlertConfig, host, containerName string, labels map[string]string) thresholds {
+	var t thresholds
+
+	for _, override := range cfg.Overrides {
+		if !override.Matches(host, containerName) {
+			continue
+		}
+		if override.CPUThreshold > 0 {
+			t.cpu = override.CPUThreshold
+		}
+		if override.MemoryThreshold > 0 {
+			t.memory = override.MemoryThreshold
+		}
+		if override.Disabled {
+			t.disabled = true
+		}
+	}
+
+	if value, ok := labelThreshold(labels, LabelAlertCPU); ok {
+		t.cpu = value
+	}
+	if value, ok := labelThreshold(labels, LabelAlertMemory); ok {
+		t.memory = value
+	}
+	if raw, ok := labels[LabelAlertDisabled]; ok {
+		if disabled, err := strconv.ParseBool(strings.TrimSpace(raw)); err == nil {
+			t.disabled = disabled
+		}
+	}
+
+	return t
+}
+
+// labelThreshold reads a 0-100 threshold from labels, ignoring invalid values
+func labelThreshold(labels map[string]string, label string) (float64, bool) {
+	raw, ok := labels[label]
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || value <= 0 || value > 100 {
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,58 @@
+package alerts
+
+import (
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+)
+
+func TestResolveThresholdsAppliesOverridesThenLabels(t *testing.T) {
+	cfg := &config.AlertConfig{
+		CPUThreshold:    80,
+		MemoryThreshold: 90,
+		Overrides: []config.ThresholdOverride{
+			{Host: "db-server", MemoryThreshold: 98},
+			{ContainerPattern: "postgres*", CPUThreshold: 95},
+			{Host: "db-server", ContainerPattern: "postgres-replica", MemoryThreshold: 99},
+		},
+	}
+
+	got := resolveThresholds(cfg, "web-server", "api", nil)
+	if got.cpu != 0 || got.memory != 0 || got.disabled {
+		t.Fatalf("expected no overrides, got %+v", got)
+	}
+
+	got = resolveThresholds(cfg, "db-server", "postgres-replica", nil)
+	if got.cpu != 95 || got.memory != 99 {
+		t.Fatalf("expected later overrides to win, got %+v", got)
+	}
+
+	got = resolveThresholds(cfg, "db-server", "postgres", map[string]string{
+		LabelAlertCPU:    "60",
+		LabelAlertMemory: "not-a-number",
+	})
+	if got.cpu != 60 || got.memory != 98 {
+		t.Fatalf("expected labels to override valid values only, got %+v", got)
+	}
+}
+
+func TestResolveThresholdsDisabled(t *testing.T) {
+	cfg := &config.AlertConfig{
+		CPUThreshold:    80,
+		MemoryThreshold: 90,
+		Overrides: []config.ThresholdOverride{
+			{ContainerPattern: "ci-*", Disabled: true},
+			{ContainerPattern: "ci-runner", CPUThreshold: 99},
+		},
+	}
+
+	if !resolveThresholds(cfg, "local", "ci-runner", nil).disabled {
+		t.Fatal("expected a later threshold-only override to keep the container disabled")
+	}
+	if resolveThresholds(cfg, "local", "ci-runner", map[string]string{LabelAlertDisabled: "false"}).disabled {
+		t.Fatal("expected the label to re-enable alerts")
+	}
+	if !resolveThresholds(cfg, "local", "web", map[string]string{LabelAlertDisabled: "true"}).disabled {
+		t.Fatal("expected the label to disable alerts")
+	}
+}
@@ -1,10 +1,17 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/hhftechnology/vps-monitor/internal/alerts"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
 	"github.com/hhftechnology/vps-monitor/internal/models"
 )
 
@@ -22,23 +29,105 @@ func NewAlertHandlers(monitor *alerts.Monitor, config *models.AlertConfigRespons
 	}
 }
 
-// GetAlerts returns the list of recent alerts
+// GetAlerts returns a filtered, paginated page of the alert history
 func (h *AlertHandlers) GetAlerts(w http.ResponseWriter, r *http.Request) {
 	if h.monitor == nil {
 		WriteJsonResponse(w, http.StatusOK, map[string]any{
 			"alerts":              []models.Alert{},
 			"unacknowledgedCount": 0,
+			"total":               0,
+			"page":                1,
+			"page_size":           0,
+			"total_pages":         0,
 		})
 		return
 	}
 
+	q := r.URL.Query()
+	params := models.AlertQuery{
+		Host:      q.Get("host"),
+		Container: q.Get("container"),
+		Type:      models.AlertType(q.Get("type")),
+		Status:    models.AlertStatus(q.Get("status")),
+	}
+
+	if v := q.Get("page"); v != "" {
+		val, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "invalid page", http.StatusBadRequest)
+			return
+		}
+		params.Page = val
+	}
+	if v := q.Get("page_size"); v != "" {
+		val, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "invalid page_size", http.StatusBadRequest)
+			return
+		}
+		params.PageSize = val
+	}
+	if v := q.Get("start_date"); v != "" {
+		val, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid start_date", http.StatusBadRequest)
+			return
+		}
+		params.StartDate = val
+	}
+	if v := q.Get("end_date"); v != "" {
+		val, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid end_date", http.StatusBadRequest)
+			return
+		}
+		params.EndDate = val
+	}
+	if v := q.Get("acknowledged"); v != "" {
+		val, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid acknowledged", http.StatusBadRequest)
+			return
+		}
+		params.Acknowledged = &val
+	}
+	if v := q.Get("silenced"); v != "" {
+		val, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid silenced", http.StatusBadRequest)
+			return
+		}
+		params.Silenced = &val
+	}
+
 	history := h.monitor.GetHistory()
-	alerts := history.GetAll()
-	unacknowledgedCount := history.GetUnacknowledgedCount()
+	user, scoped := alertScope(r)
+	var page *models.AlertPage
+	var unacknowledged int
+	var err error
+	if scoped {
+		visible := func(alert models.Alert) bool { return h.canSeeAlert(user, alert) }
+		page, err = history.QueryVisible(params, visible)
+		if err == nil {
+			unacknowledged, err = countVisibleUnacknowledged(history, visible)
+		}
+	} else {
+		page, err = history.Query(params)
+		unacknowledged = history.GetUnacknowledgedCount()
+	}
+	if err != nil {
+		log.Printf("Failed to query alert history: %v", err)
+		http.Error(w, "failed to query alerts", http.StatusInternalServerError)
+		return
+	}
 
 	WriteJsonResponse(w, http.StatusOK, map[string]any{
-		"alerts":              alerts,
-		"unacknowledgedCount": unacknowledgedCount,
+		"alerts":              page.Alerts,
+		"unacknowledgedCount": unacknowledged,
+		"total":               page.Total,
+		"page":                page.Page,
+		"page_size":           page.PageSize,
+		"total_pages":         page.TotalPages,
 	})
 }
 
@@ -49,7 +138,24 @@ func (h *AlertHandlers) GetAlertConfig(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// AcknowledgeAlert marks an alert as acknowledged
+// GetHostStatus returns the connectivity of each configured Docker host
+func (h *AlertHandlers) GetHostStatus(w http.ResponseWriter, r *http.Request) {
+	hosts := []models.DockerHostStatus{}
+	if h.monitor != nil {
+		hosts = h.monitor.HostStatuses()
+	}
+	if user, scoped := alertScope(r); scoped {
+		hosts = slices.DeleteFunc(hosts, func(status models.DockerHostStatus) bool {
+			return !auth.CanAccessHost(user, status.Name)
+		})
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"hosts": hosts,
+	})
+}
+
+// AcknowledgeAlert marks an alert as acknowledged by the requesting user,
+// which stops its escalation
 func (h *AlertHandlers) AcknowledgeAlert(w http.ResponseWriter, r *http.Request) {
 	if h.monitor == nil {
 		http.Error(w, "alerts not enabled", http.StatusNotFound)
@@ -61,9 +167,15 @@ func (h *AlertHandlers) AcknowledgeAlert(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "alert id is required", http.StatusBadRequest)
 		return
 	}
+	if user, scoped := alertScope(r); scoped {
+		alert, ok := h.monitor.GetHistory().Get(alertID)
+		if !ok || !h.canSeeAlert(user, alert) {
+			http.Error(w, "alert not found", http.StatusNotFound)
+			return
+		}
+	}
 
-	history := h.monitor.GetHistory()
-	if history.Acknowledge(alertID) {
+	if h.monitor.Acknowledge(alertID, requestUsername(r)) {
 		WriteJsonResponse(w, http.StatusOK, map[string]any{
 			"message": "Alert acknowledged",
 		})
@@ -72,21 +184,21 @@ func (h *AlertHandlers) AcknowledgeAlert(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// AcknowledgeAllAlerts marks all alerts as acknowledged
+// AcknowledgeAllAlerts marks all alerts the requesting user may see as
+// acknowledged
 func (h *AlertHandlers) AcknowledgeAllAlerts(w http.ResponseWriter, r *http.Request) {
 	if h.monitor == nil {
 		http.Error(w, "alerts not enabled", http.StatusNotFound)
 		return
 	}
 
-	history := h.monitor.GetHistory()
-	alerts := history.GetAll()
-	count := 0
-	for _, alert := range alerts {
-		if !alert.Acknowledged {
-			history.Acknowledge(alert.ID)
-			count++
-		}
+	var count int
+	if user, scoped := alertScope(r); scoped {
+		count = h.monitor.AcknowledgeVisible(requestUsername(r), func(alert models.Alert) bool {
+			return h.canSeeAlert(user, alert)
+		})
+	} else {
+		count = h.monitor.AcknowledgeAll(requestUsername(r))
 	}
 
 	WriteJsonResponse(w, http.StatusOK, map[string]any{
@@ -94,3 +206,345 @@ func (h *AlertHandlers) AcknowledgeAllAlerts(w http.ResponseWriter, r *http.Requ
 		"count":   count,
 	})
 }
+
+// ListAlertRules returns all alert rules
+func (h *AlertHandlers) ListAlertRules(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		WriteJsonResponse(w, http.StatusOK, map[string]any{
+			"rules": []models.AlertRule{},
+		})
+		return
+	}
+
+	rules, err := h.monitor.GetRules().List()
+	if err != nil {
+		log.Printf("Failed to list alert rules: %v", err)
+		http.Error(w, "failed to list alert rules", http.StatusInternalServerError)
+		return
+	}
+	if user, scoped := alertScope(r); scoped {
+		rules = slices.DeleteFunc(rules, func(rule models.AlertRule) bool {
+			return !canSeeAlertRule(user, rule)
+		})
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"rules": rules,
+	})
+}
+
+// GetAlertRule returns a single alert rule
+func (h *AlertHandlers) GetAlertRule(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		http.Error(w, "alerts not enabled", http.StatusNotFound)
+		return
+	}
+
+	rule, err := h.monitor.GetRules().Get(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		log.Printf("Failed to load alert rule: %v", err)
+		http.Error(w, "failed to load alert rule", http.StatusInternalServerError)
+		return
+	}
+	if rule == nil {
+		http.Error(w, "alert rule not found", http.StatusNotFound)
+		return
+	}
+	if user, scoped := alertScope(r); scoped && !canSeeAlertRule(user, *rule) {
+		http.Error(w, "alert rule not found", http.StatusNotFound)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"rule": rule,
+	})
+}
+
+// CreateAlertRule adds a new alert rule
+func (h *AlertHandlers) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		http.Error(w, "alerts not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req models.AlertRule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.monitor.GetRules().Create(req)
+	if err != nil {
+		writeAlertRuleError(w, err)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusCreated, map[string]any{
+		"rule": rule,
+	})
+}
+
+// UpdateAlertRule replaces an existing alert rule
+func (h *AlertHandlers) UpdateAlertRule(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		http.Error(w, "alerts not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req models.AlertRule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.monitor.GetRules().Update(chi.URLParam(r, "ruleID"), req)
+	if err != nil {
+		writeAlertRuleError(w, err)
+		return
+	}
+	if rule == nil {
+		http.Error(w, "alert rule not found", http.StatusNotFound)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"rule": rule,
+	})
+}
+
+// DeleteAlertRule removes an alert rule
+func (h *AlertHandlers) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		http.Error(w, "alerts not enabled", http.StatusNotFound)
+		return
+	}
+
+	deleted, err := h.monitor.GetRules().Delete(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		log.Printf("Failed to delete alert rule: %v", err)
+		http.Error(w, "failed to delete alert rule", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "alert rule not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAlertRuleError(w http.ResponseWriter, err error) {
+	if errors.Is(err, alerts.ErrInvalidRule) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Failed to save alert rule: %v", err)
+	http.Error(w, "failed to save alert rule", http.StatusInternalServerError)
+}
+
+// ListSilences returns all silences. ?active=true limits the result to
+// silences in effect now.
+func (h *AlertHandlers) ListSilences(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		WriteJsonResponse(w, http.StatusOK, map[string]any{
+			"silences": []models.Silence{},
+		})
+		return
+	}
+
+	var activeOnly bool
+	if v := r.URL.Query().Get("active"); v != "" {
+		val, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid active", http.StatusBadRequest)
+			return
+		}
+		activeOnly = val
+	}
+
+	silences, err := h.monitor.GetSilences().List()
+	if err != nil {
+		log.Printf("Failed to list silences: %v", err)
+		http.Error(w, "failed to list silences", http.StatusInternalServerError)
+		return
+	}
+	if activeOnly {
+		now := time.Now().Unix()
+		silences = slices.DeleteFunc(silences, func(s models.Silence) bool {
+			return s.StartsAt > now || s.EndsAt <= now
+		})
+	}
+	if user, scoped := alertScope(r); scoped {
+		silences = slices.DeleteFunc(silences, func(s models.Silence) bool {
+			return !canSeeSilenceMatcher(user, s.SilenceMatcher)
+		})
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"silences": silences,
+	})
+}
+
+// CreateSilence adds a new silence, recording the requesting user
+func (h *AlertHandlers) CreateSilence(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		http.Error(w, "alerts not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req models.Silence
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.CreatedBy = requestUsername(r)
+
+	silence, err := h.monitor.GetSilences().Create(req)
+	if err != nil {
+		writeSilenceError(w, err)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusCreated, map[string]any{
+		"silence": silence,
+	})
+}
+
+// ExpireSilence ends a silence immediately
+func (h *AlertHandlers) ExpireSilence(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		http.Error(w, "alerts not enabled", http.StatusNotFound)
+		return
+	}
+
+	silence, err := h.monitor.GetSilences().Expire(chi.URLParam(r, "silenceID"))
+	if err != nil {
+		writeSilenceError(w, err)
+		return
+	}
+	if silence == nil {
+		http.Error(w, "silence not found", http.StatusNotFound)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"silence": silence,
+	})
+}
+
+// ListMaintenanceWindows returns all maintenance windows
+func (h *AlertHandlers) ListMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		WriteJsonResponse(w, http.StatusOK, map[string]any{
+			"windows": []models.MaintenanceWindow{},
+		})
+		return
+	}
+
+	windows, err := h.monitor.GetSilences().ListWindows()
+	if err != nil {
+		log.Printf("Failed to list maintenance windows: %v", err)
+		http.Error(w, "failed to list maintenance windows", http.StatusInternalServerError)
+		return
+	}
+	if user, scoped := alertScope(r); scoped {
+		windows = slices.DeleteFunc(windows, func(window models.MaintenanceWindow) bool {
+			return !canSeeSilenceMatcher(user, window.SilenceMatcher)
+		})
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"windows": windows,
+	})
+}
+
+// CreateMaintenanceWindow adds a new maintenance window
+func (h *AlertHandlers) CreateMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		http.Error(w, "alerts not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req models.MaintenanceWindow
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	window, err := h.monitor.GetSilences().CreateWindow(req)
+	if err != nil {
+		writeSilenceError(w, err)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusCreated, map[string]any{
+		"window": window,
+	})
+}
+
+// UpdateMaintenanceWindow replaces an existing maintenance window
+func (h *AlertHandlers) UpdateMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		http.Error(w, "alerts not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req models.MaintenanceWindow
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	window, err := h.monitor.GetSilences().UpdateWindow(chi.URLParam(r, "windowID"), req)
+	if err != nil {
+		writeSilenceError(w, err)
+		return
+	}
+	if window == nil {
+		http.Error(w, "maintenance window not found", http.StatusNotFound)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"window": window,
+	})
+}
+
+// DeleteMaintenanceWindow removes a maintenance window
+func (h *AlertHandlers) DeleteMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		http.Error(w, "alerts not enabled", http.StatusNotFound)
+		return
+	}
+
+	deleted, err := h.monitor.GetSilences().DeleteWindow(chi.URLParam(r, "windowID"))
+	if err != nil {
+		log.Printf("Failed to delete maintenance window: %v", err)
+		http.Error(w, "failed to delete maintenance window", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "maintenance window not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeSilenceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, alerts.ErrInvalidSilence) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Failed to save silence: %v", err)
+	http.Error(w, "failed to save silence", http.StatusInternalServerError)
+}
+
+// requestUsername returns the authenticated user making the request, or ""
+// when authentication is disabled
+func requestUsername(r *http.Request) string {
+	if user, ok := r.Context().Value(auth.UserContextKey).(models.User); ok {
+		return user.Username
+	}
+	return ""
+}
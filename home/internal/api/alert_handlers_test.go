@@ -0,0 +1,417 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/hhftechnology/vps-monitor/internal/alerts"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func newAlertRulesRouter() chi.Router {
+	monitor := alerts.NewMonitor(nil, &config.AlertConfig{Enabled: true, CPUThreshold: 80, MemoryThreshold: 90}, nil, 0)
+	handlers := NewAlertHandlers(monitor, &models.AlertConfigResponse{})
+
+	r := chi.NewRouter()
+	r.Get("/alerts/rules", handlers.ListAlertRules)
+	r.Post("/alerts/rules", handlers.CreateAlertRule)
+	r.Get("/alerts/rules/{ruleID}", handlers.GetAlertRule)
+	r.Put("/alerts/rules/{ruleID}", handlers.UpdateAlertRule)
+	r.Delete("/alerts/rules/{ruleID}", handlers.DeleteAlertRule)
+	return r
+}
+
+func TestAlertRulesCRUD(t *testing.T) {
+	router := newAlertRulesRouter()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/alerts/rules", nil))
+	var list struct {
+		Rules []models.AlertRule `json:"rules"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Rules) != 2 || list.Rules[0].ID != alerts.DefaultCPURuleID || list.Rules[0].Threshold != 80 {
+		t.Fatalf("expected the env thresholds as default rules, got %+v", list.Rules)
+	}
+
+	body := `{"name":"Too many processes","enabled":true,"metric":"pids","comparator":"gte","threshold":500,"scope":{"label":"com.docker.compose.project=shop"}}`
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/alerts/rules", bytes.NewBufferString(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Rule models.AlertRule `json:"rule"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.Rule.ID == "" || created.Rule.Severity != models.AlertSeverityWarning {
+		t.Fatalf("expected an ID and default severity, got %+v", created.Rule)
+	}
+
+	update := `{"name":"Too many processes","enabled":false,"metric":"pids","comparator":"gte","threshold":800,"severity":"critical"}`
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/alerts/rules/"+created.Rule.ID, bytes.NewBufferString(update)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/alerts/rules/"+created.Rule.ID, nil))
+	var fetched struct {
+		Rule models.AlertRule `json:"rule"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &fetched); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if fetched.Rule.Threshold != 800 || fetched.Rule.Enabled || fetched.Rule.CreatedAt != created.Rule.CreatedAt {
+		t.Fatalf("unexpected updated rule: %+v", fetched.Rule)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/alerts/rules/"+created.Rule.ID, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/alerts/rules/"+created.Rule.ID, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for a deleted rule, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestCreateAlertRuleRejectsInvalidRules(t *testing.T) {
+	router := newAlertRulesRouter()
+
+	for name, body := range map[string]string{
+		"unknown metric":    `{"name":"x","metric":"disk","threshold":1}`,
+		"cpu above 100":     `{"name":"x","metric":"cpu","threshold":150}`,
+		"wrong clear side":  `{"name":"x","metric":"cpu","comparator":"gt","threshold":80,"clear_threshold":90}`,
+		"missing name":      `{"metric":"cpu","threshold":80}`,
+		"bad scope pattern": `{"name":"x","metric":"cpu","threshold":80,"scope":{"container":"["}}`,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/alerts/rules", bytes.NewBufferString(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", name, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func newSilencesRouter() chi.Router {
+	monitor := alerts.NewMonitor(nil, &config.AlertConfig{Enabled: true}, nil, 0)
+	handlers := NewAlertHandlers(monitor, &models.AlertConfigResponse{})
+
+	r := chi.NewRouter()
+	r.Get("/alerts/silences", handlers.ListSilences)
+	r.Post("/alerts/silences", handlers.CreateSilence)
+	r.Post("/alerts/silences/{silenceID}/expire", handlers.ExpireSilence)
+	r.Get("/alerts/maintenance", handlers.ListMaintenanceWindows)
+	r.Post("/alerts/maintenance", handlers.CreateMaintenanceWindow)
+	r.Put("/alerts/maintenance/{windowID}", handlers.UpdateMaintenanceWindow)
+	r.Delete("/alerts/maintenance/{windowID}", handlers.DeleteMaintenanceWindow)
+	return r
+}
+
+func TestSilencesCreateListAndExpire(t *testing.T) {
+	router := newSilencesRouter()
+
+	body := fmt.Sprintf(`{"container":"shop-*","alert_type":"container_stopped","comment":"deploy","ends_at":%d}`,
+		time.Now().Add(time.Hour).Unix())
+	req := httptest.NewRequest(http.MethodPost, "/alerts/silences", bytes.NewBufferString(body))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, models.User{Username: "ops"}))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Silence models.Silence `json:"silence"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.Silence.ID == "" || created.Silence.CreatedBy != "ops" {
+		t.Fatalf("expected an ID and the creating user, got %+v", created.Silence)
+	}
+
+	activeCount := func() int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/alerts/silences?active=true", nil))
+		var list struct {
+			Silences []models.Silence `json:"silences"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return len(list.Silences)
+	}
+	if got := activeCount(); got != 1 {
+		t.Fatalf("expected one active silence, got %d", got)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/alerts/silences/"+created.Silence.ID+"/expire", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got := activeCount(); got != 0 {
+		t.Fatalf("expected no active silences after expiry, got %d", got)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/alerts/silences/missing/expire", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for an unknown silence, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/alerts/silences", bytes.NewBufferString(`{"comment":"everything"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for a silence without matchers, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestMaintenanceWindowsCRUD(t *testing.T) {
+	router := newSilencesRouter()
+
+	body := `{"name":"Sunday patching","enabled":true,"host":"prod","weekdays":[0],"start_time":"02:00","duration_minutes":60}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/alerts/maintenance", bytes.NewBufferString(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Window models.MaintenanceWindow `json:"window"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	update := `{"name":"Sunday patching","enabled":false,"host":"prod","start_time":"03:00","duration_minutes":60,"timezone":"Nowhere/Else"}`
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/alerts/maintenance/"+created.Window.ID, bytes.NewBufferString(update)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an unknown time zone, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/alerts/maintenance/"+created.Window.ID, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/alerts/maintenance", nil))
+	var list struct {
+		Windows []models.MaintenanceWindow `json:"windows"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Windows) != 0 {
+		t.Fatalf("expected no windows after delete, got %s", rec.Body.String())
+	}
+}
+
+func TestStreamAlertsPushesEvents(t *testing.T) {
+	monitor := alerts.NewMonitor(nil, &config.AlertConfig{Enabled: true}, nil, 0)
+	handlers := NewAlertHandlers(monitor, &models.AlertConfigResponse{})
+	srv := httptest.NewServer(http.HandlerFunc(handlers.StreamAlerts))
+	defer srv.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer ws.Close()
+
+	monitor.GetHistory().Add(models.Alert{ID: "a1", Type: models.AlertContainerStopped, Host: "host-a", Timestamp: time.Now().Unix()})
+	// The subscription is registered after the upgrade, so keep
+	// acknowledging until the first event arrives
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				monitor.Acknowledge("a1", "ops")
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var event models.AlertEvent
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := ws.ReadJSON(&event); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if event.Type != models.AlertEventAcknowledged || event.AlertID != "a1" || event.AcknowledgedBy != "ops" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
+
+func TestStreamAlertsWithoutMonitor(t *testing.T) {
+	handlers := NewAlertHandlers(nil, &models.AlertConfigResponse{})
+	rec := httptest.NewRecorder()
+	handlers.StreamAlerts(rec, httptest.NewRequest(http.MethodGet, "/alerts/stream", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when alerts are disabled, got %d", rec.Code)
+	}
+}
+
+func TestGetAlertsFiltersByGrants(t *testing.T) {
+	monitor := alerts.NewMonitor(nil, &config.AlertConfig{Enabled: true}, nil, 0)
+	handlers := NewAlertHandlers(monitor, &models.AlertConfigResponse{})
+	history := monitor.GetHistory()
+	now := time.Now().Unix()
+	history.Add(models.Alert{ID: "web", Type: models.AlertContainerStopped, Host: "host-a", ContainerID: "c1", ContainerName: "web", Timestamp: now})
+	history.Add(models.Alert{ID: "db", Type: models.AlertContainerStopped, Host: "host-a", ContainerID: "c2", ContainerName: "db", Timestamp: now})
+	history.Add(models.Alert{ID: "other-host", Type: models.AlertContainerStopped, Host: "host-b", ContainerID: "c3", ContainerName: "web", Timestamp: now})
+	history.Add(models.Alert{ID: "host", Type: models.AlertHostDown, Host: "host-a", Timestamp: now})
+	history.Add(models.Alert{ID: "lockout", Type: models.AlertLoginLockout, Timestamp: now})
+
+	user := models.User{Username: "dev", Role: models.RoleViewer, Grants: []models.PermissionGrant{{Host: "host-a", Container: "web"}}}
+	req := httptest.NewRequest(http.MethodGet, "/alerts", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+	rec := httptest.NewRecorder()
+	handlers.GetAlerts(rec, req)
+
+	var resp struct {
+		Alerts         []models.Alert `json:"alerts"`
+		Unacknowledged int            `json:"unacknowledgedCount"`
+		Total          int            `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	var ids []string
+	for _, alert := range resp.Alerts {
+		ids = append(ids, alert.ID)
+	}
+	if fmt.Sprint(ids) != "[host web]" || resp.Total != 2 || resp.Unacknowledged != 2 {
+		t.Fatalf("expected the granted container and host alerts only, got %v (total %d, unacknowledged %d)", ids, resp.Total, resp.Unacknowledged)
+	}
+}
+
+func TestAcknowledgeAlertsFiltersByGrants(t *testing.T) {
+	monitor := alerts.NewMonitor(nil, &config.AlertConfig{Enabled: true}, nil, 0)
+	handlers := NewAlertHandlers(monitor, &models.AlertConfigResponse{})
+	history := monitor.GetHistory()
+	now := time.Now().Unix()
+	history.Add(models.Alert{ID: "web", Type: models.AlertContainerStopped, Host: "host-a", ContainerID: "c1", ContainerName: "web", Timestamp: now})
+	history.Add(models.Alert{ID: "web-2", Type: models.AlertContainerStopped, Host: "host-a", ContainerID: "c4", ContainerName: "web", Timestamp: now})
+	history.Add(models.Alert{ID: "db", Type: models.AlertContainerStopped, Host: "host-a", ContainerID: "c2", ContainerName: "db", Timestamp: now})
+	history.Add(models.Alert{ID: "other-host", Type: models.AlertContainerStopped, Host: "host-b", ContainerID: "c3", ContainerName: "web", Timestamp: now})
+
+	r := chi.NewRouter()
+	r.Post("/alerts/{id}/acknowledge", handlers.AcknowledgeAlert)
+	r.Post("/alerts/acknowledge-all", handlers.AcknowledgeAllAlerts)
+	user := models.User{Username: "dev", Role: models.RoleOperator, Grants: []models.PermissionGrant{{Host: "host-a", Container: "web"}}}
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("/alerts/db/acknowledge"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an alert outside the grants, got %d", rec.Code)
+	}
+	if rec := post("/alerts/web/acknowledge"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a granted alert, got %d", rec.Code)
+	}
+
+	rec := post("/alerts/acknowledge-all")
+	var resp struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Count != 1 {
+		t.Fatalf("expected the remaining granted alert to be acknowledged, got %d", resp.Count)
+	}
+	for id, want := range map[string]bool{"web": true, "web-2": true, "db": false, "other-host": false} {
+		if alert, _ := history.Get(id); alert.Acknowledged != want {
+			t.Fatalf("alert %s: acknowledged = %v, want %v", id, alert.Acknowledged, want)
+		}
+	}
+}
+
+func TestAlertSettingsFilteredByGrants(t *testing.T) {
+	monitor := alerts.NewMonitor(nil, &config.AlertConfig{Enabled: true}, nil, 0)
+	handlers := NewAlertHandlers(monitor, &models.AlertConfigResponse{})
+	endsAt := time.Now().Add(time.Hour).Unix()
+	for _, matcher := range []models.SilenceMatcher{
+		{Host: "host-a", Container: "web"},
+		{Host: "host-a", Container: "db"},
+		{AlertType: models.AlertHostDown},
+	} {
+		if _, err := monitor.GetSilences().Create(models.Silence{SilenceMatcher: matcher, Comment: "deploy", EndsAt: endsAt}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if _, err := monitor.GetRules().Create(models.AlertRule{Name: "Shop", Metric: models.MetricPIDs, Comparator: models.ComparatorGreaterOrEqual, Threshold: 500, Scope: models.AlertRuleScope{Host: "host-b"}}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	user := models.User{Username: "dev", Role: models.RoleOperator, Grants: []models.PermissionGrant{{Host: "host-a", Container: "web"}}}
+	get := func(handler http.HandlerFunc, path string, v any) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+
+	var silences struct {
+		Silences []models.Silence `json:"silences"`
+	}
+	get(handlers.ListSilences, "/alerts/silences", &silences)
+	if len(silences.Silences) != 2 || silences.Silences[0].Container == "db" || silences.Silences[1].Container == "db" {
+		t.Fatalf("expected the granted and unnarrowed silences only, got %+v", silences.Silences)
+	}
+
+	var rules struct {
+		Rules []models.AlertRule `json:"rules"`
+	}
+	get(handlers.ListAlertRules, "/alerts/rules", &rules)
+	for _, rule := range rules.Rules {
+		if rule.Scope.Host == "host-b" {
+			t.Fatalf("expected the rule for another host to be hidden, got %+v", rules.Rules)
+		}
+	}
+	if len(rules.Rules) != 2 {
+		t.Fatalf("expected the default rules to stay visible, got %+v", rules.Rules)
+	}
+
+	handler := requireUnscoped(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/alerts/silences", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected scoped users to be refused changes, got %d", rec.Code)
+	}
+}
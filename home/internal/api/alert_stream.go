@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// StreamAlerts handles WebSocket connections that receive alert events
+// (fired, resolved, acknowledged) as they happen
+func (h *AlertHandlers) StreamAlerts(w http.ResponseWriter, r *http.Request) {
+	if h.monitor == nil {
+		http.Error(w, "alerts not enabled", http.StatusNotFound)
+		return
+	}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("websocket upgrade failed for alert stream: %v", err)
+		return
+	}
+	defer ws.Close()
+
+	// Set up ping/pong keep-alive
+	ws.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	ws.SetPongHandler(func(string) error {
+		ws.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		return nil
+	})
+
+	user, scoped := alertScope(r)
+	events, unsubscribe := h.monitor.Subscribe()
+	defer unsubscribe()
+
+	ctx := r.Context()
+
+	// Handle WebSocket close from client; nothing else is expected from it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, _, err := ws.ReadMessage()
+			if err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNoStatusReceived) {
+					log.Printf("alert stream websocket closed unexpectedly: %v", err)
+				}
+				return
+			}
+		}
+	}()
+
+	pingTicker := time.NewTicker(wsPingInterval)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// The client fell behind; closing lets it reload and resubscribe
+				ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+				_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "alert stream lagged"))
+				return
+			}
+			// Acknowledgements only carry the ID of the alert, which tells
+			// nothing to a client that never received the alert itself
+			if scoped && event.Alert != nil && !h.canSeeAlert(user, *event.Alert) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("failed to marshal alert event: %v", err)
+				continue
+			}
+			ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := ws.WriteMessage(websocket.TextMessage, data); err != nil {
+				log.Printf("failed to write alert event to websocket: %v", err)
+				return
+			}
+
+		case <-pingTicker.C:
+			ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+
+		case <-done:
+			return
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
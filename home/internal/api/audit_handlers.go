@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// auditExportPageSize is how many events an export reads at a time
+const auditExportPageSize = 1000
+
+// GetAuditEvents returns a filtered, paginated page of the audit log
+func (ar *APIRouter) GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	params, ok := parseAuditQuery(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := ar.audit.Query(params)
+	if err != nil {
+		log.Printf("Failed to query audit log: %v", err)
+		http.Error(w, "failed to query audit log", http.StatusInternalServerError)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, page)
+}
+
+// ExportAuditEvents downloads every event matching the filters, newest
+// first, as CSV or, with format=json, as a JSON array
+func (ar *APIRouter) ExportAuditEvents(w http.ResponseWriter, r *http.Request) {
+	params, ok := parseAuditQuery(w, r)
+	if !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		http.Error(w, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	// Read everything first so a failing query still gets an error status
+	events := make([]models.AuditEvent, 0)
+	params.PageSize = auditExportPageSize
+	for params.Page = 1; ; params.Page++ {
+		page, err := ar.audit.Query(params)
+		if err != nil {
+			log.Printf("Failed to export audit log: %v", err)
+			http.Error(w, "failed to export audit log", http.StatusInternalServerError)
+			return
+		}
+		events = append(events, page.Events...)
+		if params.Page >= page.TotalPages {
+			break
+		}
+	}
+
+	filename := "audit_" + time.Now().UTC().Format("20060102_150405") + "." + format
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=%s", filename))
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(events)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"id", "time", "username", "role", "api_token", "source_ip", "action", "host", "target", "summary", "status", "outcome", "error", "duration_ms"})
+	for _, event := range events {
+		_ = writer.Write([]string{
+			strconv.FormatInt(event.ID, 10),
+			time.Unix(event.Time, 0).UTC().Format(time.RFC3339),
+			event.Username,
+			event.Role,
+			strconv.FormatBool(event.APIToken),
+			event.SourceIP,
+			event.Action,
+			event.Host,
+			event.Target,
+			event.Summary,
+			strconv.Itoa(event.Status),
+			event.Outcome,
+			event.Error,
+			strconv.FormatInt(event.DurationMs, 10),
+		})
+	}
+	writer.Flush()
+}
+
+// recordBackgroundResult adds the result of work a request left running
+// in the background, such as stopping a container, to the audit log
+func (ar *APIRouter) recordBackgroundResult(event models.AuditEvent, err error) {
+	if ar.audit == nil {
+		return
+	}
+	event.DurationMs = time.Since(time.Unix(event.Time, 0)).Milliseconds()
+	event.Time = time.Now().Unix()
+	event.Status = 0
+	event.Outcome = models.AuditOutcomeSuccess
+	event.Error = ""
+	if err != nil {
+		event.Outcome = models.AuditOutcomeFailure
+		event.Error = err.Error()
+	}
+	ar.audit.Record(event)
+}
+
+func parseAuditQuery(w http.ResponseWriter, r *http.Request) (models.AuditQuery, bool) {
+	q := r.URL.Query()
+	params := models.AuditQuery{
+		Username: q.Get("username"),
+		Action:   q.Get("action"),
+		Host:     q.Get("host"),
+		Target:   q.Get("target"),
+		Outcome:  q.Get("outcome"),
+	}
+
+	for name, dst := range map[string]*int{"page": &params.Page, "page_size": &params.PageSize} {
+		if v := q.Get(name); v != "" {
+			val, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return params, false
+			}
+			*dst = val
+		}
+	}
+	for name, dst := range map[string]*int64{"start_date": &params.StartDate, "end_date": &params.EndDate} {
+		if v := q.Get(name); v != "" {
+			val, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return params, false
+			}
+			*dst = val
+		}
+	}
+	return params, true
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestAuditLog(t *testing.T) {
+	h := newUsersTestRouter(t)
+	adminToken, _ := login(t, h, "admin", "secret")
+
+	if rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/settings/users", `{"username":"vic","password":"viewer-pass","role":"viewer"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, adminToken, http.MethodGet, "/api/v1/settings/users", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	viewerToken, _ := login(t, h, "vic", "viewer-pass")
+	if rec := serveAs(t, h, viewerToken, http.MethodDelete, "/api/v1/settings/users/admin", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+	if rec := serveAs(t, h, viewerToken, http.MethodGet, "/api/v1/audit", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the audit log to be admin-only, got %d", rec.Code)
+	}
+
+	// Reads and logins are not recorded; the refused delete is, by its path
+	// as it was refused before reaching its route
+	rec := serveAs(t, h, adminToken, http.MethodGet, "/api/v1/audit", "")
+	var page models.AuditPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || page.Total != 2 {
+		t.Fatalf("unexpected audit log %d: %s", rec.Code, rec.Body.String())
+	}
+	denied, created := page.Events[0], page.Events[1]
+	if denied.Username != "vic" || denied.Action != "DELETE /settings/users/admin" ||
+		denied.Status != http.StatusForbidden || denied.Outcome != models.AuditOutcomeDenied || denied.Error == "" {
+		t.Fatalf("unexpected event %+v", denied)
+	}
+	if created.Username != "admin" || created.Role != models.RoleAdmin || created.Action != "POST /settings/users" ||
+		created.Outcome != models.AuditOutcomeSuccess || created.SourceIP == "" ||
+		created.Summary != `password=[redacted] role="viewer" username="vic"` {
+		t.Fatalf("unexpected event %+v", created)
+	}
+	if strings.Contains(rec.Body.String(), "viewer-pass") {
+		t.Fatalf("audit log leaks the password: %s", rec.Body.String())
+	}
+
+	rec = serveAs(t, h, adminToken, http.MethodGet, "/api/v1/audit?outcome=denied&username=vic", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || page.Total != 1 {
+		t.Fatalf("unexpected filtered audit log %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, adminToken, http.MethodGet, "/api/v1/audit?start_date=soon", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = serveAs(t, h, adminToken, http.MethodGet, "/api/v1/audit/export", "")
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || rec.Code != http.StatusOK || len(records) != 3 || records[0][0] != "id" || records[2][6] != "POST /settings/users" {
+		t.Fatalf("unexpected CSV export %d: %v, %v", rec.Code, records, err)
+	}
+	rec = serveAs(t, h, adminToken, http.MethodGet, "/api/v1/audit/export?format=json&outcome=success", "")
+	var events []models.AuditEvent
+	if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil || len(events) != 1 || events[0].Username != "admin" {
+		t.Fatalf("unexpected JSON export %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, adminToken, http.MethodGet, "/api/v1/audit/export?format=xml", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	// Routed requests are described by their route and its parameters
+	if rec := serveAs(t, h, adminToken, http.MethodDelete, "/api/v1/settings/users/vic", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	rec = serveAs(t, h, adminToken, http.MethodGet, "/api/v1/audit?page_size=1", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || len(page.Events) != 1 ||
+		page.Events[0].Action != "DELETE /settings/users/{username}" || page.Events[0].Target != "username=vic" {
+		t.Fatalf("unexpected audit log %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/hhftechnology/vps-monitor/internal/auth"
 	"github.com/hhftechnology/vps-monitor/internal/bot"
 	"github.com/hhftechnology/vps-monitor/internal/config"
 )
@@ -21,6 +22,12 @@ func (ar *APIRouter) RelayBotCommand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Bot commands cover every container, as /metrics does
+	if user, ok := auth.UserFromContext(r.Context()); ok && auth.Scoped(user) {
+		http.Error(w, "bot commands cover every container and are not available to scoped users", http.StatusForbidden)
+		return
+	}
+
 	if ar.registry.Config().Bot.Mode != config.BotModeJWTRelay {
 		http.Error(w, "bot relay mode is disabled", http.StatusConflict)
 		return
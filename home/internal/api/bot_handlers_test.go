@@ -12,6 +12,7 @@ import (
 	"github.com/hhftechnology/vps-monitor/internal/auth"
 	"github.com/hhftechnology/vps-monitor/internal/bot"
 	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
 	"github.com/hhftechnology/vps-monitor/internal/services"
 )
 
@@ -81,6 +82,26 @@ func TestRelayBotCommandReturnsReply(t *testing.T) {
 	}
 }
 
+func TestRelayBotCommandRejectsScopedUsers(t *testing.T) {
+	relay := &fakeBotRelayService{reply: "ok"}
+	router := &APIRouter{
+		registry: services.NewRegistry(nil, nil, newUsableAuthService(t), &config.Config{
+			Bot: config.BotConfig{Enabled: true, Mode: config.BotModeJWTRelay},
+		}, nil),
+		botService: relay,
+	}
+
+	user := models.User{Username: "dev", Role: models.RoleOperator, Grants: []models.PermissionGrant{{Host: "host-a"}}}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bot/relay/command", strings.NewReader(`{"text":"/status"}`))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+	rec := httptest.NewRecorder()
+	router.RelayBotCommand(rec, req)
+
+	if rec.Code != http.StatusForbidden || relay.callCount != 0 {
+		t.Fatalf("expected %d without relaying, got %d (%d calls)", http.StatusForbidden, rec.Code, relay.callCount)
+	}
+}
+
 func TestRelayBotCommandRejectsNilBotService(t *testing.T) {
 	router := &APIRouter{}
 
@@ -2,20 +2,35 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/push"
 )
 
 type deviceRegistrationRequest struct {
-	Token    string `json:"token"`
-	Platform string `json:"platform"`
+	Token       string               `json:"token"`
+	Platform    string               `json:"platform"`
+	Provider    models.PushProvider  `json:"provider"`
+	Name        string               `json:"name"`
+	MinSeverity models.AlertSeverity `json:"min_severity"`
 }
 
-// RegisterDevice accepts mobile push device registrations.
-//
-// Push delivery is not implemented on the server yet, but we accept and validate
-// registrations so mobile clients can complete their setup flow without failing.
+// RegisterDevice registers the current user's mobile device for alert push
+// notifications. Registering the same token again updates its preferences.
+// Single sign-on users have no account to check the alerts they may see
+// against, so they cannot register devices.
 func (ar *APIRouter) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	if user, ok := auth.UserFromContext(r.Context()); ok && user.Provider != "" {
+		http.Error(w, "this requires a local user account", http.StatusForbidden)
+		return
+	}
+
 	var req deviceRegistrationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
@@ -27,9 +42,77 @@ func (ar *APIRouter) RegisterDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	WriteJsonResponse(w, http.StatusAccepted, map[string]any{
-		"message":   "Device registration accepted",
-		"platform":  strings.TrimSpace(req.Platform),
-		"delivered": false,
+	if ar.pushService == nil {
+		http.Error(w, "push notifications are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	device, created, err := ar.pushService.Register(models.Device{
+		Username:    requestUsername(r),
+		Provider:    req.Provider,
+		Token:       req.Token,
+		Platform:    req.Platform,
+		Name:        req.Name,
+		MinSeverity: req.MinSeverity,
 	})
+	if err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	WriteJsonResponse(w, status, map[string]any{
+		"message": "Device registered",
+		"device":  device,
+	})
+}
+
+// ListDevices returns the devices the current user registered
+func (ar *APIRouter) ListDevices(w http.ResponseWriter, r *http.Request) {
+	if ar.pushService == nil {
+		WriteJsonResponse(w, http.StatusOK, map[string]any{"devices": []models.Device{}})
+		return
+	}
+
+	devices, err := ar.pushService.List(requestUsername(r))
+	if err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"devices": devices})
+}
+
+// UnregisterDevice stops push notifications to one of the current user's devices
+func (ar *APIRouter) UnregisterDevice(w http.ResponseWriter, r *http.Request) {
+	if ar.pushService == nil {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+
+	ok, err := ar.pushService.Unregister(requestUsername(r), chi.URLParam(r, "deviceID"))
+	if err != nil {
+		writeDeviceError(w, err)
+		return
+	}
+	if !ok {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "Device unregistered"})
+}
+
+func writeDeviceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, push.ErrInvalidDevice) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, push.ErrDeviceTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	log.Printf("Failed to update devices: %v", err)
+	http.Error(w, "failed to update devices", http.StatusInternalServerError)
 }
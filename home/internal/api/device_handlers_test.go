@@ -2,31 +2,94 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/push"
 )
 
+func newDeviceRouter() chi.Router {
+	pushService := push.NewService(nil)
+	pushService.SetSender(models.PushUnifiedPush, push.NewUnifiedPushSender(nil))
+	ar := &APIRouter{pushService: pushService}
+
+	r := chi.NewRouter()
+	ar.registerDeviceRoutes(r)
+	return r
+}
+
+func deviceRequest(method, target, body, username string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	return req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, models.User{Username: username, Role: models.RoleOperator}))
+}
+
 func TestRegisterDevice(t *testing.T) {
-	t.Run("accepts valid registration", func(t *testing.T) {
-		router := &APIRouter{}
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/devices/register", bytes.NewBufferString(`{"token":"abc","platform":"ios"}`))
+	t.Run("registers and updates a device", func(t *testing.T) {
+		router := newDeviceRouter()
+		body := `{"token":"https://ntfy.example.com/upAbc","platform":"android","provider":"unifiedpush","min_severity":"warning"}`
 		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, deviceRequest(http.MethodPost, "/devices/register", body, "alice"))
 
-		router.RegisterDevice(rec, req)
-
-		if rec.Code != http.StatusAccepted {
-			t.Fatalf("expected status %d, got %d", http.StatusAccepted, rec.Code)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
 		}
 
-		var body map[string]any
-		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		var created struct {
+			Device models.Device `json:"device"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
 			t.Fatalf("failed to decode response: %v", err)
 		}
+		if created.Device.Username != "alice" || created.Device.MinSeverity != models.AlertSeverityWarning {
+			t.Fatalf("unexpected device: %+v", created.Device)
+		}
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, deviceRequest(http.MethodPost, "/devices/register", body, "alice"))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected re-registration to return %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("refuses another user's token", func(t *testing.T) {
+		router := newDeviceRouter()
+		body := `{"token":"https://ntfy.example.com/upAbc","provider":"unifiedpush"}`
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, deviceRequest(http.MethodPost, "/devices/register", body, "alice"))
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, deviceRequest(http.MethodPost, "/devices/register", body, "bob"))
+
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusConflict, rec.Code, rec.Body.String())
+		}
+	})
 
-		if body["message"] != "Device registration accepted" {
-			t.Fatalf("unexpected message: %v", body["message"])
+	t.Run("refuses local endpoints", func(t *testing.T) {
+		router := newDeviceRouter()
+		for _, endpoint := range []string{"http://127.0.0.1:6789/api/v1", "http://localhost/x", "http://169.254.169.254/latest", "http://[::1]/x", "https://10.0.0.5/up"} {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, deviceRequest(http.MethodPost, "/devices/register", `{"token":"`+endpoint+`","provider":"unifiedpush"}`, "alice"))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d for %s, got %d", http.StatusBadRequest, endpoint, rec.Code)
+			}
+		}
+	})
+
+	t.Run("requires an operator", func(t *testing.T) {
+		router := newDeviceRouter()
+		req := httptest.NewRequest(http.MethodPost, "/devices/register", bytes.NewBufferString(`{"token":"https://ntfy.example.com/up1","provider":"unifiedpush"}`))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, models.User{Username: "carol", Role: models.RoleViewer}))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("expected status %d for a viewer, got %d", http.StatusForbidden, rec.Code)
 		}
 	})
 
@@ -41,4 +104,53 @@ func TestRegisterDevice(t *testing.T) {
 			t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
 		}
 	})
+
+	t.Run("rejects providers that are not configured", func(t *testing.T) {
+		router := newDeviceRouter()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, deviceRequest(http.MethodPost, "/devices/register", `{"token":"abc","platform":"ios"}`, "alice"))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d without FCM credentials, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestListAndUnregisterDevices(t *testing.T) {
+	router := newDeviceRouter()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, deviceRequest(http.MethodPost, "/devices/register", `{"token":"https://ntfy.example.com/up1","provider":"unifiedpush"}`, "alice"))
+	var created struct {
+		Device models.Device `json:"device"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	var list struct {
+		Devices []models.Device `json:"devices"`
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, deviceRequest(http.MethodGet, "/devices", "", "bob"))
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Devices) != 0 {
+		t.Fatalf("bob should not see alice's devices, got %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, deviceRequest(http.MethodDelete, "/devices/"+created.Device.ID, "", "bob"))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected bob's unregister to return 404, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, deviceRequest(http.MethodDelete, "/devices/"+created.Device.ID, "", "alice"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, deviceRequest(http.MethodGet, "/devices", "", "alice"))
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Devices) != 0 {
+		t.Fatalf("expected no devices after unregistering, got %s", rec.Body.String())
+	}
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,6 +14,9 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/hhftechnology/vps-monitor/internal/api/middleware"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/config"
 	"github.com/hhftechnology/vps-monitor/internal/coolify"
 	"github.com/hhftechnology/vps-monitor/internal/models"
 	"github.com/hhftechnology/vps-monitor/internal/system"
@@ -155,10 +159,18 @@ func (ar *APIRouter) GetContainers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Users limited by grants only see their containers and the hosts
+	// their grants can match
+	user, _ := auth.UserFromContext(r.Context())
+
 	// Flatten the map for easier frontend consumption
 	allContainers := []models.ContainerInfo{}
 	for _, containers := range containersMap {
-		allContainers = append(allContainers, containers...)
+		for _, ctr := range containers {
+			if auth.CanAccessContainer(user, ctr) {
+				allContainers = append(allContainers, ctr)
+			}
+		}
 	}
 
 	ar.enrichContainersWithHistoricalStats(allContainers)
@@ -166,15 +178,25 @@ func (ar *APIRouter) GetContainers(w http.ResponseWriter, r *http.Request) {
 	// Build host errors list for the frontend (graceful partial results)
 	hostErrorMessages := make([]map[string]string, 0, len(hostErrors))
 	for _, he := range hostErrors {
+		if !auth.CanAccessHost(user, he.HostName) {
+			continue
+		}
 		hostErrorMessages = append(hostErrorMessages, map[string]string{
 			"host":    he.HostName,
 			"message": he.Err.Error(),
 		})
 	}
 
+	hosts := make([]config.DockerHost, 0)
+	for _, host := range dockerClient.GetHosts() {
+		if auth.CanAccessHost(user, host.Name) {
+			hosts = append(hosts, host)
+		}
+	}
+
 	WriteJsonResponse(w, http.StatusOK, map[string]any{
 		"containers":        allContainers,
-		"hosts":             dockerClient.GetHosts(),
+		"hosts":             hosts,
 		"readOnly":          ar.registry.Config().ReadOnly,
 		"hostErrors":        hostErrorMessages,
 		"coolifyConfigured": ar.registry.Coolify() != nil,
@@ -305,7 +327,7 @@ func (ar *APIRouter) StopContainer(w http.ResponseWriter, r *http.Request) {
 		"status":  "pending",
 	})
 
-	ar.runAsyncContainerAction(host, id, "stop", func(ctx context.Context, client *docker.MultiHostClient) error {
+	ar.runAsyncContainerAction(r, host, id, "stop", func(ctx context.Context, client *docker.MultiHostClient) error {
 		return client.StopContainer(ctx, host, id)
 	})
 }
@@ -331,7 +353,7 @@ func (ar *APIRouter) RestartContainer(w http.ResponseWriter, r *http.Request) {
 		"status":  "pending",
 	})
 
-	ar.runAsyncContainerAction(host, id, "restart", func(ctx context.Context, client *docker.MultiHostClient) error {
+	ar.runAsyncContainerAction(r, host, id, "restart", func(ctx context.Context, client *docker.MultiHostClient) error {
 		return client.RestartContainer(ctx, host, id)
 	})
 }
@@ -357,7 +379,7 @@ func (ar *APIRouter) RemoveContainer(w http.ResponseWriter, r *http.Request) {
 		"status":  "pending",
 	})
 
-	ar.runAsyncContainerAction(host, id, "remove", func(ctx context.Context, client *docker.MultiHostClient) error {
+	ar.runAsyncContainerAction(r, host, id, "remove", func(ctx context.Context, client *docker.MultiHostClient) error {
 		return client.RemoveContainer(ctx, host, id)
 	})
 }
@@ -392,27 +414,31 @@ func (ar *APIRouter) GetContainerHistoricalStats(w http.ResponseWriter, r *http.
 	WriteJsonResponse(w, http.StatusOK, history)
 }
 
-func (ar *APIRouter) runAsyncContainerAction(host, id, action string, fn func(context.Context, *docker.MultiHostClient) error) {
+func (ar *APIRouter) runAsyncContainerAction(r *http.Request, host, id, action string, fn func(context.Context, *docker.MultiHostClient) error) {
 	RecordActionJob(host, id, action, "pending", "")
+	event, audited := middleware.AuditEventFor(r)
 	go func() {
 		dockerClient, release := ar.registry.AcquireDocker()
 		defer release()
 
+		var err error
 		if dockerClient == nil {
-			log.Printf("failed to %s container %s on host %s: docker client unavailable", action, id, host)
-			RecordActionJob(host, id, action, "failed", "docker client unavailable")
-			return
+			err = errors.New("docker client unavailable")
+		} else {
+			ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
+			defer cancel()
+			err = fn(ctx, dockerClient)
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
-		defer cancel()
-
-		if err := fn(ctx, dockerClient); err != nil {
+		if err != nil {
 			log.Printf("failed to %s container %s on host %s: %v", action, id, host, err)
 			RecordActionJob(host, id, action, "failed", err.Error())
 		} else {
 			RecordActionJob(host, id, action, "success", "")
 		}
+		if audited {
+			ar.recordBackgroundResult(event, err)
+		}
 	}()
 }
 
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/api/middleware"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestLoginLockout(t *testing.T) {
+	ar := newUsersTestAPIRouter(t)
+	ar.loginGuard = auth.NewLoginGuard(config.LoginProtectionConfig{
+		MaxFailures:   3,
+		Lockout:       time.Minute,
+		MaxLockout:    time.Hour,
+		FailureWindow: 15 * time.Minute,
+	})
+	var lockouts []models.LoginLockout
+	ar.loginGuard.SetLockoutHandler(func(lockout models.LoginLockout) {
+		lockouts = append(lockouts, lockout)
+	})
+	h := ar.Routes()
+
+	for range 3 {
+		rec := serveAs(t, h, "", http.MethodPost, "/api/v1/auth/login", `{"username":"admin","password":"guess"}`)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("wrong password: expected %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	}
+	// httptest requests all come from the same address, so both are locked out
+	if len(lockouts) != 2 || lockouts[0].IP != "192.0.2.1" || lockouts[1].Username != "admin" {
+		t.Fatalf("expected the address and the username to be locked out, got %+v", lockouts)
+	}
+
+	rec := serveAs(t, h, "", http.MethodPost, "/api/v1/auth/login", `{"username":"admin","password":"secret"}`)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("locked out login: expected %d with Retry-After 60, got %d (%q): %s", http.StatusTooManyRequests, rec.Code, rec.Header().Get("Retry-After"), rec.Body.String())
+	}
+	rec = serveAs(t, h, "", http.MethodPost, "/api/v1/auth/login/2fa", `{"challenge":"abc","code":"123456"}`)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked out two-factor login: expected %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+
+	// A complete login clears the failures of the username
+	ar = newUsersTestAPIRouter(t)
+	ar.loginGuard = auth.NewLoginGuard(config.LoginProtectionConfig{MaxFailures: 2, Lockout: time.Minute, MaxLockout: time.Hour, FailureWindow: time.Hour})
+	h = ar.Routes()
+	serveAs(t, h, "", http.MethodPost, "/api/v1/auth/login", `{"username":"admin","password":"guess"}`)
+	login(t, h, "admin", "secret")
+	serveAs(t, h, "", http.MethodPost, "/api/v1/auth/login", `{"username":"admin","password":"guess"}`)
+	if wait := ar.loginGuard.Check("", "admin"); wait != 0 {
+		t.Fatalf("expected the username to start over after a login, got a %s lockout", wait)
+	}
+	if wait := ar.loginGuard.Check("192.0.2.1", ""); wait == 0 {
+		t.Fatal("expected the address to keep its failures")
+	}
+}
+
+func TestLoginLockoutBehindTrustedProxy(t *testing.T) {
+	ar := newUsersTestAPIRouter(t)
+	ar.registry.UpdateConfig(&config.Config{TrustedProxies: config.TrustedProxiesConfig{
+		Networks: []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")},
+	}})
+	ar.loginGuard = auth.NewLoginGuard(config.LoginProtectionConfig{MaxFailures: 2, Lockout: time.Minute, MaxLockout: time.Hour, FailureWindow: time.Hour})
+	h := ar.Routes()
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"username":"nobody","password":"guess"}`))
+		req.Header.Set("X-Forwarded-For", "198.51.100.1")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	// Only the client behind the proxy is locked out, not the proxy itself
+	if wait := ar.loginGuard.Check("198.51.100.1", ""); wait == 0 {
+		t.Fatal("expected the forwarded client address to be locked out")
+	}
+	if wait := ar.loginGuard.Check("192.0.2.1", ""); wait != 0 {
+		t.Fatalf("expected the proxy address to stay usable, got a %s lockout", wait)
+	}
+	login(t, h, "admin", "secret")
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	ar := newUsersTestAPIRouter(t)
+	ar.loginLimit = middleware.NewRateLimiter(2, time.Minute)
+	h := ar.Routes()
+
+	login(t, h, "admin", "secret")
+	login(t, h, "admin", "secret")
+	rec := serveAs(t, h, "", http.MethodPost, "/api/v1/auth/login", `{"username":"admin","password":"secret"}`)
+	// Tokens refill while the logins hash passwords, so the wait is at most
+	// the 30s of one token
+	retryAfter, _ := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if rec.Code != http.StatusTooManyRequests || retryAfter < 1 || retryAfter > 30 {
+		t.Fatalf("expected %d with Retry-After up to 30 over the limit, got %d (%q)", http.StatusTooManyRequests, rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Refresh tokens are credentials too and share the limit
+	if rec := serveAs(t, h, "", http.MethodPost, "/api/v1/auth/refresh", `{"refresh_token":"guess"}`); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected refreshes over the limit to get %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+}
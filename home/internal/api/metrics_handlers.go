@@ -0,0 +1,305 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/metrics"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/system"
+)
+
+// metricsScrapeTimeout bounds the Docker calls of one scrape, below the
+// default Prometheus scrape timeout
+const metricsScrapeTimeout = 9 * time.Second
+
+// requireMetricsAuth accepts the METRICS_TOKEN bearer token when one is
+// set. Otherwise /metrics takes the API authentication, for users that
+// may see every container.
+func (ar *APIRouter) requireMetricsAuth(next http.Handler) http.Handler {
+	withAPIAuth := auth.DynamicMiddleware(ar.registry.Auth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := auth.UserFromContext(r.Context()); ok && auth.Scoped(user) {
+			http.Error(w, "metrics cover every container and are not available to scoped users", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := ar.registry.Config().Metrics.Token
+		if token == "" {
+			withAPIAuth.ServeHTTP(w, r)
+			return
+		}
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetMetrics serves host, container, alert and scan metrics in the
+// Prometheus text format. Parts that cannot be read are left out and logged.
+func (ar *APIRouter) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), metricsScrapeTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	mw := metrics.NewWriter(w)
+	ar.writeHostMetrics(ctx, mw)
+	ar.writeContainerMetrics(ctx, mw)
+	ar.writeAlertMetrics(mw)
+	ar.writeScanMetrics(mw)
+	metrics.WriteInternal(mw)
+	if err := mw.Flush(); err != nil {
+		log.Printf("Failed to write metrics: %v", err)
+	}
+}
+
+func (ar *APIRouter) writeHostMetrics(ctx context.Context, mw *metrics.Writer) {
+	stats, err := system.GetStats(ctx)
+	if err != nil {
+		log.Printf("Failed to read host stats for metrics: %v", err)
+		return
+	}
+	info := stats.HostInfo
+	if hostname := ar.registry.Config().Hostname; hostname != "" {
+		info.Hostname = hostname
+	}
+
+	gauges := []struct {
+		name, help string
+		value      float64
+	}{
+		{"vps_monitor_host_cpu_usage_percent", "CPU usage of the host in percent.", stats.Usage.CPUPercent},
+		{"vps_monitor_host_memory_used_bytes", "Memory used on the host.", float64(stats.Usage.MemoryUsed)},
+		{"vps_monitor_host_memory_total_bytes", "Memory of the host.", float64(stats.Usage.MemoryTotal)},
+		{"vps_monitor_host_disk_used_bytes", "Disk space used on the root filesystem.", float64(stats.Usage.DiskUsed)},
+		{"vps_monitor_host_disk_total_bytes", "Size of the root filesystem.", float64(stats.Usage.DiskTotal)},
+		{"vps_monitor_host_uptime_seconds", "Uptime of the host.", float64(info.Uptime)},
+		{"vps_monitor_host_cpus", "Logical CPUs of the host.", float64(info.CPULogical)},
+	}
+	mw.Family("vps_monitor_host_info", "Host vps-monitor runs on.", metrics.TypeGauge)
+	mw.Sample("vps_monitor_host_info", 1,
+		"hostname", info.Hostname, "platform", info.Platform, "platform_version", info.PlatformVersion,
+		"kernel", info.KernelVersion, "arch", info.Arch)
+	for _, g := range gauges {
+		mw.Family(g.name, g.help, metrics.TypeGauge)
+		mw.Sample(g.name, g.value)
+	}
+}
+
+// containerSample is a container with its labels and, while running, its stats
+type containerSample struct {
+	labels []string
+	state  string
+	stats  *models.ContainerStats
+}
+
+func (ar *APIRouter) writeContainerMetrics(ctx context.Context, mw *metrics.Writer) {
+	dockerClient, releaseDocker := ar.registry.AcquireDocker()
+	if dockerClient == nil {
+		releaseDocker()
+		return
+	}
+	defer releaseDocker()
+
+	containersByHost, hostErrors, err := dockerClient.ListContainersAllHosts(ctx)
+	if err != nil {
+		log.Printf("Failed to list containers for metrics: %v", err)
+		return
+	}
+	down := make(map[string]bool, len(hostErrors))
+	for _, hostErr := range hostErrors {
+		log.Printf("Failed to list containers of %s for metrics: %v", hostErr.HostName, hostErr.Err)
+		down[hostErr.HostName] = true
+	}
+
+	// Stats are read one container at a time per host, hosts in parallel
+	samplesByHost := make(map[string][]containerSample, len(containersByHost))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for host, containers := range containersByHost {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			samples := make([]containerSample, 0, len(containers))
+			for _, ctr := range containers {
+				sample := containerSample{labels: containerLabels(host, ctr), state: ctr.State}
+				if ctr.State == "running" {
+					if stats, err := dockerClient.GetContainerStatsOnce(ctx, host, ctr.ID); err == nil {
+						sample.stats = stats
+					}
+				}
+				samples = append(samples, sample)
+			}
+			mu.Lock()
+			samplesByHost[host] = samples
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	hosts := make([]string, 0, len(dockerClient.GetHosts()))
+	for _, host := range dockerClient.GetHosts() {
+		hosts = append(hosts, host.Name)
+	}
+	slices.Sort(hosts)
+	var samples []containerSample
+	for _, host := range hosts {
+		samples = append(samples, samplesByHost[host]...)
+	}
+
+	mw.Family("vps_monitor_docker_host_up", "Whether the Docker host answered the last scrape.", metrics.TypeGauge)
+	for _, host := range hosts {
+		up := 1.0
+		if down[host] {
+			up = 0
+		}
+		mw.Sample("vps_monitor_docker_host_up", up, "host", host)
+	}
+
+	mw.Family("vps_monitor_container_state", "Containers by state; 1 for the current state.", metrics.TypeGauge)
+	for _, s := range samples {
+		mw.Sample("vps_monitor_container_state", 1, append(slices.Clone(s.labels), "state", s.state)...)
+	}
+
+	families := []struct {
+		name, help, typ string
+		value           func(*models.ContainerStats) float64
+	}{
+		{"vps_monitor_container_cpu_usage_percent", "CPU usage of a container in percent of one CPU.", metrics.TypeGauge,
+			func(s *models.ContainerStats) float64 { return s.CPUPercent }},
+		{"vps_monitor_container_memory_usage_bytes", "Memory used by a container.", metrics.TypeGauge,
+			func(s *models.ContainerStats) float64 { return float64(s.MemoryUsage) }},
+		{"vps_monitor_container_memory_limit_bytes", "Memory limit of a container.", metrics.TypeGauge,
+			func(s *models.ContainerStats) float64 { return float64(s.MemoryLimit) }},
+		{"vps_monitor_container_network_receive_bytes_total", "Bytes received by a container on all interfaces.", metrics.TypeCounter,
+			func(s *models.ContainerStats) float64 { return float64(s.NetworkRx) }},
+		{"vps_monitor_container_network_transmit_bytes_total", "Bytes sent by a container on all interfaces.", metrics.TypeCounter,
+			func(s *models.ContainerStats) float64 { return float64(s.NetworkTx) }},
+		{"vps_monitor_container_blkio_read_bytes_total", "Bytes read from block devices by a container.", metrics.TypeCounter,
+			func(s *models.ContainerStats) float64 { return float64(s.BlockRead) }},
+		{"vps_monitor_container_blkio_write_bytes_total", "Bytes written to block devices by a container.", metrics.TypeCounter,
+			func(s *models.ContainerStats) float64 { return float64(s.BlockWrite) }},
+		{"vps_monitor_container_pids", "Processes in a container.", metrics.TypeGauge,
+			func(s *models.ContainerStats) float64 { return float64(s.PIDs) }},
+	}
+	for _, f := range families {
+		mw.Family(f.name, f.help, f.typ)
+		for _, s := range samples {
+			if s.stats != nil {
+				mw.Sample(f.name, f.value(s.stats), s.labels...)
+			}
+		}
+	}
+}
+
+// containerLabels identifies a container by host, name, image and compose
+// project
+func containerLabels(host string, ctr models.ContainerInfo) []string {
+	name := ctr.ID
+	if len(ctr.Names) > 0 {
+		name = strings.TrimPrefix(ctr.Names[0], "/")
+	}
+	return []string{
+		"host", host,
+		"container", name,
+		"image", ctr.Image,
+		"compose_project", ctr.Labels["com.docker.compose.project"],
+	}
+}
+
+func (ar *APIRouter) writeAlertMetrics(mw *metrics.Writer) {
+	monitor := ar.registry.Alerts()
+	if monitor == nil {
+		return
+	}
+	firing, err := monitor.GetHistory().Firing()
+	if err != nil {
+		log.Printf("Failed to read firing alerts for metrics: %v", err)
+		return
+	}
+
+	type alertKey struct {
+		typ      models.AlertType
+		severity models.AlertSeverity
+	}
+	counts := make(map[alertKey]int)
+	for _, alert := range firing {
+		counts[alertKey{alert.Type, alert.Severity}]++
+	}
+	keys := make([]alertKey, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b alertKey) int {
+		return cmp.Or(cmp.Compare(a.typ, b.typ), cmp.Compare(a.severity, b.severity))
+	})
+
+	mw.Family("vps_monitor_alerts_firing", "Alerts that are firing, by type and severity.", metrics.TypeGauge)
+	for _, key := range keys {
+		mw.Sample("vps_monitor_alerts_firing", float64(counts[key]), "type", string(key.typ), "severity", string(key.severity))
+	}
+	mw.Family("vps_monitor_alerts_unacknowledged", "Alerts in the history that are not acknowledged.", metrics.TypeGauge)
+	mw.Sample("vps_monitor_alerts_unacknowledged", float64(monitor.GetHistory().GetUnacknowledgedCount()))
+}
+
+func (ar *APIRouter) writeScanMetrics(mw *metrics.Writer) {
+	if ar.statsDB != nil {
+		summaries, err := ar.statsDB.LatestScanSummaries()
+		if err != nil {
+			log.Printf("Failed to read scan results for metrics: %v", err)
+		} else {
+			mw.Family("vps_monitor_image_vulnerabilities", "Vulnerabilities found by the latest scan of an image, by severity.", metrics.TypeGauge)
+			for _, result := range summaries {
+				counts := []struct {
+					severity models.SeverityLevel
+					count    int
+				}{
+					{models.SeverityCritical, result.Summary.Critical},
+					{models.SeverityHigh, result.Summary.High},
+					{models.SeverityMedium, result.Summary.Medium},
+					{models.SeverityLow, result.Summary.Low},
+					{models.SeverityNegligible, result.Summary.Negligible},
+					{models.SeverityUnknown, result.Summary.Unknown},
+				}
+				for _, c := range counts {
+					mw.Sample("vps_monitor_image_vulnerabilities", float64(c.count),
+						"host", result.Host, "image", result.ImageRef, "severity", strings.ToLower(string(c.severity)))
+				}
+			}
+			mw.Family("vps_monitor_image_last_scan_timestamp_seconds", "When the latest scan of an image completed.", metrics.TypeGauge)
+			for _, result := range summaries {
+				mw.Sample("vps_monitor_image_last_scan_timestamp_seconds", float64(result.CompletedAt),
+					"host", result.Host, "image", result.ImageRef, "scanner", string(result.Scanner))
+			}
+		}
+	}
+
+	if ar.scanHandlers == nil {
+		return
+	}
+	counts := make(map[models.ScanJobStatus]int)
+	for _, job := range ar.scanHandlers.scanner.GetJobs() {
+		counts[job.Status]++
+	}
+	mw.Family("vps_monitor_scan_jobs", "Scan jobs of the last 24 hours by status.", metrics.TypeGauge)
+	for _, status := range []models.ScanJobStatus{
+		models.ScanJobPending, models.ScanJobPulling, models.ScanJobScanning,
+		models.ScanJobComplete, models.ScanJobFailed, models.ScanJobCancelled,
+	} {
+		mw.Sample("vps_monitor_scan_jobs", float64(counts[status]), "status", string(status))
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	ar := newUsersTestAPIRouter(t)
+	scannerService := newTestScannerService(t)
+	ar.scanHandlers = NewScanHandlers(scannerService, newTestManager(t))
+	ar.statsDB = scannerService.Store().DB()
+	if err := scannerService.Store().Add(models.ScanResult{
+		ID:          "result-1",
+		ImageRef:    "nginx:latest",
+		Host:        "local",
+		Scanner:     models.ScannerGrype,
+		CompletedAt: 1700000000,
+		Summary:     models.SeveritySummary{Critical: 2, High: 1, Total: 3},
+	}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	h := ar.Routes()
+
+	// Without METRICS_TOKEN the API authentication applies
+	if rec := serveAs(t, h, "", http.MethodGet, "/metrics", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous scrape: expected %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	adminToken, _ := login(t, h, "admin", "secret")
+	rec := serveAs(t, h, adminToken, http.MethodGet, "/metrics", "")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("admin scrape: expected %d with the text format, got %d (%q)", http.StatusOK, rec.Code, rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		`vps_monitor_image_vulnerabilities{host="local",image="nginx:latest",severity="critical"} 2`,
+		`vps_monitor_image_vulnerabilities{host="local",image="nginx:latest",severity="high"} 1`,
+		`vps_monitor_image_last_scan_timestamp_seconds{host="local",image="nginx:latest",scanner="grype"} 1.7e+09`,
+		`vps_monitor_scan_jobs{status="pending"} 0`,
+		"# TYPE vps_monitor_scan_duration_seconds histogram",
+		"# TYPE vps_monitor_webhook_failures_total counter",
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("expected %q in the metrics:\n%s", want, rec.Body.String())
+		}
+	}
+
+	// With METRICS_TOKEN only that token is accepted
+	ar.registry.UpdateConfig(&config.Config{Metrics: config.MetricsConfig{Token: "scrape-secret"}})
+	if rec := serveAs(t, h, adminToken, http.MethodGet, "/metrics", ""); rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("scrape with a session token: expected %d with a challenge, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec := serveAs(t, h, "scrape-secret", http.MethodGet, "/metrics", ""); rec.Code != http.StatusOK {
+		t.Fatalf("scrape with METRICS_TOKEN: expected %d, got %d", http.StatusOK, rec.Code)
+	}
+}
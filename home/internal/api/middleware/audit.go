@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/hhftechnology/vps-monitor/internal/audit"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const (
+	// maxAuditBody is how much of a JSON request body is read for the summary
+	maxAuditBody = 64 << 10
+	// maxAuditValue is how much of a field or error message is kept
+	maxAuditValue = 200
+)
+
+// sensitiveField matches request fields whose values are never recorded
+var sensitiveField = regexp.MustCompile(`(?i)pass|secret|token|key|hash|code|challenge|credential|env|value|url|webhook`)
+
+type auditContextKey struct{}
+
+// Audit creates a middleware that records the requests it wraps in log:
+// who sent them, from where, what they changed and how it ended. Reads are
+// not recorded, except WebSocket upgrades, which open terminal sessions.
+// It belongs before the role, scope and read-only checks, so refused
+// requests are recorded too. A nil log records nothing.
+func Audit(log *audit.Log) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if log == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upgrade := isWebSocketUpgrade(r)
+			if !upgrade && (r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			event := models.AuditEvent{
+				Time:     start.Unix(),
+				SourceIP: ClientIP(r),
+				Host:     r.URL.Query().Get("host"),
+				Summary:  summarizeRequest(r),
+			}
+			if user, ok := auth.UserFromContext(r.Context()); ok {
+				event.Username = user.Username
+				event.Role = user.Role
+				event.APIToken = user.TokenScopes != nil
+			}
+
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			errBody := &limitedBuffer{limit: maxAuditValue}
+			ww.Tee(errBody)
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), auditContextKey{}, &event)))
+
+			describeRoute(r, &event)
+			event.Status = ww.Status()
+			if event.Status == 0 {
+				// Nothing written through ww: either a hijacked WebSocket or
+				// an implicit 200
+				event.Status = http.StatusOK
+				if upgrade {
+					event.Status = http.StatusSwitchingProtocols
+				}
+			}
+			event.Outcome = outcomeFor(event.Status)
+			if event.Status >= http.StatusBadRequest {
+				event.Error = strings.TrimSpace(errBody.String())
+			}
+			event.DurationMs = time.Since(start).Milliseconds()
+			log.Record(event)
+		})
+	}
+}
+
+// AuditEventFor returns the audit event of a request wrapped by Audit, as
+// far as it is known while the handler runs. Handlers that leave work
+// running in the background use it to record the result.
+func AuditEventFor(r *http.Request) (models.AuditEvent, bool) {
+	event, ok := r.Context().Value(auditContextKey{}).(*models.AuditEvent)
+	if !ok {
+		return models.AuditEvent{}, false
+	}
+	copied := *event
+	describeRoute(r, &copied)
+	return copied, true
+}
+
+// describeRoute fills in the action and target from the matched route.
+// Requests refused before routing finished, e.g. by a check on a whole
+// subrouter, are described by their path.
+func describeRoute(r *http.Request, event *models.AuditEvent) {
+	pattern := r.URL.Path
+	var target []string
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if p := rctx.RoutePattern(); p != "" && !strings.HasSuffix(p, "*") {
+			pattern = p
+		}
+		for i, key := range rctx.URLParams.Keys {
+			if key == "*" || i >= len(rctx.URLParams.Values) {
+				continue
+			}
+			target = append(target, key+"="+rctx.URLParams.Values[i])
+		}
+	}
+	event.Action = r.Method + " " + strings.TrimPrefix(pattern, "/api/v1")
+	event.Target = strings.Join(target, " ")
+}
+
+func outcomeFor(status int) string {
+	switch {
+	case status == http.StatusAccepted:
+		return models.AuditOutcomeAccepted
+	case status < http.StatusBadRequest:
+		return models.AuditOutcomeSuccess
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return models.AuditOutcomeDenied
+	default:
+		return models.AuditOutcomeFailure
+	}
+}
+
+// summarizeRequest describes the query parameters and the top-level fields
+// of a JSON body. Values of sensitive fields, and of objects and arrays,
+// are left out. The body is put back for the handler.
+func summarizeRequest(r *http.Request) string {
+	var parts []string
+	query := r.URL.Query()
+	for _, key := range sortedKeys(query) {
+		if key == "host" || key == "token" {
+			continue
+		}
+		parts = append(parts, describeField(key, query.Get(key)))
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		body, _ := io.ReadAll(io.LimitReader(r.Body, maxAuditBody))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+
+		var fields map[string]any
+		if json.Unmarshal(body, &fields) == nil {
+			for _, key := range sortedKeys(fields) {
+				parts = append(parts, describeField(key, fields[key]))
+			}
+		}
+	}
+	return truncate(strings.Join(parts, " "), 4*maxAuditValue)
+}
+
+func describeField(key string, value any) string {
+	if sensitiveField.MatchString(key) {
+		return key + "=[redacted]"
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		return fmt.Sprintf("%s={%d fields}", key, len(v))
+	case []any:
+		return fmt.Sprintf("%s=[%d items]", key, len(v))
+	case string:
+		return fmt.Sprintf("%s=%q", key, truncate(v, maxAuditValue))
+	case nil:
+		return key + "=null"
+	default:
+		return fmt.Sprintf("%s=%v", key, v)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// limitedBuffer keeps the first limit bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
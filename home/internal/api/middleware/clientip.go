@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+)
+
+// ClientIP returns the address of the client, without the port. Behind a
+// trusted proxy it is the address TrustProxies took from X-Forwarded-For.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// TrustProxies creates a middleware that replaces the address of requests
+// sent by a trusted proxy with the client address in X-Forwarded-For. The
+// header is read from the right and trusted addresses are skipped, so a
+// client cannot choose its address by sending the header itself. Requests
+// on the Unix socket count as sent by a proxy when cfg.UnixSocket is set.
+// Lockouts and rate limits rely on it, so it belongs first.
+func TrustProxies(cfg config.TrustedProxiesConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(cfg.Networks) == 0 && !cfg.UnixSocket {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Trusts(ClientIP(r)) {
+				if client := forwardedClient(r.Header.Values("X-Forwarded-For"), cfg); client != "" {
+					r.RemoteAddr = client
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClient returns the right-most address of the X-Forwarded-For
+// headers that is not a trusted proxy, or "" when there is none
+func forwardedClient(headers []string, cfg config.TrustedProxiesConfig) string {
+	var hops []string
+	for _, header := range headers {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return ""
+		}
+		if i == 0 || !cfg.Trusts(addr.String()) {
+			return addr.Unmap().String()
+		}
+	}
+	return ""
+}
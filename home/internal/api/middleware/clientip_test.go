@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+)
+
+func TestTrustProxies(t *testing.T) {
+	h := TrustProxies(config.TrustedProxiesConfig{
+		Networks:   []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		UnixSocket: true,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ClientIP(r)))
+	}))
+
+	for _, tc := range []struct {
+		name, remoteAddr, forwardedFor, want string
+	}{
+		{"direct client", "203.0.113.7:5000", "", "203.0.113.7"},
+		{"untrusted peer cannot pick its address", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:5000", "198.51.100.1", "198.51.100.1"},
+		{"spoofed hop left of the real client", "10.0.0.2:5000", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.2:5000", "198.51.100.1, 10.0.0.9", "198.51.100.1"},
+		{"Unix socket", "@", "198.51.100.1", "198.51.100.1"},
+		{"trusted proxy without header", "10.0.0.2:5000", "", "10.0.0.2"},
+		{"malformed header", "10.0.0.2:5000", "not-an-ip", "10.0.0.2"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remoteAddr
+		if tc.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got := rec.Body.String(); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+)
+
+// RateLimiter hands every client a token bucket holding up to requests
+// tokens, refilled at requests per per. Clients are logged-in users or,
+// before login, client IPs.
+type RateLimiter struct {
+	capacity float64
+	refill   float64 // tokens per second
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter allowing requests requests per per and
+// bursts of up to requests. It returns nil, which limits nothing, when
+// either is 0.
+func NewRateLimiter(requests int, per time.Duration) *RateLimiter {
+	if requests <= 0 || per <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		capacity: float64(requests),
+		refill:   float64(requests) / per.Seconds(),
+		buckets:  make(map[string]*tokenBucket),
+	}
+}
+
+// Allow takes a token from the bucket of key. When it is empty, it returns
+// false and how long until the next token.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.capacity, last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = min(l.capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*l.refill)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.refill * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops the buckets that have refilled completely, at most once a
+// minute. Must be called with l.mu held.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.refill >= l.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit creates a middleware that answers requests over the limit with
+// 429 Too Many Requests and a Retry-After header. It belongs after the
+// authentication middleware, so logged-in users are limited per user
+// rather than per address. A nil limiter limits nothing.
+func RateLimit(limiter *RateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := "ip:" + ClientIP(r)
+			if user, ok := auth.UserFromContext(r.Context()); ok && user.Username != "" {
+				key = "user:" + user.Username
+			}
+			if ok, wait := limiter.Allow(key); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "Too many requests, try again later", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
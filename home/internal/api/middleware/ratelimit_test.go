@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestRateLimit(t *testing.T) {
+	h := RateLimit(NewRateLimiter(1, time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if username != "" {
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, models.User{Username: username}))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Users have their own buckets, apart from the address they share
+	for _, username := range []string{"", "alice", "bob"} {
+		if rec := serve(username); rec.Code != http.StatusNoContent {
+			t.Fatalf("first request of %q: expected %d, got %d", username, http.StatusNoContent, rec.Code)
+		}
+	}
+	rec := serve("alice")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected %d with Retry-After 60, got %d (%q)", http.StatusTooManyRequests, rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	if NewRateLimiter(0, time.Minute) != nil || NewRateLimiter(5, 0) != nil {
+		t.Fatal("expected a zero limit to disable the limiter")
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	limiter := NewRateLimiter(2, time.Minute)
+	for range 2 {
+		if ok, _ := limiter.Allow("ip:10.0.0.1"); !ok {
+			t.Fatal("expected the burst to be allowed")
+		}
+	}
+	if ok, wait := limiter.Allow("ip:10.0.0.1"); ok || wait <= 0 || wait > 30*time.Second {
+		t.Fatalf("expected to wait up to 30s for a token, got %t and %s", ok, wait)
+	}
+
+	// Half a minute later one token is back
+	limiter.buckets["ip:10.0.0.1"].last = time.Now().Add(-30 * time.Second)
+	if ok, _ := limiter.Allow("ip:10.0.0.1"); !ok {
+		t.Fatal("expected a refilled token")
+	}
+	if ok, _ := limiter.Allow("ip:10.0.0.1"); ok {
+		t.Fatal("expected a single refilled token")
+	}
+}
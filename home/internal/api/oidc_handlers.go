@@ -0,0 +1,109 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/hhftechnology/vps-monitor/internal/api/middleware"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+)
+
+// oidcStateCookie binds a login to the browser that started it
+const oidcStateCookie = "vps_monitor_oidc_state"
+
+// OIDCStatus tells the login page whether to offer single sign-on
+func (ar *APIRouter) OIDCStatus(w http.ResponseWriter, r *http.Request) {
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"enabled": ar.oidc != nil && ar.registry.Auth().IsUsable(),
+	})
+}
+
+// OIDCLogin sends the browser to the OpenID Connect provider
+func (ar *APIRouter) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if !ar.registry.Auth().IsUsable() {
+		http.Error(w, "Authentication is not enabled", http.StatusNotFound)
+		return
+	}
+
+	target, state, err := ar.oidc.AuthCodeURL(r.Context())
+	if err != nil {
+		log.Printf("Failed to start OIDC login: %v", err)
+		http.Error(w, "identity provider unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	setOIDCStateCookie(w, r, state, int(auth.OIDCLoginTimeout.Seconds()))
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// OIDCCallback finishes an OpenID Connect login and sends the browser back
+// to the frontend with vps-monitor tokens in the URL fragment, which
+// browsers do not send to servers
+func (ar *APIRouter) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	svc := ar.registry.Auth()
+	if !svc.IsUsable() {
+		http.Error(w, "Authentication is not enabled", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	cookie, cookieErr := r.Cookie(oidcStateCookie)
+	setOIDCStateCookie(w, r, "", -1)
+	if reason := query.Get("error"); reason != "" {
+		http.Error(w, "login refused by the identity provider: "+reason, http.StatusUnauthorized)
+		return
+	}
+
+	// Without the cookie the callback may come from a login someone else
+	// started, who would then be logged in as in the victim's browser
+	state := query.Get("state")
+	if cookieErr != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		log.Printf("OIDC login failed: the state does not belong to this browser")
+		http.Error(w, "OIDC login failed", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := ar.oidc.Exchange(r.Context(), state, query.Get("code"))
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		switch {
+		case errors.Is(err, auth.ErrOIDCNoRole):
+			http.Error(w, "your account has no access to vps-monitor", http.StatusForbidden)
+		case errors.Is(err, auth.ErrOIDCLogin):
+			http.Error(w, "OIDC login failed", http.StatusUnauthorized)
+		default:
+			http.Error(w, "identity provider unavailable", http.StatusBadGateway)
+		}
+		return
+	}
+
+	tokens, err := svc.StartSession(user, r.UserAgent(), middleware.ClientIP(r))
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	fragment := url.Values{"token": {tokens.Token}}
+	if tokens.RefreshToken != "" {
+		fragment.Set("refresh_token", tokens.RefreshToken)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, ar.oidc.PostLoginRedirect()+"#"+fragment.Encode(), http.StatusFound)
+}
+
+// setOIDCStateCookie sets the state cookie, or removes it when maxAge is
+// negative. It is only sent to the OIDC routes, and Lax lets it come along
+// on the provider's redirect back to the callback.
+func setOIDCStateCookie(w http.ResponseWriter, r *http.Request, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/v1/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
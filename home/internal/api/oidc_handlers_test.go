@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/config"
+)
+
+func TestOIDCCallbackRequiresTheStateCookie(t *testing.T) {
+	var redeemed atomic.Int32
+	var provider *httptest.Server
+	provider = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			WriteJsonResponse(w, http.StatusOK, map[string]string{
+				"issuer":                 provider.URL,
+				"authorization_endpoint": provider.URL + "/authorize",
+				"token_endpoint":         provider.URL + "/token",
+				"jwks_uri":               provider.URL + "/jwks",
+			})
+		case "/token":
+			redeemed.Add(1)
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer provider.Close()
+
+	ar := newUsersTestAPIRouter(t)
+	ar.oidc = auth.NewOIDC(config.OIDCConfig{
+		IssuerURL:         provider.URL,
+		ClientID:          "vps-monitor",
+		RedirectURL:       "https://monitor.example.com/api/v1/auth/oidc/callback",
+		PostLoginRedirect: "/",
+	})
+	h := ar.Routes()
+
+	rec := serveAs(t, h, "", http.MethodGet, "/api/v1/auth/oidc", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"enabled":true`) {
+		t.Fatalf("expected single sign-on to be offered, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serveAs(t, h, "", http.MethodGet, "/api/v1/auth/oidc/login", "")
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || err != nil {
+		t.Fatalf("expected a redirect to the provider, got %d: %s", rec.Code, rec.Body.String())
+	}
+	state := location.Query().Get("state")
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != state || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode || cookies[0].MaxAge <= 0 {
+		t.Fatalf("expected an HttpOnly, SameSite=Lax cookie holding the state, got %+v", cookies)
+	}
+
+	callback := "/api/v1/auth/oidc/callback?code=abc&state=" + state
+	for name, cookie := range map[string]*http.Cookie{
+		"no cookie":   nil,
+		"other login": {Name: oidcStateCookie, Value: "0123456789abcdef"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, callback, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized || redeemed.Load() != 0 {
+			t.Fatalf("%s: expected the callback to be refused before redeeming the code, got %d", name, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, callback, nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if redeemed.Load() != 1 {
+		t.Fatalf("expected the code to be redeemed by the browser that started the login, got %d", rec.Code)
+	}
+	if cleared := rec.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Fatalf("expected the callback to remove the state cookie, got %+v", cleared)
+	}
+}
@@ -4,17 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 	"github.com/hhftechnology/vps-monitor/internal/alerts"
 	"github.com/hhftechnology/vps-monitor/internal/api/middleware"
+	"github.com/hhftechnology/vps-monitor/internal/audit"
 	"github.com/hhftechnology/vps-monitor/internal/auth"
 	"github.com/hhftechnology/vps-monitor/internal/config"
 	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/push"
 	"github.com/hhftechnology/vps-monitor/internal/scanner"
 	"github.com/hhftechnology/vps-monitor/internal/services"
 	"github.com/hhftechnology/vps-monitor/internal/static"
@@ -39,6 +45,19 @@ type APIRouter struct {
 	scanHandlers  *ScanHandlers
 	botService    botRelayService
 	statsDB       *scanner.ScanDB
+	pushService   *push.Service
+	users         *auth.Users
+	tokens        *auth.Tokens
+	oidc          *auth.OIDC
+	sessions      *auth.Sessions
+	twoFactor     *auth.TwoFactor
+	audit         *audit.Log
+	loginGuard    *auth.LoginGuard
+
+	// Request rate limits; nil limits nothing
+	loginLimit *middleware.RateLimiter
+	execLimit  *middleware.RateLimiter
+	scanLimit  *middleware.RateLimiter
 }
 
 // RouterOptions contains optional dependencies for the router
@@ -48,6 +67,14 @@ type RouterOptions struct {
 	AutoScanner    *scanner.AutoScanner
 	BotService     botRelayService
 	ScanDB         *scanner.ScanDB
+	PushService    *push.Service
+	Users          *auth.Users
+	Tokens         *auth.Tokens
+	OIDC           *auth.OIDC
+	Sessions       *auth.Sessions
+	TwoFactor      *auth.TwoFactor
+	Audit          *audit.Log
+	LoginGuard     *auth.LoginGuard
 }
 
 func NewRouter(registry *services.Registry, manager *config.Manager, opts *RouterOptions) *chi.Mux {
@@ -57,9 +84,21 @@ func NewRouter(registry *services.Registry, manager *config.Manager, opts *Route
 		router:   chi.NewRouter(),
 		registry: registry,
 		manager:  manager,
+
+		loginLimit: middleware.NewRateLimiter(cfg.RateLimits.Login.Requests, cfg.RateLimits.Login.Per),
+		execLimit:  middleware.NewRateLimiter(cfg.RateLimits.Exec.Requests, cfg.RateLimits.Exec.Per),
+		scanLimit:  middleware.NewRateLimiter(cfg.RateLimits.Scans.Requests, cfg.RateLimits.Scans.Per),
 	}
 	if opts != nil {
 		r.botService = opts.BotService
+		r.pushService = opts.PushService
+		r.users = opts.Users
+		r.tokens = opts.Tokens
+		r.oidc = opts.OIDC
+		r.sessions = opts.Sessions
+		r.twoFactor = opts.TwoFactor
+		r.audit = opts.Audit
+		r.loginGuard = opts.LoginGuard
 		r.statsDB = opts.ScanDB
 		if r.statsDB == nil && opts.ScannerService != nil {
 			r.statsDB = opts.ScannerService.Store().DB()
@@ -79,21 +118,37 @@ func NewRouter(registry *services.Registry, manager *config.Manager, opts *Route
 	// Set up alert handlers
 	if opts != nil && opts.AlertMonitor != nil {
 		r.alertHandlers = NewAlertHandlers(opts.AlertMonitor, &models.AlertConfigResponse{
-			Enabled:         cfg.Alerts.Enabled,
-			CPUThreshold:    cfg.Alerts.CPUThreshold,
-			MemoryThreshold: cfg.Alerts.MemoryThreshold,
-			CheckInterval:   cfg.Alerts.CheckInterval.String(),
-			WebhookEnabled:  cfg.Alerts.WebhookURL != "",
-			AlertsFilter:    cfg.Alerts.AlertsFilter,
+			Enabled:              cfg.Alerts.Enabled,
+			CPUThreshold:         cfg.Alerts.CPUThreshold,
+			MemoryThreshold:      cfg.Alerts.MemoryThreshold,
+			CPUFor:               cfg.Alerts.CPUFor.String(),
+			MemoryFor:            cfg.Alerts.MemoryFor.String(),
+			CPUClearThreshold:    cfg.Alerts.CPUClearThreshold(),
+			MemoryClearThreshold: cfg.Alerts.MemoryClearThreshold(),
+			CheckInterval:        cfg.Alerts.CheckInterval.String(),
+			WebhookEnabled:       cfg.Alerts.WebhookURL != "",
+			AlertsFilter:         cfg.Alerts.AlertsFilter,
+			HistoryRetention:     cfg.Alerts.HistoryRetention.String(),
+			Cooldown:             cfg.Alerts.Cooldown.String(),
+			ThresholdOverrides:   thresholdOverridesResponse(cfg.Alerts.Overrides),
+			Host:                 hostAlertConfigResponse(cfg.Alerts.Host),
 		})
 	} else {
 		r.alertHandlers = NewAlertHandlers(nil, &models.AlertConfigResponse{
-			Enabled:         false,
-			CPUThreshold:    cfg.Alerts.CPUThreshold,
-			MemoryThreshold: cfg.Alerts.MemoryThreshold,
-			CheckInterval:   cfg.Alerts.CheckInterval.String(),
-			WebhookEnabled:  cfg.Alerts.WebhookURL != "",
-			AlertsFilter:    cfg.Alerts.AlertsFilter,
+			Enabled:              false,
+			CPUThreshold:         cfg.Alerts.CPUThreshold,
+			MemoryThreshold:      cfg.Alerts.MemoryThreshold,
+			CPUFor:               cfg.Alerts.CPUFor.String(),
+			MemoryFor:            cfg.Alerts.MemoryFor.String(),
+			CPUClearThreshold:    cfg.Alerts.CPUClearThreshold(),
+			MemoryClearThreshold: cfg.Alerts.MemoryClearThreshold(),
+			CheckInterval:        cfg.Alerts.CheckInterval.String(),
+			WebhookEnabled:       cfg.Alerts.WebhookURL != "",
+			AlertsFilter:         cfg.Alerts.AlertsFilter,
+			HistoryRetention:     cfg.Alerts.HistoryRetention.String(),
+			Cooldown:             cfg.Alerts.Cooldown.String(),
+			ThresholdOverrides:   thresholdOverridesResponse(cfg.Alerts.Overrides),
+			Host:                 hostAlertConfigResponse(cfg.Alerts.Host),
 		})
 	}
 
@@ -119,6 +174,7 @@ func WriteJsonResponse(w http.ResponseWriter, status int, data interface{}) {
 }
 
 func (ar *APIRouter) Routes() *chi.Mux {
+	ar.router.Use(middleware.TrustProxies(ar.registry.Config().TrustedProxies))
 	ar.router.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"*"},
 		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
@@ -131,7 +187,10 @@ func (ar *APIRouter) Routes() *chi.Mux {
 		r.Get("/system/stats", ar.GetSystemStats)
 
 		// Auth login - always registered, dynamic behavior
-		r.Post("/auth/login", ar.handleLogin)
+		r.With(middleware.RateLimit(ar.loginLimit)).Post("/auth/login", ar.handleLogin)
+		r.With(middleware.RateLimit(ar.loginLimit)).Post("/auth/login/2fa", ar.LoginTwoFactor)
+		r.With(middleware.RateLimit(ar.loginLimit)).Post("/auth/refresh", ar.RefreshSession)
+		ar.registerOIDCRoutes(r)
 
 		// Settings endpoints (protected by dynamic auth)
 		ar.registerSettingsRoutes(r)
@@ -141,7 +200,11 @@ func (ar *APIRouter) Routes() *chi.Mux {
 			protected.Use(auth.DynamicMiddleware(ar.registry.Auth))
 
 			protected.Get("/auth/me", ar.handleGetMe)
-			protected.Post("/devices/register", ar.RegisterDevice)
+			protected.Post("/auth/logout", ar.Logout)
+			ar.registerTokenRoutes(protected)
+			ar.registerTwoFactorRoutes(protected)
+			ar.registerAuditRoutes(protected)
+			ar.registerDeviceRoutes(protected)
 			ar.registerContainerRoutes(protected)
 			ar.registerImageRoutes(protected)
 			ar.registerNetworkRoutes(protected)
@@ -151,6 +214,9 @@ func (ar *APIRouter) Routes() *chi.Mux {
 		})
 	})
 
+	// Prometheus scrapes /metrics with METRICS_TOKEN or an API token
+	ar.router.With(ar.requireMetricsAuth).Get("/metrics", ar.GetMetrics)
+
 	// Serve embedded frontend static files
 	staticFS, err := static.GetFileSystem()
 	if err != nil {
@@ -167,6 +233,8 @@ func (ar *APIRouter) Routes() *chi.Mux {
 func (ar *APIRouter) registerContainerRoutes(r chi.Router) {
 	r.Get("/containers", ar.GetContainers)
 	r.Route("/containers/{id}", func(r chi.Router) {
+		r.Use(ar.requireContainerAccess)
+
 		// Read-only routes (always available)
 		r.Get("/", ar.GetContainer)
 		r.Get("/logs/parsed", ar.GetContainerLogsParsed)
@@ -177,6 +245,9 @@ func (ar *APIRouter) registerContainerRoutes(r chi.Router) {
 
 		// Mutating routes (blocked in read-only mode)
 		r.Group(func(mutating chi.Router) {
+			mutating.Use(middleware.Audit(ar.audit))
+			mutating.Use(auth.RequireRole(models.RoleOperator))
+			mutating.Use(auth.RequireScope(models.TokenScopeOperate))
 			mutating.Use(middleware.ReadOnly(func() bool {
 				return ar.registry.Config().ReadOnly
 			}))
@@ -185,7 +256,7 @@ func (ar *APIRouter) registerContainerRoutes(r chi.Router) {
 			mutating.Post("/restart", ar.RestartContainer)
 			mutating.Post("/remove", ar.RemoveContainer)
 			mutating.Put("/env", ar.UpdateEnvVariables)
-			mutating.Get("/exec", ar.HandleTerminal)
+			mutating.With(middleware.RateLimit(ar.execLimit)).Get("/exec", ar.HandleTerminal)
 		})
 	})
 }
@@ -197,6 +268,9 @@ func (ar *APIRouter) registerImageRoutes(r chi.Router) {
 
 		// Mutating routes (blocked in read-only mode)
 		r.Group(func(mutating chi.Router) {
+			mutating.Use(middleware.Audit(ar.audit))
+			mutating.Use(auth.RequireRole(models.RoleOperator))
+			mutating.Use(auth.RequireScope(models.TokenScopeOperate))
 			mutating.Use(middleware.ReadOnly(func() bool {
 				return ar.registry.Config().ReadOnly
 			}))
@@ -206,6 +280,9 @@ func (ar *APIRouter) registerImageRoutes(r chi.Router) {
 
 	// Image pull (mutating)
 	r.Group(func(mutating chi.Router) {
+		mutating.Use(middleware.Audit(ar.audit))
+		mutating.Use(auth.RequireRole(models.RoleOperator))
+		mutating.Use(auth.RequireScope(models.TokenScopeOperate))
 		mutating.Use(middleware.ReadOnly(func() bool {
 			return ar.registry.Config().ReadOnly
 		}))
@@ -221,8 +298,119 @@ func (ar *APIRouter) registerNetworkRoutes(r chi.Router) {
 func (ar *APIRouter) registerAlertRoutes(r chi.Router) {
 	r.Get("/alerts", ar.alertHandlers.GetAlerts)
 	r.Get("/alerts/config", ar.alertHandlers.GetAlertConfig)
-	r.Post("/alerts/{id}/acknowledge", ar.alertHandlers.AcknowledgeAlert)
-	r.Post("/alerts/acknowledge-all", ar.alertHandlers.AcknowledgeAllAlerts)
+	r.Get("/alerts/stream", ar.alertHandlers.StreamAlerts)
+	r.Get("/alerts/rules", ar.alertHandlers.ListAlertRules)
+	r.Get("/alerts/rules/{ruleID}", ar.alertHandlers.GetAlertRule)
+	r.Get("/alerts/silences", ar.alertHandlers.ListSilences)
+	r.Get("/alerts/maintenance", ar.alertHandlers.ListMaintenanceWindows)
+	r.Get("/hosts/status", ar.alertHandlers.GetHostStatus)
+
+	// Acknowledging and changing rules, silences and maintenance windows
+	// takes at least an operator
+	r.Group(func(operator chi.Router) {
+		operator.Use(middleware.Audit(ar.audit))
+		operator.Use(auth.RequireRole(models.RoleOperator))
+		operator.Use(auth.RequireScope(models.TokenScopeOperate))
+		operator.Post("/alerts/{id}/acknowledge", ar.alertHandlers.AcknowledgeAlert)
+		operator.Post("/alerts/acknowledge-all", ar.alertHandlers.AcknowledgeAllAlerts)
+
+		// Rules, silences and maintenance windows may apply to containers
+		// outside any grant, so users limited by grants cannot change them
+		operator.Group(func(unscoped chi.Router) {
+			unscoped.Use(requireUnscoped)
+			unscoped.Post("/alerts/rules", ar.alertHandlers.CreateAlertRule)
+			unscoped.Put("/alerts/rules/{ruleID}", ar.alertHandlers.UpdateAlertRule)
+			unscoped.Delete("/alerts/rules/{ruleID}", ar.alertHandlers.DeleteAlertRule)
+
+			unscoped.Post("/alerts/silences", ar.alertHandlers.CreateSilence)
+			unscoped.Post("/alerts/silences/{silenceID}/expire", ar.alertHandlers.ExpireSilence)
+
+			unscoped.Post("/alerts/maintenance", ar.alertHandlers.CreateMaintenanceWindow)
+			unscoped.Put("/alerts/maintenance/{windowID}", ar.alertHandlers.UpdateMaintenanceWindow)
+			unscoped.Delete("/alerts/maintenance/{windowID}", ar.alertHandlers.DeleteMaintenanceWindow)
+		})
+	})
+}
+
+func thresholdOverridesResponse(overrides []config.ThresholdOverride) []models.AlertThresholdOverride {
+	result := make([]models.AlertThresholdOverride, 0, len(overrides))
+	for _, o := range overrides {
+		result = append(result, models.AlertThresholdOverride{
+			Host:            o.Host,
+			Container:       o.ContainerPattern,
+			CPUThreshold:    o.CPUThreshold,
+			MemoryThreshold: o.MemoryThreshold,
+			Disabled:        o.Disabled,
+		})
+	}
+	return result
+}
+
+func hostAlertConfigResponse(host config.HostAlertConfig) models.HostAlertConfigResponse {
+	return models.HostAlertConfigResponse{
+		Enabled:         host.Enabled,
+		CPUThreshold:    host.CPUThreshold,
+		MemoryThreshold: host.MemoryThreshold,
+		DiskThreshold:   host.DiskThreshold,
+		InodeThreshold:  host.InodeThreshold,
+		LoadThreshold:   host.LoadThreshold,
+		For:             host.For.String(),
+		IgnoreMounts:    host.IgnoreMounts,
+	}
+}
+
+func (ar *APIRouter) registerDeviceRoutes(r chi.Router) {
+	r.Get("/devices", ar.ListDevices)
+	// Devices receive alerts as they fire, which takes the role of handling them
+	r.With(auth.RequireRole(models.RoleOperator), auth.RequireScope(models.TokenScopeOperate)).Post("/devices/register", ar.RegisterDevice)
+	r.Delete("/devices/{deviceID}", ar.UnregisterDevice)
+}
+
+func (ar *APIRouter) registerOIDCRoutes(r chi.Router) {
+	r.Get("/auth/oidc", ar.OIDCStatus)
+	if ar.oidc == nil {
+		return
+	}
+
+	r.Get("/auth/oidc/login", ar.OIDCLogin)
+	r.Get("/auth/oidc/callback", ar.OIDCCallback)
+}
+
+func (ar *APIRouter) registerTokenRoutes(r chi.Router) {
+	if ar.tokens == nil {
+		return
+	}
+
+	r.Get("/auth/tokens", ar.ListAPITokens)
+	r.With(middleware.Audit(ar.audit)).Post("/auth/tokens", ar.CreateAPIToken)
+	r.With(middleware.Audit(ar.audit)).Delete("/auth/tokens/{tokenID}", ar.RevokeAPIToken)
+}
+
+func (ar *APIRouter) registerTwoFactorRoutes(r chi.Router) {
+	if ar.twoFactor == nil {
+		return
+	}
+
+	r.Get("/auth/2fa", ar.GetTwoFactor)
+	r.Group(func(audited chi.Router) {
+		audited.Use(middleware.Audit(ar.audit))
+		audited.Post("/auth/2fa/enroll", ar.EnrollTwoFactor)
+		audited.Post("/auth/2fa/confirm", ar.ConfirmTwoFactor)
+		audited.Post("/auth/2fa/disable", ar.DisableTwoFactor)
+	})
+}
+
+func (ar *APIRouter) registerAuditRoutes(r chi.Router) {
+	if ar.audit == nil {
+		return
+	}
+
+	r.Group(func(admin chi.Router) {
+		admin.Use(auth.RequireRole(models.RoleAdmin))
+		admin.Use(auth.RequireScope(models.TokenScopeSettings))
+		admin.Get("/audit", ar.GetAuditEvents)
+		admin.Get("/audit/export", ar.ExportAuditEvents)
+	})
 }
 
 func (ar *APIRouter) registerBotRoutes(r chi.Router) {
@@ -230,7 +418,7 @@ func (ar *APIRouter) registerBotRoutes(r chi.Router) {
 		return
 	}
 
-	r.Post("/bot/relay/command", ar.RelayBotCommand)
+	r.With(middleware.Audit(ar.audit), auth.RequireRole(models.RoleOperator), auth.RequireScope(models.TokenScopeOperate)).Post("/bot/relay/command", ar.RelayBotCommand)
 }
 
 func (ar *APIRouter) registerScanRoutes(r chi.Router) {
@@ -260,6 +448,10 @@ func (ar *APIRouter) registerScanRoutes(r chi.Router) {
 
 	// Mutating routes (blocked in read-only mode)
 	r.Group(func(mutating chi.Router) {
+		mutating.Use(middleware.Audit(ar.audit))
+		mutating.Use(middleware.RateLimit(ar.scanLimit))
+		mutating.Use(auth.RequireRole(models.RoleOperator))
+		mutating.Use(auth.RequireScope(models.TokenScopeScan))
 		mutating.Use(middleware.ReadOnly(func() bool {
 			return ar.registry.Config().ReadOnly
 		}))
@@ -275,11 +467,15 @@ func (ar *APIRouter) registerScanRoutes(r chi.Router) {
 func (ar *APIRouter) registerSettingsRoutes(r chi.Router) {
 	r.Route("/settings", func(r chi.Router) {
 		r.Use(auth.DynamicMiddleware(ar.registry.Auth))
+		r.Use(middleware.Audit(ar.audit))
+		r.Use(auth.RequireRole(models.RoleAdmin))
+		r.Use(auth.RequireScope(models.TokenScopeSettings))
 
 		r.Get("/", ar.GetSettings)
 		r.Put("/read-only", ar.UpdateReadOnly)
 		r.Post("/test/docker-host", ar.TestDockerHost)
 		r.Post("/test/coolify-host", ar.TestCoolifyHost)
+		r.Get("/webhooks/deliveries", ar.GetWebhookDeliveries)
 		r.Group(func(mutating chi.Router) {
 			mutating.Use(middleware.ReadOnly(func() bool {
 				return ar.registry.Config().ReadOnly
@@ -290,7 +486,30 @@ func (ar *APIRouter) registerSettingsRoutes(r chi.Router) {
 			mutating.Put("/coolify-hosts", ar.UpdateCoolifyHosts)
 			mutating.Put("/auth", ar.UpdateAuth)
 			mutating.Put("/bot", ar.UpdateBot)
+			mutating.Put("/notifications", ar.UpdateNotifications)
+			mutating.Post("/test/notification", ar.TestNotification)
+			mutating.Post("/test/webhook", ar.TestWebhook)
 		})
+		if ar.users != nil {
+			r.Get("/users", ar.ListUsers)
+			r.Group(func(mutating chi.Router) {
+				mutating.Use(middleware.ReadOnly(func() bool {
+					return ar.registry.Config().ReadOnly
+				}))
+				mutating.Post("/users", ar.CreateUser)
+				mutating.Put("/users/{username}", ar.UpdateUser)
+				mutating.Delete("/users/{username}", ar.DeleteUser)
+			})
+			if ar.twoFactor != nil {
+				// Like revoking a session, this stays possible in read-only mode
+				r.Delete("/users/{username}/2fa", ar.ResetUserTwoFactor)
+			}
+		}
+		if ar.sessions != nil {
+			r.Get("/sessions", ar.ListSessions)
+			// Like logging out, revoking a session stays possible in read-only mode
+			r.Delete("/sessions/{sessionID}", ar.RevokeSession)
+		}
 		if ar.scanHandlers != nil {
 			r.Get("/scan", ar.scanHandlers.GetScannerConfig)
 			r.Group(func(mutating chi.Router) {
@@ -326,26 +545,68 @@ func (ar *APIRouter) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := svc.ValidateCredentials(loginReq.Username, loginReq.Password); err != nil {
+	ip := middleware.ClientIP(r)
+	if ar.lockedOut(w, ip, loginReq.Username) {
+		return
+	}
+
+	user, err := svc.Authenticate(loginReq.Username, loginReq.Password)
+	if err != nil {
+		if ar.loginGuard != nil {
+			ar.loginGuard.Fail(ip, loginReq.Username)
+		}
 		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
 
-	token, err := svc.GenerateToken(loginReq.Username)
+	challenge, err := svc.StartTwoFactor(user)
+	if err != nil {
+		log.Printf("Failed to start two-factor login: %v", err)
+		http.Error(w, "Failed to start two-factor login", http.StatusInternalServerError)
+		return
+	}
+	if challenge != "" {
+		// The token is issued by /auth/login/2fa once the code checks out
+		WriteJsonResponse(w, http.StatusOK, map[string]any{
+			"two_factor_required": true,
+			"challenge":           challenge,
+		})
+		return
+	}
+	if ar.loginGuard != nil {
+		ar.loginGuard.Succeed(user.Username)
+	}
+
+	tokens, err := svc.StartSession(user, r.UserAgent(), ip)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
 	WriteJsonResponse(w, http.StatusOK, map[string]any{
-		"token": token,
-		"user": map[string]string{
-			"username": loginReq.Username,
-			"role":     "admin",
-		},
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
 	})
 }
 
+// lockedOut answers 429 Too Many Requests when logins from ip, or for
+// username, are locked out after too many failures
+func (ar *APIRouter) lockedOut(w http.ResponseWriter, ip, username string) bool {
+	if ar.loginGuard == nil {
+		return false
+	}
+	wait := ar.loginGuard.Check(ip, username)
+	if wait <= 0 {
+		return false
+	}
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, fmt.Sprintf("Too many failed logins, try again in %s", time.Duration(seconds)*time.Second), http.StatusTooManyRequests)
+	return true
+}
+
 // handleGetMe returns the current authenticated user's information.
 func (ar *APIRouter) handleGetMe(w http.ResponseWriter, r *http.Request) {
 	userValue := r.Context().Value(auth.UserContextKey)
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"time"
 
@@ -23,6 +24,7 @@ type ScanHandlers struct {
 	manager          *config.Manager
 	autoScanner      *scanner.AutoScanner
 	resolveImageIDFn func(host, imageRef string) string
+	listContainersFn func(ctx context.Context) ([]models.ContainerInfo, error)
 }
 
 // NewScanHandlers creates new scan handlers
@@ -60,6 +62,15 @@ func (h *ScanHandlers) StartScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+	if !canAccessImage(images, req.Host, req.ImageRef) {
+		http.Error(w, "image not found", http.StatusNotFound)
+		return
+	}
+
 	// Rescan gating: check if image has changed since last scan
 	cfg := h.scanner.Config()
 	if !cfg.ForceRescan {
@@ -114,6 +125,17 @@ func (h *ScanHandlers) StartBulkScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A bulk scan covers every image on the hosts, so it is not available
+	// to users limited by grants
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+	if images != nil {
+		http.Error(w, "bulk scans are not available to users limited by grants", http.StatusForbidden)
+		return
+	}
+
 	bulkJob, err := h.scanner.StartBulkScan(req.Scanner, req.Hosts)
 	if err != nil {
 		log.Printf("Failed to start bulk scan: %v", err)
@@ -128,8 +150,19 @@ func (h *ScanHandlers) StartBulkScan(w http.ResponseWriter, r *http.Request) {
 
 // GetScanJobs handles GET /api/v1/scan/jobs
 func (h *ScanHandlers) GetScanJobs(w http.ResponseWriter, r *http.Request) {
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+
 	jobs := h.scanner.GetJobs()
 	bulkJobs := h.scanner.GetBulkJobs()
+	if images != nil {
+		jobs = slices.DeleteFunc(jobs, func(job *models.ScanJob) bool {
+			return !canAccessImage(images, job.Host, job.ImageRef)
+		})
+		bulkJobs = []*models.BulkScanJob{}
+	}
 
 	WriteJsonResponse(w, http.StatusOK, map[string]any{
 		"jobs":     jobs,
@@ -145,9 +178,14 @@ func (h *ScanHandlers) GetScanJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+
 	// Check if it's a regular job or bulk job
 	job := h.scanner.GetJob(id)
-	if job != nil {
+	if job != nil && canAccessImage(images, job.Host, job.ImageRef) {
 		WriteJsonResponse(w, http.StatusOK, map[string]any{
 			"job": job,
 		})
@@ -155,7 +193,7 @@ func (h *ScanHandlers) GetScanJob(w http.ResponseWriter, r *http.Request) {
 	}
 
 	bulkJob := h.scanner.GetBulkJob(id)
-	if bulkJob != nil {
+	if bulkJob != nil && images == nil {
 		WriteJsonResponse(w, http.StatusOK, map[string]any{
 			"bulkJob": bulkJob,
 		})
@@ -173,6 +211,18 @@ func (h *ScanHandlers) CancelScanJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+	if images != nil {
+		job := h.scanner.GetJob(id)
+		if job == nil || !canAccessImage(images, job.Host, job.ImageRef) {
+			http.Error(w, "job not found or already completed", http.StatusNotFound)
+			return
+		}
+	}
+
 	if h.scanner.CancelJob(id) {
 		WriteJsonResponse(w, http.StatusOK, map[string]any{
 			"message": "Job cancelled",
@@ -195,6 +245,15 @@ func (h *ScanHandlers) GetScanResults(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+	if !canAccessImage(images, host, imageRef) {
+		http.Error(w, "image not found", http.StatusNotFound)
+		return
+	}
+
 	results := h.scanner.Store().GetResults(host, imageRef)
 	WriteJsonResponse(w, http.StatusOK, map[string]any{
 		"results": results,
@@ -214,6 +273,15 @@ func (h *ScanHandlers) GetLatestScanResult(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+	if !canAccessImage(images, host, imageRef) {
+		http.Error(w, "no scan results found", http.StatusNotFound)
+		return
+	}
+
 	result := h.scanner.Store().GetLatest(host, imageRef)
 	if result == nil {
 		http.Error(w, "no scan results found", http.StatusNotFound)
@@ -247,6 +315,15 @@ func (h *ScanHandlers) StartSBOMGeneration(w http.ResponseWriter, r *http.Reques
 		req.Format = models.SBOMFormatSPDX
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+	if !canAccessImage(images, req.Host, req.ImageRef) {
+		http.Error(w, "image not found", http.StatusNotFound)
+		return
+	}
+
 	cfg := h.scanner.Config()
 	if !cfg.ForceRescan && !req.Force {
 		db := h.scanner.Store().DB()
@@ -291,8 +368,13 @@ func (h *ScanHandlers) GetSBOMJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+
 	job := h.scanner.GetSBOMJob(id)
-	if job == nil {
+	if job == nil || !canAccessImage(images, job.Host, job.ImageRef) {
 		http.Error(w, "SBOM job not found", http.StatusNotFound)
 		return
 	}
@@ -469,6 +551,12 @@ func (h *ScanHandlers) GetScanHistory(w http.ResponseWriter, r *http.Request) {
 		params.EndDate = val
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+	params.Images = images
+
 	db := h.scanner.Store().DB()
 	page, err := db.QueryHistory(params)
 	if err != nil {
@@ -488,6 +576,11 @@ func (h *ScanHandlers) GetScanHistoryDetail(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+
 	db := h.scanner.Store().DB()
 	result, err := db.GetResultByID(id)
 	if err != nil {
@@ -495,7 +588,7 @@ func (h *ScanHandlers) GetScanHistoryDetail(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "failed to get scan result", http.StatusInternalServerError)
 		return
 	}
-	if result == nil {
+	if result == nil || !canAccessImage(images, result.Host, result.ImageRef) {
 		http.Error(w, "scan result not found", http.StatusNotFound)
 		return
 	}
@@ -507,6 +600,11 @@ func (h *ScanHandlers) GetScanHistoryDetail(w http.ResponseWriter, r *http.Reque
 
 // GetScannedImages handles GET /api/v1/scan/history/images
 func (h *ScanHandlers) GetScannedImages(w http.ResponseWriter, r *http.Request) {
+	accessible, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+
 	db := h.scanner.Store().DB()
 	images, err := db.ListScannedImages()
 	if err != nil {
@@ -514,6 +612,9 @@ func (h *ScanHandlers) GetScannedImages(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "failed to list scanned images", http.StatusInternalServerError)
 		return
 	}
+	images = slices.DeleteFunc(images, func(img scanner.ScannedImage) bool {
+		return !canAccessImage(accessible, img.Host, img.ImageRef)
+	})
 
 	WriteJsonResponse(w, http.StatusOK, map[string]any{
 		"images": images,
@@ -541,13 +642,18 @@ func (h *ScanHandlers) ExportScanHistory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+
 	result, err := h.scanner.Store().DB().GetResultByID(id)
 	if err != nil {
 		log.Printf("Failed to map export for %s: %v", id, err)
 		http.Error(w, "failed to get scan result", http.StatusInternalServerError)
 		return
 	}
-	if result == nil {
+	if result == nil || !canAccessImage(images, result.Host, result.ImageRef) {
 		http.Error(w, "scan result not found", http.StatusNotFound)
 		return
 	}
@@ -588,6 +694,23 @@ func (h *ScanHandlers) DeleteScanHistory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+	if images != nil {
+		result, err := h.scanner.Store().DB().GetResultByID(id)
+		if err != nil {
+			log.Printf("Failed to load scan result for deletion: %v", err)
+			http.Error(w, "failed to delete scan result", http.StatusInternalServerError)
+			return
+		}
+		if result == nil || !canAccessImage(images, result.Host, result.ImageRef) {
+			http.Error(w, "scan result not found", http.StatusNotFound)
+			return
+		}
+	}
+
 	err := h.scanner.Store().DB().DeleteScanResult(id)
 	if err != nil {
 		http.Error(w, "failed to delete scan result", http.StatusInternalServerError)
@@ -641,6 +764,12 @@ func (h *ScanHandlers) GetSBOMHistory(w http.ResponseWriter, r *http.Request) {
 		params.EndDate = val
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+	params.Images = images
+
 	page, err := h.scanner.Store().DB().QuerySBOMHistory(params)
 	if err != nil {
 		log.Printf("Failed to query SBOM history: %v", err)
@@ -659,13 +788,18 @@ func (h *ScanHandlers) GetSBOMHistoryDetail(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+
 	result, err := h.scanner.Store().DB().GetSBOMResultByID(id)
 	if err != nil {
 		log.Printf("Failed to get SBOM result: %v", err)
 		http.Error(w, "failed to get SBOM result", http.StatusInternalServerError)
 		return
 	}
-	if result == nil {
+	if result == nil || !canAccessImage(images, result.Host, result.ImageRef) {
 		http.Error(w, "sbom result not found", http.StatusNotFound)
 		return
 	}
@@ -677,12 +811,20 @@ func (h *ScanHandlers) GetSBOMHistoryDetail(w http.ResponseWriter, r *http.Reque
 
 // GetSBOMedImages handles GET /api/v1/scan/sbom/history/images.
 func (h *ScanHandlers) GetSBOMedImages(w http.ResponseWriter, r *http.Request) {
+	accessible, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+
 	images, err := h.scanner.Store().DB().ListSBOMedImages()
 	if err != nil {
 		log.Printf("Failed to list SBOMed images: %v", err)
 		http.Error(w, "failed to list SBOMed images", http.StatusInternalServerError)
 		return
 	}
+	images = slices.DeleteFunc(images, func(img scanner.SBOMedImage) bool {
+		return !canAccessImage(accessible, img.Host, img.ImageRef)
+	})
 
 	WriteJsonResponse(w, http.StatusOK, map[string]any{
 		"images": images,
@@ -697,13 +839,18 @@ func (h *ScanHandlers) DownloadSBOMHistory(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+
 	result, err := h.scanner.Store().DB().GetSBOMResultByID(id)
 	if err != nil {
 		log.Printf("Failed to get SBOM result for download: %v", err)
 		http.Error(w, "failed to get SBOM result", http.StatusInternalServerError)
 		return
 	}
-	if result == nil {
+	if result == nil || !canAccessImage(images, result.Host, result.ImageRef) {
 		http.Error(w, "sbom result not found", http.StatusNotFound)
 		return
 	}
@@ -725,13 +872,18 @@ func (h *ScanHandlers) DeleteSBOMHistory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	images, ok := h.accessibleImages(w, r)
+	if !ok {
+		return
+	}
+
 	result, err := h.scanner.Store().DB().GetSBOMResultByID(id)
 	if err != nil {
 		log.Printf("Failed to load SBOM result for deletion: %v", err)
 		http.Error(w, "failed to delete SBOM result", http.StatusInternalServerError)
 		return
 	}
-	if result == nil {
+	if result == nil || !canAccessImage(images, result.Host, result.ImageRef) {
 		http.Error(w, "sbom result not found", http.StatusNotFound)
 		return
 	}
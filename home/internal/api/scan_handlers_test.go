@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
 	"github.com/hhftechnology/vps-monitor/internal/config"
 	"github.com/hhftechnology/vps-monitor/internal/models"
 	"github.com/hhftechnology/vps-monitor/internal/scanner"
@@ -259,6 +260,61 @@ func TestGetScanResultsReturnsStoredResults(t *testing.T) {
 	}
 }
 
+func TestScanEndpointsRespectGrants(t *testing.T) {
+	svc := newTestScannerService(t)
+	for _, result := range []models.ScanResult{
+		{ID: "shop", ImageRef: "shop:1", Host: "prod", CompletedAt: 2},
+		{ID: "blog", ImageRef: "blog:1", Host: "prod", CompletedAt: 1},
+	} {
+		if err := svc.Store().Add(result); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	h := &ScanHandlers{
+		scanner: svc,
+		listContainersFn: func(context.Context) ([]models.ContainerInfo, error) {
+			return []models.ContainerInfo{
+				{ID: "1", Names: []string{"/shop"}, Image: "shop:1", Host: "prod"},
+				{ID: "2", Names: []string{"/blog"}, Image: "blog:1", Host: "prod"},
+			}, nil
+		},
+	}
+	asShopTeam := func(req *http.Request) *http.Request {
+		user := models.User{Username: "shop", Role: models.RoleOperator, Grants: []models.PermissionGrant{{Container: "shop*"}}}
+		return req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetScanHistory(rec, asShopTeam(httptest.NewRequest(http.MethodGet, "/api/v1/scan/history", nil)))
+	var page scanner.HistoryPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if page.Total != 1 || page.Results[0].ID != "shop" {
+		t.Fatalf("expected only the shop scan, got %+v", page)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetScanHistoryDetail(rec, chiContext(asShopTeam(httptest.NewRequest(http.MethodGet, "/api/v1/scan/history/blog", nil)), map[string]string{"id": "blog"}))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected %d for another team's scan, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.StartBulkScan(rec, asShopTeam(httptest.NewRequest(http.MethodPost, "/api/v1/scan/bulk", bytes.NewBufferString(`{}`))))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected %d for a bulk scan, got %d", http.StatusForbidden, rec.Code)
+	}
+
+	// Without grants everything stays visible
+	rec = httptest.NewRecorder()
+	h.GetScanHistory(rec, httptest.NewRequest(http.MethodGet, "/api/v1/scan/history", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || page.Total != 2 {
+		t.Fatalf("expected both scans, got %+v, %v", page, err)
+	}
+}
+
 // ─── GetLatestScanResult ──────────────────────────────────────────────────────
 
 func TestGetLatestScanResultRequiresHostParam(t *testing.T) {
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/go-chi/chi/v5"
+	"github.com/hhftechnology/vps-monitor/internal/alerts"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/scanner"
+)
+
+// requireContainerAccess answers 404 for containers outside the grants of
+// a scoped user, before the handler touches the container
+func (ar *APIRouter) requireContainerAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.UserFromContext(r.Context())
+		host := r.URL.Query().Get("host")
+		if !ok || !auth.Scoped(user) || host == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !auth.CanAccessHost(user, host) {
+			http.Error(w, "container not found", http.StatusNotFound)
+			return
+		}
+
+		dockerClient, releaseDocker := ar.registry.AcquireDocker()
+		if dockerClient == nil {
+			releaseDocker()
+			http.Error(w, "docker client unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		inspect, err := dockerClient.GetContainer(r.Context(), host, chi.URLParam(r, "id"))
+		releaseDocker()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if !auth.CanAccessContainer(user, containerInfoFromInspect(host, inspect)) {
+			http.Error(w, "container not found", http.StatusNotFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// containerInfoFromInspect keeps the fields grants are matched against
+func containerInfoFromInspect(host string, inspect container.InspectResponse) models.ContainerInfo {
+	ctr := models.ContainerInfo{Host: host, ImageID: inspect.Image}
+	if inspect.ContainerJSONBase != nil {
+		ctr.ID = inspect.ID
+		ctr.Names = []string{inspect.Name}
+	}
+	if inspect.Config != nil {
+		ctr.Image = inspect.Config.Image
+		ctr.Labels = inspect.Config.Labels
+	}
+	return ctr
+}
+
+// accessibleImages returns the images of the containers the request's user
+// may access, or nil when the user is not limited by grants. On failure it
+// writes the error response and returns false.
+func (h *ScanHandlers) accessibleImages(w http.ResponseWriter, r *http.Request) ([]scanner.HostImage, bool) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok || !auth.Scoped(user) {
+		return nil, true
+	}
+
+	containers, err := h.listContainers(r.Context())
+	if err != nil {
+		log.Printf("Failed to list containers for scan permissions: %v", err)
+		http.Error(w, "failed to check permissions", http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	images := make([]scanner.HostImage, 0)
+	for _, ctr := range containers {
+		if !auth.CanAccessContainer(user, ctr) {
+			continue
+		}
+		for _, ref := range []string{ctr.Image, ctr.ImageID} {
+			image := scanner.HostImage{Host: ctr.Host, ImageRef: ref}
+			if ref != "" && !slices.Contains(images, image) {
+				images = append(images, image)
+			}
+		}
+	}
+	return images, true
+}
+
+func (h *ScanHandlers) listContainers(ctx context.Context) ([]models.ContainerInfo, error) {
+	if h.listContainersFn != nil {
+		return h.listContainersFn(ctx)
+	}
+
+	dockerClient, release := h.scanner.Registry().AcquireDocker()
+	defer release()
+	if dockerClient == nil {
+		return nil, fmt.Errorf("docker client unavailable")
+	}
+	// Hosts that fail to answer contribute no containers
+	byHost, _, err := dockerClient.ListContainersAllHosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var containers []models.ContainerInfo
+	for _, hostContainers := range byHost {
+		containers = append(containers, hostContainers...)
+	}
+	return containers, nil
+}
+
+// canAccessImage reports whether images, as returned by accessibleImages,
+// include imageRef on host
+func canAccessImage(images []scanner.HostImage, host, imageRef string) bool {
+	return images == nil || slices.Contains(images, scanner.HostImage{Host: host, ImageRef: imageRef})
+}
+
+// alertScope returns the request's user when grants limit the alerts and
+// hosts it may see
+func alertScope(r *http.Request) (models.User, bool) {
+	user, ok := auth.UserFromContext(r.Context())
+	return user, ok && auth.Scoped(user)
+}
+
+// canSeeAlert reports whether user may see alert, matching container alerts
+// against the labels the monitor last saw on the container
+func (h *AlertHandlers) canSeeAlert(user models.User, alert models.Alert) bool {
+	return auth.CanAccessAlert(user, alert, h.monitor.ContainerLabels(alert.Host, alert.ContainerID))
+}
+
+// canSeeAlertRule reports whether user may see rule. Grants cannot match
+// images, so rules limited to an image stay hidden.
+func canSeeAlertRule(user models.User, rule models.AlertRule) bool {
+	return rule.Scope.Image == "" && auth.CanAccessMatcher(user, rule.Scope.Host, rule.Scope.Container, rule.Scope.Label)
+}
+
+// canSeeSilenceMatcher reports whether user may see a silence or
+// maintenance window selecting the alerts matcher does
+func canSeeSilenceMatcher(user models.User, matcher models.SilenceMatcher) bool {
+	return auth.CanAccessMatcher(user, matcher.Host, matcher.Container, matcher.Label)
+}
+
+// requireUnscoped refuses users limited by grants, for changes that reach
+// beyond the containers they are granted
+func requireUnscoped(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, scoped := alertScope(r); scoped {
+			http.Error(w, "not available to users limited by grants", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// countVisibleUnacknowledged counts the unacknowledged alerts visible accepts
+func countVisibleUnacknowledged(history *alerts.AlertHistory, visible func(models.Alert) bool) (int, error) {
+	unacknowledged := false
+	page, err := history.QueryVisible(models.AlertQuery{Acknowledged: &unacknowledged, PageSize: 1}, visible)
+	if err != nil {
+		return 0, err
+	}
+	return page.Total, nil
+}
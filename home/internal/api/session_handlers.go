@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+)
+
+// RefreshSession swaps a refresh token for a new access token and refresh
+// token
+func (ar *APIRouter) RefreshSession(w http.ResponseWriter, r *http.Request) {
+	svc := ar.registry.Auth()
+	if svc == nil || svc.IsDisabled() {
+		http.Error(w, "Authentication is not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "refresh_token is required", http.StatusBadRequest)
+		return
+	}
+
+	tokens, user, err := svc.RefreshSession(req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrTokenExpired):
+			http.Error(w, "Session has expired", http.StatusUnauthorized)
+		case errors.Is(err, auth.ErrInvalidToken):
+			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		default:
+			log.Printf("Failed to refresh session: %v", err)
+			http.Error(w, "failed to refresh session", http.StatusInternalServerError)
+		}
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
+	})
+}
+
+// Logout ends the session of the request's access token
+func (ar *APIRouter) Logout(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication is not enabled", http.StatusNotFound)
+		return
+	}
+	if user.SessionID == "" {
+		http.Error(w, "this token has no session to log out of", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := ar.registry.Auth().EndSession(user.SessionID); err != nil {
+		log.Printf("Failed to end session: %v", err)
+		http.Error(w, "failed to log out", http.StatusInternalServerError)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "Logged out"})
+}
+
+// ListSessions returns the active login sessions of all users
+func (ar *APIRouter) ListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := ar.sessions.List()
+	if err != nil {
+		log.Printf("Failed to list sessions: %v", err)
+		http.Error(w, "failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"sessions": sessions})
+}
+
+// RevokeSession ends a login session; its access tokens stop working at once
+func (ar *APIRouter) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ok, err := ar.sessions.Revoke(chi.URLParam(r, "sessionID"))
+	if err != nil {
+		log.Printf("Failed to revoke session: %v", err)
+		http.Error(w, "failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "Session revoked"})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestSessionRefreshLogoutAndRevocation(t *testing.T) {
+	h := newUsersTestRouter(t)
+
+	rec := serveAs(t, h, "", http.MethodPost, "/api/v1/auth/login", `{"username":"admin","password":"secret"}`)
+	var session struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil || session.RefreshToken == "" || session.ExpiresIn != 900 {
+		t.Fatalf("unexpected login response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serveAs(t, h, "", http.MethodPost, "/api/v1/auth/refresh", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	var refreshed struct {
+		Token        string      `json:"token"`
+		RefreshToken string      `json:"refresh_token"`
+		User         models.User `json:"user"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &refreshed); err != nil || rec.Code != http.StatusOK || refreshed.User.Role != models.RoleAdmin {
+		t.Fatalf("unexpected refresh response %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, "", http.MethodPost, "/api/v1/auth/refresh", `{"refresh_token":"`+session.RefreshToken+`"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a used refresh token to be rejected, got %d", rec.Code)
+	}
+
+	// An admin sees and revokes other users' sessions
+	if rec := serveAs(t, h, refreshed.Token, http.MethodPost, "/api/v1/settings/users", `{"username":"vic","password":"viewer-pass","role":"viewer"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	viewerToken, viewer := login(t, h, "vic", "viewer-pass")
+	if viewer.Role != models.RoleViewer {
+		t.Fatalf("unexpected user %+v", viewer)
+	}
+	rec = serveAs(t, h, refreshed.Token, http.MethodGet, "/api/v1/settings/sessions", "")
+	var list struct {
+		Sessions []models.Session `json:"sessions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Sessions) != 2 {
+		t.Fatalf("unexpected sessions %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, viewerToken, http.MethodGet, "/api/v1/settings/sessions", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected sessions to be admin-only, got %d", rec.Code)
+	}
+	for _, s := range list.Sessions {
+		if s.Username != "vic" {
+			continue
+		}
+		if rec := serveAs(t, h, refreshed.Token, http.MethodDelete, "/api/v1/settings/sessions/"+s.ID, ""); rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+	if rec := serveAs(t, h, viewerToken, http.MethodGet, "/api/v1/auth/me", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a revoked session's token to be rejected, got %d", rec.Code)
+	}
+
+	if rec := serveAs(t, h, refreshed.Token, http.MethodPost, "/api/v1/auth/logout", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, refreshed.Token, http.MethodGet, "/api/v1/auth/me", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a logged out token to be rejected, got %d", rec.Code)
+	}
+	if rec := serveAs(t, h, "", http.MethodPost, "/api/v1/auth/refresh", `{"refresh_token":"`+refreshed.RefreshToken+`"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a logged out session not to refresh, got %d", rec.Code)
+	}
+}
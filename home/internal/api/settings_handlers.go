@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/hhftechnology/vps-monitor/internal/config"
 	"github.com/hhftechnology/vps-monitor/internal/coolify"
 	"github.com/hhftechnology/vps-monitor/internal/docker"
+	"github.com/hhftechnology/vps-monitor/internal/notify"
 )
 
 const secretMask = "••••••••"
@@ -117,6 +119,12 @@ func (ar *APIRouter) GetSettings(w http.ResponseWriter, r *http.Request) {
 		},
 		"auth": authResp,
 		"bot":  botResp,
+		"notifications": map[string]any{
+			"source":      sources.Notifications,
+			"channels":    maskNotificationChannels(cfg.Notifications.Channels),
+			"routes":      cfg.Notifications.Routes,
+			"escalations": cfg.Notifications.Escalations,
+		},
 	})
 }
 
@@ -467,6 +475,241 @@ func (ar *APIRouter) TestDiscordBot(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// UpdateNotifications handles PUT /api/v1/settings/notifications.
+func (ar *APIRouter) UpdateNotifications(w http.ResponseWriter, r *http.Request) {
+	var req config.NotificationsConfig
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	channels := make([]config.NotificationChannel, 0, len(req.Channels))
+	for _, ch := range req.Channels {
+		ch, err := ar.restoreChannelSecrets(ch)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateChannelTemplate(ch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		channels = append(channels, ch)
+	}
+	req.Channels = channels
+
+	if err := ar.manager.UpdateNotifications(&req); err != nil {
+		http.Error(w, err.Error(), settingsErrorStatus(err))
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "Notification settings updated"})
+}
+
+// TestNotification handles POST /api/v1/settings/test/notification.
+func (ar *APIRouter) TestNotification(w http.ResponseWriter, r *http.Request) {
+	var ch config.NotificationChannel
+	if err := json.NewDecoder(r.Body).Decode(&ch); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ch, err := ar.restoreChannelSecrets(ch)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ch.Name == "" {
+		ch.Name = "test"
+	}
+	if err := (config.NotificationsConfig{Channels: []config.NotificationChannel{ch}}).Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateChannelTemplate(ch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	if err := notify.NewDispatcher(ar.registry.Config()).Test(ctx, ch); err != nil {
+		WriteJsonResponse(w, http.StatusOK, map[string]any{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"success": true,
+		"message": "Test notification sent",
+	})
+}
+
+// restoreChannelSecrets replaces masked secrets with the values stored for
+// the channel of the same name.
+func (ar *APIRouter) restoreChannelSecrets(ch config.NotificationChannel) (config.NotificationChannel, error) {
+	var stored *config.NotificationChannel
+	if fc := ar.manager.FileConfigSnapshot(); fc.Notifications != nil {
+		for i := range fc.Notifications.Channels {
+			if fc.Notifications.Channels[i].Name == ch.Name {
+				stored = &fc.Notifications.Channels[i]
+				break
+			}
+		}
+	}
+
+	if ch.Token == secretMask {
+		if stored == nil || stored.Token == "" {
+			return ch, fmt.Errorf("no stored token for channel %q; provide the actual token", ch.Name)
+		}
+		ch.Token = stored.Token
+	}
+	if ch.Secret == secretMask {
+		if stored == nil || stored.Secret == "" {
+			return ch, fmt.Errorf("no stored secret for channel %q; provide the actual secret", ch.Name)
+		}
+		ch.Secret = stored.Secret
+	}
+	if ch.SMTP != nil && ch.SMTP.Password == secretMask {
+		if stored == nil || stored.SMTP == nil || stored.SMTP.Password == "" {
+			return ch, fmt.Errorf("no stored smtp password for channel %q; provide the actual password", ch.Name)
+		}
+		smtpCfg := *ch.SMTP
+		smtpCfg.Password = stored.SMTP.Password
+		ch.SMTP = &smtpCfg
+	}
+	if len(ch.Headers) > 0 {
+		headers := make(map[string]string, len(ch.Headers))
+		for name, value := range ch.Headers {
+			if value == secretMask {
+				storedValue, ok := "", false
+				if stored != nil {
+					storedValue, ok = stored.Headers[name]
+				}
+				if !ok {
+					return ch, fmt.Errorf("no stored value for header %q of channel %q; provide the actual value", name, ch.Name)
+				}
+				value = storedValue
+			}
+			headers[name] = value
+		}
+		ch.Headers = headers
+	}
+	return ch, nil
+}
+
+// validateChannelTemplate checks that a webhook body template parses
+func validateChannelTemplate(ch config.NotificationChannel) error {
+	if ch.Template == "" {
+		return nil
+	}
+	if _, err := notify.ParseWebhookTemplate(ch.Template); err != nil {
+		return fmt.Errorf("channel %q: invalid template: %v", ch.Name, err)
+	}
+	return nil
+}
+
+// maskNotificationChannels hides tokens, secrets, SMTP passwords and custom
+// header values, which commonly carry credentials.
+func maskNotificationChannels(channels []config.NotificationChannel) []config.NotificationChannel {
+	masked := make([]config.NotificationChannel, 0, len(channels))
+	for _, ch := range channels {
+		if ch.Token != "" {
+			ch.Token = secretMask
+		}
+		if ch.Secret != "" {
+			ch.Secret = secretMask
+		}
+		if ch.SMTP != nil {
+			smtpCfg := *ch.SMTP
+			if smtpCfg.Password != "" {
+				smtpCfg.Password = secretMask
+			}
+			ch.SMTP = &smtpCfg
+		}
+		if len(ch.Headers) > 0 {
+			headers := make(map[string]string, len(ch.Headers))
+			for name := range ch.Headers {
+				headers[name] = secretMask
+			}
+			ch.Headers = headers
+		}
+		masked = append(masked, ch)
+	}
+	return masked
+}
+
+// TestWebhook handles POST /api/v1/settings/test/webhook.
+func (ar *APIRouter) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	var ch config.NotificationChannel
+	if err := json.NewDecoder(r.Body).Decode(&ch); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	ch.Type = config.ChannelWebhook
+
+	ch, err := ar.restoreChannelSecrets(ch)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ch.Name == "" {
+		ch.Name = "test"
+	}
+	if err := (config.NotificationsConfig{Channels: []config.NotificationChannel{ch}}).Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateChannelTemplate(ch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	result, err := notify.NewDispatcher(ar.registry.Config()).TestWebhook(ctx, ch)
+	response := map[string]any{
+		"success":    err == nil,
+		"message":    "Test webhook delivered",
+		"statusCode": result.StatusCode,
+		"durationMs": result.Duration.Milliseconds(),
+	}
+	if err != nil {
+		response["message"] = err.Error()
+	}
+	WriteJsonResponse(w, http.StatusOK, response)
+}
+
+// GetWebhookDeliveries handles GET /api/v1/settings/webhooks/deliveries.
+func (ar *APIRouter) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if ar.statsDB == nil {
+		http.Error(w, "webhook delivery log not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries, err := ar.statsDB.ListWebhookDeliveries(r.URL.Query().Get("channel"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"deliveries": deliveries})
+}
+
 // TestDockerHost handles POST /api/v1/settings/test/docker-host.
 func (ar *APIRouter) TestDockerHost(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -607,6 +850,9 @@ func settingsErrorStatus(err error) int {
 	if errors.Is(err, config.ErrEnvironmentConfigured) {
 		return http.StatusConflict
 	}
+	if errors.Is(err, config.ErrInvalidNotifications) {
+		return http.StatusBadRequest
+	}
 	return http.StatusInternalServerError
 }
 
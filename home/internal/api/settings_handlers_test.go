@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"github.com/hhftechnology/vps-monitor/internal/auth"
 	"github.com/hhftechnology/vps-monitor/internal/config"
 	"github.com/hhftechnology/vps-monitor/internal/coolify"
+	"github.com/hhftechnology/vps-monitor/internal/notify"
 	"github.com/hhftechnology/vps-monitor/internal/services"
 )
 
@@ -336,6 +338,7 @@ func TestSettingsMutationRoutesRespectReadOnlyMode(t *testing.T) {
 		"/api/v1/settings/coolify-hosts",
 		"/api/v1/settings/auth",
 		"/api/v1/settings/bot",
+		"/api/v1/settings/notifications",
 	} {
 		req := httptest.NewRequest(http.MethodPut, route, strings.NewReader(`{}`))
 		rec := httptest.NewRecorder()
@@ -347,6 +350,164 @@ func TestSettingsMutationRoutesRespectReadOnlyMode(t *testing.T) {
 	}
 }
 
+func TestUpdateNotificationsPreservesMaskedSecrets(t *testing.T) {
+	manager := newTestSettingsManager(t)
+	router := &APIRouter{
+		manager:  manager,
+		registry: services.NewRegistry(nil, nil, nil, manager.Config(), nil),
+	}
+
+	body := `{
+		"channels": [
+			{"name": "gotify", "type": "gotify", "enabled": true, "url": "https://gotify.example.com", "token": "%s"},
+			{"name": "mail", "type": "email", "enabled": true, "smtp": {
+				"host": "smtp.example.com", "port": 587, "username": "user", "password": "%s",
+				"from": "monitor@example.com", "to": ["ops@example.com"]
+			}}
+		],
+		"routes": [{"channels": ["gotify"], "minSeverity": "critical"}]
+	}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/settings/notifications", strings.NewReader(fmt.Sprintf(body, "app-token", "smtp-pass")))
+	rec := httptest.NewRecorder()
+	router.UpdateNotifications(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected initial update to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	router.registry.UpdateConfig(manager.Config())
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/settings", nil)
+	rec = httptest.NewRecorder()
+	router.GetSettings(rec, req)
+
+	var settings struct {
+		Notifications struct {
+			Source   config.Source                `json:"source"`
+			Channels []config.NotificationChannel `json:"channels"`
+		} `json:"notifications"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&settings); err != nil {
+		t.Fatalf("decode settings response: %v", err)
+	}
+	if settings.Notifications.Source != config.SourceFile || len(settings.Notifications.Channels) != 2 {
+		t.Fatalf("unexpected notifications settings: %+v", settings.Notifications)
+	}
+	if settings.Notifications.Channels[0].Token != secretMask || settings.Notifications.Channels[1].SMTP.Password != secretMask {
+		t.Fatalf("expected masked secrets, got %+v", settings.Notifications.Channels)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/settings/notifications", strings.NewReader(fmt.Sprintf(body, secretMask, secretMask)))
+	rec = httptest.NewRecorder()
+	router.UpdateNotifications(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected masked update to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	channels := manager.Config().Notifications.Channels
+	if channels[0].Token != "app-token" || channels[1].SMTP.Password != "smtp-pass" {
+		t.Fatalf("expected masked update to preserve secrets, got %+v", channels)
+	}
+}
+
+func TestUpdateNotificationsRejectsInvalidRoutes(t *testing.T) {
+	manager := newTestSettingsManager(t)
+	router := &APIRouter{
+		manager:  manager,
+		registry: services.NewRegistry(nil, nil, nil, manager.Config(), nil),
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/settings/notifications", strings.NewReader(`{
+		"channels": [{"name": "tg", "type": "telegram", "enabled": true}],
+		"routes": [{"channels": ["missing"]}]
+	}`))
+	rec := httptest.NewRecorder()
+	router.UpdateNotifications(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
+
+func TestTestNotificationRejectsMaskedTokenWithoutStoredToken(t *testing.T) {
+	manager := newTestSettingsManager(t)
+	router := &APIRouter{
+		manager:  manager,
+		registry: services.NewRegistry(nil, nil, nil, manager.Config(), nil),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/settings/test/notification", strings.NewReader(`{
+		"name": "gotify", "type": "gotify", "url": "https://gotify.example.com", "token": "••••••••"
+	}`))
+	rec := httptest.NewRecorder()
+	router.TestNotification(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
+
+func TestTestWebhookSignsAndReportsStatus(t *testing.T) {
+	var gotSignature, gotAuth string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(notify.SignatureHeader)
+		gotAuth = r.Header.Get("Authorization")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	manager := newTestSettingsManager(t)
+	if err := manager.UpdateNotifications(&config.NotificationsConfig{Channels: []config.NotificationChannel{{
+		Name: "hook", Type: config.ChannelWebhook, URL: srv.URL,
+		Secret: "s3cret", Headers: map[string]string{"Authorization": "Bearer abc"},
+	}}}); err != nil {
+		t.Fatalf("UpdateNotifications returned error: %v", err)
+	}
+	router := &APIRouter{
+		manager:  manager,
+		registry: services.NewRegistry(nil, nil, nil, manager.Config(), nil),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/settings/test/webhook", strings.NewReader(fmt.Sprintf(`{
+		"name": "hook", "url": %q, "secret": "••••••••", "headers": {"Authorization": "••••••••"}
+	}`, srv.URL)))
+	rec := httptest.NewRecorder()
+	router.TestWebhook(rec, req)
+
+	var body struct {
+		Success    bool   `json:"success"`
+		Message    string `json:"message"`
+		StatusCode int    `json:"statusCode"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !body.Success || body.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected test webhook response: %+v", body)
+	}
+	if gotSignature != notify.SignWebhookBody("s3cret", gotBody) || gotAuth != "Bearer abc" {
+		t.Fatalf("expected stored secret and header to be used, got signature %q auth %q", gotSignature, gotAuth)
+	}
+}
+
+func TestUpdateNotificationsRejectsInvalidTemplate(t *testing.T) {
+	manager := newTestSettingsManager(t)
+	router := &APIRouter{
+		manager:  manager,
+		registry: services.NewRegistry(nil, nil, nil, manager.Config(), nil),
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/settings/notifications", strings.NewReader(`{
+		"channels": [{"name": "hook", "type": "webhook", "enabled": true, "url": "https://example.com", "template": "{{.Alert.Host"}]
+	}`))
+	rec := httptest.NewRecorder()
+	router.UpdateNotifications(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
+
 func newTestSettingsManager(t *testing.T) *config.Manager {
 	t.Helper()
 	t.Setenv("CONFIG_PATH", t.TempDir()+"/config.json")
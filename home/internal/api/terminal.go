@@ -100,7 +100,12 @@ func (ar *APIRouter) HandleTerminal(w http.ResponseWriter, r *http.Request) {
 	go streamContainerOutput(resp.Reader, ws, &writeMu, done)
 	go ar.forwardClientInput(ctx, host, execID, resp.Conn, io.NopCloser(resp.Conn), ws)
 
-	<-done
+	// The request context ends when the server shuts down; closing the
+	// websocket and the exec stream then ends the session
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
 }
 
 func (ar *APIRouter) startExecSession(ctx context.Context, host, containerID string) (string, *types.HijackedResponse, error) {
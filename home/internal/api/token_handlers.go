@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+type apiTokenRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt int64    `json:"expires_at"`
+}
+
+// ListAPITokens returns the current user's API tokens
+func (ar *APIRouter) ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := ar.tokens.List(user.Username)
+	if err != nil {
+		writeAPITokenError(w, err)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"tokens": tokens})
+}
+
+// CreateAPIToken issues an API token for the current user. The token is
+// only part of this response.
+func (ar *APIRouter) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req apiTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	secret, token, err := ar.tokens.Create(user, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		writeAPITokenError(w, err)
+		return
+	}
+	WriteJsonResponse(w, http.StatusCreated, map[string]any{
+		"token":     secret,
+		"api_token": token,
+	})
+}
+
+// RevokeAPIToken deletes one of the current user's API tokens
+func (ar *APIRouter) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	ok, err := ar.tokens.Revoke(user.Username, chi.URLParam(r, "tokenID"))
+	if err != nil {
+		writeAPITokenError(w, err)
+		return
+	}
+	if !ok {
+		http.Error(w, "token not found", http.StatusNotFound)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "Token revoked"})
+}
+
+// sessionUser returns the user of a request authenticated through
+// /auth/login, for managing the user's own credentials. API tokens cannot
+// manage credentials, and single sign-on users have no account to attach
+// them to.
+func sessionUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication is not enabled", http.StatusNotFound)
+		return models.User{}, false
+	}
+	if user.TokenScopes != nil {
+		http.Error(w, "API tokens cannot manage credentials", http.StatusForbidden)
+		return models.User{}, false
+	}
+	if user.Provider != "" {
+		http.Error(w, "this requires a local user account", http.StatusForbidden)
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func writeAPITokenError(w http.ResponseWriter, err error) {
+	if errors.Is(err, auth.ErrInvalidAPIToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Failed to update API tokens: %v", err)
+	http.Error(w, "failed to update API tokens", http.StatusInternalServerError)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestAPITokens(t *testing.T) {
+	h := newUsersTestRouter(t)
+	adminToken, _ := login(t, h, "admin", "secret")
+
+	if rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/settings/users", `{"username":"vic","password":"viewer-pass","role":"viewer"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	viewerToken, _ := login(t, h, "vic", "viewer-pass")
+	if rec := serveAs(t, h, viewerToken, http.MethodPost, "/api/v1/auth/tokens", `{"name":"ci","scopes":["operate"]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a scope above the role to be rejected with %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/auth/tokens", `{"name":"dashboards","scopes":["read"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Token    string          `json:"token"`
+		APIToken models.APIToken `json:"api_token"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode token: %v", err)
+	}
+
+	rec = serveAs(t, h, created.Token, http.MethodGet, "/api/v1/auth/me", "")
+	if rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte(`"token_scopes":["read"]`)) {
+		t.Fatalf("expected the token to authenticate, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, "", http.MethodGet, "/api/v1/alerts/config?token="+created.Token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the token to work as a query parameter, got %d", rec.Code)
+	}
+	for _, route := range [][2]string{
+		{http.MethodPost, "/api/v1/alerts/acknowledge-all"},
+		{http.MethodGet, "/api/v1/settings/users"},
+		{http.MethodGet, "/api/v1/auth/tokens"},
+	} {
+		if rec := serveAs(t, h, created.Token, route[0], route[1], "{}"); rec.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected %d for a read token, got %d", route[0], route[1], http.StatusForbidden, rec.Code)
+		}
+	}
+
+	rec = serveAs(t, h, adminToken, http.MethodGet, "/api/v1/auth/tokens", "")
+	if rec.Code != http.StatusOK || bytes.Contains(rec.Body.Bytes(), []byte(created.Token)) || !bytes.Contains(rec.Body.Bytes(), []byte(created.APIToken.Prefix)) {
+		t.Fatalf("unexpected token list %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, viewerToken, http.MethodDelete, "/api/v1/auth/tokens/"+created.APIToken.ID, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected another user's token to be left alone, got %d", rec.Code)
+	}
+	if rec := serveAs(t, h, adminToken, http.MethodDelete, "/api/v1/auth/tokens/"+created.APIToken.ID, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if rec := serveAs(t, h, created.Token, http.MethodGet, "/api/v1/alerts/config", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a revoked token to be rejected, got %d", rec.Code)
+	}
+}
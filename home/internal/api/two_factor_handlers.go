@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hhftechnology/vps-monitor/internal/api/middleware"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+)
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// LoginTwoFactor is the second step of a password login for users with
+// two-factor authentication: it swaps the challenge from /auth/login and a
+// code for the tokens
+func (ar *APIRouter) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	svc := ar.registry.Auth()
+	if svc == nil || svc.IsDisabled() {
+		http.Error(w, "Authentication is not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Challenge string `json:"challenge"`
+		Code      string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Challenge == "" || req.Code == "" {
+		http.Error(w, "challenge and code are required", http.StatusBadRequest)
+		return
+	}
+
+	// The challenge does not tell whose login it is, so wrong codes count
+	// against the client IP only
+	ip := middleware.ClientIP(r)
+	if ar.lockedOut(w, ip, "") {
+		return
+	}
+
+	user, err := svc.FinishTwoFactor(req.Challenge, req.Code)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidTwoFactor) {
+			if ar.loginGuard != nil {
+				ar.loginGuard.Fail(ip, "")
+			}
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		log.Printf("Failed to check two-factor code: %v", err)
+		http.Error(w, "failed to check two-factor code", http.StatusInternalServerError)
+		return
+	}
+	if ar.loginGuard != nil {
+		ar.loginGuard.Succeed(user.Username)
+	}
+
+	tokens, err := svc.StartSession(user, r.UserAgent(), ip)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
+	})
+}
+
+// GetTwoFactor reports whether the current user has two-factor
+// authentication enabled and how many recovery codes are left
+func (ar *APIRouter) GetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := ar.twoFactor.Get(user.Username)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	if enrollment == nil || !enrollment.Enabled {
+		WriteJsonResponse(w, http.StatusOK, map[string]any{"enabled": false})
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"enabled":             true,
+		"enabled_at":          enrollment.EnabledAt,
+		"recovery_codes_left": len(enrollment.RecoveryHashes),
+	})
+}
+
+// EnrollTwoFactor starts a TOTP enrollment for the current user. The
+// returned URI is what authenticator apps read from a QR code.
+func (ar *APIRouter) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	secret, uri, err := ar.twoFactor.Begin(user.Username)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"secret":           secret,
+		"provisioning_uri": uri,
+	})
+}
+
+// ConfirmTwoFactor turns two-factor authentication on with a first code
+// from the authenticator app. The recovery codes are only part of this
+// response.
+func (ar *APIRouter) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := ar.twoFactor.Confirm(user.Username, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// DisableTwoFactor turns two-factor authentication off for the current
+// user, who must enter a code or a recovery code
+func (ar *APIRouter) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := ar.twoFactor.Disable(user.Username, req.Code); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "Two-factor authentication disabled"})
+}
+
+// ResetUserTwoFactor turns two-factor authentication off for a user who
+// lost their authenticator and recovery codes
+func (ar *APIRouter) ResetUserTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ok, err := ar.twoFactor.Reset(chi.URLParam(r, "username"))
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	if !ok {
+		http.Error(w, "two-factor authentication is not set up for this user", http.StatusNotFound)
+		return
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "Two-factor authentication reset"})
+}
+
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	if errors.Is(err, auth.ErrInvalidTwoFactor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Failed to update two-factor authentication: %v", err)
+	http.Error(w, "failed to update two-factor authentication", http.StatusInternalServerError)
+}
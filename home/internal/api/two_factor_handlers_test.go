@@ -0,0 +1,143 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// totpTestCode computes the RFC 6238 code of secret steps time steps from
+// now, as an authenticator app would
+func totpTestCode(t *testing.T, secret string, steps int64) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("invalid TOTP secret %q: %v", secret, err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(time.Now().Unix()/30+steps))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1_000_000)
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	h := newUsersTestRouter(t)
+	adminToken, _ := login(t, h, "admin", "secret")
+
+	rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/auth/2fa/enroll", "")
+	var enroll struct {
+		Secret          string `json:"secret"`
+		ProvisioningURI string `json:"provisioning_uri"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &enroll); err != nil || rec.Code != http.StatusOK || enroll.Secret == "" {
+		t.Fatalf("unexpected enroll response %d: %s", rec.Code, rec.Body.String())
+	}
+	if uri, err := url.Parse(enroll.ProvisioningURI); err != nil || uri.Query().Get("secret") != enroll.Secret {
+		t.Fatalf("unexpected provisioning URI %q", enroll.ProvisioningURI)
+	}
+	if rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/auth/2fa/confirm", `{"code":"000000"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a wrong code to be rejected, got %d", rec.Code)
+	}
+	rec = serveAs(t, h, adminToken, http.MethodPost, "/api/v1/auth/2fa/confirm", `{"code":"`+totpTestCode(t, enroll.Secret, 0)+`"}`)
+	var confirm struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &confirm); err != nil || rec.Code != http.StatusOK || len(confirm.RecoveryCodes) != 10 {
+		t.Fatalf("unexpected confirm response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The password alone no longer logs in
+	rec = serveAs(t, h, "", http.MethodPost, "/api/v1/auth/login", `{"username":"admin","password":"secret"}`)
+	var first struct {
+		Token             string `json:"token"`
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		Challenge         string `json:"challenge"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &first); err != nil || !first.TwoFactorRequired || first.Challenge == "" || first.Token != "" {
+		t.Fatalf("unexpected login response %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, "", http.MethodPost, "/api/v1/auth/login/2fa", `{"challenge":"`+first.Challenge+`","code":"000000"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong code to be rejected, got %d", rec.Code)
+	}
+	rec = serveAs(t, h, "", http.MethodPost, "/api/v1/auth/login/2fa", `{"challenge":"`+first.Challenge+`","code":"`+confirm.RecoveryCodes[0]+`"}`)
+	var second struct {
+		Token        string      `json:"token"`
+		RefreshToken string      `json:"refresh_token"`
+		User         models.User `json:"user"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &second); err != nil || rec.Code != http.StatusOK || second.RefreshToken == "" || second.User.Role != models.RoleAdmin {
+		t.Fatalf("unexpected second step response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serveAs(t, h, second.Token, http.MethodGet, "/api/v1/auth/2fa", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"recovery_codes_left":9`) {
+		t.Fatalf("unexpected status response %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, second.Token, http.MethodPost, "/api/v1/auth/2fa/enroll", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected enrolling twice to be rejected, got %d", rec.Code)
+	}
+
+	// Users show whether they use 2FA, and an admin can reset it
+	rec = serveAs(t, h, second.Token, http.MethodGet, "/api/v1/settings/users", "")
+	if !strings.Contains(rec.Body.String(), `"two_factor":true`) {
+		t.Fatalf("expected the admin to be listed with 2FA: %s", rec.Body.String())
+	}
+	if rec := serveAs(t, h, second.Token, http.MethodDelete, "/api/v1/settings/users/admin/2fa", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, second.Token, http.MethodDelete, "/api/v1/settings/users/admin/2fa", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	login(t, h, "admin", "secret")
+}
+
+func TestTwoFactorDisable(t *testing.T) {
+	h := newUsersTestRouter(t)
+	adminToken, _ := login(t, h, "admin", "secret")
+
+	if rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/auth/2fa/disable", `{"code":"123456"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected disabling without an enrollment to be rejected, got %d", rec.Code)
+	}
+	rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/auth/2fa/enroll", "")
+	var enroll struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &enroll); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected enroll response %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/auth/2fa/confirm", `{"code":"`+totpTestCode(t, enroll.Secret, 0)+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/auth/2fa/disable", `{"code":"`+totpTestCode(t, enroll.Secret, 0)+`"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a used code to be rejected, got %d", rec.Code)
+	}
+	if rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/auth/2fa/disable", `{"code":"`+totpTestCode(t, enroll.Secret, 1)+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	login(t, h, "admin", "secret")
+
+	// API tokens cannot change the second factor
+	rec = serveAs(t, h, adminToken, http.MethodPost, "/api/v1/auth/tokens", `{"name":"ci","scopes":["settings"]}`)
+	var created struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || created.Token == "" {
+		t.Fatalf("unexpected token response %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, created.Token, http.MethodPost, "/api/v1/auth/2fa/enroll", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected API tokens to be refused, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+type userRequest struct {
+	Username string                    `json:"username"`
+	Password string                    `json:"password"`
+	Role     string                    `json:"role"`
+	Grants   *[]models.PermissionGrant `json:"grants"`
+}
+
+// ListUsers returns the built-in admin, when authentication is enabled,
+// followed by the stored user accounts
+func (ar *APIRouter) ListUsers(w http.ResponseWriter, r *http.Request) {
+	accounts, err := ar.users.List()
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	users := make([]models.UserAccount, 0, len(accounts)+1)
+	if name := ar.builtinAdmin(); name != "" {
+		users = append(users, models.UserAccount{Username: name, Role: models.RoleAdmin, Builtin: true})
+	}
+	users = append(users, accounts...)
+	if ar.twoFactor != nil {
+		enabled, err := ar.twoFactor.EnabledUsers()
+		if err != nil {
+			writeUserError(w, err)
+			return
+		}
+		for i := range users {
+			users[i].TwoFactor = slices.Contains(enabled, users[i].Username)
+		}
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"users": users})
+}
+
+// CreateUser adds a user account
+func (ar *APIRouter) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req userRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if name := strings.TrimSpace(req.Username); name != "" && name == ar.builtinAdmin() {
+		http.Error(w, fmt.Sprintf("%q is the built-in admin", name), http.StatusConflict)
+		return
+	}
+
+	var grants []models.PermissionGrant
+	if req.Grants != nil {
+		grants = *req.Grants
+	}
+	user, err := ar.users.Create(req.Username, req.Password, req.Role, grants)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	WriteJsonResponse(w, http.StatusCreated, map[string]any{"user": user})
+}
+
+// UpdateUser changes the role, password and/or grants of a user account
+func (ar *APIRouter) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	var req userRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if username == ar.builtinAdmin() {
+		http.Error(w, "the built-in admin is managed through the auth settings", http.StatusConflict)
+		return
+	}
+
+	before, err := ar.users.Get(username)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	user, err := ar.users.Update(username, auth.UserChanges{
+		Role:     req.Role,
+		Password: req.Password,
+		Grants:   req.Grants,
+	})
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	if user == nil || before == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	// A new password or a lower role logs the user out everywhere, and
+	// tokens may not keep scopes the lower role does not allow. Promotions
+	// apply to the sessions already open.
+	demoted := !auth.HasRole(user.Role, before.Role)
+	if req.Password != "" || demoted {
+		ar.revokeUserSessions(username)
+	}
+	if demoted && ar.tokens != nil {
+		if _, err := ar.tokens.RevokeOutsideRole(username, user.Role); err != nil {
+			log.Printf("Failed to revoke API tokens of demoted user %q: %v", username, err)
+		}
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"user": user})
+}
+
+// revokeUserSessions ends every login session of username
+func (ar *APIRouter) revokeUserSessions(username string) {
+	if ar.sessions == nil {
+		return
+	}
+	if err := ar.sessions.RevokeUser(username); err != nil {
+		log.Printf("Failed to revoke sessions of user %q: %v", username, err)
+	}
+}
+
+// DeleteUser removes a user account
+func (ar *APIRouter) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	if username == ar.builtinAdmin() {
+		http.Error(w, "the built-in admin is managed through the auth settings", http.StatusConflict)
+		return
+	}
+
+	ok, err := ar.users.Delete(username)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	if !ok {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	// Tokens, sessions and two-factor enrollments must not come back to life if the name is reused
+	if ar.tokens != nil {
+		if err := ar.tokens.RevokeAll(username); err != nil {
+			log.Printf("Failed to revoke API tokens of deleted user %q: %v", username, err)
+		}
+	}
+	ar.revokeUserSessions(username)
+	if ar.twoFactor != nil {
+		if _, err := ar.twoFactor.Reset(username); err != nil {
+			log.Printf("Failed to remove two-factor enrollment of deleted user %q: %v", username, err)
+		}
+	}
+	WriteJsonResponse(w, http.StatusOK, map[string]any{"message": "User deleted"})
+}
+
+// builtinAdmin returns the name of the built-in admin, or "" when
+// authentication is disabled
+func (ar *APIRouter) builtinAdmin() string {
+	return ar.registry.Auth().AdminUsername()
+}
+
+func writeUserError(w http.ResponseWriter, err error) {
+	if errors.Is(err, auth.ErrInvalidUser) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Failed to update users: %v", err)
+	http.Error(w, "failed to update users", http.StatusInternalServerError)
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hhftechnology/vps-monitor/internal/audit"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/services"
+)
+
+func newUsersTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+	return newUsersTestAPIRouter(t).Routes()
+}
+
+// newUsersTestAPIRouter is newUsersTestRouter before its routes are built,
+// for tests that need more dependencies
+func newUsersTestAPIRouter(t *testing.T) *APIRouter {
+	t.Helper()
+	users := auth.NewUsers(nil)
+	tokens := auth.NewTokens(nil)
+	sessions := auth.NewSessions(nil, config.SessionConfig{AccessTokenTTL: 15 * time.Minute, SessionTTL: time.Hour})
+	svc := newUsableAuthService(t)
+	svc.SetUsers(users)
+	svc.SetTokens(tokens)
+	svc.SetSessions(sessions)
+	twoFactor := auth.NewTwoFactor(nil)
+	svc.SetTwoFactor(twoFactor)
+
+	return &APIRouter{
+		router:        chi.NewRouter(),
+		registry:      services.NewRegistry(nil, nil, svc, &config.Config{}, nil),
+		alertHandlers: NewAlertHandlers(nil, &models.AlertConfigResponse{}),
+		users:         users,
+		tokens:        tokens,
+		sessions:      sessions,
+		twoFactor:     twoFactor,
+		audit:         audit.NewLog(nil, 0),
+	}
+}
+
+func serveAs(t *testing.T, h http.Handler, token, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func login(t *testing.T, h http.Handler, username, password string) (string, models.User) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"username": username, "password": password})
+	rec := serveAs(t, h, "", http.MethodPost, "/api/v1/auth/login", string(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login as %s: expected %d, got %d: %s", username, http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Token string      `json:"token"`
+		User  models.User `json:"user"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode login response: %v", err)
+	}
+	return resp.Token, resp.User
+}
+
+func TestUserAccountsAndRoles(t *testing.T) {
+	h := newUsersTestRouter(t)
+	adminToken, admin := login(t, h, "admin", "secret")
+	if admin.Role != models.RoleAdmin {
+		t.Fatalf("expected the built-in admin to be an admin, got %+v", admin)
+	}
+
+	rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/settings/users", `{"username":"vic","password":"viewer-pass","role":"viewer"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if bytes.Contains(rec.Body.Bytes(), []byte("viewer-pass")) || bytes.Contains(rec.Body.Bytes(), []byte("$2a$")) {
+		t.Fatalf("response leaks the password: %s", rec.Body.String())
+	}
+	if rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/settings/users", `{"username":"admin","password":"whatever1","role":"viewer"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected the built-in admin name to be rejected with %d, got %d", http.StatusConflict, rec.Code)
+	}
+	if rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/settings/users", `{"username":"eve","password":"short","role":"viewer"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a short password to be rejected with %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = serveAs(t, h, adminToken, http.MethodGet, "/api/v1/settings/users", "")
+	var list struct {
+		Users []models.UserAccount `json:"users"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to decode users: %v", err)
+	}
+	if len(list.Users) != 2 || !list.Users[0].Builtin || list.Users[1].Username != "vic" {
+		t.Fatalf("unexpected users %+v", list.Users)
+	}
+
+	viewerToken, viewer := login(t, h, "vic", "viewer-pass")
+	if viewer.Role != models.RoleViewer {
+		t.Fatalf("expected a viewer, got %+v", viewer)
+	}
+	for _, route := range [][2]string{
+		{http.MethodPost, "/api/v1/containers/abc/start"},
+		{http.MethodGet, "/api/v1/containers/abc/exec"},
+		{http.MethodPut, "/api/v1/containers/abc/env"},
+		{http.MethodPost, "/api/v1/images/pull"},
+		{http.MethodPost, "/api/v1/alerts/acknowledge-all"},
+		{http.MethodGet, "/api/v1/settings/users"},
+	} {
+		if rec := serveAs(t, h, viewerToken, route[0], route[1], "{}"); rec.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected %d for a viewer, got %d", route[0], route[1], http.StatusForbidden, rec.Code)
+		}
+	}
+	if rec := serveAs(t, h, viewerToken, http.MethodGet, "/api/v1/alerts/config", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected a viewer to read alerts, got %d", rec.Code)
+	}
+
+	// Promotion applies to the token already issued
+	if rec := serveAs(t, h, adminToken, http.MethodPut, "/api/v1/settings/users/vic", `{"role":"operator"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, viewerToken, http.MethodPost, "/api/v1/alerts/acknowledge-all", ""); rec.Code == http.StatusForbidden {
+		t.Fatal("expected an operator to acknowledge alerts")
+	}
+	if rec := serveAs(t, h, viewerToken, http.MethodGet, "/api/v1/settings/users", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected settings to stay admin-only, got %d", rec.Code)
+	}
+
+	if rec := serveAs(t, h, adminToken, http.MethodDelete, "/api/v1/settings/users/vic", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if rec := serveAs(t, h, viewerToken, http.MethodGet, "/api/v1/alerts/config", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a deleted user's token to be rejected, got %d", rec.Code)
+	}
+	if rec := serveAs(t, h, adminToken, http.MethodDelete, "/api/v1/settings/users/vic", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestUpdateUserRevokesSessionsAndTokens(t *testing.T) {
+	h := newUsersTestRouter(t)
+	adminToken, _ := login(t, h, "admin", "secret")
+	if rec := serveAs(t, h, adminToken, http.MethodPost, "/api/v1/settings/users", `{"username":"olga","password":"operator-pass","role":"operator"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	sessionToken, _ := login(t, h, "olga", "operator-pass")
+	createToken := func(scopes string) string {
+		t.Helper()
+		rec := serveAs(t, h, sessionToken, http.MethodPost, "/api/v1/auth/tokens", `{"name":"ci","scopes":`+scopes+`}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create token %s: expected %d, got %d: %s", scopes, http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		var created struct {
+			Token string `json:"token"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+			t.Fatalf("failed to decode token: %v", err)
+		}
+		return created.Token
+	}
+	readToken := createToken(`["read"]`)
+	operateToken := createToken(`["read","operate"]`)
+
+	// Changing the grants alone keeps everything
+	if rec := serveAs(t, h, adminToken, http.MethodPut, "/api/v1/settings/users/olga", `{"grants":[]}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, sessionToken, http.MethodGet, "/api/v1/alerts/config", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the session to survive a grants change, got %d", rec.Code)
+	}
+
+	// A demotion ends the sessions and the tokens the new role cannot hold
+	if rec := serveAs(t, h, adminToken, http.MethodPut, "/api/v1/settings/users/olga", `{"role":"viewer"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	for name, want := range map[string]struct {
+		token string
+		code  int
+	}{
+		"session":       {sessionToken, http.StatusUnauthorized},
+		"operate token": {operateToken, http.StatusUnauthorized},
+		"read token":    {readToken, http.StatusOK},
+	} {
+		if rec := serveAs(t, h, want.token, http.MethodGet, "/api/v1/alerts/config", ""); rec.Code != want.code {
+			t.Fatalf("%s after demotion: expected %d, got %d", name, want.code, rec.Code)
+		}
+	}
+
+	// A new password ends the sessions opened with the old one
+	sessionToken, _ = login(t, h, "olga", "operator-pass")
+	if rec := serveAs(t, h, adminToken, http.MethodPut, "/api/v1/settings/users/olga", `{"password":"new-password"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec := serveAs(t, h, sessionToken, http.MethodGet, "/api/v1/alerts/config", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the session to end with the password change, got %d", rec.Code)
+	}
+	login(t, h, "olga", "new-password")
+}
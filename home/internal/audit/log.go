@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const (
+	// maxMemoryEvents bounds the events kept when there is no store
+	maxMemoryEvents = 10000
+	// pruneInterval limits how often old events are deleted
+	pruneInterval = time.Hour
+)
+
+// Store persists the audit log beyond the lifetime of the process
+type Store interface {
+	InsertAuditEvent(event models.AuditEvent) error
+	QueryAuditEvents(params models.AuditQuery) (*models.AuditPage, error)
+	PruneAuditEventsOlderThan(cutoff time.Time) error
+}
+
+// Log records changes made through the API, either in a persistent store
+// or, when none is configured, in memory using a ring buffer
+type Log struct {
+	store     Store
+	retention time.Duration
+
+	mu        sync.Mutex
+	events    []models.AuditEvent // newest first
+	nextID    int64
+	lastPrune time.Time
+}
+
+// NewLog creates an audit log backed by store that keeps events for
+// retention, or forever when retention is 0. A nil store keeps the most
+// recent events in memory.
+func NewLog(store Store, retention time.Duration) *Log {
+	return &Log{store: store, retention: retention}
+}
+
+// Record appends event to the log. Failures are logged rather than
+// returned: the change the event describes has already happened.
+func (l *Log) Record(event models.AuditEvent) {
+	if event.Time == 0 {
+		event.Time = time.Now().Unix()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune()
+
+	if l.store != nil {
+		if err := l.store.InsertAuditEvent(event); err != nil {
+			log.Printf("Audit log: failed to record %s by %q: %v", event.Action, event.Username, err)
+		}
+		return
+	}
+
+	l.nextID++
+	event.ID = l.nextID
+	l.events = append([]models.AuditEvent{event}, l.events...)
+	if len(l.events) > maxMemoryEvents {
+		l.events = l.events[:maxMemoryEvents]
+	}
+}
+
+// Query returns a filtered, paginated page of the log, newest first
+func (l *Log) Query(params models.AuditQuery) (*models.AuditPage, error) {
+	if l.store != nil {
+		return l.store.QueryAuditEvents(params)
+	}
+
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > maxMemoryEvents {
+		params.PageSize = maxMemoryEvents
+	}
+
+	l.mu.Lock()
+	matched := make([]models.AuditEvent, 0)
+	for _, event := range l.events {
+		if eventMatchesQuery(event, params) {
+			matched = append(matched, event)
+		}
+	}
+	l.mu.Unlock()
+
+	page := &models.AuditPage{
+		Events:     []models.AuditEvent{},
+		Total:      len(matched),
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		TotalPages: (len(matched) + params.PageSize - 1) / params.PageSize,
+	}
+	start := (params.Page - 1) * params.PageSize
+	if start < len(matched) {
+		page.Events = matched[start:min(start+params.PageSize, len(matched))]
+	}
+	return page, nil
+}
+
+// prune deletes events past the retention, at most once per
+// pruneInterval. Must be called with l.mu held.
+func (l *Log) prune() {
+	if l.retention <= 0 || time.Since(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = time.Now()
+	cutoff := time.Now().Add(-l.retention)
+
+	if l.store != nil {
+		if err := l.store.PruneAuditEventsOlderThan(cutoff); err != nil {
+			log.Printf("Audit log: failed to prune old events: %v", err)
+		}
+		return
+	}
+	l.events = slices.DeleteFunc(l.events, func(event models.AuditEvent) bool {
+		return event.Time < cutoff.Unix()
+	})
+}
+
+func eventMatchesQuery(event models.AuditEvent, params models.AuditQuery) bool {
+	if params.Username != "" && event.Username != params.Username {
+		return false
+	}
+	if params.Action != "" && !strings.Contains(event.Action, params.Action) {
+		return false
+	}
+	if params.Host != "" && event.Host != params.Host {
+		return false
+	}
+	if params.Target != "" && !strings.Contains(event.Target, params.Target) {
+		return false
+	}
+	if params.Outcome != "" && event.Outcome != params.Outcome {
+		return false
+	}
+	if params.StartDate > 0 && event.Time < params.StartDate {
+		return false
+	}
+	if params.EndDate > 0 && event.Time > params.EndDate {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestMemoryLog(t *testing.T) {
+	l := NewLog(nil, time.Hour)
+	now := time.Now().Unix()
+
+	l.Record(models.AuditEvent{Username: "alice", Action: "POST /containers/{id}/start", Host: "prod", Outcome: models.AuditOutcomeSuccess})
+	l.Record(models.AuditEvent{Time: now, Username: "bob", Action: "PUT /settings/auth", Outcome: models.AuditOutcomeDenied})
+	l.Record(models.AuditEvent{Time: now, Username: "alice", Action: "POST /images/pull", Host: "prod", Outcome: models.AuditOutcomeFailure})
+
+	page, err := l.Query(models.AuditQuery{})
+	if err != nil || page.Total != 3 || page.Events[0].ID != 3 || page.Events[2].Time == 0 {
+		t.Fatalf("Query() = %+v, %v", page, err)
+	}
+	page, err = l.Query(models.AuditQuery{Username: "alice", Host: "prod", PageSize: 1, Page: 2})
+	if err != nil || page.Total != 2 || page.TotalPages != 2 || len(page.Events) != 1 || page.Events[0].Action != "POST /containers/{id}/start" {
+		t.Fatalf("Query() = %+v, %v", page, err)
+	}
+
+	// Events past the retention are dropped on the next Record
+	l.mu.Lock()
+	l.events[0].Time = now - 7200
+	l.lastPrune = time.Time{}
+	l.mu.Unlock()
+	l.Record(models.AuditEvent{Username: "carol", Action: "POST /scan", Outcome: models.AuditOutcomeAccepted})
+	page, err = l.Query(models.AuditQuery{Action: "/images/"})
+	if err != nil || page.Total != 0 {
+		t.Fatalf("expected the old event to be pruned, got %+v, %v", page, err)
+	}
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// maxLoginGuardEntries bounds the client IPs and usernames tracked at once
+const maxLoginGuardEntries = 10000
+
+// LoginGuard counts failed logins per client IP and per username and locks
+// either out once it reaches MaxFailures. Every further failure after a
+// lockout doubles the next one, up to MaxLockout. Failures are forgotten
+// FailureWindow after the last one and its lockout are over.
+type LoginGuard struct {
+	cfg config.LoginProtectionConfig
+
+	mu        sync.Mutex
+	entries   map[string]*loginFailures // "ip:<addr>" or "user:<name>"
+	onLockout func(models.LoginLockout)
+}
+
+type loginFailures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// NewLoginGuard creates a login guard. A cfg with MaxFailures 0 never
+// locks anyone out.
+func NewLoginGuard(cfg config.LoginProtectionConfig) *LoginGuard {
+	return &LoginGuard{cfg: cfg, entries: make(map[string]*loginFailures)}
+}
+
+// SetLockoutHandler sets fn to be called, without locks held, for every new
+// lockout
+func (g *LoginGuard) SetLockoutHandler(fn func(models.LoginLockout)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onLockout = fn
+}
+
+// Check returns how long logins from ip, or for username, must wait, or 0
+// when they may be attempted. An empty ip or username is not checked.
+func (g *LoginGuard) Check(ip, username string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range loginGuardKeys(ip, username) {
+		if entry, ok := g.entries[key]; ok && entry.lockedUntil.After(now) {
+			wait = max(wait, entry.lockedUntil.Sub(now))
+		}
+	}
+	return wait
+}
+
+// Fail records a failed login from ip for username and locks either out
+// when it has failed too often. An empty ip or username is not recorded.
+func (g *LoginGuard) Fail(ip, username string) {
+	if g.cfg.MaxFailures <= 0 {
+		return
+	}
+
+	now := time.Now()
+	var lockouts []models.LoginLockout
+
+	g.mu.Lock()
+	for _, key := range loginGuardKeys(ip, username) {
+		entry, ok := g.entries[key]
+		if !ok || g.expired(entry, now) {
+			g.makeRoom(now)
+			entry = &loginFailures{}
+			g.entries[key] = entry
+		}
+		entry.count++
+		entry.last = now
+		if entry.count < g.cfg.MaxFailures {
+			continue
+		}
+
+		lockout := g.lockoutFor(entry.count)
+		entry.lockedUntil = now.Add(lockout)
+		event := models.LoginLockout{
+			Failures:        entry.count,
+			DurationSeconds: int64(lockout / time.Second),
+			Until:           entry.lockedUntil.Unix(),
+		}
+		if key == "ip:"+ip {
+			event.IP = ip
+		} else {
+			event.Username = username
+		}
+		lockouts = append(lockouts, event)
+	}
+	onLockout := g.onLockout
+	g.mu.Unlock()
+
+	for _, lockout := range lockouts {
+		subject := "client " + lockout.IP
+		if lockout.Username != "" {
+			subject = "user " + lockout.Username
+		}
+		log.Printf("Login locked out for %s for %ds after %d failed attempts", subject, lockout.DurationSeconds, lockout.Failures)
+		if onLockout != nil {
+			onLockout(lockout)
+		}
+	}
+}
+
+// Succeed forgets the failed logins of username after a complete login.
+// Failures of the client IP are kept, so one valid account does not buy
+// more guesses at others.
+func (g *LoginGuard) Succeed(username string) {
+	if username == "" {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.entries, "user:"+username)
+}
+
+// lockoutFor returns the lockout for the count-th failure
+func (g *LoginGuard) lockoutFor(count int) time.Duration {
+	lockout := g.cfg.Lockout
+	for i := g.cfg.MaxFailures; i < count && lockout < g.cfg.MaxLockout; i++ {
+		lockout *= 2
+	}
+	return min(lockout, g.cfg.MaxLockout)
+}
+
+func (g *LoginGuard) expired(entry *loginFailures, now time.Time) bool {
+	end := entry.last
+	if entry.lockedUntil.After(end) {
+		end = entry.lockedUntil
+	}
+	return now.Sub(end) > g.cfg.FailureWindow
+}
+
+// makeRoom drops expired entries once the guard is full and, if that is
+// not enough, the one with the oldest failure. Must be called with g.mu held.
+func (g *LoginGuard) makeRoom(now time.Time) {
+	if len(g.entries) < maxLoginGuardEntries {
+		return
+	}
+	oldestKey := ""
+	var oldest time.Time
+	for key, entry := range g.entries {
+		if g.expired(entry, now) {
+			delete(g.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.last.Before(oldest) {
+			oldestKey, oldest = key, entry.last
+		}
+	}
+	if len(g.entries) >= maxLoginGuardEntries {
+		delete(g.entries, oldestKey)
+	}
+}
+
+func loginGuardKeys(ip, username string) []string {
+	keys := make([]string, 0, 2)
+	if ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	if username != "" {
+		keys = append(keys, "user:"+username)
+	}
+	return keys
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestLoginGuardLockout(t *testing.T) {
+	guard := NewLoginGuard(config.LoginProtectionConfig{
+		MaxFailures:   3,
+		Lockout:       time.Minute,
+		MaxLockout:    3 * time.Minute,
+		FailureWindow: 15 * time.Minute,
+	})
+	var lockouts []models.LoginLockout
+	guard.SetLockoutHandler(func(lockout models.LoginLockout) {
+		lockouts = append(lockouts, lockout)
+	})
+
+	guard.Fail("10.0.0.1", "admin")
+	guard.Fail("10.0.0.2", "admin")
+	if wait := guard.Check("10.0.0.1", "admin"); wait != 0 || len(lockouts) != 0 {
+		t.Fatalf("expected no lockout before 3 failures, got %s and %+v", wait, lockouts)
+	}
+
+	// The username reaches the limit from a third address
+	guard.Fail("10.0.0.3", "admin")
+	if len(lockouts) != 1 || lockouts[0].Username != "admin" || lockouts[0].IP != "" || lockouts[0].Failures != 3 || lockouts[0].DurationSeconds != 60 {
+		t.Fatalf("unexpected lockouts: %+v", lockouts)
+	}
+	if wait := guard.Check("10.0.0.9", "admin"); wait <= 0 || wait > time.Minute {
+		t.Fatalf("expected the username to be locked out for a minute, got %s", wait)
+	}
+	if wait := guard.Check("10.0.0.1", "other"); wait != 0 {
+		t.Fatalf("expected other users to be unaffected, got %s", wait)
+	}
+
+	// Further failures after the lockout double it up to MaxLockout
+	for _, want := range []int64{120, 180, 180} {
+		guard.entries["user:admin"].lockedUntil = time.Now()
+		guard.Fail("", "admin")
+		if got := lockouts[len(lockouts)-1].DurationSeconds; got != want {
+			t.Fatalf("expected a %ds lockout, got %ds", want, got)
+		}
+	}
+
+	// A complete login clears the username but not the addresses
+	guard.Succeed("admin")
+	if wait := guard.Check("", "admin"); wait != 0 {
+		t.Fatalf("expected the username to be cleared, got %s", wait)
+	}
+	guard.Fail("10.0.0.1", "bob")
+	guard.Fail("10.0.0.1", "carol")
+	if last := lockouts[len(lockouts)-1]; last.IP != "10.0.0.1" || last.Failures != 3 {
+		t.Fatalf("expected the address to be locked out, got %+v", last)
+	}
+	if wait := guard.Check("10.0.0.1", ""); wait <= 0 {
+		t.Fatal("expected the address to be locked out")
+	}
+
+	// Failures are forgotten once the window has passed
+	entry := guard.entries["ip:10.0.0.2"]
+	entry.last = time.Now().Add(-time.Hour)
+	guard.Fail("10.0.0.2", "")
+	if entry := guard.entries["ip:10.0.0.2"]; entry.count != 1 {
+		t.Fatalf("expected old failures to be forgotten, got %d", entry.count)
+	}
+}
+
+func TestLoginGuardDisabled(t *testing.T) {
+	guard := NewLoginGuard(config.LoginProtectionConfig{})
+	for range 20 {
+		guard.Fail("10.0.0.1", "admin")
+	}
+	if wait := guard.Check("10.0.0.1", "admin"); wait != 0 {
+		t.Fatalf("expected no lockout when disabled, got %s", wait)
+	}
+}
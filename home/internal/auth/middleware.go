@@ -2,10 +2,13 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"strings"
 	"sync"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
 )
 
 type contextKey string
@@ -57,7 +60,7 @@ func Middleware(authService *Service) func(http.Handler) http.Handler {
 	}
 }
 
-// validateAndServe extracts JWT, validates it, adds user to context, and calls next.
+// validateAndServe extracts the JWT or API token, validates it, adds user to context, and calls next.
 func validateAndServe(svc *Service, next http.Handler, w http.ResponseWriter, r *http.Request) {
 	authHeader := r.Header.Get("Authorization")
 	var tokenString string
@@ -77,18 +80,69 @@ func validateAndServe(svc *Service, next http.Handler, w http.ResponseWriter, r
 		}
 	}
 
-	claims, err := svc.VerifyToken(tokenString)
+	user, err := svc.userForCredential(tokenString)
 	if err != nil {
-		if errors.Is(err, ErrTokenExpired) {
+		switch {
+		case errors.Is(err, ErrTokenExpired):
 			http.Error(w, "Token has expired", http.StatusUnauthorized)
-			return
+		case errors.Is(err, ErrInvalidToken):
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		default:
+			http.Error(w, "Failed to look up user", http.StatusInternalServerError)
 		}
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
 		return
 	}
-
-	user := GetUserFromClaims(claims)
 	ctx := context.WithValue(r.Context(), UserContextKey, user)
 
 	next.ServeHTTP(w, r.WithContext(ctx))
 }
+
+// RequireRole creates a middleware that rejects users whose role grants
+// less than required. Requests without a user, i.e. with authentication
+// disabled, are let through.
+func RequireRole(required string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if ok && !HasRole(user.Role, required) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"error":         "Your role does not allow this operation",
+					"role":          user.Role,
+					"required_role": required,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope creates a middleware that rejects API tokens without scope.
+// Sessions from /auth/login are not limited by scopes.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if ok && user.TokenScopes != nil && !HasScope(user.TokenScopes, scope) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"error":          "This API token does not allow this operation",
+					"scopes":         user.TokenScopes,
+					"required_scope": scope,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UserFromContext returns the user the auth middleware stored in ctx. It
+// reports false when there is none, i.e. with authentication disabled.
+func UserFromContext(ctx context.Context) (models.User, bool) {
+	user, ok := ctx.Value(UserContextKey).(models.User)
+	return user, ok
+}
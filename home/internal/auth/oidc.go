@@ -0,0 +1,466 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// ProviderOIDC marks users who logged in through OpenID Connect
+const ProviderOIDC = "oidc"
+
+const (
+	// OIDCLoginTimeout is how long a user has to finish logging in at the
+	// provider
+	OIDCLoginTimeout = 10 * time.Minute
+	// maxPendingOIDCLogins bounds the logins started but not finished
+	maxPendingOIDCLogins = 1000
+	// jwksRefreshInterval limits how often an unknown key ID refetches the
+	// provider's keys
+	jwksRefreshInterval = time.Minute
+)
+
+var (
+	// ErrOIDCLogin is returned when an OIDC login cannot be completed
+	ErrOIDCLogin = errors.New("OIDC login failed")
+	// ErrOIDCNoRole is returned when the user's groups map to no role and
+	// no default role is configured
+	ErrOIDCNoRole = errors.New("no role for OIDC user")
+)
+
+// OIDC logs users in through an OpenID Connect provider with the
+// authorization code flow and PKCE
+type OIDC struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]any
+	keysFetched time.Time
+	pending     map[string]oidcPendingLogin
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcPendingLogin struct {
+	nonce     string
+	verifier  string
+	expiresAt time.Time
+}
+
+// NewOIDC creates an OIDC client for cfg. The provider is only contacted
+// once the first login starts.
+func NewOIDC(cfg config.OIDCConfig) *OIDC {
+	return &OIDC{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		pending: make(map[string]oidcPendingLogin),
+	}
+}
+
+// PostLoginRedirect returns where the browser goes after logging in
+func (o *OIDC) PostLoginRedirect() string {
+	return o.cfg.PostLoginRedirect
+}
+
+// AuthCodeURL starts a login and returns the provider URL to send the
+// browser to, and the state the callback must come back with. The state
+// should be bound to the browser, so that nobody can have a victim's
+// browser finish a login they started.
+func (o *OIDC) AuthCodeURL(ctx context.Context) (string, string, error) {
+	discovery, err := o.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := GenerateRandomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := GenerateRandomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := GenerateRandomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	o.mu.Lock()
+	now := time.Now()
+	for key, login := range o.pending {
+		if now.After(login.expiresAt) {
+			delete(o.pending, key)
+		}
+	}
+	if len(o.pending) >= maxPendingOIDCLogins {
+		o.mu.Unlock()
+		return "", "", fmt.Errorf("%w: too many logins in progress", ErrOIDCLogin)
+	}
+	o.pending[state] = oidcPendingLogin{nonce: nonce, verifier: verifier, expiresAt: now.Add(OIDCLoginTimeout)}
+	o.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.cfg.ClientID},
+		"redirect_uri":          {o.cfg.RedirectURL},
+		"scope":                 {strings.Join(o.scopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), state, nil
+}
+
+// Exchange finishes the login started with state, redeeming code for an
+// ID token and mapping its claims to a user
+func (o *OIDC) Exchange(ctx context.Context, state, code string) (models.User, error) {
+	o.mu.Lock()
+	login, ok := o.pending[state]
+	delete(o.pending, state)
+	o.mu.Unlock()
+	if !ok || time.Now().After(login.expiresAt) {
+		return models.User{}, fmt.Errorf("%w: unknown or expired login state", ErrOIDCLogin)
+	}
+	if code == "" {
+		return models.User{}, fmt.Errorf("%w: missing authorization code", ErrOIDCLogin)
+	}
+
+	discovery, err := o.discover(ctx)
+	if err != nil {
+		return models.User{}, err
+	}
+	rawIDToken, err := o.redeem(ctx, discovery.TokenEndpoint, code, login.verifier)
+	if err != nil {
+		return models.User{}, err
+	}
+	claims, err := o.verifyIDToken(ctx, discovery.Issuer, rawIDToken)
+	if err != nil {
+		return models.User{}, err
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != login.nonce {
+		return models.User{}, fmt.Errorf("%w: ID token nonce does not match", ErrOIDCLogin)
+	}
+	return o.userFromClaims(claims)
+}
+
+func (o *OIDC) scopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range o.cfg.Scopes {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// discover fetches the provider metadata, keeping it once it was read
+func (o *OIDC) discover(ctx context.Context) (*oidcDiscovery, error) {
+	o.mu.Lock()
+	cached := o.discovery
+	o.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := o.getJSON(ctx, o.cfg.IssuerURL+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != o.cfg.IssuerURL {
+		return nil, fmt.Errorf("OIDC discovery: issuer %q does not match %q", discovery.Issuer, o.cfg.IssuerURL)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery: provider metadata is incomplete")
+	}
+
+	o.mu.Lock()
+	o.discovery = &discovery
+	o.mu.Unlock()
+	return &discovery, nil
+}
+
+// redeem exchanges code at the token endpoint and returns the ID token
+func (o *OIDC) redeem(ctx context.Context, endpoint, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"client_id":     {o.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(o.cfg.ClientSecret))
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("OIDC token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("OIDC token request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: token endpoint returned %d: %s", ErrOIDCLogin, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("OIDC token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("%w: token response has no ID token", ErrOIDCLogin)
+	}
+	return token.IDToken, nil
+}
+
+// verifyIDToken checks the signature, issuer, audience and lifetime of an
+// ID token and returns its claims
+func (o *OIDC) verifyIDToken(ctx context.Context, issuer, raw string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return o.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(o.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid ID token: %v", ErrOIDCLogin, err)
+	}
+
+	audience, _ := claims.GetAudience()
+	if azp, ok := claims["azp"].(string); (ok || len(audience) > 1) && azp != o.cfg.ClientID {
+		return nil, fmt.Errorf("%w: ID token was issued to another client", ErrOIDCLogin)
+	}
+	return claims, nil
+}
+
+// key returns the provider key with ID kid, refetching the keys when kid is
+// unknown, e.g. after the provider rotated them
+func (o *OIDC) key(ctx context.Context, kid string) (any, error) {
+	o.mu.Lock()
+	key, ok := o.lookupKey(kid)
+	refresh := !ok && time.Since(o.keysFetched) >= jwksRefreshInterval
+	o.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !refresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	discovery, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := o.getJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("OIDC keys: %w", err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if publicKey, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = publicKey
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.keys = keys
+	o.keysFetched = time.Now()
+	if key, ok := o.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds kid in the cached keys. A token without a key ID may use
+// the only key there is. Must be called with o.mu held.
+func (o *OIDC) lookupKey(kid string) (any, bool) {
+	if key, ok := o.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// userFromClaims maps verified ID token claims to a user
+func (o *OIDC) userFromClaims(claims jwt.MapClaims) (models.User, error) {
+	username := ""
+	for _, claim := range []string{o.cfg.UsernameClaim, "email", "sub"} {
+		if value, ok := claims[claim].(string); ok && strings.TrimSpace(value) != "" {
+			username = strings.TrimSpace(value)
+			break
+		}
+	}
+	if username == "" {
+		return models.User{}, fmt.Errorf("%w: ID token has no username", ErrOIDCLogin)
+	}
+
+	groups := claimStrings(claims[o.cfg.GroupsClaim])
+	role := o.cfg.DefaultRole
+	for _, mapping := range []struct {
+		role   string
+		groups []string
+	}{
+		{models.RoleAdmin, o.cfg.AdminGroups},
+		{models.RoleOperator, o.cfg.OperatorGroups},
+		{models.RoleViewer, o.cfg.ViewerGroups},
+	} {
+		if slices.ContainsFunc(groups, func(group string) bool { return slices.Contains(mapping.groups, group) }) {
+			role = mapping.role
+			break
+		}
+	}
+	if roleRank(role) < 0 {
+		return models.User{}, fmt.Errorf("%w %q", ErrOIDCNoRole, username)
+	}
+	return models.User{Username: username, Role: role, Provider: ProviderOIDC}, nil
+}
+
+// claimStrings reads a claim holding either a string or a list of strings
+func claimStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func (o *OIDC) getJSON(ctx context.Context, target string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// jsonWebKey is the subset of RFC 7517 used by provider signing keys
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("EC point is not on the curve")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
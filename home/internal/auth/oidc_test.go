@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// mockOIDCProvider is a minimal OpenID Connect provider that signs ID tokens
+// for the codes handed out by authorize
+type mockOIDCProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockOIDCCode
+}
+
+type mockOIDCCode struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	p := &mockOIDCProvider{key: key, codes: make(map[string]mockOIDCCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "vps-monitor" || secret != "client-secret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		p.mu.Lock()
+		code, ok := p.codes[r.PostFormValue("code")]
+		delete(p.codes, r.PostFormValue("code"))
+		p.mu.Unlock()
+		verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != code.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, code.claims)
+		token.Header["kid"] = "k1"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": signed})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// authorize plays the user logging in at the provider: it reads the login
+// URL and returns the state and code the provider redirects back with.
+// mutate may change the ID token claims.
+func (p *mockOIDCProvider) authorize(t *testing.T, loginURL string, extra jwt.MapClaims, mutate func(jwt.MapClaims)) (string, string) {
+	t.Helper()
+	parsed, err := url.Parse(loginURL)
+	if err != nil {
+		t.Fatalf("invalid login URL %q: %v", loginURL, err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("redirect_uri") != "https://monitor.example.com/api/v1/auth/oidc/callback" {
+		t.Fatalf("unexpected login URL %q", loginURL)
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   p.URL,
+		"sub":   "user-1",
+		"aud":   "vps-monitor",
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for key, value := range extra {
+		claims[key] = value
+	}
+	if mutate != nil {
+		mutate(claims)
+	}
+
+	code, _ := GenerateRandomHex(8)
+	p.mu.Lock()
+	p.codes[code] = mockOIDCCode{challenge: query.Get("code_challenge"), claims: claims}
+	p.mu.Unlock()
+	return query.Get("state"), code
+}
+
+func newTestOIDC(p *mockOIDCProvider, defaultRole string) *OIDC {
+	return NewOIDC(config.NormalizeOIDCConfig(config.OIDCConfig{
+		IssuerURL:      p.URL + "/",
+		ClientID:       "vps-monitor",
+		ClientSecret:   "client-secret",
+		RedirectURL:    "https://monitor.example.com/api/v1/auth/oidc/callback",
+		AdminGroups:    []string{"ops-admins"},
+		OperatorGroups: []string{"ops"},
+		DefaultRole:    defaultRole,
+	}))
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	oidc := newTestOIDC(provider, "")
+	ctx := context.Background()
+
+	loginURL, _, err := oidc.AuthCodeURL(ctx)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	state, code := provider.authorize(t, loginURL, jwt.MapClaims{"preferred_username": "admin", "groups": []string{"dev", "ops"}}, nil)
+	user, err := oidc.Exchange(ctx, state, code)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if user.Username != "admin" || user.Role != models.RoleOperator || user.Provider != ProviderOIDC {
+		t.Fatalf("unexpected user %+v", user)
+	}
+	if _, err := oidc.Exchange(ctx, state, code); !errors.Is(err, ErrOIDCLogin) {
+		t.Fatalf("expected a replayed state to be rejected, got %v", err)
+	}
+
+	// The JWT keeps the mapped role, even for a name matching the built-in admin
+	svc, _ := newTestServiceWithUsers(t)
+	token, err := svc.GenerateToken(user)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	resolved, err := svc.userForCredential(token)
+	if err != nil || resolved.Role != models.RoleOperator || resolved.Provider != ProviderOIDC {
+		t.Fatalf("userForCredential() = %+v, %v", resolved, err)
+	}
+}
+
+func TestOIDCRejectsInvalidLogins(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		defaultRole string
+		mutate      func(jwt.MapClaims)
+		wantErr     error
+		wantRole    string
+	}{
+		"other audience": {mutate: func(c jwt.MapClaims) { c["aud"] = "someone-else" }, wantErr: ErrOIDCLogin},
+		"other issuer":   {mutate: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, wantErr: ErrOIDCLogin},
+		"wrong nonce":    {mutate: func(c jwt.MapClaims) { c["nonce"] = "replayed" }, wantErr: ErrOIDCLogin},
+		"expired":        {mutate: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, wantErr: ErrOIDCLogin},
+		"no group":       {mutate: func(c jwt.MapClaims) { delete(c, "groups") }, wantErr: ErrOIDCNoRole},
+		"default role":   {defaultRole: models.RoleViewer, mutate: func(c jwt.MapClaims) { c["groups"] = "dev" }, wantRole: models.RoleViewer},
+		"highest group":  {mutate: func(c jwt.MapClaims) { c["groups"] = []string{"ops", "ops-admins"} }, wantRole: models.RoleAdmin},
+	} {
+		oidc := newTestOIDC(provider, tc.defaultRole)
+		loginURL, _, err := oidc.AuthCodeURL(ctx)
+		if err != nil {
+			t.Fatalf("%s: AuthCodeURL() error = %v", name, err)
+		}
+		state, code := provider.authorize(t, loginURL, jwt.MapClaims{"email": "dev@example.com", "groups": []string{"dev"}}, tc.mutate)
+		user, err := oidc.Exchange(ctx, state, code)
+		if tc.wantErr != nil {
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected %v, got %+v, %v", name, tc.wantErr, user, err)
+			}
+			continue
+		}
+		if err != nil || user.Username != "dev@example.com" || user.Role != tc.wantRole {
+			t.Fatalf("%s: Exchange() = %+v, %v", name, user, err)
+		}
+	}
+
+	oidc := newTestOIDC(provider, "")
+	loginURL, _, err := oidc.AuthCodeURL(ctx)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	state, _ := provider.authorize(t, loginURL, nil, nil)
+	if _, err := oidc.Exchange(ctx, state, "stolen-code"); !errors.Is(err, ErrOIDCLogin) {
+		t.Fatalf("expected an unknown code to be rejected, got %v", err)
+	}
+}
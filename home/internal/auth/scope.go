@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// Scoped reports whether user is limited to the containers its grants
+// match. Grants do not apply to admins.
+func Scoped(user models.User) bool {
+	return user.Role != models.RoleAdmin && len(user.Grants) > 0
+}
+
+// CanAccessContainer reports whether user may see and act on ctr
+func CanAccessContainer(user models.User, ctr models.ContainerInfo) bool {
+	if !Scoped(user) {
+		return true
+	}
+	name := ctr.ID
+	if len(ctr.Names) > 0 {
+		name = strings.TrimPrefix(ctr.Names[0], "/")
+	}
+	for _, grant := range user.Grants {
+		if grantMatches(grant, ctr.Host, name, ctr.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessHost reports whether any of user's grants may match containers
+// on host
+func CanAccessHost(user models.User, host string) bool {
+	if !Scoped(user) {
+		return true
+	}
+	for _, grant := range user.Grants {
+		if grant.Host == "" || grant.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessAlert reports whether user may see alert. Container alerts
+// follow the container grants, matched against labels, the labels of the
+// container when known; other alerts need access to their host, so alerts
+// without a host, such as login lockouts, are hidden from scoped users.
+func CanAccessAlert(user models.User, alert models.Alert, labels map[string]string) bool {
+	if !Scoped(user) {
+		return true
+	}
+	if alert.Host == "" {
+		return false
+	}
+	if alert.ContainerID == "" {
+		return CanAccessHost(user, alert.Host)
+	}
+
+	ctr := models.ContainerInfo{Host: alert.Host, ID: alert.ContainerID, Labels: labels}
+	if alert.ContainerName != "" {
+		ctr.Names = []string{alert.ContainerName}
+	}
+	return CanAccessContainer(user, ctr)
+}
+
+// CanAccessMatcher reports whether user may see a rule, silence or
+// maintenance window selecting alerts by host, container and label, where
+// empty fields match everything. Matchers that only narrow by host need
+// access to the host; others must fall within one of user's grants.
+func CanAccessMatcher(user models.User, host, container, label string) bool {
+	if !Scoped(user) {
+		return true
+	}
+	if container == "" && label == "" {
+		return host == "" || CanAccessHost(user, host)
+	}
+
+	var labels map[string]string
+	if label != "" {
+		key, value, _ := strings.Cut(label, "=")
+		labels = map[string]string{key: value}
+	}
+	for _, grant := range user.Grants {
+		if grantMatches(grant, host, container, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+func grantMatches(grant models.PermissionGrant, host, name string, labels map[string]string) bool {
+	if grant.Host != "" && grant.Host != host {
+		return false
+	}
+	if grant.Container != "" {
+		if ok, _ := path.Match(grant.Container, name); !ok {
+			return false
+		}
+	}
+	if grant.Label != "" {
+		key, want, hasValue := strings.Cut(grant.Label, "=")
+		got, ok := labels[key]
+		if !ok || (hasValue && got != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeGrants trims and validates grants. A grant must narrow access
+// by at least one field.
+func normalizeGrants(grants []models.PermissionGrant) ([]models.PermissionGrant, error) {
+	if len(grants) == 0 {
+		return nil, nil
+	}
+	normalized := make([]models.PermissionGrant, 0, len(grants))
+	for _, grant := range grants {
+		grant.Host = strings.TrimSpace(grant.Host)
+		grant.Container = strings.TrimSpace(grant.Container)
+		grant.Label = strings.TrimSpace(grant.Label)
+		if grant == (models.PermissionGrant{}) {
+			return nil, fmt.Errorf("%w: a grant needs a host, container or label", ErrInvalidUser)
+		}
+		if _, err := path.Match(grant.Container, ""); err != nil {
+			return nil, fmt.Errorf("%w: invalid container pattern %q", ErrInvalidUser, grant.Container)
+		}
+		if strings.HasPrefix(grant.Label, "=") {
+			return nil, fmt.Errorf("%w: label %q has no key", ErrInvalidUser, grant.Label)
+		}
+		normalized = append(normalized, grant)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestCanAccessContainer(t *testing.T) {
+	shop := models.ContainerInfo{
+		ID:     "abc",
+		Names:  []string{"/shop-web-1"},
+		Host:   "prod",
+		Labels: map[string]string{"com.docker.compose.project": "shop"},
+	}
+	blog := models.ContainerInfo{ID: "def", Names: []string{"/blog"}, Host: "prod"}
+	staging := models.ContainerInfo{ID: "ghi", Names: []string{"/shop-web-1"}, Host: "staging"}
+
+	team := models.User{Role: models.RoleOperator, Grants: []models.PermissionGrant{
+		{Host: "prod", Label: "com.docker.compose.project=shop"},
+		{Container: "blog*"},
+	}}
+	for _, tc := range []struct {
+		user models.User
+		ctr  models.ContainerInfo
+		want bool
+	}{
+		{team, shop, true},
+		{team, blog, true},
+		{team, staging, false},
+		{models.User{Role: models.RoleViewer}, staging, true},
+		{models.User{Role: models.RoleAdmin, Grants: team.Grants}, staging, true},
+		{models.User{Role: models.RoleViewer, Grants: []models.PermissionGrant{{Label: "com.docker.compose.project"}}}, blog, false},
+	} {
+		if got := CanAccessContainer(tc.user, tc.ctr); got != tc.want {
+			t.Fatalf("CanAccessContainer(%+v, %s) = %v, want %v", tc.user.Grants, tc.ctr.Names[0]+"@"+tc.ctr.Host, got, tc.want)
+		}
+	}
+
+	hostOnly := models.User{Role: models.RoleViewer, Grants: []models.PermissionGrant{{Host: "prod"}}}
+	if !CanAccessHost(hostOnly, "prod") || CanAccessHost(hostOnly, "staging") || !CanAccessHost(team, "staging") {
+		t.Fatal("unexpected host access")
+	}
+}
+
+func TestCanAccessAlert(t *testing.T) {
+	team := models.User{Role: models.RoleOperator, Grants: []models.PermissionGrant{{Host: "prod", Label: "com.docker.compose.project=shop"}}}
+	shopLabels := map[string]string{"com.docker.compose.project": "shop"}
+	for _, tc := range []struct {
+		alert  models.Alert
+		labels map[string]string
+		want   bool
+	}{
+		{models.Alert{Host: "prod", ContainerID: "abc", ContainerName: "shop-web-1"}, shopLabels, true},
+		{models.Alert{Host: "prod", ContainerID: "abc", ContainerName: "shop-web-1"}, nil, false},
+		{models.Alert{Host: "staging", ContainerID: "ghi", ContainerName: "shop-web-1"}, shopLabels, false},
+		{models.Alert{Host: "prod", Type: models.AlertHostDown}, nil, true},
+		{models.Alert{Host: "staging", Type: models.AlertHostDown}, nil, false},
+		{models.Alert{Type: models.AlertLoginLockout}, nil, false},
+	} {
+		if got := CanAccessAlert(team, tc.alert, tc.labels); got != tc.want {
+			t.Fatalf("CanAccessAlert(%+v, %v) = %v, want %v", tc.alert, tc.labels, got, tc.want)
+		}
+	}
+	if !CanAccessAlert(models.User{Role: models.RoleViewer}, models.Alert{Type: models.AlertLoginLockout}, nil) {
+		t.Fatal("users without grants should see every alert")
+	}
+}
+
+func TestCanAccessMatcher(t *testing.T) {
+	team := models.User{Role: models.RoleOperator, Grants: []models.PermissionGrant{{Host: "prod", Container: "shop-*"}}}
+	for _, tc := range []struct {
+		host, container, label string
+		want                   bool
+	}{
+		{"", "", "", true},
+		{"prod", "", "", true},
+		{"staging", "", "", false},
+		{"prod", "shop-web-1", "", true},
+		{"prod", "shop-*", "", true},
+		{"prod", "billing", "", false},
+		{"", "shop-web-1", "", false},
+		{"prod", "", "com.docker.compose.project=shop", false},
+	} {
+		if got := CanAccessMatcher(team, tc.host, tc.container, tc.label); got != tc.want {
+			t.Fatalf("CanAccessMatcher(%q, %q, %q) = %v, want %v", tc.host, tc.container, tc.label, got, tc.want)
+		}
+	}
+}
+
+func TestUsersValidateGrants(t *testing.T) {
+	users := NewUsers(nil)
+	for name, grant := range map[string]models.PermissionGrant{
+		"empty":       {Host: " "},
+		"bad pattern": {Container: "["},
+		"no key":      {Label: "=shop"},
+	} {
+		if _, err := users.Create("alice", "password1", models.RoleViewer, []models.PermissionGrant{grant}); !errors.Is(err, ErrInvalidUser) {
+			t.Fatalf("%s: expected ErrInvalidUser, got %v", name, err)
+		}
+	}
+
+	user, err := users.Create("alice", "password1", models.RoleViewer, []models.PermissionGrant{{Host: " prod "}})
+	if err != nil || user.Grants[0].Host != "prod" {
+		t.Fatalf("Create() = %+v, %v", user, err)
+	}
+	cleared := []models.PermissionGrant{}
+	if user, err = users.Update("alice", UserChanges{Grants: &cleared}); err != nil || user.Grants != nil {
+		t.Fatalf("expected grants to be cleared, got %+v, %v", user, err)
+	}
+}
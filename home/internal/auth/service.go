@@ -4,8 +4,10 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -28,11 +30,23 @@ type Service struct {
 	adminPasswordHash string
 	tokenExpiration   time.Duration
 	disabled          bool
+	users             *Users
+	tokens            *Tokens
+	sessions          *Sessions
+	twoFactor         *TwoFactor
+}
+
+// LoginTokens are returned by a login or a refresh
+type LoginTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in"` // seconds until Token expires
 }
 
 type Claims struct {
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	Provider string `json:"provider,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -74,40 +88,210 @@ func (s *Service) IsUsable() bool {
 	return s != nil && !s.disabled && len(s.jwtSecret) > 0 && s.adminUsername != "" && s.adminPasswordHash != ""
 }
 
+// LookupUser returns the current role and grants of the built-in admin or
+// a user account. It reports false for unknown users, which includes single
+// sign-on users as they have no account. With authentication disabled
+// everyone is an admin.
+func (s *Service) LookupUser(username string) (models.User, bool, error) {
+	switch {
+	case s == nil:
+		return models.User{}, false, nil
+	case s.disabled:
+		return models.User{Username: username, Role: models.RoleAdmin}, true, nil
+	case username == s.adminUsername:
+		return models.User{Username: username, Role: models.RoleAdmin}, true, nil
+	case s.users == nil:
+		return models.User{}, false, nil
+	}
+	account, err := s.users.Get(username)
+	if err != nil || account == nil {
+		return models.User{}, false, err
+	}
+	return models.User{Username: account.Username, Role: account.Role, Grants: account.Grants}, true, nil
+}
+
+// SetUsers sets the user accounts that can log in next to the built-in admin
+func (s *Service) SetUsers(users *Users) {
+	s.users = users
+}
+
+// SetTokens sets the API tokens accepted next to JWTs
+func (s *Service) SetTokens(tokens *Tokens) {
+	s.tokens = tokens
+}
+
+// SetSessions sets the session registry. Without one, tokens cannot be
+// refreshed or revoked and live for the full token expiration.
+func (s *Service) SetSessions(sessions *Sessions) {
+	s.sessions = sessions
+}
+
+// SetTwoFactor sets the TOTP enrollments checked after a password login
+func (s *Service) SetTwoFactor(twoFactor *TwoFactor) {
+	s.twoFactor = twoFactor
+}
+
+// StartTwoFactor returns a challenge when user, who logged in with a
+// password, must still enter a two-factor code, or "" when the password is
+// enough
+func (s *Service) StartTwoFactor(user models.User) (string, error) {
+	if s.twoFactor == nil {
+		return "", nil
+	}
+	return s.twoFactor.StartLogin(user)
+}
+
+// FinishTwoFactor checks the code for a challenge from StartTwoFactor and
+// returns the user logging in
+func (s *Service) FinishTwoFactor(challenge, code string) (models.User, error) {
+	if s.twoFactor == nil {
+		return models.User{}, fmt.Errorf("%w: two-factor authentication is not available", ErrInvalidTwoFactor)
+	}
+	return s.twoFactor.FinishLogin(challenge, code)
+}
+
+// AdminUsername returns the name of the built-in admin configured through
+// ADMIN_USERNAME or the auth settings, or "" when authentication is off
+func (s *Service) AdminUsername() string {
+	if !s.IsUsable() {
+		return ""
+	}
+	return s.adminUsername
+}
+
 // ValidateCredentials checks if the provided credentials are valid
 func (s *Service) ValidateCredentials(username, password string) error {
+	_, err := s.Authenticate(username, password)
+	return err
+}
+
+// Authenticate checks the credentials of the built-in admin or a user
+// account and returns the user they belong to
+func (s *Service) Authenticate(username, password string) (models.User, error) {
 	if !s.IsUsable() {
-		return ErrInvalidCredentials
+		return models.User{}, ErrInvalidCredentials
 	}
 
-	if username != s.adminUsername {
-		return ErrInvalidCredentials
+	if username == s.adminUsername {
+		if isBcryptHash(s.adminPasswordHash) {
+			if err := bcrypt.CompareHashAndPassword([]byte(s.adminPasswordHash), []byte(password)); err != nil {
+				return models.User{}, ErrInvalidCredentials
+			}
+			return models.User{Username: username, Role: models.RoleAdmin}, nil
+		}
+		return models.User{}, ErrInvalidCredentials
 	}
 
-	if isBcryptHash(s.adminPasswordHash) {
-		if err := bcrypt.CompareHashAndPassword([]byte(s.adminPasswordHash), []byte(password)); err != nil {
-			return ErrInvalidCredentials
+	var account *models.UserAccount
+	if s.users != nil {
+		account, _ = s.users.Get(username)
+	}
+	if account == nil {
+		// Unknown users take as long as wrong passwords, so response times
+		// do not tell which accounts exist
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return models.User{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)); err != nil {
+		return models.User{}, ErrInvalidCredentials
+	}
+	return models.User{Username: account.Username, Role: account.Role, Grants: account.Grants}, nil
+}
+
+// dummyPasswordHash is compared against for unknown users, at the cost of
+// real password hashes
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	// Only fails for passwords over 72 bytes
+	hash, _ := bcrypt.GenerateFromPassword([]byte("vps-monitor-unknown-user"), bcrypt.DefaultCost)
+	return hash
+})
+
+// GenerateToken creates a new JWT token for the user
+func (s *Service) GenerateToken(user models.User) (string, error) {
+	return s.generateToken(user, "", s.tokenExpiration)
+}
+
+// StartSession logs user in, returning an access token and, when sessions
+// are configured, a refresh token
+func (s *Service) StartSession(user models.User, userAgent, remoteAddr string) (LoginTokens, error) {
+	if s.sessions == nil {
+		token, err := s.GenerateToken(user)
+		if err != nil {
+			return LoginTokens{}, err
 		}
-		return nil
+		return LoginTokens{Token: token, ExpiresIn: int64(s.tokenExpiration / time.Second)}, nil
 	}
 
-	return ErrInvalidCredentials
+	refresh, session, err := s.sessions.Create(user, userAgent, remoteAddr)
+	if err != nil {
+		return LoginTokens{}, err
+	}
+	return s.sessionTokens(user, session.ID, refresh)
 }
 
-// GenerateToken creates a new JWT token for the user
-func (s *Service) GenerateToken(username string) (string, error) {
+// RefreshSession redeems a refresh token for a new access token and a new
+// refresh token
+func (s *Service) RefreshSession(refreshToken string) (LoginTokens, models.User, error) {
+	if s.sessions == nil {
+		return LoginTokens{}, models.User{}, ErrInvalidToken
+	}
+	refresh, session, err := s.sessions.Refresh(refreshToken)
+	if err != nil {
+		return LoginTokens{}, models.User{}, err
+	}
+
+	user, err := s.ResolveUser(&Claims{Username: session.Username, Role: session.Role, Provider: session.Provider})
+	if errors.Is(err, ErrInvalidToken) {
+		// The user was deleted
+		if _, err := s.sessions.Revoke(session.ID); err != nil {
+			return LoginTokens{}, models.User{}, err
+		}
+		return LoginTokens{}, models.User{}, ErrInvalidToken
+	}
+	if err != nil {
+		return LoginTokens{}, models.User{}, err
+	}
+	user.SessionID = session.ID
+
+	tokens, err := s.sessionTokens(user, session.ID, refresh)
+	return tokens, user, err
+}
+
+// EndSession revokes a session and the access tokens issued for it.
+// Returns false when there is no such session.
+func (s *Service) EndSession(sessionID string) (bool, error) {
+	if s.sessions == nil {
+		return false, nil
+	}
+	return s.sessions.Revoke(sessionID)
+}
+
+func (s *Service) sessionTokens(user models.User, sessionID, refresh string) (LoginTokens, error) {
+	ttl := s.sessions.AccessTokenTTL()
+	token, err := s.generateToken(user, sessionID, ttl)
+	if err != nil {
+		return LoginTokens{}, err
+	}
+	return LoginTokens{Token: token, RefreshToken: refresh, ExpiresIn: int64(ttl / time.Second)}, nil
+}
+
+// generateToken signs a JWT for user. Tokens of a session carry its ID as
+// jti, which is what revocation denies.
+func (s *Service) generateToken(user models.User, sessionID string, ttl time.Duration) (string, error) {
 	now := time.Now()
-	expirationTime := now.Add(s.tokenExpiration)
+	expirationTime := now.Add(ttl)
 
 	claims := &Claims{
-		Username: username,
-		Role:     "admin", // For now, all users are admins
+		Username: user.Username,
+		Role:     user.Role,
+		Provider: user.Provider,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "vps-monitor",
-			Subject:   username,
+			Subject:   user.Username,
+			ID:        sessionID,
 		},
 	}
 
@@ -153,6 +337,84 @@ func GetUserFromClaims(claims *Claims) models.User {
 	}
 }
 
+// ResolveUser returns the current user for verified claims. The role is
+// looked up again so role changes and deleted accounts take effect before
+// the token expires. Single sign-on users keep the role mapped at login.
+func (s *Service) ResolveUser(claims *Claims) (models.User, error) {
+	if claims.Provider != "" {
+		if claims.Provider != ProviderOIDC {
+			return models.User{}, ErrInvalidToken
+		}
+		if roleRank(claims.Role) < 0 {
+			return models.User{}, ErrInvalidToken
+		}
+		return models.User{Username: claims.Username, Role: claims.Role, Provider: claims.Provider}, nil
+	}
+	if claims.Username == s.adminUsername {
+		return models.User{Username: claims.Username, Role: models.RoleAdmin}, nil
+	}
+	if s.users == nil {
+		return models.User{}, ErrInvalidToken
+	}
+	account, err := s.users.Get(claims.Username)
+	if err != nil {
+		return models.User{}, err
+	}
+	if account == nil {
+		return models.User{}, ErrInvalidToken
+	}
+	return models.User{Username: account.Username, Role: account.Role, Grants: account.Grants}, nil
+}
+
+// ResolveAPIToken returns the owner of an API token, limited to the
+// token's scopes
+func (s *Service) ResolveAPIToken(secret string) (models.User, error) {
+	if s.tokens == nil {
+		return models.User{}, ErrInvalidToken
+	}
+	token, err := s.tokens.Authenticate(secret)
+	if err != nil {
+		return models.User{}, err
+	}
+	user, err := s.ResolveUser(&Claims{Username: token.Username})
+	if err != nil {
+		return models.User{}, err
+	}
+	user.TokenScopes = token.Scopes
+	return user, nil
+}
+
+// userForCredential returns the user a JWT or an API token belongs to
+func (s *Service) userForCredential(credential string) (models.User, error) {
+	if IsAPIToken(credential) {
+		return s.ResolveAPIToken(credential)
+	}
+	claims, err := s.VerifyToken(credential)
+	if err != nil {
+		return models.User{}, err
+	}
+	if s.sessions != nil {
+		// Tokens from before sessions cannot be revoked and are not accepted
+		if claims.ID == "" {
+			return models.User{}, ErrInvalidToken
+		}
+		denied, err := s.sessions.IsDenied(claims.ID)
+		if err != nil {
+			return models.User{}, err
+		}
+		if denied {
+			return models.User{}, ErrInvalidToken
+		}
+	}
+
+	user, err := s.ResolveUser(claims)
+	if err != nil {
+		return models.User{}, err
+	}
+	user.SessionID = claims.ID
+	return user, nil
+}
+
 // NewServiceFromFileConfig creates an auth service from file-based config.
 // Returns a disabled service when the config is nil, disabled, or incomplete.
 func NewServiceFromFileConfig(cfg *config.FileAuthConfig) *Service {
@@ -5,6 +5,8 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
 )
 
 func TestValidateCredentialsSupportsBcrypt(t *testing.T) {
@@ -80,7 +82,7 @@ func TestDynamicMiddlewareUsesLastKnownGoodService(t *testing.T) {
 		adminPasswordHash: hash,
 		tokenExpiration:   time.Hour,
 	}
-	token, err := svc.GenerateToken("admin")
+	token, err := svc.GenerateToken(models.User{Username: "admin", Role: models.RoleAdmin})
 	if err != nil {
 		t.Fatalf("GenerateToken failed: %v", err)
 	}
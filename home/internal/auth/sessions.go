@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// maxUserAgentLength bounds the user agent kept with a session
+const maxUserAgentLength = 256
+
+// SessionStore persists login sessions and the token denylist
+type SessionStore interface {
+	ListSessions() ([]models.Session, error)
+	GetSessionByRefreshHash(hash string) (*models.Session, error)
+	SaveSession(session models.Session) error
+	DeleteSession(id string) (bool, error)
+	DeleteExpiredSessions(now int64) error
+	ListDeniedTokens() (map[string]int64, error)
+	DenyToken(jti string, expiresAt int64) error
+	DeleteExpiredDeniedTokens(now int64) error
+}
+
+// Sessions tracks logins, either in a persistent store or, when none is
+// configured, in memory. Access tokens carry their session's ID as jti;
+// revoking a session puts that ID on a denylist until the last access token
+// issued for it has expired.
+type Sessions struct {
+	store SessionStore
+	cfg   config.SessionConfig
+
+	mu       sync.Mutex
+	sessions []models.Session
+	// denied caches the denylist, loaded from the store on first use
+	denied map[string]int64
+}
+
+// NewSessions creates a session registry backed by store. A nil store
+// keeps sessions in memory.
+func NewSessions(store SessionStore, cfg config.SessionConfig) *Sessions {
+	return &Sessions{store: store, cfg: cfg}
+}
+
+// AccessTokenTTL returns the lifetime of access tokens
+func (s *Sessions) AccessTokenTTL() time.Duration {
+	return s.cfg.AccessTokenTTL
+}
+
+// Create starts a session for user and returns its refresh token, which is
+// not stored and cannot be shown again
+func (s *Sessions) Create(user models.User, userAgent, remoteAddr string) (string, *models.Session, error) {
+	secret, err := GenerateRandomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:          uuid.New().String(),
+		Username:    user.Username,
+		Role:        user.Role,
+		Provider:    user.Provider,
+		RefreshHash: hashSecret(secret),
+		UserAgent:   userAgent,
+		RemoteAddr:  remoteAddr,
+		CreatedAt:   now.Unix(),
+		LastUsedAt:  now.Unix(),
+		ExpiresAt:   now.Add(s.cfg.SessionTTL).Unix(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.deleteExpired(now.Unix()); err != nil {
+		return "", nil, err
+	}
+	if err := s.save(session); err != nil {
+		return "", nil, err
+	}
+	return secret, &session, nil
+}
+
+// Refresh replaces the refresh token secret with a new one and returns it
+// with its session. Each refresh token works once.
+func (s *Sessions) Refresh(secret string) (string, *models.Session, error) {
+	next, err := GenerateRandomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Held throughout so two requests cannot redeem the same token
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.byRefreshHash(hashSecret(secret))
+	if err != nil {
+		return "", nil, err
+	}
+	if session == nil {
+		return "", nil, ErrInvalidToken
+	}
+	now := time.Now().Unix()
+	if session.ExpiresAt <= now {
+		if _, err := s.delete(session.ID); err != nil {
+			return "", nil, err
+		}
+		return "", nil, ErrTokenExpired
+	}
+
+	session.RefreshHash = hashSecret(next)
+	session.LastUsedAt = now
+	if err := s.save(*session); err != nil {
+		return "", nil, err
+	}
+	return next, session, nil
+}
+
+// List returns the sessions that have not expired
+func (s *Sessions) List() ([]models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.deleteExpired(time.Now().Unix()); err != nil {
+		return nil, err
+	}
+	return s.all()
+}
+
+// Revoke ends a session and denies the access tokens issued for it.
+// Returns false when there is no session with that ID.
+func (s *Sessions) Revoke(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ok, err := s.delete(id)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return true, s.deny(id, time.Now().Add(s.cfg.AccessTokenTTL).Unix())
+}
+
+// RevokeUser ends every session of username, e.g. when the user is deleted
+func (s *Sessions) RevokeUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions, err := s.all()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.cfg.AccessTokenTTL).Unix()
+	for _, session := range sessions {
+		if session.Username != username {
+			continue
+		}
+		if _, err := s.delete(session.ID); err != nil {
+			return err
+		}
+		if err := s.deny(session.ID, expiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsDenied reports whether access tokens with ID jti were revoked
+func (s *Sessions) IsDenied(jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadDenied(); err != nil {
+		return false, err
+	}
+	expiresAt, ok := s.denied[jti]
+	return ok && expiresAt > time.Now().Unix(), nil
+}
+
+// loadDenied fills the denylist cache. Must be called with s.mu held.
+func (s *Sessions) loadDenied() error {
+	if s.denied != nil {
+		return nil
+	}
+	if s.store == nil {
+		s.denied = make(map[string]int64)
+		return nil
+	}
+	denied, err := s.store.ListDeniedTokens()
+	if err != nil {
+		return err
+	}
+	s.denied = denied
+	return nil
+}
+
+// deny adds jti to the denylist and drops entries that no longer matter.
+// Must be called with s.mu held.
+func (s *Sessions) deny(jti string, expiresAt int64) error {
+	if err := s.loadDenied(); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	if s.store != nil {
+		if err := s.store.DenyToken(jti, expiresAt); err != nil {
+			return err
+		}
+		if err := s.store.DeleteExpiredDeniedTokens(now); err != nil {
+			return err
+		}
+	}
+	s.denied[jti] = expiresAt
+	for id, until := range s.denied {
+		if until <= now {
+			delete(s.denied, id)
+		}
+	}
+	return nil
+}
+
+func (s *Sessions) all() ([]models.Session, error) {
+	if s.store != nil {
+		return s.store.ListSessions()
+	}
+	return slices.Clone(s.sessions), nil
+}
+
+func (s *Sessions) byRefreshHash(hash string) (*models.Session, error) {
+	if s.store != nil {
+		return s.store.GetSessionByRefreshHash(hash)
+	}
+	for _, session := range s.sessions {
+		if session.RefreshHash == hash {
+			return &session, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Sessions) save(session models.Session) error {
+	if s.store != nil {
+		return s.store.SaveSession(session)
+	}
+	for i := range s.sessions {
+		if s.sessions[i].ID == session.ID {
+			s.sessions[i] = session
+			return nil
+		}
+	}
+	s.sessions = append(s.sessions, session)
+	return nil
+}
+
+func (s *Sessions) delete(id string) (bool, error) {
+	if s.store != nil {
+		return s.store.DeleteSession(id)
+	}
+	for i := range s.sessions {
+		if s.sessions[i].ID == id {
+			s.sessions = slices.Delete(s.sessions, i, i+1)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Sessions) deleteExpired(now int64) error {
+	if s.store != nil {
+		return s.store.DeleteExpiredSessions(now)
+	}
+	s.sessions = slices.DeleteFunc(s.sessions, func(session models.Session) bool {
+		return session.ExpiresAt <= now
+	})
+	return nil
+}
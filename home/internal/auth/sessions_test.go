@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func newTestServiceWithSessions(t *testing.T) (*Service, *Users, *Sessions) {
+	t.Helper()
+	svc, users := newTestServiceWithUsers(t)
+	sessions := NewSessions(nil, config.SessionConfig{AccessTokenTTL: 15 * time.Minute, SessionTTL: time.Hour})
+	svc.SetSessions(sessions)
+	return svc, users, sessions
+}
+
+func TestSessionRefreshRotatesTokens(t *testing.T) {
+	svc, _, sessions := newTestServiceWithSessions(t)
+	admin := models.User{Username: "admin", Role: models.RoleAdmin}
+
+	login, err := svc.StartSession(admin, "curl/8", "203.0.113.7")
+	if err != nil {
+		t.Fatalf("StartSession() error = %v", err)
+	}
+	if login.RefreshToken == "" || login.ExpiresIn != int64((15*time.Minute)/time.Second) {
+		t.Fatalf("unexpected login tokens %+v", login)
+	}
+	user, err := svc.userForCredential(login.Token)
+	if err != nil || user.SessionID == "" {
+		t.Fatalf("userForCredential() = %+v, %v", user, err)
+	}
+
+	refreshed, refreshedUser, err := svc.RefreshSession(login.RefreshToken)
+	if err != nil || refreshed.RefreshToken == login.RefreshToken || refreshedUser.SessionID != user.SessionID {
+		t.Fatalf("RefreshSession() = %+v, %+v, %v", refreshed, refreshedUser, err)
+	}
+	if _, _, err := svc.RefreshSession(login.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a used refresh token to be rejected, got %v", err)
+	}
+
+	sessions.mu.Lock()
+	sessions.sessions[0].ExpiresAt = time.Now().Unix()
+	sessions.mu.Unlock()
+	if _, _, err := svc.RefreshSession(refreshed.RefreshToken); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("expected an expired session to be rejected, got %v", err)
+	}
+	if list, _ := sessions.List(); len(list) != 0 {
+		t.Fatalf("expected the expired session to be removed, got %+v", list)
+	}
+}
+
+func TestRevokedSessionsDenyAccessTokens(t *testing.T) {
+	svc, users, sessions := newTestServiceWithSessions(t)
+	if _, err := users.Create("alice", "password1", models.RoleViewer, nil); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	alice := models.User{Username: "alice", Role: models.RoleViewer}
+
+	first, err := svc.StartSession(alice, "", "")
+	if err != nil {
+		t.Fatalf("StartSession() error = %v", err)
+	}
+	second, err := svc.StartSession(alice, "", "")
+	if err != nil {
+		t.Fatalf("StartSession() error = %v", err)
+	}
+	user, err := svc.userForCredential(first.Token)
+	if err != nil {
+		t.Fatalf("userForCredential() error = %v", err)
+	}
+
+	if ok, err := svc.EndSession(user.SessionID); err != nil || !ok {
+		t.Fatalf("EndSession() = %v, %v", ok, err)
+	}
+	if _, err := svc.userForCredential(first.Token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a logged out token to be rejected, got %v", err)
+	}
+	if _, err := svc.userForCredential(second.Token); err != nil {
+		t.Fatalf("expected the other session to stay valid, got %v", err)
+	}
+
+	if err := sessions.RevokeUser("alice"); err != nil {
+		t.Fatalf("RevokeUser() error = %v", err)
+	}
+	if _, err := svc.userForCredential(second.Token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a revoked user's token to be rejected, got %v", err)
+	}
+	if _, _, err := svc.RefreshSession(second.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a revoked session not to refresh, got %v", err)
+	}
+
+	// Tokens issued without a session cannot be revoked
+	legacy, err := svc.GenerateToken(alice)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	if _, err := svc.userForCredential(legacy); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a token without a session to be rejected, got %v", err)
+	}
+}
+
+func TestRefreshSessionOfDeletedUser(t *testing.T) {
+	svc, users, sessions := newTestServiceWithSessions(t)
+	if _, err := users.Create("alice", "password1", models.RoleViewer, nil); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	login, err := svc.StartSession(models.User{Username: "alice", Role: models.RoleViewer}, "", "")
+	if err != nil {
+		t.Fatalf("StartSession() error = %v", err)
+	}
+	if _, err := users.Delete("alice"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, _, err := svc.RefreshSession(login.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a deleted user's session not to refresh, got %v", err)
+	}
+	if list, _ := sessions.List(); len(list) != 0 {
+		t.Fatalf("expected the session to be revoked, got %+v", list)
+	}
+}
@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const (
+	// apiTokenPrefix starts every API token, which tells them apart from JWTs
+	apiTokenPrefix = "vpsm_"
+	// apiTokenDisplayLength is how much of a token is kept to identify it
+	apiTokenDisplayLength = len(apiTokenPrefix) + 8
+	// lastUsedInterval limits how often using a token is written back
+	lastUsedInterval = time.Minute
+)
+
+// ErrInvalidAPIToken is returned when an API token request fails validation
+var ErrInvalidAPIToken = errors.New("invalid API token")
+
+// TokenStore persists API tokens
+type TokenStore interface {
+	ListAPITokens() ([]models.APIToken, error)
+	GetAPITokenByHash(hash string) (*models.APIToken, error)
+	SaveAPIToken(token models.APIToken) error
+	DeleteAPIToken(id string) (bool, error)
+	SetAPITokenLastUsed(id string, at int64) error
+}
+
+// Tokens holds the API tokens users created, either in a persistent store
+// or, when none is configured, in memory
+type Tokens struct {
+	store  TokenStore
+	mu     sync.RWMutex
+	tokens []models.APIToken
+}
+
+// NewTokens creates an API token registry backed by store. A nil store
+// keeps tokens in memory.
+func NewTokens(store TokenStore) *Tokens {
+	return &Tokens{store: store}
+}
+
+// IsAPIToken reports whether credential looks like an API token rather
+// than a JWT
+func IsAPIToken(credential string) bool {
+	return strings.HasPrefix(credential, apiTokenPrefix)
+}
+
+// Create issues a token for owner. expiresAt is a Unix time, 0 for a token
+// that does not expire. The returned secret is not stored and cannot be
+// shown again.
+func (t *Tokens) Create(owner models.User, name string, scopes []string, expiresAt int64) (string, *models.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", nil, fmt.Errorf("%w: name must be 1-100 characters", ErrInvalidAPIToken)
+	}
+	scopes, err := normalizeScopes(owner.Role, scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	if expiresAt != 0 && expiresAt <= now.Unix() {
+		return "", nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIToken)
+	}
+
+	random, err := GenerateRandomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+	secret := apiTokenPrefix + random
+	token := models.APIToken{
+		ID:        uuid.New().String(),
+		Username:  owner.Username,
+		Name:      name,
+		Scopes:    scopes,
+		Prefix:    secret[:apiTokenDisplayLength],
+		TokenHash: hashSecret(secret),
+		CreatedAt: now.Unix(),
+		ExpiresAt: expiresAt,
+	}
+	if err := t.save(token); err != nil {
+		return "", nil, err
+	}
+	return secret, &token, nil
+}
+
+// List returns the tokens of username
+func (t *Tokens) List(username string) ([]models.APIToken, error) {
+	all, err := t.all()
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]models.APIToken, 0)
+	for _, token := range all {
+		if token.Username == username {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+// Revoke deletes one of username's tokens. Returns false when username has
+// no token with that ID.
+func (t *Tokens) Revoke(username, id string) (bool, error) {
+	tokens, err := t.List(username)
+	if err != nil {
+		return false, err
+	}
+	if !slices.ContainsFunc(tokens, func(token models.APIToken) bool { return token.ID == id }) {
+		return false, nil
+	}
+	return t.delete(id)
+}
+
+// RevokeAll deletes every token of username, e.g. when the user is deleted
+func (t *Tokens) RevokeAll(username string) error {
+	tokens, err := t.List(username)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if _, err := t.delete(token.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeOutsideRole deletes the tokens of username that have a scope role
+// may not use, e.g. after the user was demoted, and returns how many
+func (t *Tokens) RevokeOutsideRole(username, role string) (int, error) {
+	tokens, err := t.List(username)
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, token := range tokens {
+		if _, err := normalizeScopes(role, token.Scopes); err == nil {
+			continue
+		}
+		if _, err := t.delete(token.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// Authenticate returns the token secret belongs to and records its use
+func (t *Tokens) Authenticate(secret string) (*models.APIToken, error) {
+	token, err := t.byHash(hashSecret(secret))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrInvalidToken
+	}
+	now := time.Now().Unix()
+	if token.ExpiresAt != 0 && token.ExpiresAt <= now {
+		return nil, ErrTokenExpired
+	}
+
+	if now-token.LastUsedAt >= int64(lastUsedInterval/time.Second) {
+		if err := t.setLastUsed(token.ID, now); err != nil {
+			return nil, err
+		}
+		token.LastUsedAt = now
+	}
+	return token, nil
+}
+
+// HasScope reports whether scopes, the scopes of an API token, include
+// scope. Every token may read.
+func HasScope(scopes []string, scope string) bool {
+	return scope == models.TokenScopeRead || slices.Contains(scopes, scope)
+}
+
+// normalizeScopes validates scopes and checks that role may use them
+func normalizeScopes(role string, scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIToken)
+	}
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		required, ok := scopeRoles[scope]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIToken, scope)
+		}
+		if !HasRole(role, required) {
+			return nil, fmt.Errorf("%w: scope %s requires the %s role", ErrInvalidAPIToken, scope, required)
+		}
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+// scopeRoles maps each scope to the role needed to use it
+var scopeRoles = map[string]string{
+	models.TokenScopeRead:     models.RoleViewer,
+	models.TokenScopeOperate:  models.RoleOperator,
+	models.TokenScopeScan:     models.RoleOperator,
+	models.TokenScopeSettings: models.RoleAdmin,
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *Tokens) all() ([]models.APIToken, error) {
+	if t.store != nil {
+		return t.store.ListAPITokens()
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return slices.Clone(t.tokens), nil
+}
+
+func (t *Tokens) byHash(hash string) (*models.APIToken, error) {
+	if t.store != nil {
+		return t.store.GetAPITokenByHash(hash)
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, token := range t.tokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, nil
+}
+
+func (t *Tokens) save(token models.APIToken) error {
+	if t.store != nil {
+		return t.store.SaveAPIToken(token)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokens = append(t.tokens, token)
+	return nil
+}
+
+func (t *Tokens) setLastUsed(id string, at int64) error {
+	if t.store != nil {
+		return t.store.SetAPITokenLastUsed(id, at)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.tokens {
+		if t.tokens[i].ID == id {
+			t.tokens[i].LastUsedAt = at
+		}
+	}
+	return nil
+}
+
+func (t *Tokens) delete(id string) (bool, error) {
+	if t.store != nil {
+		return t.store.DeleteAPIToken(id)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.tokens {
+		if t.tokens[i].ID == id {
+			t.tokens = slices.Delete(t.tokens, i, i+1)
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestTokensCreateValidates(t *testing.T) {
+	tokens := NewTokens(nil)
+	viewer := models.User{Username: "vic", Role: models.RoleViewer}
+
+	for name, tc := range map[string]struct {
+		name      string
+		scopes    []string
+		expiresAt int64
+	}{
+		"no name":        {"", []string{models.TokenScopeRead}, 0},
+		"no scopes":      {"ci", nil, 0},
+		"unknown scope":  {"ci", []string{"delete"}, 0},
+		"above the role": {"ci", []string{models.TokenScopeOperate}, 0},
+		"expired":        {"ci", []string{models.TokenScopeRead}, time.Now().Add(-time.Minute).Unix()},
+	} {
+		if _, _, err := tokens.Create(viewer, tc.name, tc.scopes, tc.expiresAt); !errors.Is(err, ErrInvalidAPIToken) {
+			t.Fatalf("%s: expected ErrInvalidAPIToken, got %v", name, err)
+		}
+	}
+
+	secret, token, err := tokens.Create(viewer, " ci ", []string{"READ", "read"}, 0)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !IsAPIToken(secret) || !strings.HasPrefix(secret, token.Prefix) || token.TokenHash == secret || len(token.Scopes) != 1 || token.Name != "ci" {
+		t.Fatalf("unexpected token %q %+v", secret, token)
+	}
+}
+
+func TestResolveAPIToken(t *testing.T) {
+	svc, users := newTestServiceWithUsers(t)
+	tokens := NewTokens(nil)
+	svc.SetTokens(tokens)
+	if _, err := users.Create("olga", "password1", models.RoleOperator, nil); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	secret, token, err := tokens.Create(models.User{Username: "olga", Role: models.RoleOperator}, "ci", []string{models.TokenScopeScan}, 0)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	user, err := svc.userForCredential(secret)
+	if err != nil || user.Username != "olga" || user.Role != models.RoleOperator || !HasScope(user.TokenScopes, models.TokenScopeScan) {
+		t.Fatalf("userForCredential() = %+v, %v", user, err)
+	}
+	if HasScope(user.TokenScopes, models.TokenScopeOperate) || !HasScope(user.TokenScopes, models.TokenScopeRead) {
+		t.Fatalf("unexpected scopes %v", user.TokenScopes)
+	}
+	listed, _ := tokens.List("olga")
+	if len(listed) != 1 || listed[0].LastUsedAt == 0 {
+		t.Fatalf("expected the use to be recorded, got %+v", listed)
+	}
+
+	if _, err := svc.userForCredential(secret + "0"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected an unknown token to be rejected, got %v", err)
+	}
+	if ok, err := tokens.Revoke("someone-else", token.ID); err != nil || ok {
+		t.Fatalf("Revoke() by another user = %v, %v", ok, err)
+	}
+	if ok, err := tokens.Revoke("olga", token.ID); err != nil || !ok {
+		t.Fatalf("Revoke() = %v, %v", ok, err)
+	}
+	if _, err := svc.userForCredential(secret); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a revoked token to be rejected, got %v", err)
+	}
+
+	expiring, _, err := tokens.Create(models.User{Username: "olga", Role: models.RoleOperator}, "short", []string{models.TokenScopeRead}, time.Now().Add(time.Hour).Unix())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	tokens.mu.Lock()
+	tokens.tokens[len(tokens.tokens)-1].ExpiresAt = time.Now().Unix()
+	tokens.mu.Unlock()
+	if _, err := svc.userForCredential(expiring); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("expected an expired token to be rejected, got %v", err)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	handler := RequireScope(models.TokenScopeOperate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		scopes []string
+		want   int
+	}{
+		{nil, http.StatusOK}, // a login session
+		{[]string{models.TokenScopeRead}, http.StatusForbidden},
+		{[]string{models.TokenScopeScan, models.TokenScopeOperate}, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), UserContextKey, models.User{Username: "olga", Role: models.RoleOperator, TokenScopes: tc.scopes}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("scopes %v: expected %d, got %d", tc.scopes, tc.want, rec.Code)
+		}
+	}
+}
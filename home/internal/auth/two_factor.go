@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes an enrollment gets
+	recoveryCodeCount = 10
+	// twoFactorLoginTimeout is how long a password login may wait for its code
+	twoFactorLoginTimeout = 5 * time.Minute
+	// maxTwoFactorAttempts is how many wrong codes end a pending login
+	maxTwoFactorAttempts = 5
+	// maxPendingTwoFactorLogins bounds the logins waiting for a code
+	maxPendingTwoFactorLogins = 1000
+)
+
+// ErrInvalidTwoFactor is returned when a two-factor code is wrong or a
+// two-factor request does not fit the enrollment
+var ErrInvalidTwoFactor = errors.New("invalid two-factor request")
+
+// TwoFactorStore persists TOTP enrollments
+type TwoFactorStore interface {
+	ListTwoFactor() ([]models.TwoFactor, error)
+	GetTwoFactor(username string) (*models.TwoFactor, error)
+	SaveTwoFactor(enrollment models.TwoFactor) error
+	DeleteTwoFactor(username string) (bool, error)
+}
+
+// TwoFactor holds the TOTP enrollments of local accounts, either in a
+// persistent store or, when none is configured, in memory, and the
+// password logins waiting for their second step
+type TwoFactor struct {
+	store TwoFactorStore
+
+	mu          sync.Mutex
+	enrollments []models.TwoFactor
+	pending     map[string]pendingTwoFactorLogin
+}
+
+type pendingTwoFactorLogin struct {
+	user      models.User
+	expiresAt time.Time
+	attempts  int
+}
+
+// NewTwoFactor creates a two-factor registry backed by store. A nil store
+// keeps enrollments in memory.
+func NewTwoFactor(store TwoFactorStore) *TwoFactor {
+	return &TwoFactor{store: store, pending: make(map[string]pendingTwoFactorLogin)}
+}
+
+// Get returns the enrollment of username, or nil if there is none
+func (t *TwoFactor) Get(username string) (*models.TwoFactor, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.get(username)
+}
+
+// EnabledUsers returns the names of the users who turned two-factor
+// authentication on
+func (t *TwoFactor) EnabledUsers() ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	enrollments, err := t.all()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(enrollments))
+	for _, enrollment := range enrollments {
+		if enrollment.Enabled {
+			names = append(names, enrollment.Username)
+		}
+	}
+	return names, nil
+}
+
+// Begin starts a new enrollment for username and returns its secret and
+// otpauth:// URI. Codes are not asked for until Confirm.
+func (t *TwoFactor) Begin(username string) (string, string, error) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	current, err := t.get(username)
+	if err != nil {
+		return "", "", err
+	}
+	if current != nil && current.Enabled {
+		return "", "", fmt.Errorf("%w: two-factor authentication is already enabled", ErrInvalidTwoFactor)
+	}
+	enrollment := models.TwoFactor{Username: username, Secret: secret, CreatedAt: time.Now().Unix()}
+	if err := t.save(enrollment); err != nil {
+		return "", "", err
+	}
+	return secret, totpURI(username, secret), nil
+}
+
+// Confirm turns on the enrollment Begin started once code shows the
+// authenticator app has the secret, and returns the recovery codes. They
+// are not stored and cannot be shown again.
+func (t *TwoFactor) Confirm(username, code string) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	enrollment, err := t.get(username)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil || enrollment.Enabled {
+		return nil, fmt.Errorf("%w: no two-factor enrollment is waiting for confirmation", ErrInvalidTwoFactor)
+	}
+	counter, ok, err := matchTOTP(enrollment.Secret, normalizeTwoFactorCode(code), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: the code is not valid", ErrInvalidTwoFactor)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		code, err := GenerateRandomHex(5)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashSecret(code))
+	}
+	enrollment.Enabled = true
+	enrollment.EnabledAt = time.Now().Unix()
+	enrollment.LastCounter = counter
+	enrollment.RecoveryHashes = hashes
+	if err := t.save(*enrollment); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns two-factor authentication off for username, which must
+// prove it with a current code or a recovery code
+func (t *TwoFactor) Disable(username, code string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	enrollment, err := t.get(username)
+	if err != nil {
+		return err
+	}
+	if enrollment == nil || !enrollment.Enabled {
+		return fmt.Errorf("%w: two-factor authentication is not enabled", ErrInvalidTwoFactor)
+	}
+	if err := t.verify(enrollment, code); err != nil {
+		return err
+	}
+	_, err = t.delete(username)
+	return err
+}
+
+// Reset removes the enrollment of username without a code, for an admin
+// helping a user who lost their authenticator. Returns false when the user
+// has none.
+func (t *TwoFactor) Reset(username string) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.delete(username)
+}
+
+// StartLogin returns a challenge for a login of user that still needs a
+// code, or "" when user has not enabled two-factor authentication
+func (t *TwoFactor) StartLogin(user models.User) (string, error) {
+	challenge, err := GenerateRandomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	enrollment, err := t.get(user.Username)
+	if err != nil {
+		return "", err
+	}
+	if enrollment == nil || !enrollment.Enabled {
+		return "", nil
+	}
+
+	now := time.Now()
+	for key, login := range t.pending {
+		if now.After(login.expiresAt) {
+			delete(t.pending, key)
+		}
+	}
+	if len(t.pending) >= maxPendingTwoFactorLogins {
+		return "", errors.New("too many logins waiting for a two-factor code")
+	}
+	t.pending[challenge] = pendingTwoFactorLogin{user: user, expiresAt: now.Add(twoFactorLoginTimeout)}
+	return challenge, nil
+}
+
+// FinishLogin checks the code for a challenge from StartLogin and returns
+// the user logging in. A challenge ends after it succeeds, expires or sees
+// maxTwoFactorAttempts wrong codes.
+func (t *TwoFactor) FinishLogin(challenge, code string) (models.User, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	login, ok := t.pending[challenge]
+	if !ok || time.Now().After(login.expiresAt) {
+		delete(t.pending, challenge)
+		return models.User{}, fmt.Errorf("%w: the login has expired, sign in again", ErrInvalidTwoFactor)
+	}
+
+	enrollment, err := t.get(login.user.Username)
+	if err != nil {
+		return models.User{}, err
+	}
+	// An admin may have reset the enrollment since the password was checked
+	if enrollment != nil && enrollment.Enabled {
+		err = t.verify(enrollment, code)
+	}
+	if err != nil {
+		login.attempts++
+		if login.attempts >= maxTwoFactorAttempts {
+			delete(t.pending, challenge)
+		} else {
+			t.pending[challenge] = login
+		}
+		return models.User{}, err
+	}
+	delete(t.pending, challenge)
+	return login.user, nil
+}
+
+// verify accepts a current TOTP code, which must be newer than the last
+// one used, or an unused recovery code, and records its use. Must be called
+// with t.mu held.
+func (t *TwoFactor) verify(enrollment *models.TwoFactor, code string) error {
+	code = normalizeTwoFactorCode(code)
+	counter, ok, err := matchTOTP(enrollment.Secret, code, time.Now())
+	if err != nil {
+		return err
+	}
+	switch {
+	case ok && counter > enrollment.LastCounter:
+		enrollment.LastCounter = counter
+	case ok:
+		return fmt.Errorf("%w: the code was already used, wait for the next one", ErrInvalidTwoFactor)
+	default:
+		hash := hashSecret(strings.ReplaceAll(code, "-", ""))
+		i := slices.Index(enrollment.RecoveryHashes, hash)
+		if i < 0 {
+			return fmt.Errorf("%w: the code is not valid", ErrInvalidTwoFactor)
+		}
+		enrollment.RecoveryHashes = slices.Delete(slices.Clone(enrollment.RecoveryHashes), i, i+1)
+	}
+	return t.save(*enrollment)
+}
+
+// normalizeTwoFactorCode drops the spaces authenticator apps show in codes
+// and lowercases recovery codes
+func normalizeTwoFactorCode(code string) string {
+	return strings.ToLower(strings.Join(strings.Fields(code), ""))
+}
+
+func (t *TwoFactor) all() ([]models.TwoFactor, error) {
+	if t.store != nil {
+		return t.store.ListTwoFactor()
+	}
+	return slices.Clone(t.enrollments), nil
+}
+
+func (t *TwoFactor) get(username string) (*models.TwoFactor, error) {
+	if t.store != nil {
+		return t.store.GetTwoFactor(username)
+	}
+	for _, enrollment := range t.enrollments {
+		if enrollment.Username == username {
+			enrollment.RecoveryHashes = slices.Clone(enrollment.RecoveryHashes)
+			return &enrollment, nil
+		}
+	}
+	return nil, nil
+}
+
+func (t *TwoFactor) save(enrollment models.TwoFactor) error {
+	if t.store != nil {
+		return t.store.SaveTwoFactor(enrollment)
+	}
+	for i := range t.enrollments {
+		if t.enrollments[i].Username == enrollment.Username {
+			t.enrollments[i] = enrollment
+			return nil
+		}
+	}
+	t.enrollments = append(t.enrollments, enrollment)
+	return nil
+}
+
+func (t *TwoFactor) delete(username string) (bool, error) {
+	if t.store != nil {
+		return t.store.DeleteTwoFactor(username)
+	}
+	for i := range t.enrollments {
+		if t.enrollments[i].Username == username {
+			t.enrollments = slices.Delete(t.enrollments, i, i+1)
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// codeAt returns the TOTP code of secret steps time steps from now
+func codeAt(t *testing.T, secret string, steps int64) string {
+	t.Helper()
+	code, err := totpCode(secret, totpCounter(time.Now())+steps)
+	if err != nil {
+		t.Fatalf("totpCode() error = %v", err)
+	}
+	return code
+}
+
+func TestTOTPCode(t *testing.T) {
+	// Test vectors from RFC 6238, cut to six digits
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	for unix, want := range map[int64]string{59: "287082", 1111111109: "081804", 2000000000: "279037"} {
+		got, err := totpCode(secret, totpCounter(time.Unix(unix, 0)))
+		if err != nil || got != want {
+			t.Fatalf("totpCode() at %d = %q, %v, want %q", unix, got, err, want)
+		}
+	}
+
+	// One time step of drift is accepted either way, two are not
+	now := time.Unix(1111111109, 0)
+	for steps, ok := range map[int64]bool{-2: false, -1: true, 0: true, 1: true, 2: false} {
+		code, err := totpCode(secret, totpCounter(now)+steps)
+		if err != nil {
+			t.Fatalf("totpCode() error = %v", err)
+		}
+		if counter, got, err := matchTOTP(secret, code, now); err != nil || got != ok || (ok && counter != totpCounter(now)+steps) {
+			t.Fatalf("matchTOTP() %d steps off = %d, %v, %v, want %v", steps, counter, got, err, ok)
+		}
+	}
+	if _, ok, _ := matchTOTP(secret, "81804", now); ok {
+		t.Fatal("expected a short code to be rejected")
+	}
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	tf := NewTwoFactor(nil)
+
+	secret, uri, err := tf.Begin("alice")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "otpauth" || parsed.Query().Get("secret") != secret || !strings.HasSuffix(parsed.Path, ":alice") {
+		t.Fatalf("unexpected provisioning URI %q", uri)
+	}
+	if err := tf.Disable("alice", codeAt(t, secret, 0)); !errors.Is(err, ErrInvalidTwoFactor) {
+		t.Fatalf("expected a pending enrollment not to be disabled, got %v", err)
+	}
+	if _, err := tf.Confirm("alice", "000000"); !errors.Is(err, ErrInvalidTwoFactor) {
+		t.Fatalf("expected a wrong code to be rejected, got %v", err)
+	}
+
+	codes, err := tf.Confirm("alice", codeAt(t, secret, 0))
+	if err != nil || len(codes) != recoveryCodeCount {
+		t.Fatalf("Confirm() = %v, %v", codes, err)
+	}
+	if _, _, err := tf.Begin("alice"); !errors.Is(err, ErrInvalidTwoFactor) {
+		t.Fatalf("expected Begin() to refuse an enabled enrollment, got %v", err)
+	}
+	if users, err := tf.EnabledUsers(); err != nil || len(users) != 1 || users[0] != "alice" {
+		t.Fatalf("EnabledUsers() = %v, %v", users, err)
+	}
+
+	// The code used to confirm cannot be used again, the next one can
+	tf.mu.Lock()
+	enrollment, _ := tf.get("alice")
+	if err := tf.verify(enrollment, codeAt(t, secret, 0)); !errors.Is(err, ErrInvalidTwoFactor) {
+		t.Fatalf("expected a replayed code to be rejected, got %v", err)
+	}
+	if err := tf.verify(enrollment, codeAt(t, secret, 1)); err != nil {
+		t.Fatalf("verify() of the next code error = %v", err)
+	}
+	// Recovery codes work once, with or without the dash
+	if err := tf.verify(enrollment, strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))); err != nil {
+		t.Fatalf("verify() of a recovery code error = %v", err)
+	}
+	if err := tf.verify(enrollment, codes[0]); !errors.Is(err, ErrInvalidTwoFactor) {
+		t.Fatalf("expected a used recovery code to be rejected, got %v", err)
+	}
+	tf.mu.Unlock()
+
+	if enrollment, err := tf.Get("alice"); err != nil || len(enrollment.RecoveryHashes) != recoveryCodeCount-1 {
+		t.Fatalf("Get() = %+v, %v", enrollment, err)
+	}
+	if err := tf.Disable("alice", codes[1]); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	if enrollment, err := tf.Get("alice"); err != nil || enrollment != nil {
+		t.Fatalf("expected no enrollment after Disable(), got %+v, %v", enrollment, err)
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	svc, _ := newTestServiceWithUsers(t)
+	tf := NewTwoFactor(nil)
+	svc.SetTwoFactor(tf)
+	admin := models.User{Username: "admin", Role: models.RoleAdmin}
+
+	if challenge, err := svc.StartTwoFactor(admin); err != nil || challenge != "" {
+		t.Fatalf("expected no challenge without an enrollment, got %q, %v", challenge, err)
+	}
+	secret, _, err := tf.Begin("admin")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if challenge, err := svc.StartTwoFactor(admin); err != nil || challenge != "" {
+		t.Fatalf("expected no challenge for a pending enrollment, got %q, %v", challenge, err)
+	}
+	if _, err := tf.Confirm("admin", codeAt(t, secret, 0)); err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+
+	challenge, err := svc.StartTwoFactor(admin)
+	if err != nil || challenge == "" {
+		t.Fatalf("StartTwoFactor() = %q, %v", challenge, err)
+	}
+	user, err := svc.FinishTwoFactor(challenge, codeAt(t, secret, 1))
+	if err != nil || user.Username != "admin" || user.Role != models.RoleAdmin {
+		t.Fatalf("FinishTwoFactor() = %+v, %v", user, err)
+	}
+	if _, err := svc.FinishTwoFactor(challenge, codeAt(t, secret, 1)); !errors.Is(err, ErrInvalidTwoFactor) {
+		t.Fatalf("expected a used challenge to be rejected, got %v", err)
+	}
+
+	// Too many wrong codes end the login
+	challenge, err = svc.StartTwoFactor(admin)
+	if err != nil {
+		t.Fatalf("StartTwoFactor() error = %v", err)
+	}
+	for range maxTwoFactorAttempts {
+		if _, err := svc.FinishTwoFactor(challenge, "000000"); !errors.Is(err, ErrInvalidTwoFactor) {
+			t.Fatalf("expected a wrong code to be rejected, got %v", err)
+		}
+	}
+	tf.mu.Lock()
+	_, pending := tf.pending[challenge]
+	tf.pending["expired"] = pendingTwoFactorLogin{user: admin, expiresAt: time.Now().Add(-time.Second)}
+	tf.mu.Unlock()
+	if pending {
+		t.Fatal("expected the challenge to end after too many wrong codes")
+	}
+	if _, err := svc.FinishTwoFactor("expired", codeAt(t, secret, 0)); !errors.Is(err, ErrInvalidTwoFactor) {
+		t.Fatalf("expected an expired challenge to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// Password lengths accepted for user accounts; bcrypt ignores bytes past 72
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// ErrInvalidUser is returned when a user account fails validation
+var ErrInvalidUser = errors.New("invalid user")
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._@-]{1,64}$`)
+
+// UserStore persists user accounts
+type UserStore interface {
+	ListUsers() ([]models.UserAccount, error)
+	GetUser(username string) (*models.UserAccount, error)
+	SaveUser(user models.UserAccount) error
+	DeleteUser(username string) (bool, error)
+}
+
+// Users holds the user accounts that can log in next to the built-in admin,
+// either in a persistent store or, when none is configured, in memory
+type Users struct {
+	store UserStore
+	mu    sync.RWMutex
+	users []models.UserAccount
+}
+
+// NewUsers creates a user registry backed by store. A nil store keeps
+// accounts in memory.
+func NewUsers(store UserStore) *Users {
+	return &Users{store: store}
+}
+
+// List returns all user accounts ordered by username
+func (u *Users) List() ([]models.UserAccount, error) {
+	if u.store != nil {
+		return u.store.ListUsers()
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	users := slices.Clone(u.users)
+	slices.SortFunc(users, func(a, b models.UserAccount) int { return strings.Compare(a.Username, b.Username) })
+	return users, nil
+}
+
+// Get returns a user account, or nil if it does not exist
+func (u *Users) Get(username string) (*models.UserAccount, error) {
+	if u.store != nil {
+		return u.store.GetUser(username)
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	for _, user := range u.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+// UserChanges lists what Update changes. Empty fields and a nil Grants
+// keep the current value.
+type UserChanges struct {
+	Role     string
+	Password string
+	Grants   *[]models.PermissionGrant
+}
+
+// Create adds a user account with a bcrypt hash of password
+func (u *Users) Create(username, password, role string, grants []models.PermissionGrant) (*models.UserAccount, error) {
+	username = strings.TrimSpace(username)
+	if !usernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("%w: username must be 1-64 letters, digits or . _ @ -", ErrInvalidUser)
+	}
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+	grants, err := normalizeGrants(grants)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := hashUserPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := u.Get(username)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: user %q already exists", ErrInvalidUser, username)
+	}
+
+	now := time.Now().Unix()
+	user := models.UserAccount{
+		Username:     username,
+		Role:         role,
+		PasswordHash: hash,
+		Grants:       grants,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := u.save(user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Update applies changes to a user account. Returns nil when the user does
+// not exist.
+func (u *Users) Update(username string, changes UserChanges) (*models.UserAccount, error) {
+	user, err := u.Get(username)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	if changes.Role != "" {
+		if err := validateRole(changes.Role); err != nil {
+			return nil, err
+		}
+		user.Role = changes.Role
+	}
+	if changes.Password != "" {
+		hash, err := hashUserPassword(changes.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = hash
+	}
+	if changes.Grants != nil {
+		grants, err := normalizeGrants(*changes.Grants)
+		if err != nil {
+			return nil, err
+		}
+		user.Grants = grants
+	}
+	user.UpdatedAt = time.Now().Unix()
+
+	if err := u.save(*user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Delete removes a user account. Returns false when it does not exist.
+func (u *Users) Delete(username string) (bool, error) {
+	if u.store != nil {
+		return u.store.DeleteUser(username)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i, user := range u.users {
+		if user.Username == username {
+			u.users = slices.Delete(u.users, i, i+1)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (u *Users) save(user models.UserAccount) error {
+	if u.store != nil {
+		return u.store.SaveUser(user)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i := range u.users {
+		if u.users[i].Username == user.Username {
+			u.users[i] = user
+			return nil
+		}
+	}
+	u.users = append(u.users, user)
+	return nil
+}
+
+func validateRole(role string) error {
+	if roleRank(role) < 0 {
+		return fmt.Errorf("%w: role must be %s, %s or %s", ErrInvalidUser, models.RoleViewer, models.RoleOperator, models.RoleAdmin)
+	}
+	return nil
+}
+
+func hashUserPassword(password string) (string, error) {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", fmt.Errorf("%w: password must be %d to %d characters", ErrInvalidUser, minPasswordLength, maxPasswordLength)
+	}
+	return HashPassword(password)
+}
+
+// HasRole reports whether role grants at least the access of required
+func HasRole(role, required string) bool {
+	return roleRank(role) >= roleRank(required) && roleRank(required) >= 0
+}
+
+func roleRank(role string) int {
+	switch role {
+	case models.RoleViewer:
+		return 0
+	case models.RoleOperator:
+		return 1
+	case models.RoleAdmin:
+		return 2
+	default:
+		return -1
+	}
+}
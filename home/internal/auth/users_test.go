@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestServiceWithUsers(t *testing.T) (*Service, *Users) {
+	t.Helper()
+	hash, err := HashPassword("super-secret")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	users := NewUsers(nil)
+	svc := &Service{
+		jwtSecret:         []byte("jwt-secret"),
+		adminUsername:     "admin",
+		adminPasswordHash: hash,
+		tokenExpiration:   time.Hour,
+	}
+	svc.SetUsers(users)
+	return svc, users
+}
+
+func TestUsersValidateAccounts(t *testing.T) {
+	users := NewUsers(nil)
+
+	for name, tc := range map[string][3]string{
+		"bad username": {"bad name", "password1", models.RoleViewer},
+		"short":        {"alice", "short", models.RoleViewer},
+		"unknown role": {"alice", "password1", "root"},
+	} {
+		if _, err := users.Create(tc[0], tc[1], tc[2], nil); !errors.Is(err, ErrInvalidUser) {
+			t.Fatalf("%s: expected ErrInvalidUser, got %v", name, err)
+		}
+	}
+
+	if _, err := users.Create("alice", "password1", models.RoleViewer, nil); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := users.Create("alice", "password2", models.RoleAdmin, nil); !errors.Is(err, ErrInvalidUser) {
+		t.Fatalf("expected a duplicate username to be rejected, got %v", err)
+	}
+	if user, err := users.Update("bob", UserChanges{Role: models.RoleAdmin}); err != nil || user != nil {
+		t.Fatalf("Update() of a missing user = %v, %v", user, err)
+	}
+}
+
+func TestAuthenticateAndResolveUserAccounts(t *testing.T) {
+	svc, users := newTestServiceWithUsers(t)
+	if _, err := users.Create("alice", "password1", models.RoleViewer, nil); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if _, err := svc.Authenticate("alice", "wrong-pass"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected a wrong password to be rejected, got %v", err)
+	}
+	if _, err := svc.Authenticate("mallory", "password1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected an unknown user to be rejected, got %v", err)
+	}
+	// Unknown users are checked against a hash as costly as real ones
+	if cost, err := bcrypt.Cost(dummyPasswordHash()); err != nil || cost != bcrypt.DefaultCost {
+		t.Fatalf("dummy hash cost = %d, %v", cost, err)
+	}
+	user, err := svc.Authenticate("alice", "password1")
+	if err != nil || user.Role != models.RoleViewer {
+		t.Fatalf("Authenticate() = %+v, %v", user, err)
+	}
+	admin, err := svc.Authenticate("admin", "super-secret")
+	if err != nil || admin.Role != models.RoleAdmin {
+		t.Fatalf("Authenticate(admin) = %+v, %v", admin, err)
+	}
+
+	// Role changes apply to tokens that were already issued
+	if _, err := users.Update("alice", UserChanges{Role: models.RoleOperator}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	resolved, err := svc.ResolveUser(&Claims{Username: "alice", Role: models.RoleViewer})
+	if err != nil || resolved.Role != models.RoleOperator {
+		t.Fatalf("ResolveUser() = %+v, %v", resolved, err)
+	}
+
+	if _, err := users.Delete("alice"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := svc.ResolveUser(&Claims{Username: "alice"}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a deleted user's token to be rejected, got %v", err)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	handler := RequireRole(models.RoleOperator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		user *models.User
+		want int
+	}{
+		{nil, http.StatusOK},
+		{&models.User{Username: "v", Role: models.RoleViewer}, http.StatusForbidden},
+		{&models.User{Username: "o", Role: models.RoleOperator}, http.StatusOK},
+		{&models.User{Username: "a", Role: models.RoleAdmin}, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tc.user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), UserContextKey, *tc.user))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("user %+v: expected %d, got %d", tc.user, tc.want, rec.Code)
+		}
+		if rec.Code == http.StatusForbidden {
+			var body map[string]string
+			_ = json.NewDecoder(rec.Body).Decode(&body)
+			if body["required_role"] != models.RoleOperator {
+				t.Fatalf("unexpected 403 body %v", body)
+			}
+		}
+	}
+}
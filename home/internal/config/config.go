@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"net/netip"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -30,14 +34,98 @@ type FileAuthConfig struct {
 	AdminPasswordSalt string `json:"adminPasswordSalt,omitempty"`
 }
 
+// FileOIDCConfig represents OpenID Connect settings stored in the config file.
+type FileOIDCConfig struct {
+	IssuerURL         string   `json:"issuerUrl"`
+	ClientID          string   `json:"clientId"`
+	ClientSecret      string   `json:"clientSecret,omitempty"`
+	RedirectURL       string   `json:"redirectUrl"`
+	Scopes            []string `json:"scopes,omitempty"`
+	UsernameClaim     string   `json:"usernameClaim,omitempty"`
+	GroupsClaim       string   `json:"groupsClaim,omitempty"`
+	AdminGroups       []string `json:"adminGroups,omitempty"`
+	OperatorGroups    []string `json:"operatorGroups,omitempty"`
+	ViewerGroups      []string `json:"viewerGroups,omitempty"`
+	DefaultRole       string   `json:"defaultRole,omitempty"`
+	PostLoginRedirect string   `json:"postLoginRedirect,omitempty"`
+}
+
 // AlertConfig holds configuration for the alerting system
 type AlertConfig struct {
+	Enabled          bool
+	WebhookURL       string
+	WebhookSecret    string        // HMAC-SHA256 key for signing WebhookURL deliveries
+	CPUThreshold     float64       // 0-100, alert when exceeded
+	MemoryThreshold  float64       // 0-100, alert when exceeded
+	CPUFor           time.Duration // How long CPU must stay above CPUThreshold before alerting
+	MemoryFor        time.Duration // How long memory must stay above MemoryThreshold before alerting
+	CPUClear         float64       // Resolve CPU alerts at or below this value, 0 uses CPUThreshold
+	MemoryClear      float64       // Resolve memory alerts at or below this value, 0 uses MemoryThreshold
+	CheckInterval    time.Duration // How often to check thresholds
+	AlertsFilter     string
+	HistoryRetention time.Duration // How long alerts are kept, 0 keeps them forever
+	Cooldown         time.Duration // Minimum quiet time after a resolve before the same alert can fire again
+	Overrides        []ThresholdOverride
+
+	EventsEnabled     bool          // Watch the Docker events stream for crashes, OOM kills and failing healthchecks
+	RestartLoopCount  int           // Crashes within RestartLoopWindow that count as a restart loop
+	RestartLoopWindow time.Duration // Window used to detect restart loops
+	LogTailLines      int           // Log lines attached to crash alerts
+	HostDownAfter     int           // Consecutive failed checks before a Docker host is reported down
+
+	Host HostAlertConfig
+}
+
+// HostAlertConfig holds thresholds for alerts on the machine running the
+// monitor. A zero threshold disables that check.
+type HostAlertConfig struct {
 	Enabled         bool
-	WebhookURL      string
-	CPUThreshold    float64       // 0-100, alert when exceeded
-	MemoryThreshold float64       // 0-100, alert when exceeded
-	CheckInterval   time.Duration // How often to check thresholds
-	AlertsFilter    string
+	CPUThreshold    float64       // Percent
+	MemoryThreshold float64       // Percent
+	DiskThreshold   float64       // Percent used, checked per mount
+	InodeThreshold  float64       // Percent of inodes used, checked per mount
+	LoadThreshold   float64       // 5-minute load average per CPU core
+	For             time.Duration // How long a threshold must be exceeded before alerting
+	IgnoreMounts    []string      // Glob patterns of mountpoints to skip, including mounts below them
+}
+
+// ThresholdOverride adjusts alert thresholds for containers matching Host
+// and ContainerPattern. Zero thresholds keep the inherited value.
+type ThresholdOverride struct {
+	Host             string // Empty matches every host
+	ContainerPattern string // Glob matched against the container name, empty matches all
+	CPUThreshold     float64
+	MemoryThreshold  float64
+	Disabled         bool
+}
+
+// Matches reports whether the override applies to the container
+func (o ThresholdOverride) Matches(host, containerName string) bool {
+	if o.Host != "" && o.Host != host {
+		return false
+	}
+	if o.ContainerPattern == "" {
+		return true
+	}
+	matched, err := path.Match(o.ContainerPattern, containerName)
+	return err == nil && matched
+}
+
+// CPUClearThreshold returns the CPU value alerts resolve at, falling back to CPUThreshold
+func (c AlertConfig) CPUClearThreshold() float64 {
+	return clearThreshold(c.CPUThreshold, c.CPUClear)
+}
+
+// MemoryClearThreshold returns the memory value alerts resolve at, falling back to MemoryThreshold
+func (c AlertConfig) MemoryClearThreshold() float64 {
+	return clearThreshold(c.MemoryThreshold, c.MemoryClear)
+}
+
+func clearThreshold(threshold, clear float64) float64 {
+	if clear > 0 && clear <= threshold {
+		return clear
+	}
+	return threshold
 }
 
 type StatsConfig struct {
@@ -61,6 +149,178 @@ type DiscordBotConfig struct {
 	AllowedChannelID string
 }
 
+// PushConfig configures push notifications to registered mobile devices
+type PushConfig struct {
+	// FCMCredentialsFile is a Firebase service account JSON key; FCM
+	// devices receive nothing without it
+	FCMCredentialsFile string
+	// UnifiedPushAllowedHosts are UnifiedPush endpoint hosts that may be
+	// private or local addresses, e.g. a self-hosted ntfy server
+	UnifiedPushAllowedHosts []string
+}
+
+// SessionConfig sets how long logins last
+type SessionConfig struct {
+	// AccessTokenTTL is the lifetime of the JWTs sent with each request;
+	// revoking a session takes effect on them at once
+	AccessTokenTTL time.Duration
+	// SessionTTL is how long a login can be refreshed before logging in again
+	SessionTTL time.Duration
+}
+
+// AuditConfig configures the audit log of changes made through the API
+type AuditConfig struct {
+	Retention time.Duration // How long events are kept, 0 keeps them forever
+}
+
+// MetricsConfig configures the Prometheus endpoint
+type MetricsConfig struct {
+	// Token is the bearer token scrapers send; without it /metrics takes
+	// the same authentication as the API
+	Token string
+}
+
+// LoginProtectionConfig configures the lockout of client IPs and usernames
+// after repeated failed logins
+type LoginProtectionConfig struct {
+	MaxFailures   int           // Failed logins before a lockout, 0 disables lockouts
+	Lockout       time.Duration // First lockout, doubled with every further failure
+	MaxLockout    time.Duration // Longest lockout
+	FailureWindow time.Duration // How long failures are remembered once the last one and its lockout are over
+}
+
+// TrustedProxiesConfig lists the reverse proxies whose X-Forwarded-For
+// header names the client
+type TrustedProxiesConfig struct {
+	Networks   []netip.Prefix
+	UnixSocket bool // Requests on the Unix socket come from a proxy
+}
+
+// Trusts reports whether the peer address addr, without a port, is a
+// trusted proxy. Peers on the Unix socket have no IP address.
+func (c TrustedProxiesConfig) Trusts(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return c.UnixSocket
+	}
+	ip = ip.Unmap()
+	for _, network := range c.Networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimit allows Requests requests per Per from one client. A zero
+// RateLimit does not limit anything.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Enabled reports whether the limit applies
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// String formats the limit the way the RATE_LIMIT_* variables take it
+func (l RateLimit) String() string {
+	if !l.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// RateLimitConfig holds the request rate limits of the route groups that
+// are expensive or attractive to abuse
+type RateLimitConfig struct {
+	Login RateLimit // Password and two-factor logins, per client IP
+	Exec  RateLimit // Terminal sessions, per user
+	Scans RateLimit // Starting, cancelling and deleting scans, per user
+}
+
+// ServerConfig sets where and how the API and UI are served
+type ServerConfig struct {
+	Listen         []string    // TCP addresses, e.g. ":6789" or "127.0.0.1:8443"
+	UnixSocket     string      // Path of a Unix socket to serve plain HTTP on as well, empty for none
+	UnixSocketMode os.FileMode // Permissions of the Unix socket
+	// TLSCertFile and TLSKeyFile turn on HTTPS for the TCP addresses. The
+	// files are reloaded when they change.
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSClientCAFile requires clients to present a certificate signed by
+	// one of its CAs
+	TLSClientCAFile string
+}
+
+// TLSEnabled reports whether the TCP addresses serve HTTPS
+func (c ServerConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// Validate reports settings that cannot be served
+func (c ServerConfig) Validate() error {
+	if len(c.Listen) == 0 && c.UnixSocket == "" {
+		return errors.New("no listen address or Unix socket is configured")
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("TLS needs both a certificate and a key file")
+	}
+	if c.TLSClientCAFile != "" && !c.TLSEnabled() {
+		return errors.New("client certificates need TLS to be enabled")
+	}
+	return nil
+}
+
+// NormalizeServerConfig trims cfg and fills in the defaults
+func NormalizeServerConfig(cfg ServerConfig) ServerConfig {
+	var listen []string
+	for _, addr := range cfg.Listen {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			listen = append(listen, addr)
+		}
+	}
+	cfg.Listen = listen
+	cfg.UnixSocket = strings.TrimSpace(cfg.UnixSocket)
+	cfg.TLSCertFile = strings.TrimSpace(cfg.TLSCertFile)
+	cfg.TLSKeyFile = strings.TrimSpace(cfg.TLSKeyFile)
+	cfg.TLSClientCAFile = strings.TrimSpace(cfg.TLSClientCAFile)
+	if len(cfg.Listen) == 0 && cfg.UnixSocket == "" {
+		cfg.Listen = []string{":6789"}
+	}
+	if cfg.UnixSocketMode == 0 {
+		cfg.UnixSocketMode = 0o660
+	}
+	return cfg
+}
+
+// OIDCConfig configures single sign-on through an OpenID Connect provider
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string   // The /api/v1/auth/oidc/callback URL registered with the provider
+	Scopes       []string // Requested next to "openid"
+	// UsernameClaim and GroupsClaim name the ID token claims holding the
+	// username and the user's groups
+	UsernameClaim string
+	GroupsClaim   string
+	// Members of these groups get the role, the highest one winning
+	AdminGroups    []string
+	OperatorGroups []string
+	ViewerGroups   []string
+	// DefaultRole is given to users in none of the groups; empty refuses them
+	DefaultRole string
+	// PostLoginRedirect is where the browser is sent with the token
+	PostLoginRedirect string
+}
+
+// Enabled reports whether enough is configured to offer OIDC login
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != "" && c.ClientID != "" && c.RedirectURL != ""
+}
+
 const (
 	BotModePolling  = "polling"
 	BotModeJWTRelay = "jwt-relay"
@@ -90,14 +350,29 @@ type ScannerConfig struct {
 }
 
 type Config struct {
-	ReadOnly     bool
-	Hostname     string // Optional override for displayed hostname
-	DockerHosts  []DockerHost
-	CoolifyHosts []CoolifyHostConfig
-	Alerts       AlertConfig
-	Stats        StatsConfig
-	Bot          BotConfig
-	Scanner      ScannerConfig
+	ReadOnly      bool
+	Hostname      string // Optional override for displayed hostname
+	DockerHosts   []DockerHost
+	CoolifyHosts  []CoolifyHostConfig
+	Alerts        AlertConfig
+	Stats         StatsConfig
+	Bot           BotConfig
+	Scanner       ScannerConfig
+	Notifications NotificationsConfig
+	Push          PushConfig
+	OIDC          OIDCConfig
+	Sessions      SessionConfig
+	Audit         AuditConfig
+	Metrics       MetricsConfig
+
+	LoginProtection LoginProtectionConfig
+	RateLimits      RateLimitConfig
+	TrustedProxies  TrustedProxiesConfig
+	Server          ServerConfig
+
+	// ShutdownTimeout bounds how long requests in flight and running scans
+	// are waited for on SIGTERM
+	ShutdownTimeout time.Duration
 }
 
 func NewConfig() *Config {
@@ -137,17 +412,273 @@ func NewConfig() *Config {
 		Stats:        statsConfig,
 		Bot:          botConfig,
 		Scanner:      scannerConfig,
+		Push:         parsePushConfig(),
+		OIDC:         parseOIDCConfig(),
+		Sessions:     parseSessionConfig(),
+		Audit:        parseAuditConfig(),
+		Metrics:      MetricsConfig{Token: strings.TrimSpace(os.Getenv("METRICS_TOKEN"))},
+
+		LoginProtection: parseLoginProtectionConfig(),
+		RateLimits:      parseRateLimitConfig(),
+		TrustedProxies:  parseTrustedProxies(),
+		Server:          parseServerConfig(),
+		ShutdownTimeout: parseShutdownTimeout(),
+	}
+}
+
+func parseSessionConfig() SessionConfig {
+	cfg := SessionConfig{
+		AccessTokenTTL: 15 * time.Minute,
+		SessionTTL:     7 * 24 * time.Hour,
+	}
+	if v := strings.TrimSpace(os.Getenv("AUTH_ACCESS_TOKEN_TTL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= time.Minute {
+			cfg.AccessTokenTTL = d
+		} else {
+			log.Printf("Ignoring AUTH_ACCESS_TOKEN_TTL %q: must be a duration of at least 1m", v)
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("AUTH_SESSION_TTL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= cfg.AccessTokenTTL {
+			cfg.SessionTTL = d
+		} else {
+			log.Printf("Ignoring AUTH_SESSION_TTL %q: must be a duration of at least AUTH_ACCESS_TOKEN_TTL", v)
+		}
+	}
+	return cfg
+}
+
+func parseAuditConfig() AuditConfig {
+	cfg := AuditConfig{Retention: 90 * 24 * time.Hour}
+	if v := strings.TrimSpace(os.Getenv("AUDIT_RETENTION")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.Retention = d
+		} else {
+			log.Printf("Ignoring AUDIT_RETENTION %q: must be a duration of at least 0", v)
+		}
+	}
+	return cfg
+}
+
+func parseShutdownTimeout() time.Duration {
+	timeout := 30 * time.Second
+	if v := strings.TrimSpace(os.Getenv("SHUTDOWN_TIMEOUT")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= time.Second {
+			timeout = d
+		} else {
+			log.Printf("Ignoring SHUTDOWN_TIMEOUT %q: must be a duration of at least 1s", v)
+		}
+	}
+	return timeout
+}
+
+func parseLoginProtectionConfig() LoginProtectionConfig {
+	cfg := LoginProtectionConfig{
+		MaxFailures:   5,
+		Lockout:       time.Minute,
+		MaxLockout:    time.Hour,
+		FailureWindow: 15 * time.Minute,
+	}
+	if v := strings.TrimSpace(os.Getenv("LOGIN_MAX_FAILURES")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.MaxFailures = n
+		} else {
+			log.Printf("Ignoring LOGIN_MAX_FAILURES %q: must be a number of at least 0", v)
+		}
+	}
+	for name, dst := range map[string]*time.Duration{
+		"LOGIN_LOCKOUT":        &cfg.Lockout,
+		"LOGIN_MAX_LOCKOUT":    &cfg.MaxLockout,
+		"LOGIN_FAILURE_WINDOW": &cfg.FailureWindow,
+	} {
+		if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d > 0 {
+				*dst = d
+			} else {
+				log.Printf("Ignoring %s %q: must be a positive duration", name, v)
+			}
+		}
+	}
+	if cfg.MaxLockout < cfg.Lockout {
+		cfg.MaxLockout = cfg.Lockout
+	}
+	return cfg
+}
+
+func parseRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Login: parseRateLimit("RATE_LIMIT_LOGIN", RateLimit{Requests: 10, Per: time.Minute}),
+		Exec:  parseRateLimit("RATE_LIMIT_EXEC", RateLimit{Requests: 20, Per: time.Minute}),
+		Scans: parseRateLimit("RATE_LIMIT_SCANS", RateLimit{Requests: 30, Per: time.Minute}),
+	}
+}
+
+// parseRateLimit reads a limit written as requests/duration, e.g. 10/1m,
+// from the environment variable name. "off" or 0 disables it.
+func parseRateLimit(name string, def RateLimit) RateLimit {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return def
+	}
+	if v == "off" || v == "0" {
+		return RateLimit{}
+	}
+	limit, err := parseRateLimitValue(v)
+	if err != nil {
+		log.Printf("Ignoring %s %q: %v", name, v, err)
+		return def
+	}
+	return limit
+}
+
+// parseTrustedProxies reads TRUSTED_PROXIES, a list of addresses and CIDR
+// networks; "unix" trusts the Unix socket
+func parseTrustedProxies() TrustedProxiesConfig {
+	var cfg TrustedProxiesConfig
+	for _, entry := range parseList(os.Getenv("TRUSTED_PROXIES")) {
+		if entry == "unix" {
+			cfg.UnixSocket = true
+			continue
+		}
+		network, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				log.Printf("Ignoring TRUSTED_PROXIES entry %q: must be an IP address, a CIDR network or unix", entry)
+				continue
+			}
+			addr = addr.Unmap()
+			network = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		cfg.Networks = append(cfg.Networks, network.Masked())
 	}
+	return cfg
+}
+
+// parseRateLimitValue parses a limit written as requests/duration, e.g. 10/1m
+func parseRateLimitValue(raw string) (RateLimit, error) {
+	count, per, ok := strings.Cut(raw, "/")
+	if !ok {
+		return RateLimit{}, errors.New("must be written as requests/duration, e.g. 10/1m")
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || requests < 1 {
+		return RateLimit{}, errors.New("the number of requests must be at least 1")
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || duration <= 0 {
+		return RateLimit{}, errors.New("the duration must be positive, e.g. 1m")
+	}
+	return RateLimit{Requests: requests, Per: duration}, nil
+}
+
+func parseServerConfig() ServerConfig {
+	cfg := ServerConfig{
+		Listen:          parseList(os.Getenv("LISTEN_ADDR")),
+		UnixSocket:      os.Getenv("LISTEN_UNIX_SOCKET"),
+		TLSCertFile:     os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:      os.Getenv("TLS_KEY_FILE"),
+		TLSClientCAFile: os.Getenv("TLS_CLIENT_CA_FILE"),
+	}
+	if port := strings.TrimSpace(os.Getenv("BACKEND_PORT")); len(cfg.Listen) == 0 && port != "" {
+		cfg.Listen = []string{":" + port}
+	}
+	if v := strings.TrimSpace(os.Getenv("LISTEN_UNIX_SOCKET_MODE")); v != "" {
+		mode, err := parseFileMode(v)
+		if err != nil {
+			log.Printf("Ignoring LISTEN_UNIX_SOCKET_MODE %q: %v", v, err)
+		}
+		cfg.UnixSocketMode = mode
+	}
+	return NormalizeServerConfig(cfg)
+}
+
+// parseFileMode parses octal permissions such as 0660
+func parseFileMode(raw string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(strings.TrimSpace(raw), 8, 32)
+	if err != nil || mode == 0 || mode > 0o777 {
+		return 0, errors.New("must be octal permissions such as 0660")
+	}
+	return os.FileMode(mode), nil
+}
+
+func parseOIDCConfig() OIDCConfig {
+	return NormalizeOIDCConfig(OIDCConfig{
+		IssuerURL:         os.Getenv("OIDC_ISSUER_URL"),
+		ClientID:          os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:      os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:       os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:            parseList(os.Getenv("OIDC_SCOPES")),
+		UsernameClaim:     os.Getenv("OIDC_USERNAME_CLAIM"),
+		GroupsClaim:       os.Getenv("OIDC_GROUPS_CLAIM"),
+		AdminGroups:       parseList(os.Getenv("OIDC_ADMIN_GROUPS")),
+		OperatorGroups:    parseList(os.Getenv("OIDC_OPERATOR_GROUPS")),
+		ViewerGroups:      parseList(os.Getenv("OIDC_VIEWER_GROUPS")),
+		DefaultRole:       os.Getenv("OIDC_DEFAULT_ROLE"),
+		PostLoginRedirect: os.Getenv("OIDC_POST_LOGIN_REDIRECT"),
+	})
+}
+
+// NormalizeOIDCConfig trims cfg and fills in the defaults
+func NormalizeOIDCConfig(cfg OIDCConfig) OIDCConfig {
+	cfg.IssuerURL = strings.TrimSuffix(strings.TrimSpace(cfg.IssuerURL), "/")
+	cfg.ClientID = strings.TrimSpace(cfg.ClientID)
+	cfg.ClientSecret = strings.TrimSpace(cfg.ClientSecret)
+	cfg.RedirectURL = strings.TrimSpace(cfg.RedirectURL)
+	cfg.UsernameClaim = strings.TrimSpace(cfg.UsernameClaim)
+	cfg.GroupsClaim = strings.TrimSpace(cfg.GroupsClaim)
+	cfg.DefaultRole = strings.ToLower(strings.TrimSpace(cfg.DefaultRole))
+	cfg.PostLoginRedirect = strings.TrimSpace(cfg.PostLoginRedirect)
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"profile", "email", "groups"}
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "preferred_username"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.PostLoginRedirect == "" {
+		cfg.PostLoginRedirect = "/"
+	}
+	return cfg
+}
+
+// parseList splits a comma-separated list, dropping empty entries
+func parsePushConfig() PushConfig {
+	return PushConfig{
+		FCMCredentialsFile:      strings.TrimSpace(os.Getenv("PUSH_FCM_CREDENTIALS_FILE")),
+		UnifiedPushAllowedHosts: parseList(os.Getenv("PUSH_UNIFIEDPUSH_ALLOWED_HOSTS")),
+	}
+}
+
+func parseList(raw string) []string {
+	var values []string
+	for value := range strings.SplitSeq(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
 }
 
 func parseAlertConfig() AlertConfig {
 	config := AlertConfig{
-		Enabled:         os.Getenv("ALERTS_ENABLED") == "true",
-		WebhookURL:      os.Getenv("ALERTS_WEBHOOK_URL"),
-		CPUThreshold:    80, // Default: 80%
-		MemoryThreshold: 90, // Default: 90%
-		CheckInterval:   30 * time.Second,
-		AlertsFilter:    "all",
+		Enabled:          os.Getenv("ALERTS_ENABLED") == "true",
+		WebhookURL:       os.Getenv("ALERTS_WEBHOOK_URL"),
+		WebhookSecret:    os.Getenv("ALERTS_WEBHOOK_SECRET"),
+		CPUThreshold:     80, // Default: 80%
+		MemoryThreshold:  90, // Default: 90%
+		CheckInterval:    30 * time.Second,
+		AlertsFilter:     "all",
+		HistoryRetention: 30 * 24 * time.Hour, // Default: 30 days
+		Cooldown:         5 * time.Minute,
+
+		EventsEnabled:     os.Getenv("ALERTS_EVENTS_ENABLED") != "false",
+		RestartLoopCount:  3,
+		RestartLoopWindow: 5 * time.Minute,
+		LogTailLines:      20,
+		HostDownAfter:     3,
 	}
 
 	if cpuStr := os.Getenv("ALERTS_CPU_THRESHOLD"); cpuStr != "" {
@@ -162,12 +693,54 @@ func parseAlertConfig() AlertConfig {
 		}
 	}
 
+	config.CPUFor = parseAlertDuration("ALERTS_CPU_FOR")
+	config.MemoryFor = parseAlertDuration("ALERTS_MEMORY_FOR")
+	config.CPUClear = parseClearThreshold("ALERTS_CPU_CLEAR_THRESHOLD", config.CPUThreshold)
+	config.MemoryClear = parseClearThreshold("ALERTS_MEMORY_CLEAR_THRESHOLD", config.MemoryThreshold)
+
 	if intervalStr := os.Getenv("ALERTS_CHECK_INTERVAL"); intervalStr != "" {
 		if interval, err := time.ParseDuration(intervalStr); err == nil && interval > 0 {
 			config.CheckInterval = interval
 		}
 	}
 
+	if retentionStr := strings.TrimSpace(os.Getenv("ALERTS_HISTORY_RETENTION")); retentionStr != "" {
+		if retention, err := time.ParseDuration(retentionStr); err == nil && retention >= 0 {
+			config.HistoryRetention = retention
+		}
+	}
+
+	if cooldownStr := strings.TrimSpace(os.Getenv("ALERTS_COOLDOWN")); cooldownStr != "" {
+		if cooldown, err := time.ParseDuration(cooldownStr); err == nil && cooldown >= 0 {
+			config.Cooldown = cooldown
+		}
+	}
+
+	config.Overrides = parseThresholdOverrides()
+	config.Host = parseHostAlertConfig()
+
+	if countStr := strings.TrimSpace(os.Getenv("ALERTS_RESTART_LOOP_COUNT")); countStr != "" {
+		if count, err := strconv.Atoi(countStr); err == nil && count > 1 {
+			config.RestartLoopCount = count
+		}
+	}
+
+	if window := parseAlertDuration("ALERTS_RESTART_LOOP_WINDOW"); window > 0 {
+		config.RestartLoopWindow = window
+	}
+
+	if linesStr := strings.TrimSpace(os.Getenv("ALERTS_LOG_TAIL_LINES")); linesStr != "" {
+		if lines, err := strconv.Atoi(linesStr); err == nil && lines >= 0 && lines <= 200 {
+			config.LogTailLines = lines
+		}
+	}
+
+	if failuresStr := strings.TrimSpace(os.Getenv("ALERTS_HOST_DOWN_FAILURES")); failuresStr != "" {
+		if failures, err := strconv.Atoi(failuresStr); err == nil && failures > 0 {
+			config.HostDownAfter = failures
+		}
+	}
+
 	switch filter := strings.ToLower(strings.TrimSpace(os.Getenv("ALERTS_FILTER"))); filter {
 	case "", "all":
 		config.AlertsFilter = "all"
@@ -180,6 +753,174 @@ func parseAlertConfig() AlertConfig {
 	return config
 }
 
+func parseHostAlertConfig() HostAlertConfig {
+	config := HostAlertConfig{
+		Enabled:         os.Getenv("ALERTS_HOST_ENABLED") != "false",
+		CPUThreshold:    parsePercentThreshold("ALERTS_HOST_CPU_THRESHOLD", 90),
+		MemoryThreshold: parsePercentThreshold("ALERTS_HOST_MEMORY_THRESHOLD", 90),
+		DiskThreshold:   parsePercentThreshold("ALERTS_HOST_DISK_THRESHOLD", 90),
+		InodeThreshold:  parsePercentThreshold("ALERTS_HOST_INODE_THRESHOLD", 90),
+		For:             5 * time.Minute,
+	}
+
+	if loadStr := strings.TrimSpace(os.Getenv("ALERTS_HOST_LOAD_THRESHOLD")); loadStr != "" {
+		if threshold, err := strconv.ParseFloat(loadStr, 64); err == nil && threshold >= 0 {
+			config.LoadThreshold = threshold
+		}
+	}
+
+	if forStr := strings.TrimSpace(os.Getenv("ALERTS_HOST_FOR")); forStr != "" {
+		if d, err := time.ParseDuration(forStr); err == nil && d >= 0 {
+			config.For = d
+		}
+	}
+
+	for pattern := range strings.SplitSeq(os.Getenv("ALERTS_HOST_IGNORE_MOUNTS"), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.Printf("Ignoring ALERTS_HOST_IGNORE_MOUNTS pattern %q: %v", pattern, err)
+			continue
+		}
+		config.IgnoreMounts = append(config.IgnoreMounts, pattern)
+	}
+
+	return config
+}
+
+// parsePercentThreshold reads a 0-100 threshold where 0 disables the check,
+// returning def when unset or invalid
+func parsePercentThreshold(key string, def float64) float64 {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return def
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold < 0 || threshold > 100 {
+		return def
+	}
+	return threshold
+}
+
+// IgnoresMount reports whether mountpoint, or a directory above it, matches
+// one of IgnoreMounts
+func (c HostAlertConfig) IgnoresMount(mountpoint string) bool {
+	for dir := path.Clean(mountpoint); ; dir = path.Dir(dir) {
+		for _, pattern := range c.IgnoreMounts {
+			if matched, _ := path.Match(pattern, dir); matched {
+				return true
+			}
+		}
+		if dir == "/" || dir == "." {
+			return false
+		}
+	}
+}
+
+func parseThresholdOverrides() []ThresholdOverride {
+	// Format: ALERTS_THRESHOLD_OVERRIDES=host=db|cpu=95|memory=98,container=web-*|memory=70,container=ci-runner|disabled=true
+	raw := os.Getenv("ALERTS_THRESHOLD_OVERRIDES")
+	if raw == "" {
+		return nil
+	}
+
+	var overrides []ThresholdOverride
+	for entry := range strings.SplitSeq(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		override, err := parseThresholdOverride(entry)
+		if err != nil {
+			log.Printf("Ignoring ALERTS_THRESHOLD_OVERRIDES entry %q: %v", entry, err)
+			continue
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides
+}
+
+func parseThresholdOverride(entry string) (ThresholdOverride, error) {
+	var override ThresholdOverride
+	hasSetting := false
+
+	for field := range strings.SplitSeq(entry, "|") {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return override, fmt.Errorf("expected key=value, got %q", field)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "host":
+			override.Host = value
+		case "container":
+			if _, err := path.Match(value, ""); err != nil {
+				return override, fmt.Errorf("invalid container pattern %q", value)
+			}
+			override.ContainerPattern = value
+		case "cpu", "memory":
+			threshold, err := strconv.ParseFloat(value, 64)
+			if err != nil || threshold <= 0 || threshold > 100 {
+				return override, fmt.Errorf("%s threshold must be between 0 and 100", key)
+			}
+			if key == "cpu" {
+				override.CPUThreshold = threshold
+			} else {
+				override.MemoryThreshold = threshold
+			}
+			hasSetting = true
+		case "disabled":
+			disabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return override, fmt.Errorf("invalid disabled value %q", value)
+			}
+			override.Disabled = disabled
+			hasSetting = true
+		default:
+			return override, fmt.Errorf("unknown key %q", key)
+		}
+	}
+
+	if override.Host == "" && override.ContainerPattern == "" {
+		return override, errors.New("host or container is required")
+	}
+	if !hasSetting {
+		return override, errors.New("no cpu, memory or disabled setting")
+	}
+	return override, nil
+}
+
+// parseAlertDuration reads a non-negative duration, returning 0 when unset or invalid
+func parseAlertDuration(key string) time.Duration {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// parseClearThreshold reads a clear threshold, which must not exceed the
+// matching alert threshold. Returns 0 when unset or invalid.
+func parseClearThreshold(key string, threshold float64) float64 {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return 0
+	}
+	clear, err := strconv.ParseFloat(value, 64)
+	if err != nil || clear <= 0 || clear > threshold {
+		return 0
+	}
+	return clear
+}
+
 func parseStatsConfig(alertsCheckInterval time.Duration) StatsConfig {
 	config := StatsConfig{
 		SampleInterval: alertsCheckInterval,
@@ -55,6 +55,16 @@ type FileDiscordBotConfig struct {
 	AllowedChannelID string `json:"allowedChannelId,omitempty"`
 }
 
+// FileServerConfig represents listener and TLS settings stored in the config file.
+type FileServerConfig struct {
+	Listen          []string `json:"listen,omitempty"`
+	UnixSocket      string   `json:"unixSocket,omitempty"`
+	UnixSocketMode  string   `json:"unixSocketMode,omitempty"` // Octal, e.g. "0660"
+	TLSCertFile     string   `json:"tlsCertFile,omitempty"`
+	TLSKeyFile      string   `json:"tlsKeyFile,omitempty"`
+	TLSClientCAFile string   `json:"tlsClientCAFile,omitempty"`
+}
+
 // FileConfig represents the JSON config file structure.
 type FileConfig struct {
 	DockerHosts  []DockerHost        `json:"dockerHosts,omitempty"`
@@ -63,6 +73,10 @@ type FileConfig struct {
 	Auth         *FileAuthConfig     `json:"auth,omitempty"`
 	Bot          *FileBotConfig      `json:"bot,omitempty"`
 	Scanner      *FileScannerConfig  `json:"scanner,omitempty"`
+	OIDC         *FileOIDCConfig     `json:"oidc,omitempty"`
+	Server       *FileServerConfig   `json:"server,omitempty"`
+
+	Notifications *NotificationsConfig `json:"notifications,omitempty"`
 }
 
 // Source indicates where a config value came from.
@@ -85,6 +99,8 @@ type EnvSnapshot struct {
 	AuthSet        bool
 	BotSet         bool
 	ScannerSet     bool
+	OIDCSet        bool
+	ServerSet      bool
 }
 
 // Manager handles loading, merging, and persisting configuration.
@@ -102,11 +118,14 @@ type Manager struct {
 
 // ConfigSources tracks the source of each config category.
 type ConfigSources struct {
-	DockerHosts  Source `json:"dockerHosts"`
-	CoolifyHosts Source `json:"coolifyHosts"`
-	ReadOnly     Source `json:"readOnly"`
-	Auth         Source `json:"auth"`
-	Bot          Source `json:"bot"`
+	DockerHosts   Source `json:"dockerHosts"`
+	CoolifyHosts  Source `json:"coolifyHosts"`
+	ReadOnly      Source `json:"readOnly"`
+	Auth          Source `json:"auth"`
+	Bot           Source `json:"bot"`
+	OIDC          Source `json:"oidc"`
+	Server        Source `json:"server"`
+	Notifications Source `json:"notifications"`
 }
 
 // NewManager creates a config manager that loads from env vars and an optional file.
@@ -153,6 +172,14 @@ func NewManager() *Manager {
 			os.Getenv("SCANNER_BULK_TIMEOUT_MINUTES") != "" ||
 			os.Getenv("SCANNER_MEMORY_MB") != "" ||
 			os.Getenv("SCANNER_PIDS_LIMIT") != "",
+		OIDCSet: os.Getenv("OIDC_ISSUER_URL") != "" ||
+			os.Getenv("OIDC_CLIENT_ID") != "",
+		ServerSet: os.Getenv("LISTEN_ADDR") != "" ||
+			os.Getenv("BACKEND_PORT") != "" ||
+			os.Getenv("LISTEN_UNIX_SOCKET") != "" ||
+			os.Getenv("TLS_CERT_FILE") != "" ||
+			os.Getenv("TLS_KEY_FILE") != "" ||
+			os.Getenv("TLS_CLIENT_CA_FILE") != "",
 	}
 
 	// Load env-based config using existing parsers.
@@ -446,6 +473,25 @@ func (m *Manager) UpdateScannerConfig(scanner *FileScannerConfig) error {
 	return nil
 }
 
+// UpdateNotifications replaces the alert notification channels and routes.
+func (m *Manager) UpdateNotifications(notifications *NotificationsConfig) error {
+	if err := notifications.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+
+	oldNotifications := m.fileConfig.Notifications
+	m.fileConfig.Notifications = notifications
+	if err := m.persist(); err != nil {
+		m.fileConfig.Notifications = oldNotifications
+		m.mu.Unlock()
+		return err
+	}
+	m.remerge()
+	return nil
+}
+
 // merge produces the merged config and source tracking. Must be called with lock held.
 func (m *Manager) merge() (*Config, ConfigSources) {
 	cfg := &Config{}
@@ -455,6 +501,14 @@ func (m *Manager) merge() (*Config, ConfigSources) {
 	cfg.Hostname = m.envConfig.Hostname
 	cfg.Alerts = m.envConfig.Alerts
 	cfg.Stats = m.envConfig.Stats
+	cfg.Push = m.envConfig.Push
+	cfg.Sessions = m.envConfig.Sessions
+	cfg.Audit = m.envConfig.Audit
+	cfg.Metrics = m.envConfig.Metrics
+	cfg.LoginProtection = m.envConfig.LoginProtection
+	cfg.RateLimits = m.envConfig.RateLimits
+	cfg.TrustedProxies = m.envConfig.TrustedProxies
+	cfg.ShutdownTimeout = m.envConfig.ShutdownTimeout
 
 	// Docker hosts: env hosts + file hosts combined. Env hosts win on name collision.
 	envDockerNames := make(map[string]bool)
@@ -524,6 +578,56 @@ func (m *Manager) merge() (*Config, ConfigSources) {
 		sources.Auth = SourceDefault
 	}
 
+	// OIDC: env wins as a whole, like auth
+	if m.envSnapshot.OIDCSet {
+		cfg.OIDC = m.envConfig.OIDC
+		sources.OIDC = SourceEnv
+	} else if fc := m.fileConfig.OIDC; fc != nil {
+		cfg.OIDC = NormalizeOIDCConfig(OIDCConfig{
+			IssuerURL:         fc.IssuerURL,
+			ClientID:          fc.ClientID,
+			ClientSecret:      fc.ClientSecret,
+			RedirectURL:       fc.RedirectURL,
+			Scopes:            fc.Scopes,
+			UsernameClaim:     fc.UsernameClaim,
+			GroupsClaim:       fc.GroupsClaim,
+			AdminGroups:       fc.AdminGroups,
+			OperatorGroups:    fc.OperatorGroups,
+			ViewerGroups:      fc.ViewerGroups,
+			DefaultRole:       fc.DefaultRole,
+			PostLoginRedirect: fc.PostLoginRedirect,
+		})
+		sources.OIDC = SourceFile
+	} else {
+		sources.OIDC = SourceDefault
+	}
+
+	// Server: env wins as a whole, like auth
+	if m.envSnapshot.ServerSet {
+		cfg.Server = m.envConfig.Server
+		sources.Server = SourceEnv
+	} else if fc := m.fileConfig.Server; fc != nil {
+		server := ServerConfig{
+			Listen:          fc.Listen,
+			UnixSocket:      fc.UnixSocket,
+			TLSCertFile:     fc.TLSCertFile,
+			TLSKeyFile:      fc.TLSKeyFile,
+			TLSClientCAFile: fc.TLSClientCAFile,
+		}
+		if fc.UnixSocketMode != "" {
+			mode, err := parseFileMode(fc.UnixSocketMode)
+			if err != nil {
+				log.Printf("Ignoring server.unixSocketMode %q: %v", fc.UnixSocketMode, err)
+			}
+			server.UnixSocketMode = mode
+		}
+		cfg.Server = NormalizeServerConfig(server)
+		sources.Server = SourceFile
+	} else {
+		cfg.Server = m.envConfig.Server
+		sources.Server = SourceDefault
+	}
+
 	cfg.Bot = m.envConfig.Bot
 	if fc := m.fileConfig.Bot; fc != nil {
 		if fc.Enabled != nil {
@@ -624,6 +728,14 @@ func (m *Manager) merge() (*Config, ConfigSources) {
 		}
 	}
 
+	// Notifications are only managed through the config file
+	if m.fileConfig.Notifications != nil {
+		cfg.Notifications = *m.fileConfig.Notifications
+		sources.Notifications = SourceFile
+	} else {
+		sources.Notifications = SourceDefault
+	}
+
 	return cfg, sources
 }
 
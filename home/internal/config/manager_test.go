@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -778,6 +779,146 @@ func TestAlertFilterNormalizesSupportedValues(t *testing.T) {
 	}
 }
 
+func TestAlertSustainAndClearThresholds(t *testing.T) {
+	t.Setenv("ALERTS_CPU_THRESHOLD", "90")
+	t.Setenv("ALERTS_CPU_FOR", "5m")
+	t.Setenv("ALERTS_CPU_CLEAR_THRESHOLD", "75")
+	t.Setenv("ALERTS_MEMORY_FOR", "-1m")
+	t.Setenv("ALERTS_MEMORY_CLEAR_THRESHOLD", "99")
+	cfg := NewConfig()
+
+	if cfg.Alerts.CPUFor != 5*time.Minute || cfg.Alerts.CPUClear != 75 {
+		t.Fatalf("unexpected CPU sustain settings: for=%s clear=%v", cfg.Alerts.CPUFor, cfg.Alerts.CPUClear)
+	}
+	if cfg.Alerts.MemoryFor != 0 {
+		t.Fatalf("expected negative memory window to be ignored, got %s", cfg.Alerts.MemoryFor)
+	}
+	if cfg.Alerts.MemoryClear != 0 {
+		t.Fatalf("expected clear threshold above the alert threshold to be ignored, got %v", cfg.Alerts.MemoryClear)
+	}
+	if cfg.Alerts.CPUClearThreshold() != 75 || cfg.Alerts.MemoryClearThreshold() != cfg.Alerts.MemoryThreshold {
+		t.Fatalf("unexpected effective clear thresholds: cpu=%v mem=%v", cfg.Alerts.CPUClearThreshold(), cfg.Alerts.MemoryClearThreshold())
+	}
+}
+
+func TestAlertThresholdOverridesSkipInvalidEntries(t *testing.T) {
+	t.Setenv("ALERTS_THRESHOLD_OVERRIDES", "host=db|memory=98, container=web-*|cpu=70|disabled=false ,container=ci-*|disabled=true,cpu=90,container=x|cpu=150,container=[|cpu=50")
+	cfg := NewConfig()
+
+	want := []ThresholdOverride{
+		{Host: "db", MemoryThreshold: 98},
+		{ContainerPattern: "web-*", CPUThreshold: 70},
+		{ContainerPattern: "ci-*", Disabled: true},
+	}
+	if !reflect.DeepEqual(cfg.Alerts.Overrides, want) {
+		t.Fatalf("unexpected overrides: %+v", cfg.Alerts.Overrides)
+	}
+	if !want[1].Matches("any-host", "web-frontend") || want[1].Matches("any-host", "api") {
+		t.Fatal("unexpected container pattern matching")
+	}
+	if want[0].Matches("other", "postgres") {
+		t.Fatal("expected host-scoped override not to match other hosts")
+	}
+}
+
+func TestHostAlertConfig(t *testing.T) {
+	t.Setenv("ALERTS_HOST_CPU_THRESHOLD", "0")
+	t.Setenv("ALERTS_HOST_DISK_THRESHOLD", "85")
+	t.Setenv("ALERTS_HOST_MEMORY_THRESHOLD", "120")
+	t.Setenv("ALERTS_HOST_LOAD_THRESHOLD", "1.5")
+	t.Setenv("ALERTS_HOST_FOR", "1m")
+	t.Setenv("ALERTS_HOST_IGNORE_MOUNTS", "/boot*, [ ,/snap/*")
+	cfg := NewConfig()
+
+	want := HostAlertConfig{
+		Enabled:         true,
+		CPUThreshold:    0,
+		MemoryThreshold: 90,
+		DiskThreshold:   85,
+		InodeThreshold:  90,
+		LoadThreshold:   1.5,
+		For:             time.Minute,
+		IgnoreMounts:    []string{"/boot*", "/snap/*"},
+	}
+	if !reflect.DeepEqual(cfg.Alerts.Host, want) {
+		t.Fatalf("unexpected host alert config: %+v", cfg.Alerts.Host)
+	}
+	if !want.IgnoresMount("/boot/efi") || !want.IgnoresMount("/snap/core/123") || want.IgnoresMount("/var") || want.IgnoresMount("/") {
+		t.Fatal("unexpected mount ignore matching")
+	}
+}
+
+func TestLoginProtectionAndRateLimits(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+	t.Setenv("LOGIN_LOCKOUT", "2h")
+	t.Setenv("LOGIN_MAX_LOCKOUT", "1h")
+	t.Setenv("LOGIN_FAILURE_WINDOW", "-1m")
+	t.Setenv("RATE_LIMIT_LOGIN", "5/30s")
+	t.Setenv("RATE_LIMIT_EXEC", "off")
+	t.Setenv("RATE_LIMIT_SCANS", "fast")
+	cfg := NewConfig()
+
+	wantLogin := LoginProtectionConfig{MaxFailures: 3, Lockout: 2 * time.Hour, MaxLockout: 2 * time.Hour, FailureWindow: 15 * time.Minute}
+	if cfg.LoginProtection != wantLogin {
+		t.Fatalf("unexpected login protection config: %+v", cfg.LoginProtection)
+	}
+	wantLimits := RateLimitConfig{
+		Login: RateLimit{Requests: 5, Per: 30 * time.Second},
+		Scans: RateLimit{Requests: 30, Per: time.Minute},
+	}
+	if cfg.RateLimits != wantLimits {
+		t.Fatalf("unexpected rate limits: %+v", cfg.RateLimits)
+	}
+	if cfg.RateLimits.Exec.Enabled() || cfg.RateLimits.Exec.String() != "off" || cfg.RateLimits.Login.String() != "5/30s" {
+		t.Fatalf("unexpected rate limit formatting: %s, %s", cfg.RateLimits.Exec, cfg.RateLimits.Login)
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	t.Setenv("LISTEN_ADDR", "")
+	t.Setenv("BACKEND_PORT", "8080")
+	t.Setenv("LISTEN_UNIX_SOCKET_MODE", "999")
+	cfg := NewConfig()
+	want := ServerConfig{Listen: []string{":8080"}, UnixSocketMode: 0o660}
+	if !reflect.DeepEqual(cfg.Server, want) {
+		t.Fatalf("unexpected env server config: %+v", cfg.Server)
+	}
+
+	// Without server env vars the config file applies
+	m := &Manager{
+		envConfig: NewConfig(),
+		filePath:  filepath.Join(t.TempDir(), "config.json"),
+		fileConfig: FileConfig{Server: &FileServerConfig{
+			Listen:         []string{" 127.0.0.1:8443 ", ""},
+			UnixSocket:     "/run/vps-monitor.sock",
+			UnixSocketMode: "0600",
+			TLSCertFile:    "/certs/tls.crt",
+			TLSKeyFile:     "/certs/tls.key",
+		}},
+	}
+	m.merged, m.sources = m.merge()
+	want = ServerConfig{
+		Listen:         []string{"127.0.0.1:8443"},
+		UnixSocket:     "/run/vps-monitor.sock",
+		UnixSocketMode: 0o600,
+		TLSCertFile:    "/certs/tls.crt",
+		TLSKeyFile:     "/certs/tls.key",
+	}
+	if got := m.Config().Server; !reflect.DeepEqual(got, want) || m.Sources().Server != SourceFile {
+		t.Fatalf("unexpected file server config: %+v (%s)", got, m.Sources().Server)
+	}
+	if err := want.Validate(); err != nil || !want.TLSEnabled() {
+		t.Fatalf("expected a valid TLS config, got %v", err)
+	}
+
+	// Env wins as a whole
+	m.envSnapshot.ServerSet = true
+	m.merged, m.sources = m.merge()
+	if got := m.Config().Server; !reflect.DeepEqual(got, cfg.Server) || m.Sources().Server != SourceEnv {
+		t.Fatalf("expected the env server config, got %+v (%s)", got, m.Sources().Server)
+	}
+}
+
 func TestStatsSampleIntervalFallsBackToAlertsInterval(t *testing.T) {
 	t.Setenv("ALERTS_CHECK_INTERVAL", "45s")
 	t.Setenv("STATS_SAMPLE_INTERVAL", "")
@@ -823,3 +964,113 @@ func TestUpdateBotConfigPersistsAndMergesDiscord(t *testing.T) {
 		t.Fatalf("unexpected merged discord bot config: %+v", merged.Bot.Discord)
 	}
 }
+
+func TestUpdateNotificationsPersistsAndMerges(t *testing.T) {
+	m := &Manager{
+		envSnapshot: EnvSnapshot{},
+		envConfig:   NewConfig(),
+		filePath:    filepath.Join(t.TempDir(), "config.json"),
+	}
+	m.merged, m.sources = m.merge()
+	if m.Sources().Notifications != SourceDefault {
+		t.Fatalf("expected notifications source to be default, got %s", m.Sources().Notifications)
+	}
+
+	err := m.UpdateNotifications(&NotificationsConfig{
+		Channels: []NotificationChannel{
+			{Name: "ops", Type: ChannelSlack, Enabled: true, URL: "https://hooks.slack.com/services/x"},
+			{Name: "pager", Type: ChannelNtfy, Enabled: true, URL: "https://ntfy.sh/alerts"},
+		},
+		Routes: []NotificationRoute{{Channels: []string{"pager"}, MinSeverity: "critical"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateNotifications returned error: %v", err)
+	}
+
+	merged := m.Config()
+	if len(merged.Notifications.Channels) != 2 || len(merged.Notifications.Routes) != 1 {
+		t.Fatalf("unexpected merged notifications: %+v", merged.Notifications)
+	}
+	if m.Sources().Notifications != SourceFile {
+		t.Fatalf("expected notifications source to be file, got %s", m.Sources().Notifications)
+	}
+}
+
+func TestNotificationsValidate(t *testing.T) {
+	valid := []NotificationChannel{
+		{Name: "hook", Type: ChannelWebhook, URL: "https://example.com/hook"},
+		{Name: "tg", Type: ChannelTelegram},
+		{Name: "gotify", Type: ChannelGotify, URL: "https://gotify.example.com", Token: "app-token"},
+		{Name: "mail", Type: ChannelEmail, SMTP: &SMTPConfig{Host: "smtp.example.com", Port: 587, From: "a@example.com", To: []string{"b@example.com"}}},
+	}
+	escalations := []EscalationPolicy{{Name: "page", MinSeverity: "critical", AfterMinutes: 15, RepeatMinutes: 30, Channels: []string{"tg"}}}
+	if err := (NotificationsConfig{Channels: valid, Escalations: escalations}).Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	tests := map[string]NotificationsConfig{
+		"empty name":           {Channels: []NotificationChannel{{Type: ChannelTelegram}}},
+		"duplicate name":       {Channels: []NotificationChannel{{Name: "a", Type: ChannelTelegram}, {Name: "a", Type: ChannelTelegram}}},
+		"unknown type":         {Channels: []NotificationChannel{{Name: "a", Type: "pigeon"}}},
+		"bad url scheme":       {Channels: []NotificationChannel{{Name: "a", Type: ChannelSlack, URL: "ftp://example.com"}}},
+		"gotify no token":      {Channels: []NotificationChannel{{Name: "a", Type: ChannelGotify, URL: "https://example.com"}}},
+		"email no smtp":        {Channels: []NotificationChannel{{Name: "a", Type: ChannelEmail}}},
+		"route unknown":        {Channels: valid, Routes: []NotificationRoute{{Channels: []string{"missing"}}}},
+		"route empty":          {Channels: valid, Routes: []NotificationRoute{{Types: []string{"host_down"}}}},
+		"route severity":       {Channels: valid, Routes: []NotificationRoute{{Channels: []string{"tg"}, MinSeverity: "urgent"}}},
+		"header name":          {Channels: []NotificationChannel{{Name: "a", Type: ChannelWebhook, URL: "https://example.com", Headers: map[string]string{"Bad Header": "x"}}}},
+		"header value":         {Channels: []NotificationChannel{{Name: "a", Type: ChannelWebhook, URL: "https://example.com", Headers: map[string]string{"X-Test": "a\r\nX-Other: b"}}}},
+		"secret on slack":      {Channels: []NotificationChannel{{Name: "a", Type: ChannelSlack, URL: "https://example.com", Secret: "x"}}},
+		"escalation no name":   {Channels: valid, Escalations: []EscalationPolicy{{AfterMinutes: 10}}},
+		"escalation no delay":  {Channels: valid, Escalations: []EscalationPolicy{{Name: "page"}}},
+		"escalation repeat":    {Channels: valid, Escalations: []EscalationPolicy{{Name: "page", AfterMinutes: 10, RepeatMinutes: -1}}},
+		"escalation channel":   {Channels: valid, Escalations: []EscalationPolicy{{Name: "page", AfterMinutes: 10, Channels: []string{"missing"}}}},
+		"escalation duplicate": {Channels: valid, Escalations: []EscalationPolicy{{Name: "page", AfterMinutes: 10}, {Name: "page", AfterMinutes: 5}}},
+		"escalation severity":  {Channels: valid, Escalations: []EscalationPolicy{{Name: "page", AfterMinutes: 10, MinSeverity: "urgent"}}},
+	}
+	for name, cfg := range tests {
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidNotifications) {
+			t.Errorf("%s: expected ErrInvalidNotifications, got %v", name, err)
+		}
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	t.Setenv("SHUTDOWN_TIMEOUT", "")
+	if got := NewConfig().ShutdownTimeout; got != 30*time.Second {
+		t.Fatalf("expected the default of 30s, got %s", got)
+	}
+	t.Setenv("SHUTDOWN_TIMEOUT", "2m")
+	m := &Manager{envConfig: NewConfig(), filePath: filepath.Join(t.TempDir(), "config.json")}
+	m.merged, m.sources = m.merge()
+	if got := m.Config().ShutdownTimeout; got != 2*time.Minute {
+		t.Fatalf("expected 2m, got %s", got)
+	}
+	t.Setenv("SHUTDOWN_TIMEOUT", "500ms")
+	if got := NewConfig().ShutdownTimeout; got != 30*time.Second {
+		t.Fatalf("expected a too short timeout to be ignored, got %s", got)
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.5, unix, proxy.example.com")
+	m := &Manager{envConfig: NewConfig(), filePath: filepath.Join(t.TempDir(), "config.json")}
+	m.merged, m.sources = m.merge()
+
+	proxies := m.Config().TrustedProxies
+	if len(proxies.Networks) != 2 || !proxies.UnixSocket {
+		t.Fatalf("expected two networks and the Unix socket, got %+v", proxies)
+	}
+	for addr, want := range map[string]bool{
+		"10.1.2.3":        true,
+		"::ffff:10.1.2.3": true,
+		"192.168.1.5":     true,
+		"192.168.1.6":     false,
+		"@":               true,
+		"2001:db8::1":     false,
+	} {
+		if got := proxies.Trusts(addr); got != want {
+			t.Errorf("Trusts(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
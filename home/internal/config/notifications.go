@@ -0,0 +1,212 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// Notification channel types
+const (
+	ChannelWebhook  = "webhook"
+	ChannelSlack    = "slack"
+	ChannelDiscord  = "discord"
+	ChannelTelegram = "telegram"
+	ChannelTeams    = "teams"
+	ChannelNtfy     = "ntfy"
+	ChannelGotify   = "gotify"
+	ChannelEmail    = "email"
+)
+
+// ErrInvalidNotifications is returned when notification settings fail validation
+var ErrInvalidNotifications = errors.New("invalid notification settings")
+
+// NotificationsConfig holds the alert notification channels, the routes
+// deciding which alerts reach them and the escalation policies for alerts
+// nobody acknowledges
+type NotificationsConfig struct {
+	Channels    []NotificationChannel `json:"channels,omitempty"`
+	Routes      []NotificationRoute   `json:"routes,omitempty"`
+	Escalations []EscalationPolicy    `json:"escalations,omitempty"`
+}
+
+// NotificationChannel is one destination for alert notifications
+type NotificationChannel struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+	// Webhook URL for webhook, slack, discord and teams, the topic URL for
+	// ntfy and the server URL for gotify
+	URL    string      `json:"url,omitempty"`
+	Token  string      `json:"token,omitempty"`  // gotify app token or ntfy access token
+	ChatID string      `json:"chatId,omitempty"` // telegram chat, defaults to the bot's allowed chat
+	SMTP   *SMTPConfig `json:"smtp,omitempty"`
+
+	// Generic webhook options. Secret signs the body with HMAC-SHA256,
+	// Headers are added to every request and Template replaces the default
+	// JSON payload with a Go text/template.
+	Secret   string            `json:"secret,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Template string            `json:"template,omitempty"`
+}
+
+// SMTPConfig configures an email channel
+type SMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// NotificationRoute sends alerts matching Types and MinSeverity to Channels.
+// Empty Types matches every alert type and empty MinSeverity every severity.
+type NotificationRoute struct {
+	Name        string   `json:"name,omitempty"`
+	Channels    []string `json:"channels"`
+	Types       []string `json:"types,omitempty"`
+	MinSeverity string   `json:"minSeverity,omitempty"`
+}
+
+// EscalationPolicy notifies again about alerts matching Types and
+// MinSeverity that are not acknowledged AfterMinutes after firing, then
+// every RepeatMinutes until they are acknowledged or resolved. Escalations
+// go to Channels, or through the routes when Channels is empty.
+type EscalationPolicy struct {
+	Name          string   `json:"name"`
+	Types         []string `json:"types,omitempty"`
+	MinSeverity   string   `json:"minSeverity,omitempty"`
+	AfterMinutes  int      `json:"afterMinutes"`
+	RepeatMinutes int      `json:"repeatMinutes,omitempty"` // 0 escalates once
+	Channels      []string `json:"channels,omitempty"`
+}
+
+// Validate checks channel settings and that routes and escalation policies
+// only reference known channels
+func (c NotificationsConfig) Validate() error {
+	names := make(map[string]struct{}, len(c.Channels))
+	for _, ch := range c.Channels {
+		name := strings.TrimSpace(ch.Name)
+		if name == "" {
+			return fmt.Errorf("%w: channel name cannot be empty", ErrInvalidNotifications)
+		}
+		if _, exists := names[name]; exists {
+			return fmt.Errorf("%w: duplicate channel name %q", ErrInvalidNotifications, name)
+		}
+		names[name] = struct{}{}
+		if err := ch.validate(); err != nil {
+			return fmt.Errorf("%w: channel %q: %v", ErrInvalidNotifications, name, err)
+		}
+	}
+
+	for i, route := range c.Routes {
+		if len(route.Channels) == 0 {
+			return fmt.Errorf("%w: route %d has no channels", ErrInvalidNotifications, i+1)
+		}
+		for _, name := range route.Channels {
+			if _, ok := names[name]; !ok {
+				return fmt.Errorf("%w: route %d references unknown channel %q", ErrInvalidNotifications, i+1, name)
+			}
+		}
+		switch route.MinSeverity {
+		case "", "info", "warning", "critical":
+		default:
+			return fmt.Errorf("%w: route %d has unknown severity %q", ErrInvalidNotifications, i+1, route.MinSeverity)
+		}
+	}
+
+	policies := make(map[string]struct{}, len(c.Escalations))
+	for _, policy := range c.Escalations {
+		name := strings.TrimSpace(policy.Name)
+		if name == "" {
+			return fmt.Errorf("%w: escalation policy name cannot be empty", ErrInvalidNotifications)
+		}
+		if _, exists := policies[name]; exists {
+			return fmt.Errorf("%w: duplicate escalation policy name %q", ErrInvalidNotifications, name)
+		}
+		policies[name] = struct{}{}
+		if policy.AfterMinutes <= 0 {
+			return fmt.Errorf("%w: escalation policy %q needs a positive afterMinutes", ErrInvalidNotifications, name)
+		}
+		if policy.RepeatMinutes < 0 {
+			return fmt.Errorf("%w: escalation policy %q has a negative repeatMinutes", ErrInvalidNotifications, name)
+		}
+		for _, channel := range policy.Channels {
+			if _, ok := names[channel]; !ok {
+				return fmt.Errorf("%w: escalation policy %q references unknown channel %q", ErrInvalidNotifications, name, channel)
+			}
+		}
+		switch policy.MinSeverity {
+		case "", "info", "warning", "critical":
+		default:
+			return fmt.Errorf("%w: escalation policy %q has unknown severity %q", ErrInvalidNotifications, name, policy.MinSeverity)
+		}
+	}
+	return nil
+}
+
+func (ch NotificationChannel) validate() error {
+	if ch.Type != ChannelWebhook && (ch.Secret != "" || len(ch.Headers) > 0 || ch.Template != "") {
+		return errors.New("secret, headers and template are only supported for webhook channels")
+	}
+	for name, value := range ch.Headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %q contains a line break", name)
+		}
+	}
+
+	switch ch.Type {
+	case ChannelWebhook, ChannelSlack, ChannelDiscord, ChannelTeams, ChannelNtfy:
+		return validateChannelURL(ch.URL)
+	case ChannelGotify:
+		if ch.Token == "" {
+			return errors.New("token is required")
+		}
+		return validateChannelURL(ch.URL)
+	case ChannelTelegram:
+		return nil
+	case ChannelEmail:
+		if ch.SMTP == nil || ch.SMTP.Host == "" || ch.SMTP.From == "" || len(ch.SMTP.To) == 0 {
+			return errors.New("smtp host, from and to are required")
+		}
+		if ch.SMTP.Port < 0 || ch.SMTP.Port > 65535 {
+			return fmt.Errorf("invalid smtp port %d", ch.SMTP.Port)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown channel type %q", ch.Type)
+	}
+}
+
+// validHeaderName reports whether name is a valid HTTP header field name
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c > unicode.MaxASCII || !(unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("!#$%&'*+-.^_`|~", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func validateChannelURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return errors.New("a valid url is required")
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return errors.New("url scheme must be http or https")
+	}
+	if parsed.Hostname() == "" {
+		return errors.New("invalid url host")
+	}
+	return nil
+}
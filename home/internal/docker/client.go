@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"sync"
 	"time"
@@ -12,7 +13,9 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
 	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/metrics"
 	"github.com/hhftechnology/vps-monitor/internal/models"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type MultiHostClient struct {
@@ -47,12 +50,14 @@ func NewMultiHostClient(hosts []config.DockerHost) (*MultiHostClient, error) {
 				client.WithHost(helper.Host),
 				client.WithDialContext(helper.Dialer),
 				client.WithAPIVersionNegotiation(),
+				withLatencyMetric(host.Name),
 			)
 		} else {
 			apiClient, err = client.NewClientWithOpts(
 				client.WithHost(host.Host),
 				client.WithAPIVersionNegotiation(),
 				client.FromEnv,
+				withLatencyMetric(host.Name),
 			)
 		}
 
@@ -68,6 +73,20 @@ func NewMultiHostClient(hosts []config.DockerHost) (*MultiHostClient, error) {
 	}, nil
 }
 
+// withLatencyMetric records the time to the first response byte of every
+// Docker API request, through the HTTP tracing the client already wraps its
+// transport in
+func withLatencyMetric(hostName string) client.Opt {
+	return client.WithTraceOptions(otelhttp.WithClientTrace(func(context.Context) *httptrace.ClientTrace {
+		start := time.Now()
+		return &httptrace.ClientTrace{
+			GotFirstResponseByte: func() {
+				metrics.DockerAPIDuration.Observe(time.Since(start).Seconds(), hostName)
+			},
+		}
+	}))
+}
+
 type HostError struct {
 	HostName string
 	Err      error
@@ -155,6 +174,20 @@ func (c *MultiHostClient) GetClient(hostName string) (*client.Client, error) {
 	return apiClient, nil
 }
 
+// PingHost checks that the Docker daemon of hostName answers and returns
+// the round-trip time
+func (c *MultiHostClient) PingHost(ctx context.Context, hostName string) (time.Duration, error) {
+	apiClient, err := c.GetClient(hostName)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	if _, err := apiClient.Ping(ctx); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
 func (c *MultiHostClient) GetHosts() []config.DockerHost {
 	return c.hosts
 }
@@ -0,0 +1,237 @@
+// Package metrics writes metrics in the Prometheus text exposition format.
+// Counters and histograms of the internals are updated where the work
+// happens; everything else is read at scrape time by the /metrics handler.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric types of the exposition format
+const (
+	TypeGauge     = "gauge"
+	TypeCounter   = "counter"
+	TypeHistogram = "histogram"
+)
+
+// Internal metrics, written after the scrape-time metrics
+var (
+	ScanDuration = NewHistogramVec("vps_monitor_scan_duration_seconds",
+		"Duration of completed vulnerability scans.",
+		[]float64{5, 15, 30, 60, 120, 300, 600, 1200}, "scanner")
+	ScansTotal = NewCounterVec("vps_monitor_scans_total",
+		"Finished vulnerability scans by result.", "scanner", "status")
+	DockerAPIDuration = NewHistogramVec("vps_monitor_docker_api_request_duration_seconds",
+		"Time from sending a Docker API request to the first response byte.",
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}, "host")
+	WebhookFailures = NewCounterVec("vps_monitor_webhook_failures_total",
+		"Notifications that could not be delivered, after retries.", "source", "type")
+
+	internal = []interface{ write(*Writer) }{ScanDuration, ScansTotal, DockerAPIDuration, WebhookFailures}
+)
+
+// WriteInternal writes the internal metrics
+func WriteInternal(w *Writer) {
+	for _, m := range internal {
+		m.write(w)
+	}
+}
+
+// Writer writes metric families. The samples of a family must follow its
+// Family call.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewWriter buffers the output; call Flush when done
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Family starts a metric family with its help text and type
+func (w *Writer) Family(name, help, typ string) {
+	w.writeString("# HELP ", name, " ", escapeHelp(help), "\n")
+	w.writeString("# TYPE ", name, " ", typ, "\n")
+}
+
+// Sample writes one sample; labels are name, value pairs
+func (w *Writer) Sample(name string, value float64, labels ...string) {
+	w.writeString(name)
+	if len(labels) > 1 {
+		w.writeString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.writeString(",")
+			}
+			w.writeString(labels[i], `="`, escapeLabel(labels[i+1]), `"`)
+		}
+		w.writeString("}")
+	}
+	w.writeString(" ", formatValue(value), "\n")
+}
+
+// Flush writes out the buffered samples and returns the first error
+func (w *Writer) Flush() error {
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	return w.err
+}
+
+func (w *Writer) writeString(parts ...string) {
+	for _, p := range parts {
+		if w.err != nil {
+			return
+		}
+		_, w.err = w.w.WriteString(p)
+	}
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+// labelPairs zips label names with values
+func labelPairs(names, values []string, extra ...string) []string {
+	pairs := make([]string, 0, 2*len(names)+len(extra))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, name, value)
+	}
+	return append(pairs, extra...)
+}
+
+// vec holds one value per combination of label values
+type vec[T any] struct {
+	mu     sync.Mutex
+	labels []string
+	values map[string]*T
+	keys   map[string][]string
+}
+
+func (v *vec[T]) get(labelValues []string) *T {
+	key := strings.Join(labelValues, "\xff")
+	if value, ok := v.values[key]; ok {
+		return value
+	}
+	if v.values == nil {
+		v.values = make(map[string]*T)
+		v.keys = make(map[string][]string)
+	}
+	value := new(T)
+	v.values[key] = value
+	v.keys[key] = slices.Clone(labelValues)
+	return value
+}
+
+// each calls fn in a stable order
+func (v *vec[T]) each(fn func(labelValues []string, value *T)) {
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		fn(v.keys[key], v.values[key])
+	}
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	name, help string
+	vec        vec[float64]
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{name: name, help: help, vec: vec[float64]{labels: labels}}
+}
+
+// Inc adds one to the counter with the given label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.vec.mu.Lock()
+	defer c.vec.mu.Unlock()
+	*c.vec.get(labelValues)++
+}
+
+func (c *CounterVec) write(w *Writer) {
+	c.vec.mu.Lock()
+	defer c.vec.mu.Unlock()
+	w.Family(c.name, c.help, TypeCounter)
+	c.vec.each(func(labelValues []string, value *float64) {
+		w.Sample(c.name, *value, labelPairs(c.vec.labels, labelValues)...)
+	})
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	name, help string
+	buckets    []float64
+	vec        vec[histogram]
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{name: name, help: help, buckets: buckets, vec: vec[histogram]{labels: labels}}
+}
+
+// Observe records a value for the given label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.vec.mu.Lock()
+	defer h.vec.mu.Unlock()
+	hist := h.vec.get(labelValues)
+	if hist.counts == nil {
+		hist.counts = make([]uint64, len(h.buckets))
+	}
+	if i, _ := slices.BinarySearch(h.buckets, value); i < len(h.buckets) {
+		hist.counts[i]++
+	}
+	hist.count++
+	hist.sum += value
+}
+
+func (h *HistogramVec) write(w *Writer) {
+	h.vec.mu.Lock()
+	defer h.vec.mu.Unlock()
+	w.Family(h.name, h.help, TypeHistogram)
+	h.vec.each(func(labelValues []string, hist *histogram) {
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += hist.counts[i]
+			w.Sample(h.name+"_bucket", float64(cumulative), labelPairs(h.vec.labels, labelValues, "le", formatValue(bound))...)
+		}
+		w.Sample(h.name+"_bucket", float64(hist.count), labelPairs(h.vec.labels, labelValues, "le", "+Inf")...)
+		w.Sample(h.name+"_sum", hist.sum, labelPairs(h.vec.labels, labelValues)...)
+		w.Sample(h.name+"_count", float64(hist.count), labelPairs(h.vec.labels, labelValues)...)
+	})
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriterEscapesLabels(t *testing.T) {
+	var out strings.Builder
+	w := NewWriter(&out)
+	w.Family("vps_monitor_container_pids", "Processes in a container.\nPer host.", TypeGauge)
+	w.Sample("vps_monitor_container_pids", 12, "host", "local", "container", `web "1"\n`)
+	w.Sample("vps_monitor_up", 1)
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := `# HELP vps_monitor_container_pids Processes in a container.\nPer host.
+# TYPE vps_monitor_container_pids gauge
+vps_monitor_container_pids{host="local",container="web \"1\"\\n"} 12
+vps_monitor_up 1
+`
+	if out.String() != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestCounterAndHistogram(t *testing.T) {
+	failures := NewCounterVec("test_failures_total", "Failures.", "type")
+	failures.Inc("slack")
+	failures.Inc("discord")
+	failures.Inc("slack")
+
+	durations := NewHistogramVec("test_duration_seconds", "Durations.", []float64{1, 5}, "scanner")
+	durations.Observe(0.5, "grype")
+	durations.Observe(1, "grype")
+	durations.Observe(3, "grype")
+	durations.Observe(60, "grype")
+
+	var out strings.Builder
+	w := NewWriter(&out)
+	failures.write(w)
+	durations.write(w)
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := `# HELP test_failures_total Failures.
+# TYPE test_failures_total counter
+test_failures_total{type="discord"} 1
+test_failures_total{type="slack"} 2
+# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{scanner="grype",le="1"} 2
+test_duration_seconds_bucket{scanner="grype",le="5"} 3
+test_duration_seconds_bucket{scanner="grype",le="+Inf"} 4
+test_duration_seconds_sum{scanner="grype"} 64.5
+test_duration_seconds_count{scanner="grype"} 4
+`
+	if out.String() != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -8,6 +8,35 @@ const (
 	AlertContainerStarted AlertType = "container_started"
 	AlertCPUThreshold     AlertType = "cpu_threshold"
 	AlertMemoryThreshold  AlertType = "memory_threshold"
+	AlertMetricThreshold  AlertType = "metric_threshold"
+
+	// Raised from the Docker events stream
+	AlertContainerDied        AlertType = "container_died"
+	AlertContainerOOM         AlertType = "container_oom"
+	AlertContainerUnhealthy   AlertType = "container_unhealthy"
+	AlertContainerRestartLoop AlertType = "container_restart_loop"
+
+	// Raised for the machine running the monitor
+	AlertHostCPU    AlertType = "host_cpu"
+	AlertHostMemory AlertType = "host_memory"
+	AlertHostLoad   AlertType = "host_load"
+	AlertHostDisk   AlertType = "host_disk"
+	AlertHostInodes AlertType = "host_inodes"
+
+	// Raised when a configured Docker host stops or starts answering
+	AlertHostDown      AlertType = "host_down"
+	AlertHostRecovered AlertType = "host_recovered"
+
+	// Raised when a client IP or username is locked out after failed logins
+	AlertLoginLockout AlertType = "login_lockout"
+)
+
+// AlertStatus represents the lifecycle state of a stateful alert
+type AlertStatus string
+
+const (
+	AlertStatusFiring   AlertStatus = "firing"
+	AlertStatusResolved AlertStatus = "resolved"
 )
 
 // Alert represents a system alert
@@ -22,14 +51,111 @@ type Alert struct {
 	Threshold     float64   `json:"threshold,omitempty"`
 	Timestamp     int64     `json:"timestamp"`
 	Acknowledged  bool      `json:"acknowledged"`
+	// AcknowledgedBy and AcknowledgedAt record who acknowledged the alert and when
+	AcknowledgedBy string `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt int64  `json:"acknowledged_at,omitempty"`
+
+	// Status, ResolvedAt and DurationSeconds are only set for stateful
+	// (threshold) alerts; event alerts such as container_stopped leave them empty.
+	Status          AlertStatus `json:"status,omitempty"`
+	ResolvedAt      int64       `json:"resolved_at,omitempty"`
+	DurationSeconds int64       `json:"duration_seconds,omitempty"`
+
+	// RuleID and Severity are set for alerts raised by an alert rule
+	RuleID   string        `json:"rule_id,omitempty"`
+	Severity AlertSeverity `json:"severity,omitempty"`
+
+	// ExitCode and LogTail describe crashed containers
+	ExitCode int    `json:"exit_code,omitempty"`
+	LogTail  string `json:"log_tail,omitempty"`
+
+	// Silenced alerts are recorded but not notified. SilencedBy is the ID of
+	// the silence, or "maintenance:<id>" for a maintenance window.
+	Silenced   bool   `json:"silenced,omitempty"`
+	SilencedBy string `json:"silenced_by,omitempty"`
+}
+
+// AlertEventType identifies a change pushed on the alert stream
+type AlertEventType string
+
+const (
+	AlertEventFired        AlertEventType = "alert_fired"
+	AlertEventResolved     AlertEventType = "alert_resolved"
+	AlertEventAcknowledged AlertEventType = "alert_acknowledged"
+)
+
+// AlertEvent is a change to the alert history pushed to stream subscribers
+type AlertEvent struct {
+	Type      AlertEventType `json:"type"`
+	Timestamp int64          `json:"timestamp"`
+	// Alert is set for fired and resolved alerts
+	Alert *Alert `json:"alert,omitempty"`
+
+	// AlertID and AcknowledgedBy are set for acknowledgements; an empty
+	// AlertID means every alert was acknowledged at once
+	AlertID        string `json:"alert_id,omitempty"`
+	AcknowledgedBy string `json:"acknowledged_by,omitempty"`
+}
+
+// AlertQuery defines parameters for querying persisted alert history.
+type AlertQuery struct {
+	Host         string      `json:"host,omitempty"`
+	Container    string      `json:"container,omitempty"`
+	Type         AlertType   `json:"type,omitempty"`
+	Status       AlertStatus `json:"status,omitempty"`
+	StartDate    int64       `json:"start_date,omitempty"`
+	EndDate      int64       `json:"end_date,omitempty"`
+	Acknowledged *bool       `json:"acknowledged,omitempty"`
+	Silenced     *bool       `json:"silenced,omitempty"`
+	Page         int         `json:"page,omitempty"`
+	PageSize     int         `json:"page_size,omitempty"`
+}
+
+// AlertPage holds paginated alert history results.
+type AlertPage struct {
+	Alerts     []Alert `json:"alerts"`
+	Total      int     `json:"total"`
+	Page       int     `json:"page"`
+	PageSize   int     `json:"page_size"`
+	TotalPages int     `json:"total_pages"`
 }
 
 // AlertConfigResponse represents the alert configuration for API responses
 type AlertConfigResponse struct {
-	Enabled         bool    `json:"enabled"`
-	CPUThreshold    float64 `json:"cpu_threshold"`
-	MemoryThreshold float64 `json:"memory_threshold"`
-	CheckInterval   string  `json:"check_interval"`
-	WebhookEnabled  bool    `json:"webhook_enabled"`
-	AlertsFilter    string  `json:"alerts_filter"`
+	Enabled              bool    `json:"enabled"`
+	CPUThreshold         float64 `json:"cpu_threshold"`
+	MemoryThreshold      float64 `json:"memory_threshold"`
+	CPUFor               string  `json:"cpu_for"`
+	MemoryFor            string  `json:"memory_for"`
+	CPUClearThreshold    float64 `json:"cpu_clear_threshold"`
+	MemoryClearThreshold float64 `json:"memory_clear_threshold"`
+	CheckInterval        string  `json:"check_interval"`
+	WebhookEnabled       bool    `json:"webhook_enabled"`
+	AlertsFilter         string  `json:"alerts_filter"`
+	HistoryRetention     string  `json:"history_retention"`
+	Cooldown             string  `json:"cooldown"`
+
+	ThresholdOverrides []AlertThresholdOverride `json:"threshold_overrides"`
+	Host               HostAlertConfigResponse  `json:"host"`
+}
+
+// HostAlertConfigResponse describes the thresholds for host-level alerts
+type HostAlertConfigResponse struct {
+	Enabled         bool     `json:"enabled"`
+	CPUThreshold    float64  `json:"cpu_threshold"`
+	MemoryThreshold float64  `json:"memory_threshold"`
+	DiskThreshold   float64  `json:"disk_threshold"`
+	InodeThreshold  float64  `json:"inode_threshold"`
+	LoadThreshold   float64  `json:"load_threshold"`
+	For             string   `json:"for"`
+	IgnoreMounts    []string `json:"ignore_mounts,omitempty"`
+}
+
+// AlertThresholdOverride describes a configured per-host or per-container threshold override
+type AlertThresholdOverride struct {
+	Host            string  `json:"host,omitempty"`
+	Container       string  `json:"container,omitempty"`
+	CPUThreshold    float64 `json:"cpu_threshold,omitempty"`
+	MemoryThreshold float64 `json:"memory_threshold,omitempty"`
+	Disabled        bool    `json:"disabled,omitempty"`
 }
@@ -0,0 +1,59 @@
+package models
+
+// AlertRuleMetric identifies the container metric an alert rule evaluates
+type AlertRuleMetric string
+
+const (
+	MetricCPU            AlertRuleMetric = "cpu"              // percent
+	MetricMemory         AlertRuleMetric = "memory"           // percent
+	MetricPIDs           AlertRuleMetric = "pids"             // process count
+	MetricNetworkRxRate  AlertRuleMetric = "network_rx_rate"  // bytes per second
+	MetricNetworkTxRate  AlertRuleMetric = "network_tx_rate"  // bytes per second
+	MetricBlockReadRate  AlertRuleMetric = "block_read_rate"  // bytes per second
+	MetricBlockWriteRate AlertRuleMetric = "block_write_rate" // bytes per second
+	MetricRestartCount   AlertRuleMetric = "restart_count"    // restarts reported by the Docker daemon
+)
+
+// AlertComparator compares a metric value against a rule threshold
+type AlertComparator string
+
+const (
+	ComparatorGreater        AlertComparator = "gt"
+	ComparatorGreaterOrEqual AlertComparator = "gte"
+	ComparatorLess           AlertComparator = "lt"
+	ComparatorLessOrEqual    AlertComparator = "lte"
+)
+
+// AlertSeverity ranks how urgent an alert is
+type AlertSeverity string
+
+const (
+	AlertSeverityInfo     AlertSeverity = "info"
+	AlertSeverityWarning  AlertSeverity = "warning"
+	AlertSeverityCritical AlertSeverity = "critical"
+)
+
+// AlertRuleScope limits which containers a rule applies to.
+// Empty fields match everything.
+type AlertRuleScope struct {
+	Host      string `json:"host,omitempty"`
+	Container string `json:"container,omitempty"` // glob matched against the container name
+	Label     string `json:"label,omitempty"`     // "key" or "key=value"
+	Image     string `json:"image,omitempty"`     // glob matched against the image reference
+}
+
+// AlertRule is a user-defined alert condition evaluated by the monitor
+type AlertRule struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Enabled        bool            `json:"enabled"`
+	Metric         AlertRuleMetric `json:"metric"`
+	Comparator     AlertComparator `json:"comparator"`
+	Threshold      float64         `json:"threshold"`
+	ClearThreshold float64         `json:"clear_threshold,omitempty"` // 0 resolves as soon as the condition stops matching
+	ForSeconds     int64           `json:"for_seconds"`               // how long the condition must hold before firing
+	Severity       AlertSeverity   `json:"severity"`
+	Scope          AlertRuleScope  `json:"scope"`
+	CreatedAt      int64           `json:"created_at"`
+	UpdatedAt      int64           `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// API token scopes. Every token may read; the other scopes each unlock one
+// kind of change and still require the owner's role.
+const (
+	TokenScopeRead     = "read"     // read containers, logs, stats, alerts and scans
+	TokenScopeOperate  = "operate"  // manage containers and images and handle alerts
+	TokenScopeScan     = "scan"     // start and manage vulnerability scans and SBOMs
+	TokenScopeSettings = "settings" // read and change settings
+)
+
+// APIToken is a named, long-lived credential for automation. The token
+// itself is only returned when it is created; the database keeps a hash.
+type APIToken struct {
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	Name     string   `json:"name"`
+	Scopes   []string `json:"scopes"`
+	// Prefix is the start of the token, to tell tokens apart
+	Prefix    string `json:"prefix"`
+	TokenHash string `json:"-"`
+
+	CreatedAt  int64 `json:"created_at"`
+	ExpiresAt  int64 `json:"expires_at,omitempty"` // 0 means the token does not expire
+	LastUsedAt int64 `json:"last_used_at,omitempty"`
+}
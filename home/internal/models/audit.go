@@ -0,0 +1,54 @@
+package models
+
+// Audit outcomes
+const (
+	AuditOutcomeSuccess  = "success"
+	AuditOutcomeAccepted = "accepted" // started in the background; a second event records the result
+	AuditOutcomeDenied   = "denied"   // refused for the user's role, scope or the read-only mode
+	AuditOutcomeFailure  = "failure"
+)
+
+// AuditEvent records one change made through the API
+type AuditEvent struct {
+	ID       int64  `json:"id"`
+	Time     int64  `json:"time"` // Unix time the request arrived
+	Username string `json:"username,omitempty"`
+	Role     string `json:"role,omitempty"`
+	// APIToken is set when the request authenticated with an API token
+	APIToken bool   `json:"api_token,omitempty"`
+	SourceIP string `json:"source_ip"`
+	// Action is the method and route, e.g. "POST /containers/{id}/restart"
+	Action string `json:"action"`
+	Host   string `json:"host,omitempty"`
+	// Target lists the route parameters, e.g. "id=3f2a9c"
+	Target string `json:"target,omitempty"`
+	// Summary describes the request without secrets: query parameters and
+	// the fields of a JSON body
+	Summary    string `json:"summary,omitempty"`
+	Status     int    `json:"status"`
+	Outcome    string `json:"outcome"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// AuditQuery defines parameters for querying the audit log
+type AuditQuery struct {
+	Username  string `json:"username,omitempty"`
+	Action    string `json:"action,omitempty"` // substring of the action
+	Host      string `json:"host,omitempty"`
+	Target    string `json:"target,omitempty"` // substring of the target
+	Outcome   string `json:"outcome,omitempty"`
+	StartDate int64  `json:"start_date,omitempty"`
+	EndDate   int64  `json:"end_date,omitempty"`
+	Page      int    `json:"page,omitempty"`
+	PageSize  int    `json:"page_size,omitempty"`
+}
+
+// AuditPage holds paginated audit log results
+type AuditPage struct {
+	Events     []AuditEvent `json:"events"`
+	Total      int          `json:"total"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"page_size"`
+	TotalPages int          `json:"total_pages"`
+}
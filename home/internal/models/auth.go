@@ -1,5 +1,13 @@
 package models
 
+// Roles grant increasing access: each role may do everything the roles
+// before it may
+const (
+	RoleViewer   = "viewer"   // read-only access
+	RoleOperator = "operator" // may also manage containers, images, scans and alerts
+	RoleAdmin    = "admin"    // may also change settings and manage users
+)
+
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
@@ -11,6 +19,51 @@ type LoginResponse struct {
 }
 
 type User struct {
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	Username string            `json:"username"`
+	Role     string            `json:"role"`
+	Grants   []PermissionGrant `json:"grants,omitempty"`
+	// Provider names the single sign-on provider the user logged in
+	// through, empty for the built-in admin and user accounts
+	Provider string `json:"provider,omitempty"`
+	// SessionID is the login session the request's access token belongs to
+	SessionID string `json:"session_id,omitempty"`
+	// TokenScopes is set when the request authenticated with an API token
+	TokenScopes []string `json:"token_scopes,omitempty"`
+}
+
+// PermissionGrant gives a user access to the containers it matches. Empty
+// fields match everything. Users without grants, and admins, may access
+// every container.
+type PermissionGrant struct {
+	Host      string `json:"host,omitempty"`
+	Container string `json:"container,omitempty"` // glob matched against the container name
+	Label     string `json:"label,omitempty"`     // "key" or "key=value"
+}
+
+// UserAccount is a stored user who can log in next to the built-in admin
+type UserAccount struct {
+	Username     string `json:"username"`
+	Role         string `json:"role"`
+	PasswordHash string `json:"-"`
+	// Grants limit the user to the containers they match
+	Grants    []PermissionGrant `json:"grants,omitempty"`
+	CreatedAt int64             `json:"created_at"`
+	UpdatedAt int64             `json:"updated_at"`
+	// Builtin marks the admin configured through ADMIN_USERNAME or the
+	// auth settings, which cannot be edited here
+	Builtin bool `json:"builtin,omitempty"`
+	// TwoFactor reports whether the user turned on TOTP two-factor
+	// authentication
+	TwoFactor bool `json:"two_factor,omitempty"`
+}
+
+// LoginLockout describes a client IP or username locked out after
+// repeated failed logins
+type LoginLockout struct {
+	IP       string `json:"ip,omitempty"`       // Set when a client IP is locked out
+	Username string `json:"username,omitempty"` // Set when a username is locked out
+	Failures int    `json:"failures"`
+	// DurationSeconds is how long the lockout lasts, Until the Unix time it ends
+	DurationSeconds int64 `json:"duration_seconds"`
+	Until           int64 `json:"until"`
 }
@@ -0,0 +1,29 @@
+package models
+
+// PushProvider identifies the service used to reach a mobile device
+type PushProvider string
+
+const (
+	// PushFCM delivers through Firebase Cloud Messaging; Token is the FCM
+	// registration token
+	PushFCM PushProvider = "fcm"
+	// PushUnifiedPush posts to a UnifiedPush endpoint such as an ntfy
+	// topic; Token is the endpoint URL
+	PushUnifiedPush PushProvider = "unifiedpush"
+)
+
+// Device is a mobile device registered to receive alert push notifications
+type Device struct {
+	ID       string       `json:"id"`
+	Username string       `json:"username"`
+	Provider PushProvider `json:"provider"`
+	Token    string       `json:"token"`
+	Platform string       `json:"platform,omitempty"`
+	Name     string       `json:"name,omitempty"`
+	// MinSeverity is the lowest alert severity pushed to the device
+	MinSeverity AlertSeverity `json:"min_severity"`
+
+	CreatedAt  int64 `json:"created_at"`
+	UpdatedAt  int64 `json:"updated_at"`
+	LastPushAt int64 `json:"last_push_at,omitempty"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// DockerHostStatus describes the connectivity of a configured Docker host
+type DockerHostStatus struct {
+	Name                string  `json:"name"`
+	Reachable           bool    `json:"reachable"`
+	LastCheck           int64   `json:"last_check"`
+	LastSuccess         int64   `json:"last_success,omitempty"`
+	LastError           string  `json:"last_error,omitempty"`
+	LastErrorAt         int64   `json:"last_error_at,omitempty"`
+	LatencyMs           float64 `json:"latency_ms"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// Session is a login that can be refreshed until it expires or is revoked.
+// The refresh token itself is only returned to the client; the database
+// keeps a hash.
+type Session struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	// Role and Provider are the ones at login; single sign-on users keep
+	// that role for the whole session
+	Role        string `json:"role"`
+	Provider    string `json:"provider,omitempty"`
+	RefreshHash string `json:"-"`
+	UserAgent   string `json:"user_agent,omitempty"`
+	RemoteAddr  string `json:"remote_addr,omitempty"`
+
+	CreatedAt  int64 `json:"created_at"`
+	LastUsedAt int64 `json:"last_used_at"` // last login or refresh
+	ExpiresAt  int64 `json:"expires_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// SilenceMatcher selects the alerts a silence or maintenance window mutes.
+// Empty fields match everything, but at least one must be set.
+type SilenceMatcher struct {
+	Host      string    `json:"host,omitempty"`
+	Container string    `json:"container,omitempty"` // glob matched against the container name
+	Label     string    `json:"label,omitempty"`     // "key" or "key=value"
+	AlertType AlertType `json:"alert_type,omitempty"`
+}
+
+// Silence mutes notifications for matching alerts between StartsAt and
+// EndsAt. Muted alerts are still recorded in the history.
+type Silence struct {
+	ID string `json:"id"`
+	SilenceMatcher
+	Comment   string `json:"comment"`
+	CreatedBy string `json:"created_by,omitempty"`
+	StartsAt  int64  `json:"starts_at"`
+	EndsAt    int64  `json:"ends_at"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// MaintenanceWindow is a recurring silence, e.g. every Sunday 02:00-04:00
+type MaintenanceWindow struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	SilenceMatcher
+	Weekdays        []int  `json:"weekdays,omitempty"` // 0 is Sunday; empty means every day
+	StartTime       string `json:"start_time"`         // "HH:MM" in Timezone
+	DurationMinutes int    `json:"duration_minutes"`
+	Timezone        string `json:"timezone"` // IANA name, defaults to UTC
+	Comment         string `json:"comment,omitempty"`
+	CreatedAt       int64  `json:"created_at"`
+	UpdatedAt       int64  `json:"updated_at"`
+}
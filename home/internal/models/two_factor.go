@@ -0,0 +1,17 @@
+package models
+
+// TwoFactor is a user's TOTP enrollment. It is pending until the user
+// confirms it with a first code. Recovery codes are kept as hashes, and
+// each one works once.
+type TwoFactor struct {
+	Username       string   `json:"username"`
+	Secret         string   `json:"-"`
+	RecoveryHashes []string `json:"-"`
+	Enabled        bool     `json:"enabled"`
+	// LastCounter is the time step of the last accepted code, so a code
+	// cannot be used twice
+	LastCounter int64 `json:"-"`
+
+	CreatedAt int64 `json:"created_at"`
+	EnabledAt int64 `json:"enabled_at,omitempty"`
+}
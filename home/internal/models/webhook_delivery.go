@@ -0,0 +1,18 @@
+package models
+
+// WebhookDelivery records one attempt to deliver a notification to a
+// webhook channel. Retries share the DeliveryID of the first attempt.
+type WebhookDelivery struct {
+	ID         int64  `json:"id"`
+	DeliveryID string `json:"delivery_id"`
+	Channel    string `json:"channel"`
+	URL        string `json:"url"`
+	Event      string `json:"event"`
+	AlertID    string `json:"alert_id"`
+	Attempt    int    `json:"attempt"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Timestamp  int64  `json:"timestamp"`
+}
@@ -0,0 +1,236 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func (d *Dispatcher) sendSlack(ctx context.Context, ch config.NotificationChannel, n Notification) error {
+	payload := map[string]interface{}{
+		"text": n.Title(),
+		"blocks": []map[string]interface{}{
+			{
+				"type": "header",
+				"text": map[string]string{"type": "plain_text", "text": n.Title()},
+			},
+			{
+				"type": "section",
+				"text": map[string]string{"type": "mrkdwn", "text": n.Text()},
+			},
+			{
+				"type": "context",
+				"elements": []map[string]string{
+					{"type": "mrkdwn", "text": fmt.Sprintf("Severity: %s | VPS Monitor", n.Severity)},
+				},
+			},
+		},
+	}
+	return d.postJSON(ctx, ch.URL, payload, nil)
+}
+
+func (d *Dispatcher) sendDiscord(ctx context.Context, ch config.NotificationChannel, n Notification) error {
+	fields := []map[string]interface{}{
+		{"name": "Severity", "value": string(n.Severity), "inline": true},
+		{"name": "Host", "value": n.Alert.Host, "inline": true},
+	}
+	if n.Alert.ContainerName != "" {
+		fields = append(fields, map[string]interface{}{"name": "Container", "value": n.Alert.ContainerName, "inline": true})
+	}
+
+	payload := map[string]interface{}{
+		"embeds": []map[string]interface{}{
+			{
+				"title":       n.Title(),
+				"description": n.Text(),
+				"color":       discordColor(n),
+				"fields":      fields,
+				"footer":      map[string]string{"text": "VPS Monitor"},
+				"timestamp":   time.Unix(n.Alert.Timestamp, 0).UTC().Format(time.RFC3339),
+			},
+		},
+	}
+	return d.postJSON(ctx, ch.URL, payload, nil)
+}
+
+func (d *Dispatcher) sendTeams(ctx context.Context, ch config.NotificationChannel, n Notification) error {
+	payload := map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body": []map[string]interface{}{
+						{"type": "TextBlock", "size": "Medium", "weight": "Bolder", "text": n.Title(), "wrap": true},
+						{"type": "TextBlock", "text": n.Text(), "wrap": true},
+						{"type": "TextBlock", "isSubtle": true, "text": fmt.Sprintf("Severity: %s", n.Severity), "wrap": true},
+					},
+				},
+			},
+		},
+	}
+	return d.postJSON(ctx, ch.URL, payload, nil)
+}
+
+func (d *Dispatcher) sendNtfy(ctx context.Context, ch config.NotificationChannel, n Notification) error {
+	headers := map[string]string{
+		"Title":    n.Title(),
+		"Priority": strconv.Itoa(ntfyPriority(n)),
+		"Tags":     ntfyTags(n),
+	}
+	if ch.Token != "" {
+		headers["Authorization"] = "Bearer " + ch.Token
+	}
+	return d.post(ctx, ch.URL, "text/plain", []byte(n.Text()), headers)
+}
+
+func (d *Dispatcher) sendGotify(ctx context.Context, ch config.NotificationChannel, n Notification) error {
+	endpoint := strings.TrimRight(ch.URL, "/") + "/message"
+	payload := map[string]interface{}{
+		"title":    n.Title(),
+		"message":  n.Text(),
+		"priority": gotifyPriority(n),
+	}
+	return d.postJSON(ctx, endpoint, payload, map[string]string{"X-Gotify-Key": ch.Token})
+}
+
+func (d *Dispatcher) sendTelegram(ctx context.Context, ch config.NotificationChannel, n Notification) error {
+	d.mu.RLock()
+	token, chatID := d.telegramToken, d.telegramChat
+	d.mu.RUnlock()
+	if ch.ChatID != "" {
+		chatID = ch.ChatID
+	}
+	if token == "" {
+		return errors.New("telegram bot token is not configured")
+	}
+	if chatID == "" {
+		return errors.New("telegram chat ID is not configured")
+	}
+
+	form := url.Values{
+		"chat_id": {chatID},
+		"text":    {n.Title() + "\n\n" + n.Text()},
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", d.telegramAPI, token)
+	err := d.post(ctx, endpoint, "application/x-www-form-urlencoded", []byte(form.Encode()), nil)
+	if err != nil {
+		// Transport errors include the request URL, which contains the token
+		return errors.New(strings.ReplaceAll(err.Error(), token, "***"))
+	}
+	return nil
+}
+
+func (d *Dispatcher) postJSON(ctx context.Context, endpoint string, payload interface{}, headers map[string]string) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+	return d.post(ctx, endpoint, "application/json", data, headers)
+}
+
+func (d *Dispatcher) post(ctx context.Context, endpoint, contentType string, body []byte, headers map[string]string) error {
+	_, err := d.do(ctx, endpoint, contentType, body, headers)
+	return err
+}
+
+// do sends a POST request and returns the response status code, which is 0
+// when no response was received
+func (d *Dispatcher) do(ctx context.Context, endpoint, contentType string, body []byte, headers map[string]string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "VPS-Monitor/1.0")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = redactURL(urlErr.URL)
+		}
+		return 0, fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, fmt.Errorf("notification endpoint returned error status: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// discordColor returns the embed color for the notification's severity
+func discordColor(n Notification) int {
+	if n.Event == EventResolved {
+		return 0x57F287 // Green
+	}
+	switch n.Severity {
+	case models.AlertSeverityCritical:
+		return 0xED4245 // Red
+	case models.AlertSeverityWarning:
+		return 0xFFA500 // Orange
+	default:
+		return 0x5865F2 // Blurple
+	}
+}
+
+func ntfyPriority(n Notification) int {
+	if n.Event == EventResolved {
+		return 3
+	}
+	switch n.Severity {
+	case models.AlertSeverityCritical:
+		return 5
+	case models.AlertSeverityWarning:
+		return 4
+	default:
+		return 3
+	}
+}
+
+func ntfyTags(n Notification) string {
+	if n.Event == EventResolved {
+		return "white_check_mark"
+	}
+	switch n.Severity {
+	case models.AlertSeverityCritical:
+		return "rotating_light"
+	case models.AlertSeverityWarning:
+		return "warning"
+	default:
+		return "information_source"
+	}
+}
+
+func gotifyPriority(n Notification) int {
+	if n.Event == EventResolved {
+		return 2
+	}
+	switch n.Severity {
+	case models.AlertSeverityCritical:
+		return 8
+	case models.AlertSeverityWarning:
+		return 5
+	default:
+		return 2
+	}
+}
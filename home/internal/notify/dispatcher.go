@@ -0,0 +1,270 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/metrics"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// Events describe which transition of an alert a notification reports
+const (
+	EventFired     = "fired"
+	EventResolved  = "resolved"
+	EventEscalated = "escalated" // still firing and not acknowledged
+)
+
+// legacyWebhookChannel is the name of the channel created from ALERTS_WEBHOOK_URL
+const legacyWebhookChannel = "ALERTS_WEBHOOK_URL"
+
+const telegramAPIBase = "https://api.telegram.org"
+
+// Notification is one alert transition sent to notification channels
+type Notification struct {
+	Event    string
+	Alert    models.Alert
+	Severity models.AlertSeverity
+	// Labels are the labels of the alert's container, when known
+	Labels map[string]string
+}
+
+// Title returns a one-line summary of the notification
+func (n Notification) Title() string {
+	state := "FIRING"
+	switch n.Event {
+	case EventResolved:
+		state = "RESOLVED"
+	case EventEscalated:
+		state = "ESCALATED"
+	}
+	subject := n.Alert.Host
+	if n.Alert.ContainerName != "" {
+		subject = n.Alert.ContainerName + " on " + n.Alert.Host
+	}
+	return fmt.Sprintf("[%s] %s: %s", state, n.Alert.Type, subject)
+}
+
+// Text returns the notification body
+func (n Notification) Text() string {
+	switch n.Event {
+	case EventResolved:
+		return fmt.Sprintf("%s\nResolved after %s", n.Alert.Message, time.Duration(n.Alert.DurationSeconds)*time.Second)
+	case EventEscalated:
+		unacked := time.Since(time.Unix(n.Alert.Timestamp, 0)).Truncate(time.Minute)
+		return fmt.Sprintf("%s\nNot acknowledged for %s", n.Alert.Message, unacked)
+	}
+	return n.Alert.Message
+}
+
+// Dispatcher delivers notifications to the configured channels according
+// to the notification routes
+type Dispatcher struct {
+	client      *http.Client
+	telegramAPI string
+	retryDelay  time.Duration // first webhook retry delay, doubled per attempt
+
+	mu            sync.RWMutex
+	channels      []config.NotificationChannel
+	routes        []config.NotificationRoute
+	escalations   []config.EscalationPolicy
+	legacy        *config.NotificationChannel
+	telegramToken string
+	telegramChat  string
+	deliveries    DeliveryStore
+}
+
+// NewDispatcher creates a dispatcher for the notification settings in cfg
+func NewDispatcher(cfg *config.Config) *Dispatcher {
+	d := &Dispatcher{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		telegramAPI: telegramAPIBase,
+		retryDelay:  2 * time.Second,
+	}
+	d.Update(cfg)
+	return d
+}
+
+// Update applies new notification settings
+func (d *Dispatcher) Update(cfg *config.Config) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.channels = slices.Clone(cfg.Notifications.Channels)
+	d.routes = slices.Clone(cfg.Notifications.Routes)
+	d.escalations = slices.Clone(cfg.Notifications.Escalations)
+	d.telegramToken = cfg.Bot.TelegramToken
+	d.telegramChat = cfg.Bot.AllowedChatID
+
+	d.legacy = nil
+	if cfg.Alerts.WebhookURL != "" {
+		d.legacy = &config.NotificationChannel{
+			Name:    legacyWebhookChannel,
+			Type:    config.ChannelWebhook,
+			Enabled: true,
+			URL:     cfg.Alerts.WebhookURL,
+			Secret:  cfg.Alerts.WebhookSecret,
+		}
+	}
+}
+
+// Dispatch sends n to every channel routed for it. Channels are sent to
+// concurrently so a retrying webhook does not hold up the others; errors
+// of failing channels are joined.
+func (d *Dispatcher) Dispatch(ctx context.Context, n Notification) error {
+	return d.deliver(ctx, d.targets(n), n)
+}
+
+// DispatchTo sends n to the named channels, bypassing the routes. Unknown
+// and disabled channels are skipped.
+func (d *Dispatcher) DispatchTo(ctx context.Context, n Notification, names []string) error {
+	d.mu.RLock()
+	var targets []config.NotificationChannel
+	for _, ch := range d.channels {
+		if ch.Enabled && slices.Contains(names, ch.Name) {
+			targets = append(targets, ch)
+		}
+	}
+	d.mu.RUnlock()
+	return d.deliver(ctx, targets, n)
+}
+
+// EscalationPolicy returns the first escalation policy matching n, or nil
+func (d *Dispatcher) EscalationPolicy(n Notification) *config.EscalationPolicy {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, policy := range d.escalations {
+		route := config.NotificationRoute{Types: policy.Types, MinSeverity: policy.MinSeverity}
+		if routeMatches(route, n) {
+			return &policy
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, targets []config.NotificationChannel, n Notification) error {
+	errs := make([]error, len(targets))
+
+	var wg sync.WaitGroup
+	for i, ch := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.send(ctx, ch, n); err != nil {
+				metrics.WebhookFailures.Inc("notifications", ch.Type)
+				errs[i] = fmt.Errorf("%s: %w", ch.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Test sends a sample notification to ch. Webhooks get a single attempt.
+func (d *Dispatcher) Test(ctx context.Context, ch config.NotificationChannel) error {
+	if ch.Type == config.ChannelWebhook {
+		_, err := d.TestWebhook(ctx, ch)
+		return err
+	}
+	return d.send(ctx, ch, sampleNotification())
+}
+
+func sampleNotification() Notification {
+	return Notification{
+		Event:    EventFired,
+		Severity: models.AlertSeverityInfo,
+		Alert: models.Alert{
+			ID:        "test",
+			Type:      "test",
+			Host:      "vps-monitor",
+			Message:   "This is a test notification from VPS Monitor.",
+			Severity:  models.AlertSeverityInfo,
+			Timestamp: time.Now().Unix(),
+		},
+	}
+}
+
+// targets returns the channels n is routed to. Without routes every enabled
+// channel receives every notification. The ALERTS_WEBHOOK_URL channel always
+// receives everything.
+func (d *Dispatcher) targets(n Notification) []config.NotificationChannel {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var targets []config.NotificationChannel
+	if d.legacy != nil {
+		targets = append(targets, *d.legacy)
+	}
+
+	routed := make(map[string]bool)
+	for _, route := range d.routes {
+		if routeMatches(route, n) {
+			for _, name := range route.Channels {
+				routed[name] = true
+			}
+		}
+	}
+
+	for _, ch := range d.channels {
+		if !ch.Enabled {
+			continue
+		}
+		if len(d.routes) > 0 && !routed[ch.Name] {
+			continue
+		}
+		targets = append(targets, ch)
+	}
+	return targets
+}
+
+func routeMatches(route config.NotificationRoute, n Notification) bool {
+	if len(route.Types) > 0 && !slices.Contains(route.Types, string(n.Alert.Type)) {
+		return false
+	}
+	return severityRank(n.Severity) >= severityRank(models.AlertSeverity(route.MinSeverity))
+}
+
+func severityRank(severity models.AlertSeverity) int {
+	switch severity {
+	case models.AlertSeverityCritical:
+		return 2
+	case models.AlertSeverityWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, ch config.NotificationChannel, n Notification) error {
+	switch ch.Type {
+	case config.ChannelWebhook:
+		return d.sendWebhook(ctx, ch, n)
+	case config.ChannelSlack:
+		return d.sendSlack(ctx, ch, n)
+	case config.ChannelDiscord:
+		return d.sendDiscord(ctx, ch, n)
+	case config.ChannelTeams:
+		return d.sendTeams(ctx, ch, n)
+	case config.ChannelNtfy:
+		return d.sendNtfy(ctx, ch, n)
+	case config.ChannelGotify:
+		return d.sendGotify(ctx, ch, n)
+	case config.ChannelTelegram:
+		return d.sendTelegram(ctx, ch, n)
+	case config.ChannelEmail:
+		return sendEmail(ctx, ch.SMTP, n)
+	default:
+		return fmt.Errorf("unknown channel type %q", ch.Type)
+	}
+}
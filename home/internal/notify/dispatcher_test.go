@@ -0,0 +1,295 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+type capturedRequest struct {
+	path    string
+	headers http.Header
+	body    []byte
+}
+
+// newCaptureServer records every request it receives
+func newCaptureServer(t *testing.T) (*httptest.Server, func() []capturedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []capturedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, capturedRequest{path: r.URL.Path, headers: r.Header.Clone(), body: body})
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedRequest(nil), requests...)
+	}
+}
+
+// requestPaths returns the sorted paths of requests, which are sent concurrently
+func requestPaths(requests []capturedRequest) []string {
+	paths := make([]string, 0, len(requests))
+	for _, r := range requests {
+		paths = append(paths, r.path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func testNotification(severity models.AlertSeverity, alertType models.AlertType) Notification {
+	return Notification{
+		Event:    EventFired,
+		Severity: severity,
+		Alert: models.Alert{
+			ID:            "alert-1",
+			Type:          alertType,
+			Host:          "local",
+			ContainerName: "web",
+			Message:       "Container web stopped",
+			Severity:      severity,
+		},
+	}
+}
+
+func TestDispatchWithoutRoutesSendsToEveryEnabledChannel(t *testing.T) {
+	srv, requests := newCaptureServer(t)
+	d := NewDispatcher(&config.Config{Notifications: config.NotificationsConfig{
+		Channels: []config.NotificationChannel{
+			{Name: "a", Type: config.ChannelWebhook, Enabled: true, URL: srv.URL + "/a"},
+			{Name: "b", Type: config.ChannelWebhook, Enabled: true, URL: srv.URL + "/b"},
+			{Name: "off", Type: config.ChannelWebhook, Enabled: false, URL: srv.URL + "/off"},
+		},
+	}})
+
+	if err := d.Dispatch(context.Background(), testNotification(models.AlertSeverityInfo, models.AlertContainerStopped)); err != nil {
+		t.Fatalf("Dispatch returned error: %v", err)
+	}
+
+	if got := requestPaths(requests()); !slices.Equal(got, []string{"/a", "/b"}) {
+		t.Fatalf("expected requests to /a and /b, got %v", got)
+	}
+}
+
+func TestDispatchAppliesRoutes(t *testing.T) {
+	srv, requests := newCaptureServer(t)
+	d := NewDispatcher(&config.Config{Notifications: config.NotificationsConfig{
+		Channels: []config.NotificationChannel{
+			{Name: "chat", Type: config.ChannelWebhook, Enabled: true, URL: srv.URL + "/chat"},
+			{Name: "pager", Type: config.ChannelWebhook, Enabled: true, URL: srv.URL + "/pager"},
+		},
+		Routes: []config.NotificationRoute{
+			{Channels: []string{"chat"}},
+			{Channels: []string{"pager"}, Types: []string{"host_down"}, MinSeverity: "critical"},
+		},
+	}})
+
+	tests := []struct {
+		name      string
+		severity  models.AlertSeverity
+		alertType models.AlertType
+		want      []string
+	}{
+		{"critical host down", models.AlertSeverityCritical, models.AlertHostDown, []string{"/chat", "/pager"}},
+		{"warning host down", models.AlertSeverityWarning, models.AlertHostDown, []string{"/chat"}},
+		{"critical other type", models.AlertSeverityCritical, models.AlertContainerStopped, []string{"/chat"}},
+	}
+
+	seen := 0
+	for _, tt := range tests {
+		if err := d.Dispatch(context.Background(), testNotification(tt.severity, tt.alertType)); err != nil {
+			t.Fatalf("%s: Dispatch returned error: %v", tt.name, err)
+		}
+		got := requestPaths(requests()[seen:])
+		seen += len(got)
+		if !slices.Equal(got, tt.want) {
+			t.Fatalf("%s: expected requests to %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestDispatchAlwaysSendsToLegacyWebhook(t *testing.T) {
+	srv, requests := newCaptureServer(t)
+	d := NewDispatcher(&config.Config{
+		Alerts: config.AlertConfig{WebhookURL: srv.URL + "/legacy"},
+		Notifications: config.NotificationsConfig{
+			Channels: []config.NotificationChannel{
+				{Name: "pager", Type: config.ChannelWebhook, Enabled: true, URL: srv.URL + "/pager"},
+			},
+			Routes: []config.NotificationRoute{{Channels: []string{"pager"}, MinSeverity: "critical"}},
+		},
+	})
+
+	if err := d.Dispatch(context.Background(), testNotification(models.AlertSeverityInfo, models.AlertContainerStopped)); err != nil {
+		t.Fatalf("Dispatch returned error: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 || got[0].path != "/legacy" {
+		t.Fatalf("expected only the legacy webhook to be called, got %+v", got)
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(got[0].body, &payload); err != nil {
+		t.Fatalf("decode webhook payload: %v", err)
+	}
+	if payload.Event != EventFired || payload.Alert.ID != "alert-1" || payload.Source != "vps-monitor" {
+		t.Fatalf("unexpected webhook payload: %+v", payload)
+	}
+}
+
+func TestDispatchJoinsChannelErrors(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	srv, requests := newCaptureServer(t)
+
+	d := NewDispatcher(&config.Config{Notifications: config.NotificationsConfig{
+		Channels: []config.NotificationChannel{
+			{Name: "broken", Type: config.ChannelWebhook, Enabled: true, URL: failing.URL},
+			{Name: "ok", Type: config.ChannelWebhook, Enabled: true, URL: srv.URL},
+		},
+	}})
+	d.retryDelay = time.Millisecond
+
+	err := d.Dispatch(context.Background(), testNotification(models.AlertSeverityInfo, models.AlertContainerStopped))
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("expected error naming the failing channel, got %v", err)
+	}
+	if len(requests()) != 1 {
+		t.Fatal("expected delivery to continue past the failing channel")
+	}
+}
+
+func TestChannelFormats(t *testing.T) {
+	srv, requests := newCaptureServer(t)
+	d := NewDispatcher(&config.Config{})
+	n := testNotification(models.AlertSeverityCritical, models.AlertContainerStopped)
+
+	channels := []config.NotificationChannel{
+		{Name: "slack", Type: config.ChannelSlack, URL: srv.URL + "/slack"},
+		{Name: "discord", Type: config.ChannelDiscord, URL: srv.URL + "/discord"},
+		{Name: "teams", Type: config.ChannelTeams, URL: srv.URL + "/teams"},
+		{Name: "ntfy", Type: config.ChannelNtfy, URL: srv.URL + "/alerts", Token: "ntfy-token"},
+		{Name: "gotify", Type: config.ChannelGotify, URL: srv.URL + "/", Token: "app-token"},
+	}
+	for _, ch := range channels {
+		if err := d.send(context.Background(), ch, n); err != nil {
+			t.Fatalf("%s: send returned error: %v", ch.Name, err)
+		}
+	}
+
+	got := requests()
+	if len(got) != len(channels) {
+		t.Fatalf("expected %d requests, got %d", len(channels), len(got))
+	}
+
+	var slack struct {
+		Blocks []map[string]any `json:"blocks"`
+	}
+	if err := json.Unmarshal(got[0].body, &slack); err != nil || len(slack.Blocks) == 0 {
+		t.Fatalf("unexpected slack payload: %s", got[0].body)
+	}
+
+	var discord struct {
+		Embeds []struct {
+			Title string `json:"title"`
+			Color int    `json:"color"`
+		} `json:"embeds"`
+	}
+	if err := json.Unmarshal(got[1].body, &discord); err != nil || len(discord.Embeds) != 1 {
+		t.Fatalf("unexpected discord payload: %s", got[1].body)
+	}
+	if discord.Embeds[0].Color != 0xED4245 || discord.Embeds[0].Title != n.Title() {
+		t.Fatalf("unexpected discord embed: %+v", discord.Embeds[0])
+	}
+
+	if !strings.Contains(string(got[2].body), "AdaptiveCard") {
+		t.Fatalf("expected an adaptive card for teams, got %s", got[2].body)
+	}
+
+	ntfy := got[3]
+	if ntfy.headers.Get("Priority") != "5" || ntfy.headers.Get("Title") != n.Title() {
+		t.Fatalf("unexpected ntfy headers: %v", ntfy.headers)
+	}
+	if ntfy.headers.Get("Authorization") != "Bearer ntfy-token" || string(ntfy.body) != n.Text() {
+		t.Fatalf("unexpected ntfy request: %v %s", ntfy.headers, ntfy.body)
+	}
+
+	gotify := got[4]
+	if gotify.path != "/message" || gotify.headers.Get("X-Gotify-Key") != "app-token" {
+		t.Fatalf("unexpected gotify request: %s %v", gotify.path, gotify.headers)
+	}
+	var gotifyBody struct {
+		Priority int `json:"priority"`
+	}
+	if err := json.Unmarshal(gotify.body, &gotifyBody); err != nil || gotifyBody.Priority != 8 {
+		t.Fatalf("unexpected gotify payload: %s", gotify.body)
+	}
+}
+
+func TestTelegramUsesBotTokenAndDefaultChat(t *testing.T) {
+	srv, requests := newCaptureServer(t)
+	d := NewDispatcher(&config.Config{Bot: config.BotConfig{TelegramToken: "123:abc", AllowedChatID: "42"}})
+	d.telegramAPI = srv.URL
+
+	ch := config.NotificationChannel{Name: "tg", Type: config.ChannelTelegram}
+	if err := d.send(context.Background(), ch, testNotification(models.AlertSeverityWarning, models.AlertContainerStopped)); err != nil {
+		t.Fatalf("send returned error: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 || got[0].path != "/bot123:abc/sendMessage" {
+		t.Fatalf("unexpected telegram request: %+v", got)
+	}
+	if !strings.Contains(string(got[0].body), "chat_id=42") {
+		t.Fatalf("expected default chat id in body, got %s", got[0].body)
+	}
+
+	ch.ChatID = "7"
+	if err := d.send(context.Background(), ch, testNotification(models.AlertSeverityWarning, models.AlertContainerStopped)); err != nil {
+		t.Fatalf("send returned error: %v", err)
+	}
+	if body := string(requests()[1].body); !strings.Contains(body, "chat_id=7") {
+		t.Fatalf("expected channel chat id in body, got %s", body)
+	}
+}
+
+func TestTelegramErrorsHideToken(t *testing.T) {
+	d := NewDispatcher(&config.Config{Bot: config.BotConfig{TelegramToken: "secret-token", AllowedChatID: "42"}})
+	d.telegramAPI = "http://127.0.0.1:1"
+
+	err := d.send(context.Background(), config.NotificationChannel{Name: "tg", Type: config.ChannelTelegram},
+		testNotification(models.AlertSeverityWarning, models.AlertContainerStopped))
+	if err == nil {
+		t.Fatal("expected connection error")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Fatalf("error leaks the bot token: %v", err)
+	}
+}
+
+func TestEmailMessageStripsHeaderInjection(t *testing.T) {
+	n := testNotification(models.AlertSeverityCritical, models.AlertContainerStopped)
+	n.Alert.ContainerName = "web\r\nBcc: victim@example.com"
+
+	msg := string(emailMessage(&config.SMTPConfig{From: "a@example.com", To: []string{"b@example.com"}}, n))
+	headers, _, _ := strings.Cut(msg, "\r\n\r\n")
+	if strings.Contains(headers, "\r\nBcc:") {
+		t.Fatalf("alert content injected a header: %q", headers)
+	}
+}
@@ -0,0 +1,218 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// Headers added to generic webhook requests
+const (
+	SignatureHeader = "X-VPS-Monitor-Signature-256"
+	EventHeader     = "X-VPS-Monitor-Event"
+	DeliveryHeader  = "X-VPS-Monitor-Delivery"
+)
+
+// webhookMaxAttempts is the number of tries per webhook delivery
+const webhookMaxAttempts = 4
+
+// WebhookPayload is the JSON structure sent to generic webhooks
+type WebhookPayload struct {
+	Event     string       `json:"event"`
+	Alert     models.Alert `json:"alert"`
+	Timestamp int64        `json:"timestamp"`
+	Source    string       `json:"source"`
+}
+
+// webhookTemplateData is what body templates are executed with. Besides the
+// payload fields it offers the rendered Title and Text.
+type webhookTemplateData struct {
+	WebhookPayload
+	Title string
+	Text  string
+}
+
+var webhookTemplateFuncs = template.FuncMap{
+	// json encodes a value, so strings can be embedded in JSON bodies safely
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// DeliveryStore persists webhook delivery attempts
+type DeliveryStore interface {
+	InsertWebhookDelivery(delivery models.WebhookDelivery) error
+}
+
+// WebhookResult describes a single webhook delivery attempt
+type WebhookResult struct {
+	StatusCode int
+	Duration   time.Duration
+}
+
+// ParseWebhookTemplate parses a webhook body template
+func ParseWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(webhookTemplateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// SignWebhookBody returns the signature header value for body
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetDeliveryStore sets where webhook delivery attempts are recorded
+func (d *Dispatcher) SetDeliveryStore(store DeliveryStore) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deliveries = store
+}
+
+// TestWebhook makes a single delivery attempt of a sample notification to a
+// webhook channel. It is not retried or recorded.
+func (d *Dispatcher) TestWebhook(ctx context.Context, ch config.NotificationChannel) (WebhookResult, error) {
+	body, contentType, err := webhookBody(ch, sampleNotification())
+	if err != nil {
+		return WebhookResult{}, err
+	}
+	return d.attemptWebhook(ctx, ch, body, contentType, EventFired, uuid.New().String())
+}
+
+// sendWebhook delivers n to a generic webhook, retrying network errors, 429
+// and 5xx responses with exponential backoff. Every attempt is recorded in
+// the delivery store.
+func (d *Dispatcher) sendWebhook(ctx context.Context, ch config.NotificationChannel, n Notification) error {
+	body, contentType, err := webhookBody(ch, n)
+	if err != nil {
+		return err
+	}
+
+	deliveryID := uuid.New().String()
+	delay := d.retryDelay
+	for attempt := 1; ; attempt++ {
+		result, err := d.attemptWebhook(ctx, ch, body, contentType, n.Event, deliveryID)
+		d.recordDelivery(ch, n, deliveryID, attempt, result, err)
+		if err == nil {
+			return nil
+		}
+		if attempt == webhookMaxAttempts || !retryableStatus(result.StatusCode) {
+			return fmt.Errorf("after %d attempt(s): %w", attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("after %d attempt(s): %w", attempt, err)
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (d *Dispatcher) attemptWebhook(ctx context.Context, ch config.NotificationChannel, body []byte, contentType, event, deliveryID string) (WebhookResult, error) {
+	// Custom headers go first so they cannot replace the ones set below
+	headers := make(map[string]string, len(ch.Headers)+3)
+	for key, value := range ch.Headers {
+		headers[http.CanonicalHeaderKey(key)] = value
+	}
+	headers[http.CanonicalHeaderKey(EventHeader)] = event
+	headers[http.CanonicalHeaderKey(DeliveryHeader)] = deliveryID
+	if ch.Secret != "" {
+		headers[http.CanonicalHeaderKey(SignatureHeader)] = SignWebhookBody(ch.Secret, body)
+	}
+
+	start := time.Now()
+	status, err := d.do(ctx, ch.URL, contentType, body, headers)
+	return WebhookResult{StatusCode: status, Duration: time.Since(start)}, err
+}
+
+func (d *Dispatcher) recordDelivery(ch config.NotificationChannel, n Notification, deliveryID string, attempt int, result WebhookResult, err error) {
+	d.mu.RLock()
+	store := d.deliveries
+	d.mu.RUnlock()
+	if store == nil {
+		return
+	}
+
+	delivery := models.WebhookDelivery{
+		DeliveryID: deliveryID,
+		Channel:    ch.Name,
+		URL:        redactURL(ch.URL),
+		Event:      n.Event,
+		AlertID:    n.Alert.ID,
+		Attempt:    attempt,
+		StatusCode: result.StatusCode,
+		Success:    err == nil,
+		DurationMs: result.Duration.Milliseconds(),
+		Timestamp:  time.Now().Unix(),
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	if err := store.InsertWebhookDelivery(delivery); err != nil {
+		log.Printf("Failed to record webhook delivery to %s: %v", ch.Name, err)
+	}
+}
+
+// webhookBody renders the request body for n, using the channel template
+// when one is configured
+func webhookBody(ch config.NotificationChannel, n Notification) ([]byte, string, error) {
+	payload := WebhookPayload{
+		Event:     n.Event,
+		Alert:     n.Alert,
+		Timestamp: time.Now().Unix(),
+		Source:    "vps-monitor",
+	}
+
+	if ch.Template == "" {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal webhook payload: %w", err)
+		}
+		return data, "application/json", nil
+	}
+
+	tmpl, err := ParseWebhookTemplate(ch.Template)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid webhook template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, webhookTemplateData{WebhookPayload: payload, Title: n.Title(), Text: n.Text()}); err != nil {
+		return nil, "", fmt.Errorf("failed to render webhook template: %w", err)
+	}
+	contentType := "text/plain; charset=utf-8"
+	if json.Valid(buf.Bytes()) {
+		contentType = "application/json"
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+func retryableStatus(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
+
+// redactURL drops credentials and the query string, which often carry tokens
+func redactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	parsed.User = nil
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+	return parsed.String()
+}
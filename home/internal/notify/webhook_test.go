@@ -0,0 +1,200 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+type memoryDeliveryStore struct {
+	mu         sync.Mutex
+	deliveries []models.WebhookDelivery
+}
+
+func (s *memoryDeliveryStore) InsertWebhookDelivery(delivery models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, delivery)
+	return nil
+}
+
+func TestWebhookSignatureAndHeaders(t *testing.T) {
+	srv, requests := newCaptureServer(t)
+	d := NewDispatcher(&config.Config{})
+	ch := config.NotificationChannel{
+		Name:    "hook",
+		Type:    config.ChannelWebhook,
+		URL:     srv.URL,
+		Secret:  "s3cret",
+		Headers: map[string]string{"Authorization": "Bearer abc", SignatureHeader: "forged"},
+	}
+
+	if err := d.send(context.Background(), ch, testNotification(models.AlertSeverityWarning, models.AlertContainerStopped)); err != nil {
+		t.Fatalf("send returned error: %v", err)
+	}
+
+	got := requests()[0]
+	if want := SignWebhookBody("s3cret", got.body); got.headers.Get(SignatureHeader) != want {
+		t.Fatalf("expected signature %s, got %s", want, got.headers.Get(SignatureHeader))
+	}
+	if got.headers.Get("Authorization") != "Bearer abc" {
+		t.Fatalf("expected custom header, got %v", got.headers)
+	}
+	if got.headers.Get(EventHeader) != EventFired || got.headers.Get(DeliveryHeader) == "" {
+		t.Fatalf("expected event and delivery headers, got %v", got.headers)
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	srv, requests := newCaptureServer(t)
+	d := NewDispatcher(&config.Config{})
+	ch := config.NotificationChannel{
+		Name:     "hook",
+		Type:     config.ChannelWebhook,
+		URL:      srv.URL,
+		Template: `{"text": {{json .Title}}, "host": {{json .Alert.Host}}, "event": "{{.Event}}"}`,
+	}
+
+	n := testNotification(models.AlertSeverityWarning, models.AlertContainerStopped)
+	n.Alert.Host = `quote"host`
+	if err := d.send(context.Background(), ch, n); err != nil {
+		t.Fatalf("send returned error: %v", err)
+	}
+
+	got := requests()[0]
+	if got.headers.Get("Content-Type") != "application/json" {
+		t.Fatalf("expected JSON content type, got %s", got.headers.Get("Content-Type"))
+	}
+	var body map[string]string
+	if err := json.Unmarshal(got.body, &body); err != nil {
+		t.Fatalf("template did not render valid JSON: %v: %s", err, got.body)
+	}
+	if body["text"] != n.Title() || body["host"] != `quote"host` || body["event"] != EventFired {
+		t.Fatalf("unexpected rendered body: %v", body)
+	}
+
+	if _, err := ParseWebhookTemplate(`{{.Alert.Host`); err == nil {
+		t.Fatal("expected a parse error for an unterminated action")
+	}
+}
+
+func TestWebhookRetriesAndRecordsDeliveries(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	store := &memoryDeliveryStore{}
+	d := NewDispatcher(&config.Config{})
+	d.retryDelay = time.Millisecond
+	d.SetDeliveryStore(store)
+
+	ch := config.NotificationChannel{Name: "hook", Type: config.ChannelWebhook, URL: srv.URL + "/hook?token=abc"}
+	if err := d.send(context.Background(), ch, testNotification(models.AlertSeverityWarning, models.AlertContainerStopped)); err != nil {
+		t.Fatalf("expected delivery to succeed after retries, got %v", err)
+	}
+
+	if len(store.deliveries) != 3 {
+		t.Fatalf("expected 3 recorded attempts, got %d", len(store.deliveries))
+	}
+	first, last := store.deliveries[0], store.deliveries[2]
+	if first.Success || first.StatusCode != http.StatusServiceUnavailable || first.Attempt != 1 {
+		t.Fatalf("unexpected first attempt: %+v", first)
+	}
+	if !last.Success || last.Attempt != 3 || last.DeliveryID != first.DeliveryID {
+		t.Fatalf("unexpected last attempt: %+v", last)
+	}
+	if first.URL != srv.URL+"/hook" || first.AlertID != "alert-1" || first.Channel != "hook" {
+		t.Fatalf("unexpected delivery details: %+v", first)
+	}
+}
+
+func TestWebhookDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	d := NewDispatcher(&config.Config{})
+	d.retryDelay = time.Millisecond
+	ch := config.NotificationChannel{Name: "hook", Type: config.ChannelWebhook, URL: srv.URL}
+	if err := d.send(context.Background(), ch, testNotification(models.AlertSeverityWarning, models.AlertContainerStopped)); err == nil {
+		t.Fatal("expected an error for 401")
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestWebhookGivesUpAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	d := NewDispatcher(&config.Config{})
+	d.retryDelay = time.Millisecond
+	ch := config.NotificationChannel{Name: "hook", Type: config.ChannelWebhook, URL: srv.URL}
+	if err := d.send(context.Background(), ch, testNotification(models.AlertSeverityWarning, models.AlertContainerStopped)); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls != webhookMaxAttempts {
+		t.Fatalf("expected %d attempts, got %d", webhookMaxAttempts, calls)
+	}
+}
+
+func TestTestWebhookMakesSingleAttempt(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	store := &memoryDeliveryStore{}
+	d := NewDispatcher(&config.Config{})
+	d.SetDeliveryStore(store)
+
+	result, err := d.TestWebhook(context.Background(), config.NotificationChannel{Name: "hook", Type: config.ChannelWebhook, URL: srv.URL})
+	if err == nil || result.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected a 500 result, got %+v, %v", result, err)
+	}
+	if calls != 1 || len(store.deliveries) != 0 {
+		t.Fatalf("expected one unrecorded attempt, got %d calls and %d records", calls, len(store.deliveries))
+	}
+}
+
+func TestWebhookErrorsRedactQueryTokens(t *testing.T) {
+	store := &memoryDeliveryStore{}
+	d := NewDispatcher(&config.Config{})
+	d.retryDelay = time.Millisecond
+	d.SetDeliveryStore(store)
+
+	ch := config.NotificationChannel{Name: "hook", Type: config.ChannelWebhook, URL: "http://127.0.0.1:1/hook?token=secret"}
+	err := d.send(context.Background(), ch, testNotification(models.AlertSeverityWarning, models.AlertContainerStopped))
+	if err == nil {
+		t.Fatal("expected connection error")
+	}
+	if strings.Contains(err.Error(), "secret") || strings.Contains(store.deliveries[0].Error, "secret") {
+		t.Fatalf("error leaks the query token: %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hhftechnology/vps-monitor/internal/auth"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/notify"
+)
+
+var (
+	// ErrInvalidDevice is returned when a device registration fails validation
+	ErrInvalidDevice = errors.New("invalid device")
+	// ErrDeviceTaken is returned when another user registered the token
+	ErrDeviceTaken = errors.New("the device is registered by another user")
+)
+
+// UserLookup returns the current role and grants of the owner of a device,
+// reporting false when the user no longer exists
+type UserLookup func(username string) (models.User, bool, error)
+
+// DeviceStore persists registered devices
+type DeviceStore interface {
+	ListDevices() ([]models.Device, error)
+	GetDeviceByToken(token string) (*models.Device, error)
+	SaveDevice(device models.Device) error
+	DeleteDevice(id string) (bool, error)
+	SetDeviceLastPush(id string, at int64) error
+}
+
+// Service keeps the registered devices, either in a persistent store or,
+// when none is configured, in memory, and pushes alerts to them
+type Service struct {
+	store   DeviceStore
+	mu      sync.RWMutex
+	devices []models.Device
+	senders map[models.PushProvider]Sender
+	users   UserLookup
+}
+
+// NewService creates a device registry backed by store. A nil store keeps
+// devices in memory.
+func NewService(store DeviceStore) *Service {
+	return &Service{store: store, senders: make(map[models.PushProvider]Sender)}
+}
+
+// SetSender sets how devices of provider are reached. Devices of providers
+// without a sender cannot be registered.
+func (s *Service) SetSender(provider models.PushProvider, sender Sender) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.senders[provider] = sender
+}
+
+// SetUserLookup sets how device owners are found. Devices then only receive
+// the alerts their owner may see; without a lookup every device receives
+// every alert.
+func (s *Service) SetUserLookup(users UserLookup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = users
+}
+
+// Register validates and saves a device. Registering a token again updates
+// the existing device; a token registered by another user is refused with
+// ErrDeviceTaken. Reports whether a new device was created.
+func (s *Service) Register(device models.Device) (models.Device, bool, error) {
+	if err := s.normalize(&device); err != nil {
+		return models.Device{}, false, err
+	}
+
+	existing, err := s.byToken(device.Token)
+	if err != nil {
+		return models.Device{}, false, err
+	}
+	if existing != nil && existing.Username != device.Username {
+		return models.Device{}, false, ErrDeviceTaken
+	}
+	now := time.Now().Unix()
+	device.UpdatedAt = now
+	if existing != nil {
+		device.ID = existing.ID
+		device.CreatedAt = existing.CreatedAt
+		device.LastPushAt = existing.LastPushAt
+	} else {
+		device.ID = uuid.New().String()
+		device.CreatedAt = now
+	}
+
+	if err := s.save(device); err != nil {
+		return models.Device{}, false, err
+	}
+	return device, existing == nil, nil
+}
+
+// List returns the devices registered by username
+func (s *Service) List(username string) ([]models.Device, error) {
+	all, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]models.Device, 0)
+	for _, device := range all {
+		if device.Username == username {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+// Unregister removes one of username's devices. Returns false when username
+// has no device with that ID.
+func (s *Service) Unregister(username, id string) (bool, error) {
+	devices, err := s.List(username)
+	if err != nil {
+		return false, err
+	}
+	if !slices.ContainsFunc(devices, func(d models.Device) bool { return d.ID == id }) {
+		return false, nil
+	}
+	return s.delete(id)
+}
+
+// Notify pushes n to every device whose minimum severity it meets and whose
+// owner may see the alert. Devices the push service reports as gone are
+// removed; errors of failing devices are joined.
+func (s *Service) Notify(ctx context.Context, n notify.Notification) error {
+	devices, err := s.all()
+	if err != nil {
+		return err
+	}
+	msg := messageFor(n)
+
+	s.mu.RLock()
+	senders := make(map[models.PushProvider]Sender, len(s.senders))
+	for provider, sender := range s.senders {
+		senders[provider] = sender
+	}
+	users := s.users
+	s.mu.RUnlock()
+
+	errs := make([]error, len(devices))
+	mayView := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		sender := senders[device.Provider]
+		if sender == nil || severityRank(n.Severity) < severityRank(device.MinSeverity) {
+			continue
+		}
+		visible, known := mayView[device.Username]
+		if !known {
+			visible = ownerMayView(users, device.Username, n)
+			mayView[device.Username] = visible
+		}
+		if !visible {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.push(ctx, sender, device, msg)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// ownerMayView reports whether username may see the alert of n
+func ownerMayView(users UserLookup, username string, n notify.Notification) bool {
+	if users == nil {
+		return true
+	}
+	owner, ok, err := users(username)
+	if err != nil {
+		log.Printf("Push: failed to look up %q, skipping their devices: %v", username, err)
+		return false
+	}
+	return ok && auth.CanAccessAlert(owner, n.Alert, n.Labels)
+}
+
+func (s *Service) push(ctx context.Context, sender Sender, device models.Device, msg Message) error {
+	err := sender.Send(ctx, device, msg)
+	if errors.Is(err, ErrDeviceGone) {
+		log.Printf("Push: removing device %s of %q, the push service no longer knows it", device.ID, device.Username)
+		if _, err := s.delete(device.ID); err != nil {
+			return fmt.Errorf("device %s: %w", device.ID, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("device %s: %w", device.ID, err)
+	}
+
+	if err := s.setLastPush(device.ID, time.Now().Unix()); err != nil {
+		log.Printf("Push: failed to record delivery to device %s: %v", device.ID, err)
+	}
+	return nil
+}
+
+// normalize validates device and fills in defaults
+func (s *Service) normalize(device *models.Device) error {
+	device.Token = strings.TrimSpace(device.Token)
+	device.Platform = strings.ToLower(strings.TrimSpace(device.Platform))
+	device.Name = strings.TrimSpace(device.Name)
+	if device.Token == "" {
+		return fmt.Errorf("%w: token is required", ErrInvalidDevice)
+	}
+
+	if device.Provider == "" {
+		device.Provider = models.PushFCM
+	}
+	switch device.Provider {
+	case models.PushFCM, models.PushUnifiedPush:
+	default:
+		return fmt.Errorf("%w: unknown provider %q", ErrInvalidDevice, device.Provider)
+	}
+	s.mu.RLock()
+	sender, available := s.senders[device.Provider]
+	s.mu.RUnlock()
+	if !available {
+		return fmt.Errorf("%w: %s push is not configured on this server", ErrInvalidDevice, device.Provider)
+	}
+	if validator, ok := sender.(tokenValidator); ok {
+		if err := validator.validateToken(device.Token); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidDevice, err)
+		}
+	}
+
+	switch device.MinSeverity {
+	case "":
+		device.MinSeverity = models.AlertSeverityInfo
+	case models.AlertSeverityInfo, models.AlertSeverityWarning, models.AlertSeverityCritical:
+	default:
+		return fmt.Errorf("%w: unknown severity %q", ErrInvalidDevice, device.MinSeverity)
+	}
+	return nil
+}
+
+func (s *Service) all() ([]models.Device, error) {
+	if s.store != nil {
+		return s.store.ListDevices()
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.devices), nil
+}
+
+func (s *Service) byToken(token string) (*models.Device, error) {
+	if s.store != nil {
+		return s.store.GetDeviceByToken(token)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, device := range s.devices {
+		if device.Token == token {
+			return &device, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Service) save(device models.Device) error {
+	if s.store != nil {
+		return s.store.SaveDevice(device)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.devices {
+		if s.devices[i].ID == device.ID {
+			s.devices[i] = device
+			return nil
+		}
+	}
+	s.devices = append(s.devices, device)
+	return nil
+}
+
+// setLastPush records a delivery without recreating devices removed meanwhile
+func (s *Service) setLastPush(id string, at int64) error {
+	if s.store != nil {
+		return s.store.SetDeviceLastPush(id, at)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.devices {
+		if s.devices[i].ID == id {
+			s.devices[i].LastPushAt = at
+		}
+	}
+	return nil
+}
+
+func (s *Service) delete(id string) (bool, error) {
+	if s.store != nil {
+		return s.store.DeleteDevice(id)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.devices {
+		if s.devices[i].ID == id {
+			s.devices = slices.Delete(s.devices, i, i+1)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func severityRank(severity models.AlertSeverity) int {
+	switch severity {
+	case models.AlertSeverityCritical:
+		return 2
+	case models.AlertSeverityWarning:
+		return 1
+	default:
+		return 0
+	}
+}
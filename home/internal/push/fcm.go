@@ -0,0 +1,204 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const (
+	fcmAPIBase       = "https://fcm.googleapis.com"
+	fcmScope         = "https://www.googleapis.com/auth/firebase.messaging"
+	googleTokenURI   = "https://oauth2.googleapis.com/token"
+	jwtBearerGrant   = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	tokenRefreshSkew = time.Minute
+)
+
+// serviceAccount holds the fields of a Google service account JSON key used
+// to obtain FCM access tokens
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	PrivateKey  string `json:"private_key"`
+	ClientEmail string `json:"client_email"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCMSender delivers messages through the Firebase Cloud Messaging HTTP v1 API
+type FCMSender struct {
+	client  *http.Client
+	apiBase string
+	account serviceAccount
+	key     *rsa.PrivateKey
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMSender creates an FCM sender from a Firebase service account key file
+func NewFCMSender(credentialsFile string) (*FCMSender, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("read FCM credentials: %w", err)
+	}
+	return newFCMSender(data)
+}
+
+func newFCMSender(credentials []byte) (*FCMSender, error) {
+	var account serviceAccount
+	if err := json.Unmarshal(credentials, &account); err != nil {
+		return nil, fmt.Errorf("parse FCM credentials: %w", err)
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, errors.New("FCM credentials need project_id, client_email and private_key")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = googleTokenURI
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse FCM private key: %w", err)
+	}
+	return &FCMSender{
+		client:  newHTTPClient(),
+		apiBase: fcmAPIBase,
+		account: account,
+		key:     key,
+	}, nil
+}
+
+// Send delivers msg to the device's FCM registration token
+func (s *FCMSender) Send(ctx context.Context, device models.Device, msg Message) error {
+	token, err := s.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	priority := "normal"
+	apnsPriority := "5"
+	if msg.Severity == models.AlertSeverityCritical {
+		priority = "high"
+		apnsPriority = "10"
+	}
+	body, err := json.Marshal(map[string]any{
+		"message": map[string]any{
+			"token":        device.Token,
+			"notification": map[string]string{"title": msg.Title, "body": msg.Body},
+			"data":         msg.Data,
+			"android":      map[string]string{"priority": priority},
+			"apns":         map[string]any{"headers": map[string]string{"apns-priority": apnsPriority}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", s.apiBase, url.PathEscape(s.account.ProjectID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var fcmErr struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&fcmErr)
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrDeviceGone
+	}
+	for _, detail := range fcmErr.Error.Details {
+		if detail.ErrorCode == "UNREGISTERED" {
+			return ErrDeviceGone
+		}
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Fetch a new access token on the next attempt
+		s.mu.Lock()
+		s.accessToken = ""
+		s.mu.Unlock()
+	}
+	return fmt.Errorf("fcm returned status %d: %s", resp.StatusCode, strings.TrimSpace(fcmErr.Error.Message))
+}
+
+// token returns a cached OAuth access token, exchanging a signed service
+// account assertion for a new one when it is about to expire
+func (s *FCMSender) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && time.Now().Add(tokenRefreshSkew).Before(s.expiresAt) {
+		return s.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   s.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("sign FCM token request: %w", err)
+	}
+
+	form := url.Values{"grant_type": {jwtBearerGrant}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch FCM access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch FCM access token: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode FCM access token: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", errors.New("fetch FCM access token: empty token")
+	}
+	s.accessToken = result.AccessToken
+	s.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return s.accessToken, nil
+}
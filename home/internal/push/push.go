@@ -0,0 +1,64 @@
+// Package push delivers alert notifications to registered mobile devices
+// through Firebase Cloud Messaging and UnifiedPush.
+package push
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/notify"
+)
+
+// ErrDeviceGone is returned by a Sender when the push service no longer
+// knows the device, e.g. because the app was uninstalled
+var ErrDeviceGone = errors.New("device is no longer registered with the push service")
+
+// Message is a push notification sent to one device
+type Message struct {
+	Title    string
+	Body     string
+	Severity models.AlertSeverity
+	// Data is passed to the app alongside the notification
+	Data map[string]string
+}
+
+// Sender delivers push messages through one push provider
+type Sender interface {
+	Send(ctx context.Context, device models.Device, msg Message) error
+}
+
+// tokenValidator is implemented by senders that check device tokens at
+// registration beyond their presence
+type tokenValidator interface {
+	validateToken(token string) error
+}
+
+// messageFor builds the push message for an alert notification
+func messageFor(n notify.Notification) Message {
+	return Message{
+		Title:    n.Title(),
+		Body:     n.Text(),
+		Severity: n.Severity,
+		Data: map[string]string{
+			"event":          n.Event,
+			"alert_id":       n.Alert.ID,
+			"type":           string(n.Alert.Type),
+			"host":           n.Alert.Host,
+			"container_id":   n.Alert.ContainerID,
+			"container_name": n.Alert.ContainerName,
+			"severity":       string(n.Severity),
+		},
+	}
+}
+
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,310 @@
+package push
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hhftechnology/vps-monitor/internal/models"
+	"github.com/hhftechnology/vps-monitor/internal/notify"
+)
+
+// pushServer stands in for a push service, recording what it receives
+type pushServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []map[string]any
+	gone     map[string]bool // paths answered with 410 Gone
+}
+
+func newPushServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, body map[string]any) bool) *pushServer {
+	t.Helper()
+	s := &pushServer{gone: make(map[string]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if r.Header.Get("Content-Type") == "application/json" {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		gone := s.gone[r.URL.Path]
+		s.mu.Unlock()
+
+		if handler != nil && handler(w, r, body) {
+			return
+		}
+		if gone {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *pushServer) paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paths := make([]string, 0, len(s.requests))
+	for _, r := range s.requests {
+		paths = append(paths, r.URL.Path)
+	}
+	return paths
+}
+
+func testNotification(severity models.AlertSeverity) notify.Notification {
+	return notify.Notification{
+		Event:    notify.EventFired,
+		Severity: severity,
+		Alert: models.Alert{
+			ID:            "a1",
+			Type:          models.AlertContainerOOM,
+			Host:          "host-a",
+			ContainerName: "web",
+			Message:       "Container web was killed by the OOM killer",
+			Severity:      severity,
+		},
+	}
+}
+
+func TestNotifyRespectsDeviceSeverityAndRemovesGoneDevices(t *testing.T) {
+	srv := newPushServer(t, nil)
+	srv.gone["/gone"] = true
+
+	s := NewService(nil)
+	s.SetSender(models.PushUnifiedPush, NewUnifiedPushSender([]string{"127.0.0.1"}))
+	register := func(username, path string, minSeverity models.AlertSeverity) models.Device {
+		t.Helper()
+		device, created, err := s.Register(models.Device{
+			Username:    username,
+			Provider:    models.PushUnifiedPush,
+			Token:       srv.URL + path,
+			MinSeverity: minSeverity,
+		})
+		if err != nil || !created {
+			t.Fatalf("Register(%s) = %v, %v", path, created, err)
+		}
+		return device
+	}
+	all := register("alice", "/all", "")
+	register("alice", "/critical-only", models.AlertSeverityCritical)
+	register("bob", "/gone", models.AlertSeverityWarning)
+
+	if err := s.Notify(context.Background(), testNotification(models.AlertSeverityWarning)); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	paths := strings.Join(srv.paths(), " ")
+	if strings.Contains(paths, "/critical-only") || !strings.Contains(paths, "/all") || !strings.Contains(paths, "/gone") {
+		t.Fatalf("unexpected deliveries: %s", paths)
+	}
+
+	srv.mu.Lock()
+	var first map[string]any
+	var urgency string
+	for i, r := range srv.requests {
+		if r.URL.Path == "/all" {
+			first, urgency = srv.bodies[i], r.Header.Get("Urgency")
+		}
+	}
+	srv.mu.Unlock()
+	data, _ := first["data"].(map[string]any)
+	if first["title"] != "[FIRING] container_oom: web on host-a" || data["alert_id"] != "a1" || urgency != "normal" {
+		t.Fatalf("unexpected payload %v (urgency %q)", first, urgency)
+	}
+
+	bob, err := s.List("bob")
+	if err != nil || len(bob) != 0 {
+		t.Fatalf("expected the gone device to be removed, got %v, %v", bob, err)
+	}
+	alice, err := s.List("alice")
+	if err != nil || len(alice) != 2 {
+		t.Fatalf("List(alice) = %v, %v", alice, err)
+	}
+	for _, device := range alice {
+		if device.ID == all.ID && device.LastPushAt == 0 {
+			t.Fatal("expected the delivery to be recorded")
+		}
+	}
+}
+
+func TestRegisterValidatesAndUpdatesByToken(t *testing.T) {
+	s := NewService(nil)
+	s.SetSender(models.PushUnifiedPush, NewUnifiedPushSender(nil))
+
+	for name, device := range map[string]models.Device{
+		"no token":         {Provider: models.PushUnifiedPush},
+		"fcm unconfigured": {Token: "fcm-token"},
+		"unknown provider": {Provider: "apns", Token: "x"},
+		"not a url":        {Provider: models.PushUnifiedPush, Token: "topic"},
+		"bad severity":     {Provider: models.PushUnifiedPush, Token: "https://ntfy.sh/up1", MinSeverity: "fatal"},
+		"loopback":         {Provider: models.PushUnifiedPush, Token: "http://127.0.0.1:6789/api/v1/containers"},
+		"localhost":        {Provider: models.PushUnifiedPush, Token: "http://localhost/up"},
+		"link-local":       {Provider: models.PushUnifiedPush, Token: "http://169.254.169.254/latest/meta-data"},
+		"private":          {Provider: models.PushUnifiedPush, Token: "https://192.168.1.10/up"},
+	} {
+		if _, _, err := s.Register(device); !errors.Is(err, ErrInvalidDevice) {
+			t.Fatalf("%s: expected ErrInvalidDevice, got %v", name, err)
+		}
+	}
+
+	first, created, err := s.Register(models.Device{Username: "alice", Provider: models.PushUnifiedPush, Token: "https://ntfy.sh/up1", Platform: " Android "})
+	if err != nil || !created || first.MinSeverity != models.AlertSeverityInfo || first.Platform != "android" {
+		t.Fatalf("Register() = %+v, %v, %v", first, created, err)
+	}
+	if _, _, err := s.Register(models.Device{Username: "bob", Provider: models.PushUnifiedPush, Token: "https://ntfy.sh/up1"}); !errors.Is(err, ErrDeviceTaken) {
+		t.Fatalf("expected ErrDeviceTaken for another user's token, got %v", err)
+	}
+	again, created, err := s.Register(models.Device{Username: "alice", Provider: models.PushUnifiedPush, Token: "https://ntfy.sh/up1", MinSeverity: models.AlertSeverityCritical})
+	if err != nil || created || again.ID != first.ID || again.MinSeverity != models.AlertSeverityCritical {
+		t.Fatalf("re-registering should update the device, got %+v, %v, %v", again, created, err)
+	}
+
+	if ok, err := s.Unregister("bob", first.ID); err != nil || ok {
+		t.Fatalf("bob does not own the device, Unregister() = %v, %v", ok, err)
+	}
+	if ok, err := s.Unregister("alice", first.ID); err != nil || !ok {
+		t.Fatalf("Unregister() = %v, %v", ok, err)
+	}
+}
+
+func TestNotifyOnlyReachesOwnersWhoMaySeeTheAlert(t *testing.T) {
+	srv := newPushServer(t, nil)
+	s := NewService(nil)
+	s.SetSender(models.PushUnifiedPush, NewUnifiedPushSender([]string{"127.0.0.1"}))
+	users := map[string]models.User{
+		"alice": {Username: "alice", Role: models.RoleOperator, Grants: []models.PermissionGrant{{Host: "host-a", Container: "web"}}},
+		"bob":   {Username: "bob", Role: models.RoleOperator, Grants: []models.PermissionGrant{{Host: "host-b"}}},
+	}
+	s.SetUserLookup(func(username string) (models.User, bool, error) {
+		user, ok := users[username]
+		return user, ok, nil
+	})
+	for _, username := range []string{"alice", "bob", "deleted"} {
+		if _, _, err := s.Register(models.Device{Username: username, Provider: models.PushUnifiedPush, Token: srv.URL + "/" + username}); err != nil {
+			t.Fatalf("Register(%s) error = %v", username, err)
+		}
+	}
+
+	if err := s.Notify(context.Background(), testNotification(models.AlertSeverityCritical)); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if paths := strings.Join(srv.paths(), " "); paths != "/alice" {
+		t.Fatalf("expected only alice's device to be notified, got %q", paths)
+	}
+}
+
+func TestUnifiedPushSenderRefusesLocalAddresses(t *testing.T) {
+	srv := newPushServer(t, nil)
+	device := models.Device{Provider: models.PushUnifiedPush, Token: srv.URL + "/up"}
+
+	if err := NewUnifiedPushSender(nil).Send(context.Background(), device, Message{}); err == nil {
+		t.Fatal("expected a push to a loopback endpoint to fail")
+	}
+	if len(srv.paths()) != 0 {
+		t.Fatalf("expected no request to reach the server, got %v", srv.paths())
+	}
+	if err := NewUnifiedPushSender([]string{"127.0.0.1"}).Send(context.Background(), device, Message{}); err != nil {
+		t.Fatalf("expected allowed hosts to be reached, got %v", err)
+	}
+}
+
+func TestFCMSenderAuthenticatesAndSends(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() error = %v", err)
+	}
+
+	var tokenRequests atomic.Int32
+	srv := newPushServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) bool {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests.Add(1)
+			_ = r.ParseForm()
+			claims := jwt.MapClaims{}
+			_, err := jwt.ParseWithClaims(r.PostForm.Get("assertion"), claims, func(*jwt.Token) (any, error) { return &key.PublicKey, nil })
+			if err != nil || r.PostForm.Get("grant_type") != jwtBearerGrant || claims["scope"] != fcmScope {
+				w.WriteHeader(http.StatusBadRequest)
+				return true
+			}
+			writeJSON(w, map[string]any{"access_token": "access-1", "expires_in": 3600})
+			return true
+		case "/v1/projects/demo/messages:send":
+			if r.Header.Get("Authorization") != "Bearer access-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return true
+			}
+			message, _ := body["message"].(map[string]any)
+			if message["token"] == "stale" {
+				w.WriteHeader(http.StatusNotFound)
+				writeJSON(w, map[string]any{"error": map[string]any{"status": "NOT_FOUND", "details": []map[string]string{{"errorCode": "UNREGISTERED"}}}})
+				return true
+			}
+			writeJSON(w, map[string]any{"name": "projects/demo/messages/1"})
+			return true
+		}
+		return false
+	})
+
+	credentials, _ := json.Marshal(map[string]string{
+		"project_id":   "demo",
+		"client_email": "push@demo.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    srv.URL + "/token",
+	})
+	sender, err := newFCMSender(credentials)
+	if err != nil {
+		t.Fatalf("newFCMSender() error = %v", err)
+	}
+	sender.apiBase = srv.URL
+
+	msg := messageFor(testNotification(models.AlertSeverityCritical))
+	for range 2 {
+		if err := sender.Send(context.Background(), models.Device{Token: "device-1"}, msg); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if n := tokenRequests.Load(); n != 1 {
+		t.Fatalf("expected the access token to be cached, got %d token requests", n)
+	}
+
+	srv.mu.Lock()
+	sent := srv.bodies[len(srv.bodies)-1]["message"].(map[string]any)
+	srv.mu.Unlock()
+	android, _ := sent["android"].(map[string]any)
+	notification, _ := sent["notification"].(map[string]any)
+	if sent["token"] != "device-1" || android["priority"] != "high" || notification["body"] != msg.Body {
+		t.Fatalf("unexpected FCM message %v", sent)
+	}
+
+	if err := sender.Send(context.Background(), models.Device{Token: "stale"}, msg); !errors.Is(err, ErrDeviceGone) {
+		t.Fatalf("expected ErrDeviceGone for an unregistered token, got %v", err)
+	}
+
+	if _, err := newFCMSender([]byte(`{"project_id":"demo"}`)); err == nil {
+		t.Fatal("expected incomplete credentials to be rejected")
+	}
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,138 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+// UnifiedPushSender posts messages to the UnifiedPush endpoint each device
+// registered with, e.g. an ntfy topic URL handed out by the distributor
+type UnifiedPushSender struct {
+	client       *http.Client
+	allowedHosts map[string]bool
+}
+
+// NewUnifiedPushSender creates a UnifiedPush sender. Endpoints on loopback,
+// private and link-local addresses are refused unless their host is one of
+// allowedHosts, e.g. an ntfy server on the local network.
+func NewUnifiedPushSender(allowedHosts []string) *UnifiedPushSender {
+	s := &UnifiedPushSender{client: newHTTPClient(), allowedHosts: make(map[string]bool, len(allowedHosts))}
+	for _, host := range allowedHosts {
+		s.allowedHosts[strings.ToLower(host)] = true
+	}
+
+	// Endpoints are chosen by users, so requests never go through a proxy
+	// that could reach what the checks below refuse
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = s.dial
+	s.client.Transport = transport
+	return s
+}
+
+// validateToken checks that token is an http(s) endpoint URL on a host that
+// may be reached. Host names are checked again once resolved, when pushing.
+func (s *UnifiedPushSender) validateToken(token string) error {
+	endpoint, err := url.Parse(token)
+	if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+		return errors.New("unifiedpush token must be the endpoint URL")
+	}
+
+	host := strings.ToLower(endpoint.Hostname())
+	if s.allowedHosts[host] {
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("unifiedpush endpoint host is not allowed")
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivateOrLocalIP(ip) {
+		return errors.New("unifiedpush endpoint host is not allowed")
+	}
+	return nil
+}
+
+// dial connects to public addresses only, unless the endpoint's host is
+// allowed. The address is checked after resolution, so host names pointing
+// at private addresses are refused as well.
+func (s *UnifiedPushSender) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !s.allowedHosts[strings.ToLower(host)] {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			ip, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if isPrivateOrLocalIP(net.ParseIP(ip)) {
+				return fmt.Errorf("unifiedpush endpoint %s resolves to a private or local address", host)
+			}
+			return nil
+		}
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+func isPrivateOrLocalIP(ip net.IP) bool {
+	if ip == nil {
+		return true
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalMulticast() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return true
+	}
+	return ip.IsPrivate()
+}
+
+// Send posts msg as JSON to the device's endpoint
+func (s *UnifiedPushSender) Send(ctx context.Context, device models.Device, msg Message) error {
+	body, err := json.Marshal(map[string]any{
+		"title":    msg.Title,
+		"body":     msg.Body,
+		"severity": msg.Severity,
+		"data":     msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, device.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// RFC 8030 urgency, honoured by UnifiedPush distributors
+	if msg.Severity == models.AlertSeverityCritical {
+		req.Header.Set("Urgency", "high")
+	} else {
+		req.Header.Set("Urgency", "normal")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrDeviceGone
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("unifiedpush endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
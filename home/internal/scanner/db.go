@@ -32,6 +32,14 @@ type HistoryQuery struct {
 	PageSize    int    `json:"page_size,omitempty"`
 	SortBy      string `json:"sort_by,omitempty"`
 	SortDir     string `json:"sort_dir,omitempty"`
+	// Images, when not nil, limits the results to these images
+	Images []HostImage `json:"-"`
+}
+
+// HostImage identifies an image reference on a host.
+type HostImage struct {
+	Host     string
+	ImageRef string
 }
 
 // HistoryPage holds paginated scan history results.
@@ -71,6 +79,8 @@ type SBOMHistoryQuery struct {
 	PageSize  int    `json:"page_size,omitempty"`
 	SortBy    string `json:"sort_by,omitempty"`
 	SortDir   string `json:"sort_dir,omitempty"`
+	// Images, when not nil, limits the results to these images
+	Images []HostImage `json:"-"`
 }
 
 // SBOMHistoryPage holds paginated SBOM history results.
@@ -205,6 +215,184 @@ CREATE TABLE IF NOT EXISTS container_stats (
 CREATE INDEX IF NOT EXISTS idx_cs_host_container_time ON container_stats(host, container_id, timestamp DESC);
 CREATE INDEX IF NOT EXISTS idx_cs_timestamp ON container_stats(timestamp);
 
+CREATE TABLE IF NOT EXISTS alerts (
+    id             TEXT PRIMARY KEY,
+    type           TEXT NOT NULL,
+    host           TEXT NOT NULL DEFAULT '',
+    container_id   TEXT NOT NULL DEFAULT '',
+    container_name TEXT NOT NULL DEFAULT '',
+    message        TEXT NOT NULL DEFAULT '',
+    value          REAL NOT NULL DEFAULT 0,
+    threshold      REAL NOT NULL DEFAULT 0,
+    timestamp      INTEGER NOT NULL,
+    acknowledged   INTEGER NOT NULL DEFAULT 0,
+    acknowledged_by TEXT NOT NULL DEFAULT '',
+    acknowledged_at INTEGER NOT NULL DEFAULT 0,
+    status         TEXT NOT NULL DEFAULT '',
+    resolved_at    INTEGER NOT NULL DEFAULT 0,
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    rule_id        TEXT NOT NULL DEFAULT '',
+    severity       TEXT NOT NULL DEFAULT '',
+    exit_code      INTEGER NOT NULL DEFAULT 0,
+    log_tail       TEXT NOT NULL DEFAULT '',
+    silenced       INTEGER NOT NULL DEFAULT 0,
+    silenced_by    TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_timestamp ON alerts(timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_alerts_host_container ON alerts(host, container_name);
+CREATE INDEX IF NOT EXISTS idx_alerts_acknowledged ON alerts(acknowledged);
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id              TEXT PRIMARY KEY,
+    name            TEXT NOT NULL,
+    enabled         INTEGER NOT NULL DEFAULT 1,
+    metric          TEXT NOT NULL,
+    comparator      TEXT NOT NULL,
+    threshold       REAL NOT NULL,
+    clear_threshold REAL NOT NULL DEFAULT 0,
+    for_seconds     INTEGER NOT NULL DEFAULT 0,
+    severity        TEXT NOT NULL,
+    scope_host      TEXT NOT NULL DEFAULT '',
+    scope_container TEXT NOT NULL DEFAULT '',
+    scope_label     TEXT NOT NULL DEFAULT '',
+    scope_image     TEXT NOT NULL DEFAULT '',
+    created_at      INTEGER NOT NULL,
+    updated_at      INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS silences (
+    id              TEXT PRIMARY KEY,
+    match_host      TEXT NOT NULL DEFAULT '',
+    match_container TEXT NOT NULL DEFAULT '',
+    match_label     TEXT NOT NULL DEFAULT '',
+    match_type      TEXT NOT NULL DEFAULT '',
+    comment         TEXT NOT NULL DEFAULT '',
+    created_by      TEXT NOT NULL DEFAULT '',
+    starts_at       INTEGER NOT NULL,
+    ends_at         INTEGER NOT NULL,
+    created_at      INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_silences_ends_at ON silences(ends_at);
+
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id               TEXT PRIMARY KEY,
+    name             TEXT NOT NULL,
+    enabled          INTEGER NOT NULL DEFAULT 1,
+    match_host       TEXT NOT NULL DEFAULT '',
+    match_container  TEXT NOT NULL DEFAULT '',
+    match_label      TEXT NOT NULL DEFAULT '',
+    match_type       TEXT NOT NULL DEFAULT '',
+    weekdays         TEXT NOT NULL DEFAULT '',
+    start_time       TEXT NOT NULL,
+    duration_minutes INTEGER NOT NULL,
+    timezone         TEXT NOT NULL DEFAULT 'UTC',
+    comment          TEXT NOT NULL DEFAULT '',
+    created_at       INTEGER NOT NULL,
+    updated_at       INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS devices (
+    id           TEXT PRIMARY KEY,
+    username     TEXT NOT NULL DEFAULT '',
+    provider     TEXT NOT NULL,
+    token        TEXT NOT NULL UNIQUE,
+    platform     TEXT NOT NULL DEFAULT '',
+    name         TEXT NOT NULL DEFAULT '',
+    min_severity TEXT NOT NULL DEFAULT 'info',
+    created_at   INTEGER NOT NULL,
+    updated_at   INTEGER NOT NULL,
+    last_push_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id           TEXT PRIMARY KEY,
+    username     TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    scopes       TEXT NOT NULL,
+    prefix       TEXT NOT NULL,
+    token_hash   TEXT NOT NULL UNIQUE,
+    created_at   INTEGER NOT NULL,
+    expires_at   INTEGER NOT NULL DEFAULT 0,
+    last_used_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    time        INTEGER NOT NULL,
+    username    TEXT NOT NULL DEFAULT '',
+    role        TEXT NOT NULL DEFAULT '',
+    api_token   INTEGER NOT NULL DEFAULT 0,
+    source_ip   TEXT NOT NULL DEFAULT '',
+    action      TEXT NOT NULL,
+    host        TEXT NOT NULL DEFAULT '',
+    target      TEXT NOT NULL DEFAULT '',
+    summary     TEXT NOT NULL DEFAULT '',
+    status      INTEGER NOT NULL DEFAULT 0,
+    outcome     TEXT NOT NULL,
+    error       TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_time ON audit_events(time);
+
+CREATE TABLE IF NOT EXISTS auth_sessions (
+    id           TEXT PRIMARY KEY,
+    username     TEXT NOT NULL,
+    role         TEXT NOT NULL,
+    provider     TEXT NOT NULL DEFAULT '',
+    refresh_hash TEXT NOT NULL UNIQUE,
+    user_agent   TEXT NOT NULL DEFAULT '',
+    remote_addr  TEXT NOT NULL DEFAULT '',
+    created_at   INTEGER NOT NULL,
+    last_used_at INTEGER NOT NULL,
+    expires_at   INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_expires ON auth_sessions(expires_at);
+
+CREATE TABLE IF NOT EXISTS token_denylist (
+    jti        TEXT PRIMARY KEY,
+    expires_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS two_factor (
+    username        TEXT PRIMARY KEY,
+    secret          TEXT NOT NULL,
+    recovery_hashes TEXT NOT NULL DEFAULT '',
+    enabled         INTEGER NOT NULL DEFAULT 0,
+    last_counter    INTEGER NOT NULL DEFAULT 0,
+    created_at      INTEGER NOT NULL,
+    enabled_at      INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    username      TEXT PRIMARY KEY,
+    password_hash TEXT NOT NULL,
+    role          TEXT NOT NULL,
+    grants        TEXT NOT NULL DEFAULT '',
+    created_at    INTEGER NOT NULL,
+    updated_at    INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    delivery_id TEXT NOT NULL,
+    channel     TEXT NOT NULL,
+    url         TEXT NOT NULL DEFAULT '',
+    event       TEXT NOT NULL DEFAULT '',
+    alert_id    TEXT NOT NULL DEFAULT '',
+    attempt     INTEGER NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    success     INTEGER NOT NULL DEFAULT 0,
+    error       TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    timestamp   INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_channel ON webhook_deliveries(channel, id DESC);
+
 CREATE TABLE IF NOT EXISTS settings (
     key        TEXT PRIMARY KEY,
     value      TEXT NOT NULL,
@@ -248,6 +436,14 @@ func NewScanDB(dbPath string) (*ScanDB, error) {
 		db.Close()
 		return nil, fmt.Errorf("failed to migrate image_sbom_state table: %w", err)
 	}
+	if err := scanDB.migrateAlertsTable(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate alerts table: %w", err)
+	}
+	if err := scanDB.migrateUsersTable(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate users table: %w", err)
+	}
 
 	return scanDB, nil
 }
@@ -322,6 +518,52 @@ func (s *ScanDB) migrateImageSBOMStateTable() error {
 	return tx.Commit()
 }
 
+// columnDef describes a column added to an existing table after its first release.
+type columnDef struct {
+	name string
+	ddl  string
+}
+
+// addMissingColumns adds any of the given columns that an older database lacks.
+func (s *ScanDB) addMissingColumns(table string, columns []columnDef) error {
+	rows, err := s.db.Query(`PRAGMA table_info(` + table + `)`)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	for _, col := range columns {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := s.db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + col.name + ` ` + col.ddl); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", table, col.name, err)
+		}
+	}
+	return nil
+}
+
 // InsertResult inserts a scan result and its vulnerabilities in a single transaction.
 func (s *ScanDB) InsertResult(result models.ScanResult) error {
 	tx, err := s.db.Begin()
@@ -753,6 +995,32 @@ func (s *ScanDB) QuerySBOMHistory(params SBOMHistoryQuery) (*SBOMHistoryPage, er
 	}, nil
 }
 
+// LatestScanSummaries returns the most recent scan result of every image
+// on every host, without vulnerabilities.
+func (s *ScanDB) LatestScanSummaries() ([]models.ScanResult, error) {
+	rows, err := s.db.Query(`SELECT id, image_ref, host, scanner,
+		summary_critical, summary_high, summary_medium, summary_low,
+		summary_negligible, summary_unknown, summary_total,
+		started_at, completed_at, duration_ms, error
+		FROM (SELECT *, ROW_NUMBER() OVER (PARTITION BY image_ref, host ORDER BY completed_at DESC, id) AS n
+			FROM scan_results)
+		WHERE n = 1 ORDER BY host, image_ref`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.ScanResult{}
+	for rows.Next() {
+		r, err := scanResultRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
 // ListScannedImages returns distinct image+host pairs with scan counts.
 func (s *ScanDB) ListScannedImages() ([]ScannedImage, error) {
 	rows, err := s.db.Query(`SELECT image_ref, host, COUNT(*) as scan_count, MAX(completed_at) as last_scanned
@@ -1341,6 +1609,11 @@ func buildHistoryWhere(params HistoryQuery) (string, []interface{}) {
 			conditions = append(conditions, "(summary_critical > 0 OR summary_high > 0 OR summary_medium > 0 OR summary_low > 0)")
 		}
 	}
+	if params.Images != nil {
+		condition, imageArgs := hostImagesCondition(params.Images)
+		conditions = append(conditions, condition)
+		args = append(args, imageArgs...)
+	}
 
 	if len(conditions) == 0 {
 		return "", args
@@ -1372,6 +1645,11 @@ func buildSBOMHistoryWhere(params SBOMHistoryQuery) (string, []interface{}) {
 		conditions = append(conditions, "completed_at <= ?")
 		args = append(args, params.EndDate)
 	}
+	if params.Images != nil {
+		condition, imageArgs := hostImagesCondition(params.Images)
+		conditions = append(conditions, condition)
+		args = append(args, imageArgs...)
+	}
 
 	if len(conditions) == 0 {
 		return "", args
@@ -1379,6 +1657,20 @@ func buildSBOMHistoryWhere(params SBOMHistoryQuery) (string, []interface{}) {
 	return " WHERE " + strings.Join(conditions, " AND "), args
 }
 
+// hostImagesCondition matches rows of any of images; no images match nothing.
+func hostImagesCondition(images []HostImage) (string, []interface{}) {
+	if len(images) == 0 {
+		return "0", nil
+	}
+	parts := make([]string, 0, len(images))
+	args := make([]interface{}, 0, 2*len(images))
+	for _, img := range images {
+		parts = append(parts, "(host = ? AND image_ref = ?)")
+		args = append(args, img.Host, img.ImageRef)
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", args
+}
+
 func boolToStr(b bool) string {
 	if b {
 		return "true"
@@ -0,0 +1,113 @@
+package scanner
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const alertRuleColumns = `id, name, enabled, metric, comparator, threshold, clear_threshold,
+	for_seconds, severity, scope_host, scope_container, scope_label, scope_image,
+	created_at, updated_at`
+
+// ListAlertRules returns all alert rules ordered by creation time.
+func (s *ScanDB) ListAlertRules() ([]models.AlertRule, error) {
+	rows, err := s.db.Query(`SELECT ` + alertRuleColumns + ` FROM alert_rules ORDER BY created_at ASC, id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]models.AlertRule, 0)
+	for rows.Next() {
+		rule, err := alertRuleFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetAlertRule returns a single alert rule, or nil if it does not exist.
+func (s *ScanDB) GetAlertRule(id string) (*models.AlertRule, error) {
+	row := s.db.QueryRow(`SELECT `+alertRuleColumns+` FROM alert_rules WHERE id = ?`, id)
+	rule, err := alertRuleFromRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SaveAlertRule inserts or replaces an alert rule.
+func (s *ScanDB) SaveAlertRule(rule models.AlertRule) error {
+	_, err := s.db.Exec(`INSERT INTO alert_rules (`+alertRuleColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			enabled = excluded.enabled,
+			metric = excluded.metric,
+			comparator = excluded.comparator,
+			threshold = excluded.threshold,
+			clear_threshold = excluded.clear_threshold,
+			for_seconds = excluded.for_seconds,
+			severity = excluded.severity,
+			scope_host = excluded.scope_host,
+			scope_container = excluded.scope_container,
+			scope_label = excluded.scope_label,
+			scope_image = excluded.scope_image,
+			updated_at = excluded.updated_at`,
+		rule.ID,
+		rule.Name,
+		rule.Enabled,
+		string(rule.Metric),
+		string(rule.Comparator),
+		rule.Threshold,
+		rule.ClearThreshold,
+		rule.ForSeconds,
+		string(rule.Severity),
+		rule.Scope.Host,
+		rule.Scope.Container,
+		rule.Scope.Label,
+		rule.Scope.Image,
+		rule.CreatedAt,
+		rule.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save alert rule: %w", err)
+	}
+	return nil
+}
+
+// DeleteAlertRule removes an alert rule.
+// Returns false when no rule with the given ID exists.
+func (s *ScanDB) DeleteAlertRule(id string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM alert_rules WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func alertRuleFromRow(row interface{ Scan(...any) error }) (models.AlertRule, error) {
+	var rule models.AlertRule
+	var metric, comparator, severity string
+	err := row.Scan(&rule.ID, &rule.Name, &rule.Enabled, &metric, &comparator, &rule.Threshold,
+		&rule.ClearThreshold, &rule.ForSeconds, &severity, &rule.Scope.Host, &rule.Scope.Container,
+		&rule.Scope.Label, &rule.Scope.Image, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return rule, err
+	}
+	rule.Metric = models.AlertRuleMetric(metric)
+	rule.Comparator = models.AlertComparator(comparator)
+	rule.Severity = models.AlertSeverity(severity)
+	return rule, nil
+}
@@ -0,0 +1,273 @@
+package scanner
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const (
+	defaultAlertPageSize = 100
+	maxAlertPageSize     = 500
+)
+
+// alertColumns lists the alert columns in the order alertRow scans them
+const alertColumns = "id, type, host, container_id, container_name, message," +
+	" value, threshold, timestamp, acknowledged, status, resolved_at, duration_seconds," +
+	" rule_id, severity, exit_code, log_tail, silenced, silenced_by, acknowledged_by, acknowledged_at"
+
+// migrateAlertsTable adds columns introduced after the alerts table was first created.
+func (s *ScanDB) migrateAlertsTable() error {
+	return s.addMissingColumns("alerts", []columnDef{
+		{name: "status", ddl: "TEXT NOT NULL DEFAULT ''"},
+		{name: "resolved_at", ddl: "INTEGER NOT NULL DEFAULT 0"},
+		{name: "duration_seconds", ddl: "INTEGER NOT NULL DEFAULT 0"},
+		{name: "rule_id", ddl: "TEXT NOT NULL DEFAULT ''"},
+		{name: "severity", ddl: "TEXT NOT NULL DEFAULT ''"},
+		{name: "exit_code", ddl: "INTEGER NOT NULL DEFAULT 0"},
+		{name: "log_tail", ddl: "TEXT NOT NULL DEFAULT ''"},
+		{name: "silenced", ddl: "INTEGER NOT NULL DEFAULT 0"},
+		{name: "silenced_by", ddl: "TEXT NOT NULL DEFAULT ''"},
+		{name: "acknowledged_by", ddl: "TEXT NOT NULL DEFAULT ''"},
+		{name: "acknowledged_at", ddl: "INTEGER NOT NULL DEFAULT 0"},
+	})
+}
+
+// InsertAlert stores a single alert in the persistent alert history.
+func (s *ScanDB) InsertAlert(alert models.Alert) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO alerts (
+		id, type, host, container_id, container_name, message,
+		value, threshold, timestamp, acknowledged,
+		status, resolved_at, duration_seconds, rule_id, severity,
+		exit_code, log_tail, silenced, silenced_by, acknowledged_by, acknowledged_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		alert.ID,
+		string(alert.Type),
+		alert.Host,
+		alert.ContainerID,
+		alert.ContainerName,
+		alert.Message,
+		alert.Value,
+		alert.Threshold,
+		alert.Timestamp,
+		alert.Acknowledged,
+		string(alert.Status),
+		alert.ResolvedAt,
+		alert.DurationSeconds,
+		alert.RuleID,
+		string(alert.Severity),
+		alert.ExitCode,
+		alert.LogTail,
+		alert.Silenced,
+		alert.SilencedBy,
+		alert.AcknowledgedBy,
+		alert.AcknowledgedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert alert: %w", err)
+	}
+	return nil
+}
+
+// QueryAlerts returns paginated alert history, newest first, with optional filters.
+func (s *ScanDB) QueryAlerts(params models.AlertQuery) (*models.AlertPage, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = defaultAlertPageSize
+	}
+	if params.PageSize > maxAlertPageSize {
+		params.PageSize = maxAlertPageSize
+	}
+
+	where, args := buildAlertWhere(params)
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM alerts"+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("count alerts: %w", err)
+	}
+
+	totalPages := (total + params.PageSize - 1) / params.PageSize
+	offset := (params.Page - 1) * params.PageSize
+
+	query := "SELECT " + alertColumns + " FROM alerts" + where +
+		" ORDER BY timestamp DESC, rowid DESC" +
+		" LIMIT ? OFFSET ?"
+	args = append(args, params.PageSize, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("alert history query: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := make([]models.Alert, 0)
+	for rows.Next() {
+		alert, err := alertRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.AlertPage{
+		Alerts:     alerts,
+		Total:      total,
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// GetAlert returns the alert with the given ID, or nil when none exists.
+func (s *ScanDB) GetAlert(id string) (*models.Alert, error) {
+	row := s.db.QueryRow("SELECT "+alertColumns+" FROM alerts WHERE id = ?", id)
+	alert, err := alertRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// ResolveAlert marks a firing alert as resolved at resolvedAt.
+// Returns false when no alert with the given ID exists.
+func (s *ScanDB) ResolveAlert(id string, resolvedAt int64) (bool, error) {
+	res, err := s.db.Exec(`UPDATE alerts SET
+		status = ?,
+		resolved_at = ?,
+		duration_seconds = MAX(? - timestamp, 0)
+		WHERE id = ?`,
+		string(models.AlertStatusResolved), resolvedAt, resolvedAt, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// AcknowledgeAlert marks a single alert as acknowledged by user at the given
+// time. An alert that was already acknowledged keeps its first acknowledgement.
+// Returns false when no alert with the given ID exists.
+func (s *ScanDB) AcknowledgeAlert(id, user string, at int64) (bool, error) {
+	res, err := s.db.Exec(`UPDATE alerts SET acknowledged = 1, acknowledged_by = ?, acknowledged_at = ?
+		WHERE id = ? AND acknowledged = 0`, user, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return true, nil
+	}
+
+	var exists int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM alerts WHERE id = ?`, id).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists > 0, nil
+}
+
+// AcknowledgeAllAlerts marks every unacknowledged alert as acknowledged by
+// user at the given time and returns how many alerts were updated.
+func (s *ScanDB) AcknowledgeAllAlerts(user string, at int64) (int, error) {
+	res, err := s.db.Exec(`UPDATE alerts SET acknowledged = 1, acknowledged_by = ?, acknowledged_at = ?
+		WHERE acknowledged = 0`, user, at)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// CountUnacknowledgedAlerts returns the number of alerts not yet acknowledged.
+func (s *ScanDB) CountUnacknowledgedAlerts() (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM alerts WHERE acknowledged = 0`).Scan(&count)
+	return count, err
+}
+
+// PruneAlertsOlderThan removes alerts older than the cutoff. Alerts that are
+// still firing are kept so they can be resolved.
+func (s *ScanDB) PruneAlertsOlderThan(cutoff time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM alerts WHERE timestamp < ? AND status != ?`,
+		cutoff.Unix(), string(models.AlertStatusFiring))
+	return err
+}
+
+func alertRow(rows interface{ Scan(...any) error }) (models.Alert, error) {
+	var alert models.Alert
+	var typeStr, statusStr, severityStr string
+	err := rows.Scan(&alert.ID, &typeStr, &alert.Host, &alert.ContainerID, &alert.ContainerName,
+		&alert.Message, &alert.Value, &alert.Threshold, &alert.Timestamp, &alert.Acknowledged,
+		&statusStr, &alert.ResolvedAt, &alert.DurationSeconds, &alert.RuleID, &severityStr,
+		&alert.ExitCode, &alert.LogTail, &alert.Silenced, &alert.SilencedBy,
+		&alert.AcknowledgedBy, &alert.AcknowledgedAt)
+	if err != nil {
+		return alert, err
+	}
+	alert.Type = models.AlertType(typeStr)
+	alert.Status = models.AlertStatus(statusStr)
+	alert.Severity = models.AlertSeverity(severityStr)
+	return alert, nil
+}
+
+func buildAlertWhere(params models.AlertQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if params.Host != "" {
+		conditions = append(conditions, "host = ?")
+		args = append(args, params.Host)
+	}
+	if params.Container != "" {
+		conditions = append(conditions, "(container_id = ? OR container_name LIKE ?)")
+		args = append(args, params.Container, "%"+params.Container+"%")
+	}
+	if params.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, string(params.Type))
+	}
+	if params.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, string(params.Status))
+	}
+	if params.StartDate > 0 {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, params.StartDate)
+	}
+	if params.EndDate > 0 {
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, params.EndDate)
+	}
+	if params.Acknowledged != nil {
+		conditions = append(conditions, "acknowledged = ?")
+		args = append(args, *params.Acknowledged)
+	}
+	if params.Silenced != nil {
+		conditions = append(conditions, "silenced = ?")
+		args = append(args, *params.Silenced)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
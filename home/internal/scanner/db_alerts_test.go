@@ -0,0 +1,266 @@
+package scanner
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestQueryAlertsFiltersAndPaginates(t *testing.T) {
+	db := newTestScanDB(t)
+	now := time.Unix(1_700_000_000, 0).UTC()
+
+	for _, alert := range []models.Alert{
+		{ID: "a1", Type: models.AlertCPUThreshold, Host: "host-a", ContainerID: "c1", ContainerName: "web", Timestamp: now.Add(-3 * time.Hour).Unix()},
+		{ID: "a2", Type: models.AlertMemoryThreshold, Host: "host-a", ContainerID: "c2", ContainerName: "postgres", Timestamp: now.Add(-2 * time.Hour).Unix()},
+		{ID: "a3", Type: models.AlertCPUThreshold, Host: "host-b", ContainerID: "c3", ContainerName: "web-worker", Timestamp: now.Add(-1 * time.Hour).Unix(), Acknowledged: true},
+		{ID: "a4", Type: models.AlertContainerStopped, Host: "host-a", ContainerID: "c1", ContainerName: "web", Timestamp: now.Unix()},
+	} {
+		if err := db.InsertAlert(alert); err != nil {
+			t.Fatalf("InsertAlert() error = %v", err)
+		}
+	}
+
+	page, err := db.QueryAlerts(models.AlertQuery{})
+	if err != nil {
+		t.Fatalf("QueryAlerts() error = %v", err)
+	}
+	if page.Total != 4 || len(page.Alerts) != 4 {
+		t.Fatalf("expected 4 alerts, got total=%d len=%d", page.Total, len(page.Alerts))
+	}
+	if page.Alerts[0].ID != "a4" || page.Alerts[3].ID != "a1" {
+		t.Fatalf("expected newest-first ordering, got %s..%s", page.Alerts[0].ID, page.Alerts[3].ID)
+	}
+
+	page, err = db.QueryAlerts(models.AlertQuery{Host: "host-a", Type: models.AlertCPUThreshold})
+	if err != nil {
+		t.Fatalf("QueryAlerts() error = %v", err)
+	}
+	if page.Total != 1 || page.Alerts[0].ID != "a1" {
+		t.Fatalf("unexpected host/type filter result: %+v", page.Alerts)
+	}
+
+	page, err = db.QueryAlerts(models.AlertQuery{Container: "web"})
+	if err != nil {
+		t.Fatalf("QueryAlerts() error = %v", err)
+	}
+	if page.Total != 3 {
+		t.Fatalf("expected container name filter to match 3 alerts, got %d", page.Total)
+	}
+
+	acknowledged := false
+	page, err = db.QueryAlerts(models.AlertQuery{
+		Acknowledged: &acknowledged,
+		StartDate:    now.Add(-150 * time.Minute).Unix(),
+		EndDate:      now.Add(-30 * time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("QueryAlerts() error = %v", err)
+	}
+	if page.Total != 1 || page.Alerts[0].ID != "a2" {
+		t.Fatalf("unexpected acknowledged/time filter result: %+v", page.Alerts)
+	}
+
+	page, err = db.QueryAlerts(models.AlertQuery{Page: 2, PageSize: 3})
+	if err != nil {
+		t.Fatalf("QueryAlerts() error = %v", err)
+	}
+	if page.TotalPages != 2 || len(page.Alerts) != 1 || page.Alerts[0].ID != "a1" {
+		t.Fatalf("unexpected second page: pages=%d alerts=%+v", page.TotalPages, page.Alerts)
+	}
+}
+
+func TestAcknowledgeAndPruneAlerts(t *testing.T) {
+	db := newTestScanDB(t)
+	now := time.Unix(1_700_000_000, 0).UTC()
+
+	for _, alert := range []models.Alert{
+		{ID: "old", Type: models.AlertCPUThreshold, Host: "host-a", Timestamp: now.Add(-48 * time.Hour).Unix()},
+		{ID: "new-1", Type: models.AlertCPUThreshold, Host: "host-a", Timestamp: now.Add(-time.Hour).Unix()},
+		{ID: "new-2", Type: models.AlertCPUThreshold, Host: "host-a", Timestamp: now.Unix()},
+	} {
+		if err := db.InsertAlert(alert); err != nil {
+			t.Fatalf("InsertAlert() error = %v", err)
+		}
+	}
+
+	ok, err := db.AcknowledgeAlert("new-1", "alice", now.Unix())
+	if err != nil || !ok {
+		t.Fatalf("AcknowledgeAlert() = %v, %v", ok, err)
+	}
+	// A second acknowledgement keeps the first one
+	ok, err = db.AcknowledgeAlert("new-1", "bob", now.Unix()+60)
+	if err != nil || !ok {
+		t.Fatalf("AcknowledgeAlert(again) = %v, %v", ok, err)
+	}
+	ok, err = db.AcknowledgeAlert("missing", "alice", now.Unix())
+	if err != nil || ok {
+		t.Fatalf("AcknowledgeAlert(missing) = %v, %v", ok, err)
+	}
+
+	alert, err := db.GetAlert("new-1")
+	if err != nil || alert == nil || alert.AcknowledgedBy != "alice" || alert.Host != "host-a" {
+		t.Fatalf("GetAlert() = %+v, %v", alert, err)
+	}
+	if alert, err := db.GetAlert("missing"); err != nil || alert != nil {
+		t.Fatalf("GetAlert(missing) = %+v, %v", alert, err)
+	}
+
+	count, err := db.CountUnacknowledgedAlerts()
+	if err != nil || count != 2 {
+		t.Fatalf("CountUnacknowledgedAlerts() = %d, %v", count, err)
+	}
+
+	if err := db.PruneAlertsOlderThan(now.Add(-24 * time.Hour)); err != nil {
+		t.Fatalf("PruneAlertsOlderThan() error = %v", err)
+	}
+
+	updated, err := db.AcknowledgeAllAlerts("bob", now.Unix()+60)
+	if err != nil || updated != 1 {
+		t.Fatalf("AcknowledgeAllAlerts() = %d, %v", updated, err)
+	}
+
+	page, err := db.QueryAlerts(models.AlertQuery{})
+	if err != nil {
+		t.Fatalf("QueryAlerts() error = %v", err)
+	}
+	if page.Total != 2 {
+		t.Fatalf("expected pruned history to contain 2 alerts, got %d", page.Total)
+	}
+	for _, alert := range page.Alerts {
+		if !alert.Acknowledged {
+			t.Fatalf("expected alert %s to be acknowledged", alert.ID)
+		}
+	}
+	acks := map[string]string{}
+	for _, alert := range page.Alerts {
+		acks[alert.ID] = fmt.Sprintf("%s@%d", alert.AcknowledgedBy, alert.AcknowledgedAt-now.Unix())
+	}
+	if acks["new-1"] != "alice@0" || acks["new-2"] != "bob@60" {
+		t.Fatalf("unexpected acknowledgements: %v", acks)
+	}
+}
+
+func TestPruneAlertsKeepsFiringAlerts(t *testing.T) {
+	db := newTestScanDB(t)
+	now := time.Unix(1_700_000_000, 0).UTC()
+
+	for _, alert := range []models.Alert{
+		{ID: "old-resolved", Type: models.AlertHostDown, Host: "host-a", Status: models.AlertStatusResolved, Timestamp: now.Add(-72 * time.Hour).Unix()},
+		{ID: "old-firing", Type: models.AlertHostDown, Host: "host-b", Status: models.AlertStatusFiring, Timestamp: now.Add(-72 * time.Hour).Unix()},
+	} {
+		if err := db.InsertAlert(alert); err != nil {
+			t.Fatalf("InsertAlert() error = %v", err)
+		}
+	}
+
+	if err := db.PruneAlertsOlderThan(now.Add(-24 * time.Hour)); err != nil {
+		t.Fatalf("PruneAlertsOlderThan() error = %v", err)
+	}
+	page, err := db.QueryAlerts(models.AlertQuery{})
+	if err != nil {
+		t.Fatalf("QueryAlerts() error = %v", err)
+	}
+	if page.Total != 1 || page.Alerts[0].ID != "old-firing" {
+		t.Fatalf("expected only the firing alert to survive pruning, got %+v", page.Alerts)
+	}
+
+	// The outage can still be resolved after the prune
+	ok, err := db.ResolveAlert("old-firing", now.Unix())
+	if err != nil || !ok {
+		t.Fatalf("ResolveAlert() = %v, %v", ok, err)
+	}
+}
+
+func TestResolveAlertRecordsDuration(t *testing.T) {
+	db := newTestScanDB(t)
+	opened := time.Unix(1_700_000_000, 0).UTC()
+
+	if err := db.InsertAlert(models.Alert{
+		ID:        "a1",
+		Type:      models.AlertCPUThreshold,
+		Host:      "host-a",
+		Status:    models.AlertStatusFiring,
+		Timestamp: opened.Unix(),
+		ExitCode:  137,
+		LogTail:   "panic: out of memory",
+	}); err != nil {
+		t.Fatalf("InsertAlert() error = %v", err)
+	}
+
+	ok, err := db.ResolveAlert("a1", opened.Add(90*time.Second).Unix())
+	if err != nil || !ok {
+		t.Fatalf("ResolveAlert() = %v, %v", ok, err)
+	}
+	if ok, err := db.ResolveAlert("missing", opened.Unix()); err != nil || ok {
+		t.Fatalf("ResolveAlert(missing) = %v, %v", ok, err)
+	}
+
+	page, err := db.QueryAlerts(models.AlertQuery{Status: models.AlertStatusResolved})
+	if err != nil {
+		t.Fatalf("QueryAlerts() error = %v", err)
+	}
+	if page.Total != 1 {
+		t.Fatalf("expected 1 resolved alert, got %d", page.Total)
+	}
+	alert := page.Alerts[0]
+	if alert.ResolvedAt != opened.Add(90*time.Second).Unix() || alert.DurationSeconds != 90 {
+		t.Fatalf("unexpected resolution: resolved_at=%d duration=%d", alert.ResolvedAt, alert.DurationSeconds)
+	}
+	if alert.ExitCode != 137 || alert.LogTail != "panic: out of memory" {
+		t.Fatalf("unexpected crash details: exit_code=%d log_tail=%q", alert.ExitCode, alert.LogTail)
+	}
+}
+
+func TestAlertRulesRoundTrip(t *testing.T) {
+	db := newTestScanDB(t)
+
+	rule := models.AlertRule{
+		ID:             "r1",
+		Name:           "Busy web",
+		Enabled:        true,
+		Metric:         models.MetricNetworkTxRate,
+		Comparator:     models.ComparatorGreaterOrEqual,
+		Threshold:      1 << 20,
+		ClearThreshold: 1 << 19,
+		ForSeconds:     120,
+		Severity:       models.AlertSeverityWarning,
+		Scope:          models.AlertRuleScope{Host: "host-a", Container: "web-*", Label: "tier=front", Image: "nginx:*"},
+		CreatedAt:      100,
+		UpdatedAt:      100,
+	}
+	if err := db.SaveAlertRule(rule); err != nil {
+		t.Fatalf("SaveAlertRule() error = %v", err)
+	}
+
+	got, err := db.GetAlertRule("r1")
+	if err != nil || got == nil {
+		t.Fatalf("GetAlertRule() = %v, %v", got, err)
+	}
+	if *got != rule {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", *got, rule)
+	}
+
+	rule.Enabled = false
+	rule.UpdatedAt = 200
+	rule.CreatedAt = 999 // created_at is kept on update
+	if err := db.SaveAlertRule(rule); err != nil {
+		t.Fatalf("SaveAlertRule() error = %v", err)
+	}
+	rules, err := db.ListAlertRules()
+	if err != nil {
+		t.Fatalf("ListAlertRules() error = %v", err)
+	}
+	if len(rules) != 1 || rules[0].Enabled || rules[0].UpdatedAt != 200 || rules[0].CreatedAt != 100 {
+		t.Fatalf("unexpected rules after update: %+v", rules)
+	}
+
+	if ok, err := db.DeleteAlertRule("r1"); err != nil || !ok {
+		t.Fatalf("DeleteAlertRule() = %v, %v", ok, err)
+	}
+	if got, err := db.GetAlertRule("r1"); err != nil || got != nil {
+		t.Fatalf("expected deleted rule to be gone, got %v, %v", got, err)
+	}
+}
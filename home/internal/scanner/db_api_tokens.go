@@ -0,0 +1,99 @@
+package scanner
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const apiTokenColumns = `id, username, name, scopes, prefix, token_hash,
+	created_at, expires_at, last_used_at`
+
+// ListAPITokens returns all API tokens ordered by creation time.
+func (s *ScanDB) ListAPITokens() ([]models.APIToken, error) {
+	rows, err := s.db.Query(`SELECT ` + apiTokenColumns + ` FROM api_tokens ORDER BY created_at ASC, id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]models.APIToken, 0)
+	for rows.Next() {
+		token, err := apiTokenFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetAPITokenByHash returns the API token with the given hash, or nil if there is none.
+func (s *ScanDB) GetAPITokenByHash(hash string) (*models.APIToken, error) {
+	row := s.db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?`, hash)
+	token, err := apiTokenFromRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// SaveAPIToken inserts or replaces an API token.
+func (s *ScanDB) SaveAPIToken(token models.APIToken) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO api_tokens (`+apiTokenColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		token.ID,
+		token.Username,
+		token.Name,
+		strings.Join(token.Scopes, ","),
+		token.Prefix,
+		token.TokenHash,
+		token.CreatedAt,
+		token.ExpiresAt,
+		token.LastUsedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save api token: %w", err)
+	}
+	return nil
+}
+
+// DeleteAPIToken removes an API token.
+// Returns false when no token with the given ID exists.
+func (s *ScanDB) DeleteAPIToken(id string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM api_tokens WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SetAPITokenLastUsed records when an API token was last used.
+func (s *ScanDB) SetAPITokenLastUsed(id string, at int64) error {
+	_, err := s.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, at, id)
+	return err
+}
+
+func apiTokenFromRow(row interface{ Scan(...any) error }) (models.APIToken, error) {
+	var token models.APIToken
+	var scopes string
+	err := row.Scan(&token.ID, &token.Username, &token.Name, &scopes, &token.Prefix, &token.TokenHash,
+		&token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt)
+	if err != nil {
+		return token, err
+	}
+	token.Scopes = make([]string, 0)
+	if scopes != "" {
+		token.Scopes = strings.Split(scopes, ",")
+	}
+	return token, nil
+}
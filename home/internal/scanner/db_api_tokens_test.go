@@ -0,0 +1,53 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestAPITokensRoundTrip(t *testing.T) {
+	db := newTestScanDB(t)
+
+	token := models.APIToken{
+		ID:        "t1",
+		Username:  "alice",
+		Name:      "ci",
+		Scopes:    []string{models.TokenScopeRead, models.TokenScopeScan},
+		Prefix:    "vpsm_0123abcd",
+		TokenHash: "hash-1",
+		CreatedAt: 100,
+		ExpiresAt: 200,
+	}
+	if err := db.SaveAPIToken(token); err != nil {
+		t.Fatalf("SaveAPIToken() error = %v", err)
+	}
+
+	got, err := db.GetAPITokenByHash("hash-1")
+	if err != nil || got == nil || !reflect.DeepEqual(*got, token) {
+		t.Fatalf("GetAPITokenByHash() = %+v, %v", got, err)
+	}
+	if missing, err := db.GetAPITokenByHash("hash-2"); err != nil || missing != nil {
+		t.Fatalf("GetAPITokenByHash() of an unknown hash = %+v, %v", missing, err)
+	}
+
+	if err := db.SetAPITokenLastUsed("t1", 150); err != nil {
+		t.Fatalf("SetAPITokenLastUsed() error = %v", err)
+	}
+	tokens, err := db.ListAPITokens()
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsedAt != 150 {
+		t.Fatalf("ListAPITokens() = %+v, %v", tokens, err)
+	}
+
+	if ok, err := db.DeleteAPIToken("t1"); err != nil || !ok {
+		t.Fatalf("DeleteAPIToken() = %v, %v", ok, err)
+	}
+	// Recording a use must not bring a revoked token back
+	if err := db.SetAPITokenLastUsed("t1", 160); err != nil {
+		t.Fatalf("SetAPITokenLastUsed() error = %v", err)
+	}
+	if tokens, err := db.ListAPITokens(); err != nil || len(tokens) != 0 {
+		t.Fatalf("expected no tokens, got %+v, %v", tokens, err)
+	}
+}
@@ -0,0 +1,141 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+)
+
+const auditColumns = `id, time, username, role, api_token, source_ip, action, host,
+	target, summary, status, outcome, error, duration_ms`
+
+// InsertAuditEvent appends an event to the audit log. The ID is assigned
+// by the database.
+func (s *ScanDB) InsertAuditEvent(event models.AuditEvent) error {
+	_, err := s.db.Exec(`INSERT INTO audit_events (time, username, role, api_token,
+		source_ip, action, host, target, summary, status, outcome, error, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.Time,
+		event.Username,
+		event.Role,
+		event.APIToken,
+		event.SourceIP,
+		event.Action,
+		event.Host,
+		event.Target,
+		event.Summary,
+		event.Status,
+		event.Outcome,
+		event.Error,
+		event.DurationMs,
+	)
+	if err != nil {
+		return fmt.Errorf("insert audit event: %w", err)
+	}
+	return nil
+}
+
+// QueryAuditEvents returns a page of the audit log, newest first, with
+// optional filters.
+func (s *ScanDB) QueryAuditEvents(params models.AuditQuery) (*models.AuditPage, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = defaultAuditPageSize
+	}
+	if params.PageSize > maxAuditPageSize {
+		params.PageSize = maxAuditPageSize
+	}
+
+	where, args := buildAuditWhere(params)
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM audit_events"+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("count audit events: %w", err)
+	}
+
+	query := "SELECT " + auditColumns + " FROM audit_events" + where +
+		" ORDER BY time DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("audit log query: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]models.AuditEvent, 0)
+	for rows.Next() {
+		var event models.AuditEvent
+		if err := rows.Scan(&event.ID, &event.Time, &event.Username, &event.Role, &event.APIToken,
+			&event.SourceIP, &event.Action, &event.Host, &event.Target, &event.Summary,
+			&event.Status, &event.Outcome, &event.Error, &event.DurationMs); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.AuditPage{
+		Events:     events,
+		Total:      total,
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		TotalPages: (total + params.PageSize - 1) / params.PageSize,
+	}, nil
+}
+
+// PruneAuditEventsOlderThan removes audit events older than the cutoff.
+func (s *ScanDB) PruneAuditEventsOlderThan(cutoff time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM audit_events WHERE time < ?`, cutoff.Unix())
+	return err
+}
+
+func buildAuditWhere(params models.AuditQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if params.Username != "" {
+		conditions = append(conditions, "username = ?")
+		args = append(args, params.Username)
+	}
+	if params.Action != "" {
+		conditions = append(conditions, "action LIKE ?")
+		args = append(args, "%"+params.Action+"%")
+	}
+	if params.Host != "" {
+		conditions = append(conditions, "host = ?")
+		args = append(args, params.Host)
+	}
+	if params.Target != "" {
+		conditions = append(conditions, "target LIKE ?")
+		args = append(args, "%"+params.Target+"%")
+	}
+	if params.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, params.Outcome)
+	}
+	if params.StartDate > 0 {
+		conditions = append(conditions, "time >= ?")
+		args = append(args, params.StartDate)
+	}
+	if params.EndDate > 0 {
+		conditions = append(conditions, "time <= ?")
+		args = append(args, params.EndDate)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,60 @@
+package scanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestAuditEventsQueryAndPrune(t *testing.T) {
+	db := newTestScanDB(t)
+	now := time.Now().Unix()
+
+	events := []models.AuditEvent{
+		{Time: now - 7200, Username: "alice", Role: models.RoleOperator, SourceIP: "203.0.113.7", Action: "POST /containers/{id}/restart", Host: "prod", Target: "id=abc", Status: 202, Outcome: models.AuditOutcomeAccepted},
+		{Time: now - 60, Username: "admin", Role: models.RoleAdmin, APIToken: true, SourceIP: "203.0.113.8", Action: "PUT /settings/auth", Summary: `enabled=true jwtSecret=[redacted]`, Status: 200, Outcome: models.AuditOutcomeSuccess, DurationMs: 12},
+		{Time: now, Username: "vic", Role: models.RoleViewer, SourceIP: "203.0.113.9", Action: "POST /containers/{id}/stop", Host: "prod", Target: "id=def", Status: 403, Outcome: models.AuditOutcomeDenied, Error: "forbidden"},
+	}
+	for _, event := range events {
+		if err := db.InsertAuditEvent(event); err != nil {
+			t.Fatalf("InsertAuditEvent() error = %v", err)
+		}
+	}
+
+	page, err := db.QueryAuditEvents(models.AuditQuery{})
+	if err != nil || page.Total != 3 || page.Events[0].Username != "vic" || page.Events[2].Username != "alice" {
+		t.Fatalf("QueryAuditEvents() = %+v, %v", page, err)
+	}
+	got := page.Events[1]
+	events[1].ID = got.ID
+	if got != events[1] {
+		t.Fatalf("expected %+v, got %+v", events[1], got)
+	}
+
+	for name, tc := range map[string]struct {
+		query models.AuditQuery
+		want  int
+	}{
+		"user":    {models.AuditQuery{Username: "alice"}, 1},
+		"action":  {models.AuditQuery{Action: "/containers/"}, 2},
+		"host":    {models.AuditQuery{Host: "prod"}, 2},
+		"target":  {models.AuditQuery{Target: "def"}, 1},
+		"outcome": {models.AuditQuery{Outcome: models.AuditOutcomeDenied}, 1},
+		"since":   {models.AuditQuery{StartDate: now - 3600}, 2},
+		"until":   {models.AuditQuery{EndDate: now - 3600}, 1},
+		"page":    {models.AuditQuery{Page: 2, PageSize: 2}, 1},
+	} {
+		page, err := db.QueryAuditEvents(tc.query)
+		if err != nil || len(page.Events) != tc.want {
+			t.Fatalf("%s: QueryAuditEvents() = %+v, %v", name, page, err)
+		}
+	}
+
+	if err := db.PruneAuditEventsOlderThan(time.Unix(now-3600, 0)); err != nil {
+		t.Fatalf("PruneAuditEventsOlderThan() error = %v", err)
+	}
+	if page, err := db.QueryAuditEvents(models.AuditQuery{}); err != nil || page.Total != 2 {
+		t.Fatalf("expected 2 events after pruning, got %+v, %v", page, err)
+	}
+}
@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const deviceColumns = `id, username, provider, token, platform, name, min_severity,
+	created_at, updated_at, last_push_at`
+
+// ListDevices returns all registered push devices ordered by registration time.
+func (s *ScanDB) ListDevices() ([]models.Device, error) {
+	rows, err := s.db.Query(`SELECT ` + deviceColumns + ` FROM devices ORDER BY created_at ASC, id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := make([]models.Device, 0)
+	for rows.Next() {
+		device, err := deviceFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	return devices, rows.Err()
+}
+
+// GetDeviceByToken returns the device registered with token, or nil if there is none.
+func (s *ScanDB) GetDeviceByToken(token string) (*models.Device, error) {
+	row := s.db.QueryRow(`SELECT `+deviceColumns+` FROM devices WHERE token = ?`, token)
+	device, err := deviceFromRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// SaveDevice inserts or replaces a device. A device registered earlier
+// with the same token is replaced.
+func (s *ScanDB) SaveDevice(device models.Device) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO devices (`+deviceColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		device.ID,
+		device.Username,
+		string(device.Provider),
+		device.Token,
+		device.Platform,
+		device.Name,
+		string(device.MinSeverity),
+		device.CreatedAt,
+		device.UpdatedAt,
+		device.LastPushAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save device: %w", err)
+	}
+	return nil
+}
+
+// DeleteDevice removes a device.
+// Returns false when no device with the given ID exists.
+func (s *ScanDB) DeleteDevice(id string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM devices WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SetDeviceLastPush records when a push was last delivered to a device.
+func (s *ScanDB) SetDeviceLastPush(id string, at int64) error {
+	_, err := s.db.Exec(`UPDATE devices SET last_push_at = ? WHERE id = ?`, at, id)
+	return err
+}
+
+func deviceFromRow(row interface{ Scan(...any) error }) (models.Device, error) {
+	var device models.Device
+	var provider, minSeverity string
+	err := row.Scan(&device.ID, &device.Username, &provider, &device.Token, &device.Platform,
+		&device.Name, &minSeverity, &device.CreatedAt, &device.UpdatedAt, &device.LastPushAt)
+	if err != nil {
+		return device, err
+	}
+	device.Provider = models.PushProvider(provider)
+	device.MinSeverity = models.AlertSeverity(minSeverity)
+	return device, nil
+}
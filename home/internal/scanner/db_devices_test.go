@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestDevicesRoundTrip(t *testing.T) {
+	db := newTestScanDB(t)
+
+	device := models.Device{
+		ID:          "d1",
+		Username:    "alice",
+		Provider:    models.PushFCM,
+		Token:       "fcm-token",
+		Platform:    "android",
+		Name:        "Pixel",
+		MinSeverity: models.AlertSeverityWarning,
+		CreatedAt:   100,
+		UpdatedAt:   100,
+	}
+	if err := db.SaveDevice(device); err != nil {
+		t.Fatalf("SaveDevice() error = %v", err)
+	}
+
+	got, err := db.GetDeviceByToken("fcm-token")
+	if err != nil || got == nil {
+		t.Fatalf("GetDeviceByToken() = %v, %v", got, err)
+	}
+	if *got != device {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", *got, device)
+	}
+
+	if err := db.SetDeviceLastPush("d1", 200); err != nil {
+		t.Fatalf("SetDeviceLastPush() error = %v", err)
+	}
+	// A token is registered once; saving it under another ID replaces the row
+	if err := db.SaveDevice(models.Device{ID: "d2", Provider: models.PushFCM, Token: "fcm-token", MinSeverity: models.AlertSeverityInfo}); err != nil {
+		t.Fatalf("SaveDevice() error = %v", err)
+	}
+	devices, err := db.ListDevices()
+	if err != nil {
+		t.Fatalf("ListDevices() error = %v", err)
+	}
+	if len(devices) != 1 || devices[0].ID != "d2" || devices[0].LastPushAt != 0 {
+		t.Fatalf("unexpected devices: %+v", devices)
+	}
+
+	if ok, err := db.DeleteDevice("d2"); err != nil || !ok {
+		t.Fatalf("DeleteDevice() = %v, %v", ok, err)
+	}
+	if got, err := db.GetDeviceByToken("fcm-token"); err != nil || got != nil {
+		t.Fatalf("expected deleted device to be gone, got %v, %v", got, err)
+	}
+	// Recording a push for a removed device must not bring it back
+	if err := db.SetDeviceLastPush("d1", 300); err != nil {
+		t.Fatalf("SetDeviceLastPush() error = %v", err)
+	}
+	if devices, _ := db.ListDevices(); len(devices) != 0 {
+		t.Fatalf("expected no devices, got %+v", devices)
+	}
+}
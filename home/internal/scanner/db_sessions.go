@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const sessionColumns = `id, username, role, provider, refresh_hash, user_agent,
+	remote_addr, created_at, last_used_at, expires_at`
+
+// ListSessions returns all login sessions ordered by creation time.
+func (s *ScanDB) ListSessions() ([]models.Session, error) {
+	rows, err := s.db.Query(`SELECT ` + sessionColumns + ` FROM auth_sessions ORDER BY created_at ASC, id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]models.Session, 0)
+	for rows.Next() {
+		session, err := sessionFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// GetSessionByRefreshHash returns the session with the given refresh token
+// hash, or nil if there is none.
+func (s *ScanDB) GetSessionByRefreshHash(hash string) (*models.Session, error) {
+	row := s.db.QueryRow(`SELECT `+sessionColumns+` FROM auth_sessions WHERE refresh_hash = ?`, hash)
+	session, err := sessionFromRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// SaveSession inserts or replaces a login session.
+func (s *ScanDB) SaveSession(session models.Session) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO auth_sessions (`+sessionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID,
+		session.Username,
+		session.Role,
+		session.Provider,
+		session.RefreshHash,
+		session.UserAgent,
+		session.RemoteAddr,
+		session.CreatedAt,
+		session.LastUsedAt,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return nil
+}
+
+// DeleteSession removes a login session.
+// Returns false when no session with the given ID exists.
+func (s *ScanDB) DeleteSession(id string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM auth_sessions WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// DeleteExpiredSessions removes sessions that expired at or before now.
+func (s *ScanDB) DeleteExpiredSessions(now int64) error {
+	_, err := s.db.Exec(`DELETE FROM auth_sessions WHERE expires_at <= ?`, now)
+	return err
+}
+
+// ListDeniedTokens returns the denied token IDs with the time their tokens
+// expire.
+func (s *ScanDB) ListDeniedTokens() (map[string]int64, error) {
+	rows, err := s.db.Query(`SELECT jti, expires_at FROM token_denylist`)
+	if err != nil {
+		return nil, fmt.Errorf("list denied tokens: %w", err)
+	}
+	defer rows.Close()
+
+	denied := make(map[string]int64)
+	for rows.Next() {
+		var jti string
+		var expiresAt int64
+		if err := rows.Scan(&jti, &expiresAt); err != nil {
+			return nil, err
+		}
+		denied[jti] = expiresAt
+	}
+	return denied, rows.Err()
+}
+
+// DenyToken adds a token ID to the denylist until expiresAt.
+func (s *ScanDB) DenyToken(jti string, expiresAt int64) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO token_denylist (jti, expires_at) VALUES (?, ?)`, jti, expiresAt)
+	if err != nil {
+		return fmt.Errorf("deny token: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredDeniedTokens removes denylist entries whose tokens expired
+// at or before now.
+func (s *ScanDB) DeleteExpiredDeniedTokens(now int64) error {
+	_, err := s.db.Exec(`DELETE FROM token_denylist WHERE expires_at <= ?`, now)
+	return err
+}
+
+func sessionFromRow(row interface{ Scan(...any) error }) (models.Session, error) {
+	var session models.Session
+	err := row.Scan(&session.ID, &session.Username, &session.Role, &session.Provider, &session.RefreshHash,
+		&session.UserAgent, &session.RemoteAddr, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt)
+	return session, err
+}
@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestSessionsRoundTrip(t *testing.T) {
+	db := newTestScanDB(t)
+
+	session := models.Session{
+		ID:          "s1",
+		Username:    "alice",
+		Role:        models.RoleOperator,
+		Provider:    "oidc",
+		RefreshHash: "hash-1",
+		UserAgent:   "curl/8",
+		RemoteAddr:  "203.0.113.7",
+		CreatedAt:   100,
+		LastUsedAt:  100,
+		ExpiresAt:   200,
+	}
+	if err := db.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	if err := db.SaveSession(models.Session{ID: "s2", Username: "bob", Role: models.RoleViewer, RefreshHash: "hash-2", CreatedAt: 50, LastUsedAt: 50, ExpiresAt: 150}); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	got, err := db.GetSessionByRefreshHash("hash-1")
+	if err != nil || got == nil || *got != session {
+		t.Fatalf("GetSessionByRefreshHash() = %+v, %v", got, err)
+	}
+	if missing, err := db.GetSessionByRefreshHash("hash-3"); err != nil || missing != nil {
+		t.Fatalf("GetSessionByRefreshHash() of an unknown hash = %+v, %v", missing, err)
+	}
+
+	if err := db.DeleteExpiredSessions(150); err != nil {
+		t.Fatalf("DeleteExpiredSessions() error = %v", err)
+	}
+	sessions, err := db.ListSessions()
+	if err != nil || len(sessions) != 1 || sessions[0].ID != "s1" {
+		t.Fatalf("ListSessions() = %+v, %v", sessions, err)
+	}
+	if ok, err := db.DeleteSession("s1"); err != nil || !ok {
+		t.Fatalf("DeleteSession() = %v, %v", ok, err)
+	}
+	if ok, err := db.DeleteSession("s1"); err != nil || ok {
+		t.Fatalf("DeleteSession() of a deleted session = %v, %v", ok, err)
+	}
+}
+
+func TestTokenDenylist(t *testing.T) {
+	db := newTestScanDB(t)
+
+	if err := db.DenyToken("s1", 100); err != nil {
+		t.Fatalf("DenyToken() error = %v", err)
+	}
+	if err := db.DenyToken("s2", 300); err != nil {
+		t.Fatalf("DenyToken() error = %v", err)
+	}
+	if err := db.DeleteExpiredDeniedTokens(200); err != nil {
+		t.Fatalf("DeleteExpiredDeniedTokens() error = %v", err)
+	}
+	denied, err := db.ListDeniedTokens()
+	if err != nil || len(denied) != 1 || denied["s2"] != 300 {
+		t.Fatalf("ListDeniedTokens() = %v, %v", denied, err)
+	}
+}
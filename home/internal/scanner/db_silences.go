@@ -0,0 +1,186 @@
+package scanner
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const silenceColumns = `id, match_host, match_container, match_label, match_type,
+	comment, created_by, starts_at, ends_at, created_at`
+
+const maintenanceWindowColumns = `id, name, enabled, match_host, match_container, match_label, match_type,
+	weekdays, start_time, duration_minutes, timezone, comment, created_at, updated_at`
+
+// ListSilences returns all silences, most recently ending first.
+func (s *ScanDB) ListSilences() ([]models.Silence, error) {
+	rows, err := s.db.Query(`SELECT ` + silenceColumns + ` FROM silences ORDER BY ends_at DESC, id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list silences: %w", err)
+	}
+	defer rows.Close()
+
+	silences := make([]models.Silence, 0)
+	for rows.Next() {
+		silence, err := silenceFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		silences = append(silences, silence)
+	}
+	return silences, rows.Err()
+}
+
+// GetSilence returns a single silence, or nil if it does not exist.
+func (s *ScanDB) GetSilence(id string) (*models.Silence, error) {
+	row := s.db.QueryRow(`SELECT `+silenceColumns+` FROM silences WHERE id = ?`, id)
+	silence, err := silenceFromRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+// SaveSilence inserts or replaces a silence.
+func (s *ScanDB) SaveSilence(silence models.Silence) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO silences (`+silenceColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		silence.ID,
+		silence.Host,
+		silence.Container,
+		silence.Label,
+		string(silence.AlertType),
+		silence.Comment,
+		silence.CreatedBy,
+		silence.StartsAt,
+		silence.EndsAt,
+		silence.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save silence: %w", err)
+	}
+	return nil
+}
+
+// PruneSilencesEndedBefore removes silences that expired before the cutoff.
+func (s *ScanDB) PruneSilencesEndedBefore(cutoff time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM silences WHERE ends_at < ?`, cutoff.Unix())
+	return err
+}
+
+// ListMaintenanceWindows returns all maintenance windows ordered by creation time.
+func (s *ScanDB) ListMaintenanceWindows() ([]models.MaintenanceWindow, error) {
+	rows, err := s.db.Query(`SELECT ` + maintenanceWindowColumns + ` FROM maintenance_windows ORDER BY created_at ASC, id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list maintenance windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := make([]models.MaintenanceWindow, 0)
+	for rows.Next() {
+		window, err := maintenanceWindowFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, rows.Err()
+}
+
+// GetMaintenanceWindow returns a single maintenance window, or nil if it does not exist.
+func (s *ScanDB) GetMaintenanceWindow(id string) (*models.MaintenanceWindow, error) {
+	row := s.db.QueryRow(`SELECT `+maintenanceWindowColumns+` FROM maintenance_windows WHERE id = ?`, id)
+	window, err := maintenanceWindowFromRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// SaveMaintenanceWindow inserts or replaces a maintenance window.
+func (s *ScanDB) SaveMaintenanceWindow(window models.MaintenanceWindow) error {
+	weekdays := make([]string, 0, len(window.Weekdays))
+	for _, day := range window.Weekdays {
+		weekdays = append(weekdays, strconv.Itoa(day))
+	}
+
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO maintenance_windows (`+maintenanceWindowColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		window.ID,
+		window.Name,
+		window.Enabled,
+		window.Host,
+		window.Container,
+		window.Label,
+		string(window.AlertType),
+		strings.Join(weekdays, ","),
+		window.StartTime,
+		window.DurationMinutes,
+		window.Timezone,
+		window.Comment,
+		window.CreatedAt,
+		window.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save maintenance window: %w", err)
+	}
+	return nil
+}
+
+// DeleteMaintenanceWindow removes a maintenance window.
+// Returns false when no window with the given ID exists.
+func (s *ScanDB) DeleteMaintenanceWindow(id string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM maintenance_windows WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func silenceFromRow(row interface{ Scan(...any) error }) (models.Silence, error) {
+	var silence models.Silence
+	var alertType string
+	err := row.Scan(&silence.ID, &silence.Host, &silence.Container, &silence.Label, &alertType,
+		&silence.Comment, &silence.CreatedBy, &silence.StartsAt, &silence.EndsAt, &silence.CreatedAt)
+	if err != nil {
+		return silence, err
+	}
+	silence.AlertType = models.AlertType(alertType)
+	return silence, nil
+}
+
+func maintenanceWindowFromRow(row interface{ Scan(...any) error }) (models.MaintenanceWindow, error) {
+	var window models.MaintenanceWindow
+	var alertType, weekdays string
+	err := row.Scan(&window.ID, &window.Name, &window.Enabled, &window.Host, &window.Container,
+		&window.Label, &alertType, &weekdays, &window.StartTime, &window.DurationMinutes,
+		&window.Timezone, &window.Comment, &window.CreatedAt, &window.UpdatedAt)
+	if err != nil {
+		return window, err
+	}
+	window.AlertType = models.AlertType(alertType)
+	if weekdays != "" {
+		for _, part := range strings.Split(weekdays, ",") {
+			day, err := strconv.Atoi(part)
+			if err != nil {
+				return window, fmt.Errorf("maintenance window %s: invalid weekday %q", window.ID, part)
+			}
+			window.Weekdays = append(window.Weekdays, day)
+		}
+	}
+	return window, nil
+}
@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestSilencesRoundTripAndPrune(t *testing.T) {
+	db := newTestScanDB(t)
+
+	now := time.Now().Unix()
+	active := models.Silence{
+		ID:             "s1",
+		SilenceMatcher: models.SilenceMatcher{Host: "host-a", Container: "web-*", Label: "tier=front", AlertType: models.AlertContainerStopped},
+		Comment:        "deploy",
+		CreatedBy:      "admin",
+		StartsAt:       now,
+		EndsAt:         now + 3600,
+		CreatedAt:      now,
+	}
+	expired := models.Silence{ID: "s2", SilenceMatcher: models.SilenceMatcher{Host: "host-b"}, StartsAt: now - 7200, EndsAt: now - 3600, CreatedAt: now - 7200}
+	for _, silence := range []models.Silence{active, expired} {
+		if err := db.SaveSilence(silence); err != nil {
+			t.Fatalf("SaveSilence() error = %v", err)
+		}
+	}
+
+	got, err := db.GetSilence("s1")
+	if err != nil || got == nil {
+		t.Fatalf("GetSilence() = %v, %v", got, err)
+	}
+	if *got != active {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", *got, active)
+	}
+
+	if err := db.PruneSilencesEndedBefore(time.Unix(now-60, 0)); err != nil {
+		t.Fatalf("PruneSilencesEndedBefore() error = %v", err)
+	}
+	silences, err := db.ListSilences()
+	if err != nil {
+		t.Fatalf("ListSilences() error = %v", err)
+	}
+	if len(silences) != 1 || silences[0].ID != "s1" {
+		t.Fatalf("expected only the active silence to remain, got %+v", silences)
+	}
+}
+
+func TestMaintenanceWindowsRoundTrip(t *testing.T) {
+	db := newTestScanDB(t)
+
+	window := models.MaintenanceWindow{
+		ID:              "w1",
+		Name:            "Sunday patching",
+		Enabled:         true,
+		SilenceMatcher:  models.SilenceMatcher{Label: "com.docker.compose.project"},
+		Weekdays:        []int{0, 6},
+		StartTime:       "02:00",
+		DurationMinutes: 90,
+		Timezone:        "Europe/Berlin",
+		Comment:         "OS updates",
+		CreatedAt:       100,
+		UpdatedAt:       100,
+	}
+	if err := db.SaveMaintenanceWindow(window); err != nil {
+		t.Fatalf("SaveMaintenanceWindow() error = %v", err)
+	}
+
+	got, err := db.GetMaintenanceWindow("w1")
+	if err != nil || got == nil {
+		t.Fatalf("GetMaintenanceWindow() = %v, %v", got, err)
+	}
+	if !reflect.DeepEqual(*got, window) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", *got, window)
+	}
+
+	window.Weekdays = nil
+	window.Enabled = false
+	if err := db.SaveMaintenanceWindow(window); err != nil {
+		t.Fatalf("SaveMaintenanceWindow() error = %v", err)
+	}
+	windows, err := db.ListMaintenanceWindows()
+	if err != nil {
+		t.Fatalf("ListMaintenanceWindows() error = %v", err)
+	}
+	if len(windows) != 1 || windows[0].Enabled || windows[0].Weekdays != nil {
+		t.Fatalf("unexpected windows after update: %+v", windows)
+	}
+
+	if ok, err := db.DeleteMaintenanceWindow("w1"); err != nil || !ok {
+		t.Fatalf("DeleteMaintenanceWindow() = %v, %v", ok, err)
+	}
+	if got, err := db.GetMaintenanceWindow("w1"); err != nil || got != nil {
+		t.Fatalf("expected deleted window to be gone, got %v, %v", got, err)
+	}
+}
@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const twoFactorColumns = `username, secret, recovery_hashes, enabled, last_counter, created_at, enabled_at`
+
+// ListTwoFactor returns all TOTP enrollments ordered by username.
+func (s *ScanDB) ListTwoFactor() ([]models.TwoFactor, error) {
+	rows, err := s.db.Query(`SELECT ` + twoFactorColumns + ` FROM two_factor ORDER BY username ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list two-factor enrollments: %w", err)
+	}
+	defer rows.Close()
+
+	enrollments := make([]models.TwoFactor, 0)
+	for rows.Next() {
+		enrollment, err := twoFactorFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		enrollments = append(enrollments, enrollment)
+	}
+	return enrollments, rows.Err()
+}
+
+// GetTwoFactor returns the TOTP enrollment of a user, or nil if there is none.
+func (s *ScanDB) GetTwoFactor(username string) (*models.TwoFactor, error) {
+	row := s.db.QueryRow(`SELECT `+twoFactorColumns+` FROM two_factor WHERE username = ?`, username)
+	enrollment, err := twoFactorFromRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// SaveTwoFactor inserts or replaces a TOTP enrollment.
+func (s *ScanDB) SaveTwoFactor(enrollment models.TwoFactor) error {
+	var recovery []byte
+	if len(enrollment.RecoveryHashes) > 0 {
+		var err error
+		if recovery, err = json.Marshal(enrollment.RecoveryHashes); err != nil {
+			return fmt.Errorf("encode recovery codes: %w", err)
+		}
+	}
+
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO two_factor (`+twoFactorColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		enrollment.Username,
+		enrollment.Secret,
+		string(recovery),
+		enrollment.Enabled,
+		enrollment.LastCounter,
+		enrollment.CreatedAt,
+		enrollment.EnabledAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save two-factor enrollment: %w", err)
+	}
+	return nil
+}
+
+// DeleteTwoFactor removes the TOTP enrollment of a user.
+// Returns false when the user has none.
+func (s *ScanDB) DeleteTwoFactor(username string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM two_factor WHERE username = ?`, username)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func twoFactorFromRow(row interface{ Scan(...any) error }) (models.TwoFactor, error) {
+	var enrollment models.TwoFactor
+	var recovery string
+	if err := row.Scan(
+		&enrollment.Username,
+		&enrollment.Secret,
+		&recovery,
+		&enrollment.Enabled,
+		&enrollment.LastCounter,
+		&enrollment.CreatedAt,
+		&enrollment.EnabledAt,
+	); err != nil {
+		return enrollment, err
+	}
+	if recovery != "" {
+		if err := json.Unmarshal([]byte(recovery), &enrollment.RecoveryHashes); err != nil {
+			return enrollment, fmt.Errorf("decode recovery codes of user %q: %w", enrollment.Username, err)
+		}
+	}
+	return enrollment, nil
+}
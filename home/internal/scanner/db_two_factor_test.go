@@ -0,0 +1,47 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestTwoFactorRoundTrip(t *testing.T) {
+	db := newTestScanDB(t)
+
+	enrollment := models.TwoFactor{
+		Username:       "alice",
+		Secret:         "JBSWY3DPEHPK3PXP",
+		RecoveryHashes: []string{"hash-1", "hash-2"},
+		Enabled:        true,
+		LastCounter:    55555,
+		CreatedAt:      100,
+		EnabledAt:      120,
+	}
+	if err := db.SaveTwoFactor(enrollment); err != nil {
+		t.Fatalf("SaveTwoFactor() error = %v", err)
+	}
+	if err := db.SaveTwoFactor(models.TwoFactor{Username: "bob", Secret: "KRSXG5CTMVRXEZLU", CreatedAt: 130}); err != nil {
+		t.Fatalf("SaveTwoFactor() error = %v", err)
+	}
+
+	got, err := db.GetTwoFactor("alice")
+	if err != nil || got == nil || !reflect.DeepEqual(*got, enrollment) {
+		t.Fatalf("GetTwoFactor() = %+v, %v", got, err)
+	}
+	if missing, err := db.GetTwoFactor("carol"); err != nil || missing != nil {
+		t.Fatalf("GetTwoFactor() of an unknown user = %+v, %v", missing, err)
+	}
+	all, err := db.ListTwoFactor()
+	if err != nil || len(all) != 2 || all[1].Username != "bob" || all[1].Enabled || all[1].RecoveryHashes != nil {
+		t.Fatalf("ListTwoFactor() = %+v, %v", all, err)
+	}
+
+	if ok, err := db.DeleteTwoFactor("alice"); err != nil || !ok {
+		t.Fatalf("DeleteTwoFactor() = %v, %v", ok, err)
+	}
+	if ok, err := db.DeleteTwoFactor("alice"); err != nil || ok {
+		t.Fatalf("DeleteTwoFactor() of a deleted enrollment = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,102 @@
+package scanner
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const userColumns = `username, password_hash, role, grants, created_at, updated_at`
+
+// migrateUsersTable adds columns introduced after the users table was first created.
+func (s *ScanDB) migrateUsersTable() error {
+	return s.addMissingColumns("users", []columnDef{
+		{name: "grants", ddl: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ListUsers returns all stored user accounts ordered by username.
+func (s *ScanDB) ListUsers() ([]models.UserAccount, error) {
+	rows, err := s.db.Query(`SELECT ` + userColumns + ` FROM users ORDER BY username ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]models.UserAccount, 0)
+	for rows.Next() {
+		user, err := userFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// GetUser returns a single user account, or nil if it does not exist.
+func (s *ScanDB) GetUser(username string) (*models.UserAccount, error) {
+	row := s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE username = ?`, username)
+	user, err := userFromRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SaveUser inserts or replaces a user account.
+func (s *ScanDB) SaveUser(user models.UserAccount) error {
+	var grants []byte
+	if len(user.Grants) > 0 {
+		var err error
+		if grants, err = json.Marshal(user.Grants); err != nil {
+			return fmt.Errorf("encode grants: %w", err)
+		}
+	}
+
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO users (`+userColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		user.Username,
+		user.PasswordHash,
+		user.Role,
+		string(grants),
+		user.CreatedAt,
+		user.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save user: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser removes a user account.
+// Returns false when no user with the given name exists.
+func (s *ScanDB) DeleteUser(username string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM users WHERE username = ?`, username)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func userFromRow(row interface{ Scan(...any) error }) (models.UserAccount, error) {
+	var user models.UserAccount
+	var grants string
+	if err := row.Scan(&user.Username, &user.PasswordHash, &user.Role, &grants, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return user, err
+	}
+	if grants != "" {
+		if err := json.Unmarshal([]byte(grants), &user.Grants); err != nil {
+			return user, fmt.Errorf("decode grants of user %q: %w", user.Username, err)
+		}
+	}
+	return user, nil
+}
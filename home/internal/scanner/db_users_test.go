@@ -0,0 +1,51 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestUsersRoundTrip(t *testing.T) {
+	db := newTestScanDB(t)
+
+	user := models.UserAccount{
+		Username:     "alice",
+		Role:         models.RoleOperator,
+		PasswordHash: "$2a$10$hash",
+		Grants:       []models.PermissionGrant{{Host: "prod", Label: "com.docker.compose.project=shop"}},
+		CreatedAt:    100,
+		UpdatedAt:    100,
+	}
+	if err := db.SaveUser(user); err != nil {
+		t.Fatalf("SaveUser() error = %v", err)
+	}
+	if err := db.SaveUser(models.UserAccount{Username: "bob", Role: models.RoleViewer, PasswordHash: "x"}); err != nil {
+		t.Fatalf("SaveUser() error = %v", err)
+	}
+
+	got, err := db.GetUser("alice")
+	if err != nil || got == nil || !reflect.DeepEqual(*got, user) {
+		t.Fatalf("GetUser() = %+v, %v", got, err)
+	}
+	if missing, err := db.GetUser("carol"); err != nil || missing != nil {
+		t.Fatalf("GetUser() of a missing user = %+v, %v", missing, err)
+	}
+
+	user.Role = models.RoleAdmin
+	if err := db.SaveUser(user); err != nil {
+		t.Fatalf("SaveUser() error = %v", err)
+	}
+	users, err := db.ListUsers()
+	if err != nil || len(users) != 2 || users[0].Role != models.RoleAdmin || users[1].Grants != nil {
+		t.Fatalf("ListUsers() = %+v, %v", users, err)
+	}
+
+	if ok, err := db.DeleteUser("alice"); err != nil || !ok {
+		t.Fatalf("DeleteUser() = %v, %v", ok, err)
+	}
+	if ok, err := db.DeleteUser("alice"); err != nil || ok {
+		t.Fatalf("second DeleteUser() = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,79 @@
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+const (
+	// maxWebhookDeliveries is how many delivery attempts are kept
+	maxWebhookDeliveries        = 1000
+	defaultWebhookDeliveryLimit = 100
+)
+
+// InsertWebhookDelivery records a webhook delivery attempt and drops the
+// oldest attempts beyond maxWebhookDeliveries.
+func (s *ScanDB) InsertWebhookDelivery(delivery models.WebhookDelivery) error {
+	_, err := s.db.Exec(`INSERT INTO webhook_deliveries (
+		delivery_id, channel, url, event, alert_id, attempt,
+		status_code, success, error, duration_ms, timestamp
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		delivery.DeliveryID,
+		delivery.Channel,
+		delivery.URL,
+		delivery.Event,
+		delivery.AlertID,
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Success,
+		delivery.Error,
+		delivery.DurationMs,
+		delivery.Timestamp,
+	)
+	if err != nil {
+		return fmt.Errorf("insert webhook delivery: %w", err)
+	}
+
+	_, err = s.db.Exec(`DELETE FROM webhook_deliveries WHERE id <= (SELECT MAX(id) FROM webhook_deliveries) - ?`, maxWebhookDeliveries)
+	if err != nil {
+		return fmt.Errorf("prune webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the most recent delivery attempts, newest
+// first, optionally limited to one channel.
+func (s *ScanDB) ListWebhookDeliveries(channel string, limit int) ([]models.WebhookDelivery, error) {
+	if limit < 1 || limit > maxWebhookDeliveries {
+		limit = defaultWebhookDeliveryLimit
+	}
+
+	query := `SELECT id, delivery_id, channel, url, event, alert_id, attempt,
+		status_code, success, error, duration_ms, timestamp
+		FROM webhook_deliveries`
+	var args []interface{}
+	if channel != "" {
+		query += ` WHERE channel = ?`
+		args = append(args, channel)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.DeliveryID, &d.Channel, &d.URL, &d.Event, &d.AlertID, &d.Attempt,
+			&d.StatusCode, &d.Success, &d.Error, &d.DurationMs, &d.Timestamp); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
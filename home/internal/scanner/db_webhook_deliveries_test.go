@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/hhftechnology/vps-monitor/internal/models"
+)
+
+func TestWebhookDeliveriesListNewestFirstByChannel(t *testing.T) {
+	db := newTestScanDB(t)
+
+	for i, delivery := range []models.WebhookDelivery{
+		{DeliveryID: "d1", Channel: "ops", Attempt: 1, StatusCode: 503, Error: "unavailable", Timestamp: 100},
+		{DeliveryID: "d1", Channel: "ops", Attempt: 2, StatusCode: 200, Success: true, Timestamp: 102},
+		{DeliveryID: "d2", Channel: "pager", Attempt: 1, StatusCode: 200, Success: true, Timestamp: 103},
+	} {
+		if err := db.InsertWebhookDelivery(delivery); err != nil {
+			t.Fatalf("InsertWebhookDelivery(%d) error = %v", i, err)
+		}
+	}
+
+	all, err := db.ListWebhookDeliveries("", 0)
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries() error = %v", err)
+	}
+	if len(all) != 3 || all[0].DeliveryID != "d2" {
+		t.Fatalf("expected 3 deliveries newest first, got %+v", all)
+	}
+
+	ops, err := db.ListWebhookDeliveries("ops", 0)
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries(ops) error = %v", err)
+	}
+	if len(ops) != 2 || ops[0].Attempt != 2 || !ops[0].Success || ops[1].Error != "unavailable" {
+		t.Fatalf("unexpected ops deliveries: %+v", ops)
+	}
+
+	limited, err := db.ListWebhookDeliveries("", 1)
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries(limit) error = %v", err)
+	}
+	if len(limited) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(limited))
+	}
+}
+
+func TestWebhookDeliveriesAreCapped(t *testing.T) {
+	db := newTestScanDB(t)
+
+	for i := 0; i < maxWebhookDeliveries+5; i++ {
+		if err := db.InsertWebhookDelivery(models.WebhookDelivery{DeliveryID: "d", Channel: "ops", Attempt: i + 1, Timestamp: int64(i)}); err != nil {
+			t.Fatalf("InsertWebhookDelivery() error = %v", err)
+		}
+	}
+
+	var count int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries`).Scan(&count); err != nil {
+		t.Fatalf("count deliveries: %v", err)
+	}
+	if count != maxWebhookDeliveries {
+		t.Fatalf("expected %d deliveries, got %d", maxWebhookDeliveries, count)
+	}
+}
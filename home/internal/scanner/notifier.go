@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/hhftechnology/vps-monitor/internal/metrics"
 	"github.com/hhftechnology/vps-monitor/internal/models"
 )
 
@@ -42,7 +43,7 @@ func (n *Notifier) SendDiscord(webhookURL string, result *models.ScanResult, bul
 		return nil
 	}
 
-	return n.sendWebhook(webhookURL, payload)
+	return n.sendWebhook("discord", webhookURL, payload)
 }
 
 // SendSlack sends a scan result notification to a Slack webhook.
@@ -57,7 +58,7 @@ func (n *Notifier) SendSlack(webhookURL string, result *models.ScanResult, bulkJ
 		return nil
 	}
 
-	return n.sendWebhook(webhookURL, payload)
+	return n.sendWebhook("slack", webhookURL, payload)
 }
 
 // SendTestNotification sends a test notification to verify webhook configuration.
@@ -257,7 +258,7 @@ func (n *Notifier) SendDiscordAnomaly(webhookURL string, result *models.ScanResu
 		},
 	}
 
-	return n.sendWebhook(webhookURL, payload)
+	return n.sendWebhook("discord", webhookURL, payload)
 }
 
 // SendSlackAnomaly sends an anomaly notification to Slack.
@@ -291,10 +292,20 @@ func (n *Notifier) SendSlackAnomaly(webhookURL string, result *models.ScanResult
 		},
 	}
 
-	return n.sendWebhook(webhookURL, payload)
+	return n.sendWebhook("slack", webhookURL, payload)
 }
 
-func (n *Notifier) sendWebhook(webhookURL string, payload map[string]interface{}) error {
+// sendWebhook posts payload to a Discord or Slack webhook, counting
+// failures by channel type
+func (n *Notifier) sendWebhook(channel, webhookURL string, payload map[string]interface{}) error {
+	err := n.postWebhook(webhookURL, payload)
+	if err != nil {
+		metrics.WebhookFailures.Inc("scanner", channel)
+	}
+	return err
+}
+
+func (n *Notifier) postWebhook(webhookURL string, payload map[string]interface{}) error {
 	if err := validateWebhookURL(webhookURL); err != nil {
 		return err
 	}
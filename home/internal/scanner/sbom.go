@@ -35,17 +35,25 @@ func (s *ScannerService) StartSBOMGeneration(imageRef, host string, format model
 	}
 
 	s.mu.Lock()
+	if s.stopCtx.Err() != nil {
+		s.mu.Unlock()
+		return nil, ErrShuttingDown
+	}
 	s.sbomJobs[job.ID] = job
+	s.workers.Add(1)
 	s.mu.Unlock()
 
-	go s.runSBOMGeneration(job)
+	go func() {
+		defer s.workers.Done()
+		s.runSBOMGeneration(job)
+	}()
 
 	return job, nil
 }
 
 func (s *ScannerService) runSBOMGeneration(job *models.SBOMJob) {
 	cfg := s.Config()
-	ctx, cancel := context.WithTimeout(context.Background(), s.scanTimeout())
+	ctx, cancel := context.WithTimeout(s.stopCtx, s.scanTimeout())
 	defer cancel()
 
 	dockerClient, release := s.registry.AcquireDocker()
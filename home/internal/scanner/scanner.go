@@ -13,12 +13,16 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hhftechnology/vps-monitor/internal/metrics"
 	"github.com/hhftechnology/vps-monitor/internal/models"
 	"github.com/hhftechnology/vps-monitor/internal/services"
 )
 
 const maxConcurrentScansPerHost = 3
 
+// ErrShuttingDown is returned for scans requested after Shutdown
+var ErrShuttingDown = errors.New("scanner is shutting down")
+
 // ScannerService orchestrates vulnerability scanning across Docker hosts.
 type ScannerService struct {
 	registry *services.Registry
@@ -31,6 +35,13 @@ type ScannerService struct {
 	bulkJobs map[string]*bulkScanState
 	sbomJobs map[string]*models.SBOMJob
 	cancels  map[string]context.CancelFunc
+
+	// stopCtx is the parent of every scan context; Shutdown cancels it and
+	// waits for the workers, whose deferred cleanup removes the scanner
+	// containers
+	stopCtx context.Context
+	stop    context.CancelFunc
+	workers sync.WaitGroup
 }
 
 type bulkScanState struct {
@@ -49,6 +60,7 @@ func NewScannerService(registry *services.Registry, cfg *models.ScannerConfig, d
 		sbomJobs: make(map[string]*models.SBOMJob),
 		cancels:  make(map[string]context.CancelFunc),
 	}
+	s.stopCtx, s.stop = context.WithCancel(context.Background())
 	s.config.Store(cfg)
 	s.sweepOrphanSBOMs()
 
@@ -100,7 +112,13 @@ func (s *ScannerService) sweepOrphanSBOMs() {
 
 func (s *ScannerService) gcWorker() {
 	ticker := time.NewTicker(1 * time.Hour)
-	for range ticker.C {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCtx.Done():
+			return
+		case <-ticker.C:
+		}
 		now := time.Now().Unix()
 		s.mu.Lock()
 		for id, job := range s.jobs {
@@ -160,14 +178,23 @@ func (s *ScannerService) StartScan(imageRef, host string, scannerType models.Sca
 		CreatedAt: time.Now().Unix(),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), s.scanTimeout())
+	ctx, cancel := context.WithTimeout(s.stopCtx, s.scanTimeout())
 
 	s.mu.Lock()
+	if s.stopCtx.Err() != nil {
+		s.mu.Unlock()
+		cancel()
+		return nil, ErrShuttingDown
+	}
 	s.jobs[job.ID] = job
 	s.cancels[job.ID] = cancel
+	s.workers.Add(1)
 	s.mu.Unlock()
 
-	go s.runScan(ctx, job, cancel)
+	go func() {
+		defer s.workers.Done()
+		s.runScan(ctx, job, cancel)
+	}()
 
 	return job, nil
 }
@@ -268,14 +295,23 @@ func (s *ScannerService) StartBulkScan(scannerType models.ScannerType, hosts []s
 		return bulkJob, nil
 	}
 
-	bulkCtx, bulkCancel := context.WithTimeout(context.Background(), s.bulkTimeout())
+	bulkCtx, bulkCancel := context.WithTimeout(s.stopCtx, s.bulkTimeout())
 
 	s.mu.Lock()
+	if s.stopCtx.Err() != nil {
+		s.mu.Unlock()
+		bulkCancel()
+		return nil, ErrShuttingDown
+	}
 	s.bulkJobs[bulkJob.ID] = &bulkScanState{job: bulkJob, cancel: bulkCancel}
 	s.cancels[bulkJob.ID] = bulkCancel
+	s.workers.Add(1)
 	s.mu.Unlock()
 
-	go s.runBulkScan(bulkCtx, bulkJob, bulkCancel)
+	go func() {
+		defer s.workers.Done()
+		s.runBulkScan(bulkCtx, bulkJob, bulkCancel)
+	}()
 
 	return bulkJob, nil
 }
@@ -372,6 +408,27 @@ func (s *ScannerService) CancelJob(id string) bool {
 	return false
 }
 
+// Shutdown cancels every running scan and SBOM generation and waits until
+// their scanner containers are removed or ctx ends. Scans requested
+// afterwards fail with ErrShuttingDown.
+func (s *ScannerService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.stop()
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for running scans: %w", ctx.Err())
+	}
+}
+
 // GetSBOMJob returns an SBOM job by ID.
 func (s *ScannerService) GetSBOMJob(id string) *models.SBOMJob {
 	s.mu.RLock()
@@ -438,9 +495,12 @@ func (s *ScannerService) runScan(ctx context.Context, job *models.ScanJob, cance
 
 	if err != nil {
 		status, message := classifyScanFailure(ctx.Err(), err)
+		metrics.ScansTotal.Inc(string(job.Scanner), string(status))
 		s.updateJobStatus(job, status, message)
 		return
 	}
+	metrics.ScansTotal.Inc(string(job.Scanner), string(models.ScanJobComplete))
+	metrics.ScanDuration.Observe(completedAt.Sub(startedAt).Seconds(), string(job.Scanner))
 
 	summary := computeSummary(vulns)
 	result := models.ScanResult{
@@ -530,10 +590,16 @@ func (s *ScannerService) runBulkScan(ctx context.Context, bulkJob *models.BulkSc
 	wg.Wait()
 
 	s.mu.Lock()
-	if bulkJob.Status != models.ScanJobCancelled {
+	stopping := s.stopCtx.Err() != nil
+	if stopping {
+		bulkJob.Status = models.ScanJobCancelled
+	} else if bulkJob.Status != models.ScanJobCancelled {
 		bulkJob.Status = models.ScanJobComplete
 	}
 	s.mu.Unlock()
+	if stopping {
+		return
+	}
 
 	// Send bulk notification
 	cfg := s.Config()
@@ -459,3 +459,55 @@ func TestClassifyScanFailureNoContextUsesScanError(t *testing.T) {
 		t.Fatalf("expected scanner error message, got %q", msg)
 	}
 }
+
+// ─── Shutdown ─────────────────────────────────────────────────────────────────
+
+func TestShutdownCancelsAndWaitsForWorkers(t *testing.T) {
+	s := newTestScannerService(&models.ScannerConfig{})
+	s.stopCtx, s.stop = context.WithCancel(context.Background())
+
+	// A worker that ends once its scan context is cancelled, like a scan
+	// removing its container
+	ctx, cancel := context.WithCancel(s.stopCtx)
+	defer cancel()
+	var cleanedUp atomic.Bool
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		cleanedUp.Store(true)
+	}()
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if !cleanedUp.Load() {
+		t.Fatal("expected Shutdown to wait for the worker")
+	}
+	if _, err := s.StartScan("alpine:3", "local", models.ScannerGrype); !errors.Is(err, ErrShuttingDown) {
+		t.Fatalf("expected ErrShuttingDown after Shutdown, got %v", err)
+	}
+	if _, err := s.StartSBOMGeneration("alpine:3", "local", models.SBOMFormatSPDX); !errors.Is(err, ErrShuttingDown) {
+		t.Fatalf("expected ErrShuttingDown for SBOM generation after Shutdown, got %v", err)
+	}
+}
+
+func TestShutdownGivesUpWhenContextEnds(t *testing.T) {
+	s := newTestScannerService(&models.ScannerConfig{})
+	s.stopCtx, s.stop = context.WithCancel(context.Background())
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		<-stuck
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the drain timeout, got %v", err)
+	}
+}
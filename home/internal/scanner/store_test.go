@@ -118,3 +118,27 @@ func TestScanResultStoreDBAccessor(t *testing.T) {
 		t.Fatal("expected non-nil DB accessor")
 	}
 }
+
+func TestLatestScanSummaries(t *testing.T) {
+	store := newTestScanResultStore(t)
+	for _, result := range []models.ScanResult{
+		{ID: "old", ImageRef: "nginx:latest", Host: "local", Scanner: models.ScannerGrype, CompletedAt: 100, Summary: models.SeveritySummary{Total: 5, Critical: 5}},
+		{ID: "new", ImageRef: "nginx:latest", Host: "local", Scanner: models.ScannerTrivy, CompletedAt: 200, Summary: models.SeveritySummary{Total: 1, High: 1}},
+		{ID: "remote", ImageRef: "nginx:latest", Host: "remote", Scanner: models.ScannerGrype, CompletedAt: 50, Summary: models.SeveritySummary{Total: 2, Low: 2}},
+	} {
+		if err := store.Add(result); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	summaries, err := store.DB().LatestScanSummaries()
+	if err != nil {
+		t.Fatalf("LatestScanSummaries() error = %v", err)
+	}
+	if len(summaries) != 2 || summaries[0].ID != "new" || summaries[1].ID != "remote" {
+		t.Fatalf("expected the newest result per image and host, got %+v", summaries)
+	}
+	if summaries[0].Summary.High != 1 || summaries[0].Scanner != models.ScannerTrivy || summaries[0].Vulnerabilities != nil {
+		t.Fatalf("unexpected summary: %+v", summaries[0])
+	}
+}
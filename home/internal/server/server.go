@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+)
+
+// Server serves the API and UI on the configured TCP addresses, over TLS
+// when a certificate is configured, and on an optional Unix socket
+type Server struct {
+	cfg   config.ServerConfig
+	http  *http.Server
+	certs *certReloader // nil without TLS
+
+	// base is the parent context of every request; it is cancelled once
+	// the server has drained, which ends the WebSocket streams
+	base       context.Context
+	cancelBase context.CancelFunc
+}
+
+// New creates a server for handler. It fails when cfg is incomplete or the
+// TLS files cannot be loaded.
+func New(cfg config.ServerConfig, handler http.Handler) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	base, cancelBase := context.WithCancel(context.Background())
+	s := &Server{
+		cfg:        cfg,
+		base:       base,
+		cancelBase: cancelBase,
+	}
+	s.http = &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return s.base },
+	}
+	if cfg.TLSEnabled() {
+		certs, err := newCertReloader(cfg)
+		if err != nil {
+			return nil, err
+		}
+		s.certs = certs
+	}
+	return s, nil
+}
+
+// ListenAndServe opens every listener and serves until one of them fails
+// or Shutdown is called, in which case it returns nil
+func (s *Server) ListenAndServe() error {
+	listeners, err := s.listen()
+	if err != nil {
+		return err
+	}
+	defer s.removeSocket()
+
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func() {
+			errs <- s.http.Serve(l)
+		}()
+	}
+	err = <-errs
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	_ = s.http.Close()
+	return err
+}
+
+// Shutdown stops accepting connections and waits for the requests in
+// flight to finish. WebSocket connections are not tracked by net/http, so
+// they are told to close once the other requests are done. When ctx ends
+// first the remaining connections are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.http.Shutdown(ctx)
+	s.cancelBase()
+	if err != nil {
+		_ = s.http.Close()
+	}
+	return err
+}
+
+// listen opens the TCP listeners and the Unix socket, closing the ones
+// already open when one fails
+func (s *Server) listen() ([]net.Listener, error) {
+	var listeners []net.Listener
+	fail := func(err error) ([]net.Listener, error) {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+		return nil, err
+	}
+
+	scheme := "http"
+	if s.certs != nil {
+		scheme = "https"
+	}
+	for _, addr := range s.cfg.Listen {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fail(fmt.Errorf("listen on %s: %w", addr, err))
+		}
+		if s.certs != nil {
+			l = tls.NewListener(l, s.certs.tlsConfig())
+		}
+		listeners = append(listeners, l)
+		log.Printf("Server listening on %s://%s", scheme, addr)
+	}
+	if s.certs != nil && s.cfg.TLSClientCAFile != "" {
+		log.Printf("   Client certificates signed by %s are required", s.cfg.TLSClientCAFile)
+	}
+
+	if s.cfg.UnixSocket != "" {
+		l, err := listenUnix(s.cfg.UnixSocket, s.cfg.UnixSocketMode)
+		if err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, l)
+		log.Printf("Server listening on unix:%s", s.cfg.UnixSocket)
+	}
+	return listeners, nil
+}
+
+// listenUnix opens a Unix socket at path, replacing a socket left behind
+// by an earlier run, and sets its permissions
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("listen on unix:%s: file exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove old socket %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("listen on unix:%s: %w", path, err)
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on unix:%s: %w", path, err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = l.Close()
+		return nil, fmt.Errorf("set permissions of %s: %w", path, err)
+	}
+	return l, nil
+}
+
+func (s *Server) removeSocket() {
+	if s.cfg.UnixSocket == "" {
+		return
+	}
+	if err := os.Remove(s.cfg.UnixSocket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Failed to remove socket %s: %v", s.cfg.UnixSocket, err)
+	}
+}
@@ -0,0 +1,323 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert creates a certificate for commonName, signed by parent or
+// self-signed as a CA when parent is nil
+func newTestCert(t *testing.T, commonName string, parent *testCert) testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	return testCert{cert: cert, key: key}
+}
+
+// write stores the certificate and key as PEM files in dir
+func (c testCert) write(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (c testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+// serve starts s on its listeners and returns their addresses
+func serve(t *testing.T, s *Server) []net.Addr {
+	t.Helper()
+	listeners, err := s.listen()
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+	addrs := make([]net.Addr, 0, len(listeners))
+	for _, l := range listeners {
+		addrs = append(addrs, l.Addr())
+		go func() { _ = s.http.Serve(l) }()
+	}
+	t.Cleanup(func() { _ = s.http.Close() })
+	return addrs
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+})
+
+// peerName connects to addr and returns the common name of the server
+// certificate
+func peerName(t *testing.T, addr string, clientConfig *tls.Config) (string, error) {
+	t.Helper()
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	return resp.TLS.PeerCertificates[0].Subject.CommonName, nil
+}
+
+func TestServeTLSAndReloadCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test CA", nil)
+	certFile, keyFile := newTestCert(t, "first", &ca).write(t, dir, "server")
+
+	s, err := New(config.ServerConfig{Listen: []string{"127.0.0.1:0"}, TLSCertFile: certFile, TLSKeyFile: keyFile}, okHandler)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	addr := serve(t, s)[0].String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	if name, err := peerName(t, addr, &tls.Config{RootCAs: roots}); err != nil || name != "first" {
+		t.Fatalf("expected the first certificate, got %q, %v", name, err)
+	}
+
+	// A renewed certificate is served once the files are checked again
+	newTestCert(t, "renewed", &ca).write(t, dir, "server")
+	later := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.certs.mu.Lock()
+	s.certs.lastCheck = time.Time{}
+	s.certs.mu.Unlock()
+	if name, err := peerName(t, addr, &tls.Config{RootCAs: roots}); err != nil || name != "renewed" {
+		t.Fatalf("expected the renewed certificate, got %q, %v", name, err)
+	}
+
+	// A broken renewal keeps the current certificate
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s.certs.mu.Lock()
+	s.certs.lastCheck = time.Time{}
+	s.certs.mu.Unlock()
+	if name, err := peerName(t, addr, &tls.Config{RootCAs: roots}); err != nil || name != "renewed" {
+		t.Fatalf("expected the renewed certificate to stay, got %q, %v", name, err)
+	}
+}
+
+func TestServeMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test CA", nil)
+	certFile, keyFile := newTestCert(t, "server", &ca).write(t, dir, "server")
+	caFile, _ := ca.write(t, dir, "ca")
+
+	s, err := New(config.ServerConfig{
+		Listen:          []string{"127.0.0.1:0"},
+		TLSCertFile:     certFile,
+		TLSKeyFile:      keyFile,
+		TLSClientCAFile: caFile,
+	}, okHandler)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	addr := serve(t, s)[0].String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	if _, err := peerName(t, addr, &tls.Config{RootCAs: roots}); err == nil {
+		t.Fatal("expected a client without a certificate to be refused")
+	}
+
+	stranger := newTestCert(t, "stranger", nil)
+	if _, err := peerName(t, addr, &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{stranger.tlsCertificate()}}); err == nil {
+		t.Fatal("expected a client certificate from another CA to be refused")
+	}
+
+	client := newTestCert(t, "client", &ca)
+	if _, err := peerName(t, addr, &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{client.tlsCertificate()}}); err != nil {
+		t.Fatalf("expected a client certificate from the CA to be accepted, got %v", err)
+	}
+}
+
+func TestServeUnixSocket(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "vps-monitor.sock")
+
+	// A socket left behind by an earlier run is replaced
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	s, err := New(config.ServerConfig{UnixSocket: socket, UnixSocketMode: 0o600}, okHandler)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	serve(t, s)
+
+	info, err := os.Stat(socket)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected socket permissions 0600, got %v, %v", info, err)
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://vps-monitor/")
+	if err != nil {
+		t.Fatalf("request over the socket failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+
+	// Other files are never removed
+	file := filepath.Join(dir, "data.json")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, _ = New(config.ServerConfig{UnixSocket: file, UnixSocketMode: 0o600}, okHandler)
+	if _, err := s.listen(); err == nil {
+		t.Fatal("expected a regular file to be refused as socket")
+	}
+}
+
+func TestNewRejectsIncompleteConfig(t *testing.T) {
+	for _, cfg := range []config.ServerConfig{
+		{},
+		{Listen: []string{":6789"}, TLSCertFile: "server.crt"},
+		{Listen: []string{":6789"}, TLSClientCAFile: "ca.crt"},
+		{Listen: []string{":6789"}, TLSCertFile: "missing.crt", TLSKeyFile: "missing.key"},
+	} {
+		if _, err := New(cfg, okHandler); err == nil {
+			t.Fatalf("expected New(%+v) to fail", cfg)
+		}
+	}
+}
+
+func TestShutdownDrainsRequests(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "vps-monitor.sock")
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s, err := New(config.ServerConfig{UnixSocket: socket, UnixSocketMode: 0o600}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.ListenAndServe() }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	var resp *http.Response
+	requested := make(chan error, 1)
+	go func() {
+		var err error
+		for range 50 {
+			if resp, err = client.Get("http://vps-monitor/"); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		requested <- err
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+	select {
+	case err := <-shutdown:
+		t.Fatalf("expected Shutdown to wait for the request, got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-requested; err != nil {
+		t.Fatalf("request in flight failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("expected ListenAndServe to return nil after Shutdown, got %v", err)
+	}
+	if s.base.Err() == nil {
+		t.Fatal("expected the request base context to be cancelled")
+	}
+	if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the socket to be removed, got %v", err)
+	}
+}
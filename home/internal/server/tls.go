@@ -0,0 +1,129 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hhftechnology/vps-monitor/internal/config"
+)
+
+// certCheckInterval limits how often the TLS files are checked for changes
+const certCheckInterval = 10 * time.Second
+
+// certReloader serves the certificate and client CAs from the configured
+// files and loads them again when the files change, so renewed
+// certificates are picked up without a restart
+type certReloader struct {
+	mu        sync.Mutex
+	files     tlsFiles
+	modTimes  [3]time.Time
+	lastCheck time.Time
+	config    *tls.Config
+}
+
+type tlsFiles struct {
+	cert, key, clientCA string
+}
+
+// newCertReloader loads the TLS files of cfg. The paths are fixed for the
+// life of the server; only the contents of the files are reloaded.
+func newCertReloader(cfg config.ServerConfig) (*certReloader, error) {
+	files := tlsFiles{cert: cfg.TLSCertFile, key: cfg.TLSKeyFile, clientCA: cfg.TLSClientCAFile}
+	modTimes := fileModTimes(files)
+	tlsConfig, err := loadTLSConfig(files)
+	if err != nil {
+		return nil, err
+	}
+	return &certReloader{
+		files:     files,
+		modTimes:  modTimes,
+		lastCheck: time.Now(),
+		config:    tlsConfig,
+	}, nil
+}
+
+// tlsConfig is the base configuration of the TLS listeners. Each handshake
+// gets the current certificate and client CAs through GetConfigForClient.
+func (c *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return c.current(), nil
+		},
+	}
+}
+
+// current returns the configuration for a handshake, reloading the files
+// first if they changed since the last check
+func (c *certReloader) current() *tls.Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.lastCheck) < certCheckInterval {
+		return c.config
+	}
+	c.lastCheck = time.Now()
+
+	modTimes := fileModTimes(c.files)
+	if modTimes == c.modTimes {
+		return c.config
+	}
+	tlsConfig, err := loadTLSConfig(c.files)
+	if err != nil {
+		// Renewals may write the certificate and key one after the other;
+		// the next check retries
+		log.Printf("Failed to reload TLS certificate, keeping the current one: %v", err)
+		return c.config
+	}
+	c.modTimes = modTimes
+	c.config = tlsConfig
+	log.Printf("Reloaded TLS certificate from %s", c.files.cert)
+	return c.config
+}
+
+func loadTLSConfig(files tlsFiles) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(files.cert, files.key)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		// The terminal, stats and alert streams are WebSockets, which
+		// gorilla/websocket only upgrades over HTTP/1.1
+		NextProtos: []string{"http/1.1"},
+	}
+	if files.clientCA == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(files.clientCA)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("client CA file contains no PEM certificates")
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	return tlsConfig, nil
+}
+
+func fileModTimes(files tlsFiles) [3]time.Time {
+	var modTimes [3]time.Time
+	for i, path := range []string{files.cert, files.key, files.clientCA} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			modTimes[i] = info.ModTime()
+		}
+	}
+	return modTimes
+}
@@ -0,0 +1,132 @@
+package system
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// HostMetrics holds the host measurements evaluated by host alerts
+type HostMetrics struct {
+	Hostname      string
+	CPUPercent    float64
+	CPUCount      int
+	MemoryPercent float64
+	Load1         float64
+	Load5         float64
+	Load15        float64
+	Disks         []DiskUsage
+}
+
+// DiskUsage describes one mounted filesystem
+type DiskUsage struct {
+	Mountpoint    string
+	Device        string
+	Fstype        string
+	Percent       float64
+	InodesPercent float64
+	Total         uint64
+	Used          uint64
+}
+
+// Filesystems that report usage but never fill up in a meaningful way
+var ignoredFstypes = []string{"squashfs", "iso9660", "udf"}
+
+// GetHostMetrics collects CPU, memory, load and per-mount disk usage.
+// Disk and load failures are tolerated so a single broken mount does not
+// hide the remaining metrics.
+func GetHostMetrics(ctx context.Context) (*HostMetrics, error) {
+	hInfo, err := host.InfoWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vMem, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cpuPercents, err := cpu.PercentWithContext(ctx, 0, false)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &HostMetrics{
+		Hostname:      hInfo.Hostname,
+		MemoryPercent: vMem.UsedPercent,
+	}
+	if len(cpuPercents) > 0 {
+		metrics.CPUPercent = cpuPercents[0]
+	}
+	if count, err := cpu.CountsWithContext(ctx, true); err == nil {
+		metrics.CPUCount = count
+	}
+	if avg, err := load.AvgWithContext(ctx); err == nil {
+		metrics.Load1 = avg.Load1
+		metrics.Load5 = avg.Load5
+		metrics.Load15 = avg.Load15
+	}
+
+	metrics.Disks = diskUsages(ctx)
+	return metrics, nil
+}
+
+// diskUsages returns usage for every physical mount. When running in a
+// container the host filesystem is read through /host; mounts that are not
+// reachable from there are skipped. The root filesystem is always included.
+func diskUsages(ctx context.Context) []DiskUsage {
+	prefix := ""
+	if _, err := os.Stat("/host"); err == nil {
+		prefix = "/host"
+	}
+
+	var usages []DiskUsage
+	seenDevices := make(map[string]bool)
+	hasRoot := false
+
+	partitions, _ := disk.PartitionsWithContext(ctx, false)
+	for _, partition := range partitions {
+		if slices.Contains(ignoredFstypes, partition.Fstype) || seenDevices[partition.Device] {
+			continue
+		}
+		usage, err := disk.UsageWithContext(ctx, filepath.Join(prefix, partition.Mountpoint))
+		if err != nil || usage.Total == 0 {
+			continue
+		}
+		// Bind mounts show the same device more than once
+		seenDevices[partition.Device] = true
+		hasRoot = hasRoot || partition.Mountpoint == "/"
+		usages = append(usages, diskUsage(partition, usage))
+	}
+
+	if !hasRoot {
+		rootPath := "/"
+		if prefix != "" {
+			rootPath = prefix
+		}
+		if usage, err := disk.UsageWithContext(ctx, rootPath); err == nil && usage.Total > 0 {
+			usages = append(usages, diskUsage(disk.PartitionStat{Mountpoint: "/", Fstype: usage.Fstype}, usage))
+		}
+	}
+
+	return usages
+}
+
+func diskUsage(partition disk.PartitionStat, usage *disk.UsageStat) DiskUsage {
+	return DiskUsage{
+		Mountpoint:    partition.Mountpoint,
+		Device:        partition.Device,
+		Fstype:        partition.Fstype,
+		Percent:       usage.UsedPercent,
+		InodesPercent: usage.InodesUsedPercent,
+		Total:         usage.Total,
+		Used:          usage.Used,
+	}
+}